@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+)
+
+// Behaviors manages the bridge's own automations; implemented by *bridge.Home.
+type Behaviors interface {
+	GetBehaviors(ctx context.Context) ([]bridge.BehaviorInstance, error)
+	CreateBehavior(ctx context.Context, b bridge.BehaviorInstance) (string, error)
+	UpdateBehavior(ctx context.Context, id string, u bridge.BehaviorUpdate) error
+	DeleteBehavior(ctx context.Context, id string) error
+}
+
+// BehaviorsHandler serves GET /api/behaviors: the bridge's behavior instances.
+func BehaviorsHandler(b Behaviors) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list, err := b.GetBehaviors(r.Context())
+		if err != nil {
+			WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, list)
+	})
+}
+
+// CreateBehaviorHandler serves POST /api/behaviors with a behavior instance
+// (script_id, enabled, metadata, configuration) and answers {"id": "..."}.
+func CreateBehaviorHandler(b Behaviors) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in bridge.BehaviorInstance
+		if err := decodeBody(r, &in); err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if in.ScriptID == "" || in.Metadata.Name == "" || len(in.Configuration) == 0 {
+			WriteError(w, http.StatusBadRequest, errors.New("script_id, metadata.name and configuration are required"))
+			return
+		}
+		id, err := b.CreateBehavior(r.Context(), in)
+		if err != nil {
+			WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+		WriteJSON(w, http.StatusCreated, map[string]string{"id": id})
+	})
+}
+
+// UpdateBehaviorHandler serves PUT /api/behaviors/{id}; fields left out of the
+// body (enabled, metadata, configuration) are not changed.
+func UpdateBehaviorHandler(b Behaviors) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u bridge.BehaviorUpdate
+		if err := decodeBody(r, &u); err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if u.Enabled == nil && u.Metadata == nil && len(u.Configuration) == 0 {
+			WriteError(w, http.StatusBadRequest, errors.New("nothing to update: set enabled, metadata or configuration"))
+			return
+		}
+		if err := b.UpdateBehavior(r.Context(), r.PathValue("id"), u); err != nil {
+			WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// DeleteBehaviorHandler serves DELETE /api/behaviors/{id}.
+func DeleteBehaviorHandler(b Behaviors) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := b.DeleteBehavior(r.Context(), r.PathValue("id")); err != nil {
+			WriteError(w, http.StatusBadGateway, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// decodeBody decodes a JSON request body of at most maxRawBody bytes into v.
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxRawBody))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+)
+
+type fakeBehaviors struct {
+	created []bridge.BehaviorInstance
+	updated map[string]bridge.BehaviorUpdate
+	deleted []string
+}
+
+func (f *fakeBehaviors) GetBehaviors(context.Context) ([]bridge.BehaviorInstance, error) {
+	return []bridge.BehaviorInstance{{ID: "b1", ScriptID: "s1", Metadata: bridge.BehaviorMetadata{Name: "Hall"}}}, nil
+}
+
+func (f *fakeBehaviors) CreateBehavior(_ context.Context, b bridge.BehaviorInstance) (string, error) {
+	f.created = append(f.created, b)
+	return "b2", nil
+}
+
+func (f *fakeBehaviors) UpdateBehavior(_ context.Context, id string, u bridge.BehaviorUpdate) error {
+	f.updated[id] = u
+	return nil
+}
+
+func (f *fakeBehaviors) DeleteBehavior(_ context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func TestBehaviorHandlers(t *testing.T) {
+	b := &fakeBehaviors{updated: make(map[string]bridge.BehaviorUpdate)}
+	srv, err := New(Config{Addr: ":0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("GET /api/behaviors", BehaviorsHandler(b))
+	srv.Handle("POST /api/behaviors", CreateBehaviorHandler(b))
+	srv.Handle("PUT /api/behaviors/{id}", UpdateBehaviorHandler(b))
+	srv.Handle("DELETE /api/behaviors/{id}", DeleteBehaviorHandler(b))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string // substring of the response body
+	}{
+		{"list", http.MethodGet, "/api/behaviors", "", http.StatusOK, `"name":"Hall"`},
+		{"create", http.MethodPost, "/api/behaviors", `{"script_id": "s1", "enabled": true, "metadata": {"name": "Hall"}, "configuration": {"where": []}}`, http.StatusCreated, `{"id":"b2"}`},
+		{"create without configuration", http.MethodPost, "/api/behaviors", `{"script_id": "s1", "metadata": {"name": "Hall"}}`, http.StatusBadRequest, "required"},
+		{"create with unknown field", http.MethodPost, "/api/behaviors", `{"script": "s1"}`, http.StatusBadRequest, "unknown field"},
+		{"disable", http.MethodPut, "/api/behaviors/b1", `{"enabled": false}`, http.StatusNoContent, ""},
+		{"empty update", http.MethodPut, "/api/behaviors/b1", `{}`, http.StatusBadRequest, "nothing to update"},
+		{"delete", http.MethodDelete, "/api/behaviors/b1", "", http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: %d %s, want %d containing %q", tt.name, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+	if len(b.created) != 1 || string(b.created[0].Configuration) != `{"where": []}` {
+		t.Errorf("created %+v", b.created)
+	}
+	if u, ok := b.updated["b1"]; !ok || u.Enabled == nil || *u.Enabled {
+		t.Errorf("updated %+v", b.updated)
+	}
+	if len(b.deleted) != 1 || b.deleted[0] != "b1" {
+		t.Errorf("deleted %v", b.deleted)
+	}
+}
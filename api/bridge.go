@@ -0,0 +1,15 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+)
+
+// BridgeStatsHandler serves GET /api/bridge/stats: request counts, errors and
+// latencies per bridge endpoint.
+func BridgeStatsHandler(home *bridge.Home) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, home.Stats())
+	})
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/hue"
+)
+
+// ErrorsHandler serves GET /api/errors: recent command failures and the retained
+// last failure per resource.
+func ErrorsHandler(failures *hue.Failures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, struct {
+			Recent []hue.Failure          `json:"recent"`
+			Last   map[string]hue.Failure `json:"last"`
+		}{
+			Recent: failures.Recent(),
+			Last:   failures.Last(),
+		})
+	})
+}
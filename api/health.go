@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+)
+
+// HealthHandler serves GET /api/health with the gateway state (including config
+// issues found at startup); it answers 503 while the bridge is offline or the
+// event stream keeps failing.
+func HealthHandler(state *gateway.State) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := state.Health()
+		status := http.StatusOK
+		if !h.BridgeOnline || !h.EventStream {
+			status = http.StatusServiceUnavailable
+		}
+		WriteJSON(w, status, h)
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+)
+
+func TestHealthHandler(t *testing.T) {
+	state := gateway.NewState(nil)
+	state.SetConfigIssues([]string{"names: abc not found on the bridge"})
+
+	rec := httptest.NewRecorder()
+	HealthHandler(state).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var h gateway.Health
+	if err := json.NewDecoder(rec.Body).Decode(&h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.ConfigIssues) != 1 {
+		t.Errorf("ConfigIssues = %v, want one issue", h.ConfigIssues)
+	}
+
+	state.SetEventStreamOK(false)
+	rec = httptest.NewRecorder()
+	HealthHandler(state).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("stream down status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	state.SetEventStreamOK(true)
+
+	state.SetBridgeOnline(false)
+	rec = httptest.NewRecorder()
+	HealthHandler(state).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("offline status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+)
+
+// HistoryHandler serves GET /api/history: the audited commands, filtered by the
+// since, until, target, source and limit query parameters (see
+// gateway.ParseAuditQuery), oldest first.
+func HistoryHandler(audit *gateway.Audit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := gateway.ParseAuditQuery(r.URL.Query().Get, time.Now())
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		recs := audit.History(q)
+		if recs == nil {
+			recs = []gateway.AuditRecord{}
+		}
+		WriteJSON(w, http.StatusOK, recs)
+	})
+}
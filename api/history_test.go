@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	audit, err := gateway.NewAudit(gateway.AuditConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(Config{Addr: ":0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("PUT /api/raw/{rtype}/{id}", RawHandler(audit.Track(&recordHandler{})))
+	srv.Handle("GET /api/history", HistoryHandler(audit))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", strings.NewReader(`{"on":{"on":false}}`))
+	req.RemoteAddr = "10.0.0.5:41000"
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	_ = audit.Track(&recordHandler{}).Apply(udp.WithSource(context.Background(), "failsafe"), udp.Command{Domain: "grouped_light", ID: "gl-1", Action: "on", Value: udp.BoolValue(false)})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/history?source=api:", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	var got []gateway.AuditRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Source != "api:10.0.0.5" || got[0].ID != "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab" {
+		t.Errorf("history = %+v, want the raw command from api:10.0.0.5", got)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/history?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// LogBuffer is a slog.Handler that passes records on to next and keeps the last
+// lines (in text format) for GET /api/logs and support bundles.
+type LogBuffer struct {
+	next slog.Handler
+	text slog.Handler // formats into buf under ring.mu
+	buf  *bytes.Buffer
+	ring *logRing
+}
+
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	pos   int
+	full  bool
+}
+
+// NewLogBuffer keeps the last size lines; next receives every record.
+func NewLogBuffer(size int, next slog.Handler) *LogBuffer {
+	if size <= 0 {
+		size = 1000
+	}
+	buf := new(bytes.Buffer)
+	return &LogBuffer{
+		next: next,
+		text: slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		buf:  buf,
+		ring: &logRing{lines: make([]string, size)},
+	}
+}
+
+func (b *LogBuffer) Enabled(ctx context.Context, level slog.Level) bool {
+	return b.next.Enabled(ctx, level)
+}
+
+func (b *LogBuffer) Handle(ctx context.Context, r slog.Record) error {
+	b.ring.mu.Lock()
+	b.buf.Reset()
+	if err := b.text.Handle(ctx, r); err == nil {
+		b.ring.lines[b.ring.pos] = b.buf.String()
+		b.ring.pos = (b.ring.pos + 1) % len(b.ring.lines)
+		b.ring.full = b.ring.full || b.ring.pos == 0
+	}
+	b.ring.mu.Unlock()
+	return b.next.Handle(ctx, r)
+}
+
+func (b *LogBuffer) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *b
+	c.next = b.next.WithAttrs(attrs)
+	c.text = b.text.WithAttrs(attrs)
+	return &c
+}
+
+func (b *LogBuffer) WithGroup(name string) slog.Handler {
+	c := *b
+	c.next = b.next.WithGroup(name)
+	c.text = b.text.WithGroup(name)
+	return &c
+}
+
+// Lines returns the buffered lines, oldest first.
+func (b *LogBuffer) Lines() []string {
+	r := b.ring
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.pos]...)
+	}
+	return append(append([]string(nil), r.lines[r.pos:]...), r.lines[:r.pos]...)
+}
+
+// LogsHandler serves GET /api/logs with the buffered log lines as text.
+func LogsHandler(b *LogBuffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, l := range b.Lines() {
+			_, _ = w.Write([]byte(l))
+		}
+	})
+}
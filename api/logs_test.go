@@ -0,0 +1,30 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogBuffer(t *testing.T) {
+	var out strings.Builder
+	buf := NewLogBuffer(3, slog.NewTextHandler(&out, nil))
+	logger := slog.New(buf).With("module", "test")
+	for i := range 5 {
+		logger.Info(fmt.Sprintf("line %d", i))
+	}
+
+	lines := buf.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("Lines() = %d lines, want 3", len(lines))
+	}
+	for i, want := range []string{"line 2", "line 3", "line 4"} {
+		if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "module=test") {
+			t.Errorf("Lines()[%d] = %q, want %q with module=test", i, lines[i], want)
+		}
+	}
+	if got := strings.Count(out.String(), "\n"); got != 5 {
+		t.Errorf("next handler got %d lines, want 5", got)
+	}
+}
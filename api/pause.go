@@ -0,0 +1,51 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+)
+
+// PausesHandler serves GET /api/pauses: the rooms and devices not forwarded.
+func PausesHandler(pauses *gateway.Pauses) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, pauses.List())
+	})
+}
+
+// PauseHandler serves PUT /api/pauses/{kind}/{name}[?for=30m].
+func PauseHandler(pauses *gateway.Pauses) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d time.Duration
+		if raw := r.URL.Query().Get("for"); raw != "" {
+			var err error
+			if d, err = time.ParseDuration(raw); err != nil || d <= 0 {
+				WriteError(w, http.StatusBadRequest, errors.New("for expects a positive duration, e.g. 30m"))
+				return
+			}
+		}
+		if err := pauses.Pause("/"+r.PathValue("kind")+"/"+r.PathValue("name"), d); err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// ResumeHandler serves DELETE /api/pauses/{kind}/{name}.
+func ResumeHandler(pauses *gateway.Pauses) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, err := gateway.ParseScope("/" + r.PathValue("kind") + "/" + r.PathValue("name"))
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := pauses.Resume(scope); err != nil {
+			WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/ha"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// maxRawBody bounds PUT /api/raw bodies; CLIP v2 updates are small.
+const maxRawBody = 64 << 10
+
+// RawHandler serves PUT /api/raw/{rtype}/{id}: the body is passed unchanged to the
+// bridge resource through handler (usually the command queue).
+func RawHandler(handler udp.CommandHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRawBody))
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		cmd, err := udp.NewRawCommand(r.PathValue("rtype"), r.PathValue("id"), body)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := handler.Apply(udp.WithSource(r.Context(), "api:"+remoteHost(r)), cmd); err != nil {
+			status := http.StatusBadGateway
+			switch {
+			case errors.Is(err, gateway.ErrReadOnly):
+				status = http.StatusForbidden
+			case errors.Is(err, ha.ErrStandby):
+				status = http.StatusServiceUnavailable
+			}
+			WriteError(w, status, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// remoteHost is the client address of r without the port.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type recordHandler struct{ got []udp.Command }
+
+func (r *recordHandler) Apply(_ context.Context, cmd udp.Command) error {
+	r.got = append(r.got, cmd)
+	return nil
+}
+
+func TestRawHandler(t *testing.T) {
+	h := &recordHandler{}
+	srv, err := New(Config{Addr: ":0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("PUT /api/raw/{rtype}/{id}", RawHandler(h))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", strings.NewReader(`{"on":{"on":true}}`)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d (%s)", rec.Code, http.StatusNoContent, rec.Body)
+	}
+	want := udp.Command{Domain: udp.DomainRaw, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Rtype: "light", Action: "put", Value: udp.RawValue(`{"on":{"on":true}}`)}
+	if len(h.got) != 1 || h.got[0] != want {
+		t.Errorf("applied %+v, want [%+v]", h.got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", strings.NewReader(`not json`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRawHandler_ReadOnly(t *testing.T) {
+	srv, err := New(Config{Addr: ":0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("PUT /api/raw/{rtype}/{id}", RawHandler(gateway.ReadOnly{}))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", strings.NewReader(`{"on":{"on":true}}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
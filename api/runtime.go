@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// RuntimeStats is a snapshot of the gateway process.
+type RuntimeStats struct {
+	Uptime     string  `json:"uptime"`
+	Goroutines int     `json:"goroutines"`
+	CPUSeconds float64 `json:"cpu_seconds"` // estimated by the Go runtime, since start
+	CPUPercent float64 `json:"cpu_percent"` // of one core, since the previous request
+	HeapBytes  uint64  `json:"heap_bytes"`
+	SysBytes   uint64  `json:"sys_bytes"` // obtained from the OS
+	GCCycles   uint32  `json:"gc_cycles"`
+	GCPauseMs  float64 `json:"gc_pause_ms"` // last pause
+}
+
+// RuntimeHandler serves GET /api/runtime with CPU, memory and GC figures, cheap
+// enough to poll on a Pi.
+func RuntimeHandler() http.Handler {
+	start := time.Now()
+	var (
+		mu       sync.Mutex
+		lastAt   = start
+		lastCPU  float64
+		cpuTotal = []metrics.Sample{{Name: "/cpu/classes/total:cpu-seconds"}, {Name: "/cpu/classes/idle:cpu-seconds"}}
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+
+		mu.Lock()
+		metrics.Read(cpuTotal)
+		// total includes the idle time of all GOMAXPROCS; what is left was spent
+		cpu := cpuTotal[0].Value.Float64() - cpuTotal[1].Value.Float64()
+		now := time.Now()
+		var percent float64
+		if d := now.Sub(lastAt).Seconds(); d > 0 {
+			percent = (cpu - lastCPU) / d * 100
+		}
+		lastAt, lastCPU = now, cpu
+		mu.Unlock()
+
+		WriteJSON(w, http.StatusOK, RuntimeStats{
+			Uptime:     now.Sub(start).Round(time.Second).String(),
+			Goroutines: runtime.NumGoroutine(),
+			CPUSeconds: cpu,
+			CPUPercent: percent,
+			HeapBytes:  ms.HeapAlloc,
+			SysBytes:   ms.Sys,
+			GCCycles:   ms.NumGC,
+			GCPauseMs:  float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e6,
+		})
+	})
+}
+
+// HandlePprof registers net/http/pprof under /debug/pprof/, e.g.
+// go tool pprof http://<gateway>:8080/debug/pprof/profile?seconds=30.
+// /debug/pprof/cmdline is left out: the command line carries the API keys.
+func HandlePprof(s *Server) {
+	s.HandleFunc("GET /debug/pprof/", pprof.Index)
+	s.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	s.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	s.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	s.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuntimeHandler(t *testing.T) {
+	h := RuntimeHandler()
+	for range 2 { // the second request measures CPU since the first
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/runtime", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+		var got RuntimeStats
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Goroutines == 0 || got.HeapBytes == 0 || got.SysBytes == 0 {
+			t.Errorf("stats = %+v, want goroutines and memory", got)
+		}
+		if got.CPUPercent < 0 {
+			t.Errorf("cpu_percent = %v", got.CPUPercent)
+		}
+	}
+}
+
+func TestHandlePprof(t *testing.T) {
+	s, err := New(Config{Addr: ":0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	HandlePprof(s)
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/goroutine?debug=1"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d", path, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", nil))
+	if rec.Code == http.StatusOK {
+		t.Errorf("GET /debug/pprof/cmdline = %d, want it not served", rec.Code)
+	}
+}
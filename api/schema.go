@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+// SchemaHandler serves GET /api/schema: the message paths the gateway can emit.
+func SchemaHandler(specs []client.PathSpec) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, specs)
+	})
+}
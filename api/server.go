@@ -0,0 +1,91 @@
+// Package api is the gateway's small admin HTTP surface (raw passthrough, status,
+// ...). It is disabled unless --api-listen is set.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	// Addr is the listen address, e.g. ":8080".
+	Addr string
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+type Server struct {
+	addr string
+	mux  *http.ServeMux
+	log  *slog.Logger
+}
+
+func New(cfg Config) (*Server, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("Addr required")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Server{
+		addr: cfg.Addr,
+		mux:  http.NewServeMux(),
+		log:  cfg.Logger.With("module", "api", "addr", cfg.Addr),
+	}, nil
+}
+
+// Handle registers h for a net/http ServeMux pattern such as "GET /api/version".
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+func (s *Server) HandleFunc(pattern string, fn func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, fn)
+}
+
+// ServeHTTP lets tests drive the mux without listening.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run serves until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.log.Info("api server started")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// WriteJSON writes v as the JSON response body.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // keep path templates like "<id>" readable
+	_ = enc.Encode(v)
+}
+
+// WriteError writes {"error": "..."}.
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
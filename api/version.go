@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/samvdb/loxone-philips-hue/version"
+)
+
+// VersionHandler serves GET /api/version with the build information.
+func VersionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, version.Get())
+	})
+}
@@ -0,0 +1,102 @@
+package bridge
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Address is the bridge's current host (IP or hostname) plus the resolver used to
+// reach it. It is shared by Home and the event streamer so a re-discovered IP or a
+// custom DNS server takes effect everywhere at once.
+type Address struct {
+	mu       sync.RWMutex
+	host     string
+	dialer   *net.Dialer
+	proxy    proxyFunc
+	onChange []func(host string)
+}
+
+func NewAddress(host string) *Address {
+	return &Address{
+		host:   host,
+		dialer: &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second},
+		proxy:  proxyFromEnvironment(),
+	}
+}
+
+// SetDNSServer makes hostnames resolve through server ("ip" or "ip:port") instead of
+// the system resolver, e.g. when mDNS is blocked across VLANs.
+func (a *Address) SetDNSServer(server string) {
+	if server == "" {
+		return
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.dialer = &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Resolver: resolver}
+}
+
+// DialContext dials address with the configured resolver, tunnelling through the
+// proxy when one applies; use it as the transport dialer for every connection to
+// the bridge.
+func (a *Address) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	a.mu.RLock()
+	d, proxy := a.dialer, a.proxy
+	a.mu.RUnlock()
+
+	if proxy != nil {
+		p, err := proxy(&url.URL{Scheme: "https", Host: address})
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return dialConnect(ctx, d, p, address)
+		}
+	}
+	return d.DialContext(ctx, network, address)
+}
+
+func (a *Address) Host() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.host
+}
+
+// Set updates the host and notifies OnChange subscribers when it changed.
+func (a *Address) Set(host string) {
+	a.mu.Lock()
+	if host == "" || host == a.host {
+		a.mu.Unlock()
+		return
+	}
+	old := a.host
+	a.host = host
+	subs := append([]func(string){}, a.onChange...)
+	a.mu.Unlock()
+
+	slog.Warn("hue bridge address changed", "old", old, "new", host)
+	for _, fn := range subs {
+		fn(host)
+	}
+}
+
+// OnChange registers fn to be called with the new host after every change.
+func (a *Address) OnChange(fn func(host string)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onChange = append(a.onChange, fn)
+}
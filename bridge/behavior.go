@@ -0,0 +1,131 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// BehaviorInstance is an automation the bridge runs by itself, e.g. a motion
+// sensor recalling a scene, so it keeps working while the gateway or Loxone is
+// down. Configuration follows the schema of the script it instantiates.
+type BehaviorInstance struct {
+	ID            string           `json:"id,omitempty"`
+	ScriptID      string           `json:"script_id"`
+	Enabled       bool             `json:"enabled"`
+	Status        string           `json:"status,omitempty"` // initializing, running, disabled, errored
+	Metadata      BehaviorMetadata `json:"metadata"`
+	Configuration json.RawMessage  `json:"configuration"`
+}
+
+type BehaviorMetadata struct {
+	Name string `json:"name"`
+}
+
+// BehaviorUpdate changes the fields that are set and leaves the others.
+type BehaviorUpdate struct {
+	Enabled       *bool             `json:"enabled,omitempty"`
+	Metadata      *BehaviorMetadata `json:"metadata,omitempty"`
+	Configuration json.RawMessage   `json:"configuration,omitempty"`
+}
+
+// BehaviorScript is a template behavior instances are created from.
+type BehaviorScript struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Metadata    struct {
+		Name     string `json:"name"`
+		Category string `json:"category"`
+	} `json:"metadata"`
+	ConfigurationSchema json.RawMessage `json:"configuration_schema,omitempty"`
+}
+
+// MotionScene configures a motion sensor behavior that recalls a scene on
+// motion and, with OffAfter set, turns the scene's room or zone off once the
+// sensor has seen no motion for that long. Use it with the motion sensor
+// script, e.g. BehaviorInstance{ScriptID: script, Configuration: cfg}.
+type MotionScene struct {
+	Sensor   string        // device id of the motion sensor
+	Group    ResourceRef   // room or zone the scene belongs to
+	Scene    string        // scene id
+	OffAfter time.Duration // 0 leaves the lights on
+}
+
+// Configuration returns the behavior configuration for m.
+func (m MotionScene) Configuration() (json.RawMessage, error) {
+	switch {
+	case m.Sensor == "":
+		return nil, errors.New("motion scene: sensor required")
+	case m.Scene == "":
+		return nil, errors.New("motion scene: scene required")
+	case m.Group.Rid == "" || (m.Group.Rtype != "room" && m.Group.Rtype != "zone"):
+		return nil, fmt.Errorf("motion scene: group must be a room or zone, got %q", m.Group.Rtype)
+	case m.OffAfter < 0 || m.OffAfter%time.Second != 0:
+		return nil, fmt.Errorf("motion scene: off after %s is not a whole number of seconds", m.OffAfter)
+	}
+	type recall struct {
+		Action any `json:"action"`
+	}
+	slot := map[string]any{
+		"start_time": map[string]any{"kind": "time", "time": map[string]int{"hour": 0, "minute": 0}},
+		"on_motion": map[string]any{"recall_single": []recall{{Action: map[string]any{
+			"recall": ResourceRef{Rid: m.Scene, Rtype: "scene"},
+		}}}},
+	}
+	if m.OffAfter > 0 {
+		slot["on_no_motion"] = map[string]any{
+			"after":         map[string]int{"seconds": int(m.OffAfter / time.Second)},
+			"recall_single": []recall{{Action: "all_off"}},
+		}
+	}
+	return json.Marshal(map[string]any{
+		"source": map[string]any{"type": "sensor", "rid": m.Sensor, "rtype": "device"},
+		"when":   map[string]any{"timeslots": []any{slot}},
+		"where":  []map[string]ResourceRef{{"group": m.Group}},
+	})
+}
+
+// GetBehaviors lists the behavior instances: GET /clip/v2/resource/behavior_instance.
+func (h *Home) GetBehaviors(ctx context.Context) ([]BehaviorInstance, error) {
+	var out []BehaviorInstance
+	if err := h.clip(ctx, http.MethodGet, "behavior_instance", "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetBehaviorScripts lists the scripts behavior instances can be created from.
+func (h *Home) GetBehaviorScripts(ctx context.Context) ([]BehaviorScript, error) {
+	var out []BehaviorScript
+	if err := h.clip(ctx, http.MethodGet, "behavior_script", "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateBehavior adds b and returns the id the bridge assigned.
+func (h *Home) CreateBehavior(ctx context.Context, b BehaviorInstance) (string, error) {
+	b.ID, b.Status = "", ""
+	body, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	return h.createResource(ctx, "behavior_instance", body)
+}
+
+// UpdateBehavior applies u to the behavior instance id.
+func (h *Home) UpdateBehavior(ctx context.Context, id string, u BehaviorUpdate) error {
+	body, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return h.PutResource(ctx, "behavior_instance", id, body)
+}
+
+// DeleteBehavior removes the behavior instance id.
+func (h *Home) DeleteBehavior(ctx context.Context, id string) error {
+	return h.clip(ctx, http.MethodDelete, "behavior_instance", id, nil, nil)
+}
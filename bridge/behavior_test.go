@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBehaviors(t *testing.T) {
+	var got []string // "<method> <path> <body>"
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.Path+" "+string(body))
+		switch {
+		case r.Method == http.MethodGet:
+			io.WriteString(w, `{"errors": [], "data": [{"id": "b1", "type": "behavior_instance", "script_id": "s1", "enabled": true, "status": "running", "metadata": {"name": "Hall motion"}, "configuration": {"where": []}}]}`)
+		case r.Method == http.MethodPost:
+			io.WriteString(w, `{"errors": [], "data": [{"rid": "b2", "rtype": "behavior_instance"}]}`)
+		case r.URL.Path == "/clip/v2/resource/behavior_instance/missing":
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"errors": [{"description": "Not Found"}], "data": []}`)
+		default:
+			io.WriteString(w, `{"errors": [], "data": [{"rid": "b1", "rtype": "behavior_instance"}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	home, err := NewHome(NewAddress(strings.TrimPrefix(srv.URL, "https://")), NewKeys("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	list, err := home.GetBehaviors(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Metadata.Name != "Hall motion" || list[0].Status != "running" || string(list[0].Configuration) != `{"where": []}` {
+		t.Errorf("GetBehaviors() = %+v", list)
+	}
+
+	id, err := home.CreateBehavior(ctx, BehaviorInstance{ID: "ignored", ScriptID: "s1", Enabled: true, Metadata: BehaviorMetadata{Name: "Copy"}, Configuration: json.RawMessage(`{}`)})
+	if err != nil || id != "b2" {
+		t.Fatalf("CreateBehavior() = %q, %v", id, err)
+	}
+
+	off := false
+	if err := home.UpdateBehavior(ctx, "b1", BehaviorUpdate{Enabled: &off}); err != nil {
+		t.Fatal(err)
+	}
+	if err := home.DeleteBehavior(ctx, "b1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := home.DeleteBehavior(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("DeleteBehavior(missing) error = %v, want Not Found", err)
+	}
+
+	want := []string{
+		"GET /clip/v2/resource/behavior_instance ",
+		`POST /clip/v2/resource/behavior_instance {"script_id":"s1","enabled":true,"metadata":{"name":"Copy"},"configuration":{}}`,
+		`PUT /clip/v2/resource/behavior_instance/b1 {"enabled":false}`,
+		"DELETE /clip/v2/resource/behavior_instance/b1 ",
+		"DELETE /clip/v2/resource/behavior_instance/missing ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMotionScene_Configuration(t *testing.T) {
+	room := ResourceRef{Rid: "r1", Rtype: "room"}
+	tests := []struct {
+		name    string
+		m       MotionScene
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "recall only",
+			m:    MotionScene{Sensor: "d1", Group: room, Scene: "s1"},
+			want: `{"source":{"rid":"d1","rtype":"device","type":"sensor"},"when":{"timeslots":[{"on_motion":{"recall_single":[{"action":{"recall":{"rid":"s1","rtype":"scene"}}}]},"start_time":{"kind":"time","time":{"hour":0,"minute":0}}}]},"where":[{"group":{"rid":"r1","rtype":"room"}}]}`,
+		},
+		{
+			name: "off after",
+			m:    MotionScene{Sensor: "d1", Group: room, Scene: "s1", OffAfter: 5 * time.Minute},
+			want: `{"source":{"rid":"d1","rtype":"device","type":"sensor"},"when":{"timeslots":[{"on_motion":{"recall_single":[{"action":{"recall":{"rid":"s1","rtype":"scene"}}}]},"on_no_motion":{"after":{"seconds":300},"recall_single":[{"action":"all_off"}]},"start_time":{"kind":"time","time":{"hour":0,"minute":0}}}]},"where":[{"group":{"rid":"r1","rtype":"room"}}]}`,
+		},
+		{name: "no sensor", m: MotionScene{Group: room, Scene: "s1"}, wantErr: true},
+		{name: "no scene", m: MotionScene{Sensor: "d1", Group: room}, wantErr: true},
+		{name: "light group", m: MotionScene{Sensor: "d1", Group: ResourceRef{Rid: "l1", Rtype: "light"}, Scene: "s1"}, wantErr: true},
+		{name: "fractional off after", m: MotionScene{Sensor: "d1", Group: room, Scene: "s1", OffAfter: 1500 * time.Millisecond}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.m.Configuration()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Configuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
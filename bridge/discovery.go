@@ -0,0 +1,197 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+)
+
+const (
+	bridgeService = "_hue._tcp"
+	discoveryURL  = "https://discovery.meethue.com"
+)
+
+var ErrBridgeNotFound = errors.New("hue bridge not found")
+
+// Discover looks up the IP of the bridge with the given bridge id (as shown in the
+// Hue app, e.g. "ecb5fafffe0a1b2c") via mDNS, falling back to the meethue
+// discovery endpoint.
+func Discover(ctx context.Context, bridgeID string, timeout time.Duration) (string, error) {
+	bridgeID = strings.ToLower(bridgeID)
+
+	ip, err := discoverMDNS(ctx, bridgeID, timeout)
+	if err == nil {
+		return ip, nil
+	}
+	slog.Debug("mDNS discovery failed; trying discovery endpoint", "bridge_id", bridgeID, "err", err)
+	return discoverURL(ctx, bridgeID)
+}
+
+func discoverMDNS(ctx context.Context, bridgeID string, timeout time.Duration) (string, error) {
+	resolver, err := zeroconf.NewResolver()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry)
+	if err := resolver.Browse(ctx, bridgeService, "local", entries); err != nil {
+		return "", err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ErrBridgeNotFound
+		case e, ok := <-entries:
+			if !ok {
+				return "", ErrBridgeNotFound
+			}
+			if len(e.AddrIPv4) == 0 {
+				continue
+			}
+			for _, txt := range e.Text {
+				if strings.EqualFold(txt, "bridgeid="+bridgeID) {
+					return e.AddrIPv4[0].String(), nil
+				}
+			}
+		}
+	}
+}
+
+func discoverURL(ctx context.Context, bridgeID string) (string, error) {
+	bridges, err := listURL(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, b := range bridges {
+		if strings.EqualFold(b.ID, bridgeID) {
+			return b.IP, nil
+		}
+	}
+	return "", ErrBridgeNotFound
+}
+
+// Found is a bridge seen on the network.
+type Found struct {
+	ID string `json:"id"`                // bridge id, e.g. "ecb5fafffe0a1b2c"
+	IP string `json:"internalipaddress"` // as the discovery endpoint names it
+}
+
+// DiscoverAll lists the bridges answering mDNS within timeout, falling back to
+// the meethue discovery endpoint when none does.
+func DiscoverAll(ctx context.Context, timeout time.Duration) ([]Found, error) {
+	found, err := listMDNS(ctx, timeout)
+	if err == nil && len(found) > 0 {
+		return found, nil
+	}
+	slog.Debug("mDNS discovery found nothing; trying discovery endpoint", "err", err)
+	return listURL(ctx)
+}
+
+func listMDNS(ctx context.Context, timeout time.Duration) ([]Found, error) {
+	resolver, err := zeroconf.NewResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry)
+	if err := resolver.Browse(ctx, bridgeService, "local", entries); err != nil {
+		return nil, err
+	}
+
+	var found []Found
+	seen := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return found, nil
+		case e, ok := <-entries:
+			if !ok {
+				return found, nil
+			}
+			if len(e.AddrIPv4) == 0 {
+				continue
+			}
+			for _, txt := range e.Text {
+				if id, ok := strings.CutPrefix(strings.ToLower(txt), "bridgeid="); ok && !seen[id] {
+					seen[id] = true
+					found = append(found, Found{ID: id, IP: e.AddrIPv4[0].String()})
+				}
+			}
+		}
+	}
+}
+
+func listURL(ctx context.Context) ([]Found, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery endpoint: %s", resp.Status)
+	}
+
+	var bridges []Found
+	if err := json.NewDecoder(resp.Body).Decode(&bridges); err != nil {
+		return nil, err
+	}
+	return bridges, nil
+}
+
+// Locator periodically re-discovers the bridge by id and updates Address, so a new
+// DHCP lease doesn't leave the gateway talking to a stale IP.
+type Locator struct {
+	BridgeID string
+	Address  *Address
+	Interval time.Duration // default 5m
+	Timeout  time.Duration // per discovery, default 5s
+}
+
+func (l *Locator) Run(ctx context.Context) error {
+	interval := l.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			l.locate(ctx)
+		}
+	}
+}
+
+func (l *Locator) locate(ctx context.Context) {
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ip, err := Discover(ctx, l.BridgeID, timeout)
+	if err != nil {
+		slog.Warn("bridge re-discovery failed", "bridge_id", l.BridgeID, "err", err)
+		return
+	}
+	l.Address.Set(ip)
+}
@@ -0,0 +1,289 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	openhue "github.com/openhue/openhue-go"
+)
+
+// EnsureConfig declares zones and scenes that must exist on the bridge, e.g.
+// {"ensure": {"zones": [{"name": "Downstairs", "rooms": ["Living room", "Kitchen"]}],
+// "scenes": [{"name": "Evening", "group": "Downstairs", "brightness": 40, "mirek": 400}]}}.
+type EnsureConfig struct {
+	Zones  []ZoneSpec  `mapstructure:"zones"`
+	Scenes []SceneSpec `mapstructure:"scenes"`
+}
+
+type ZoneSpec struct {
+	Name      string   `mapstructure:"name"`
+	Archetype string   `mapstructure:"archetype"` // e.g. "downstairs"; default "other"
+	Rooms     []string `mapstructure:"rooms"`     // rooms (by name or id) whose lights join the zone
+	Lights    []string `mapstructure:"lights"`    // light service ids
+}
+
+type SceneSpec struct {
+	Name       string  `mapstructure:"name"`
+	Group      string  `mapstructure:"group"`      // room or zone, by name or id
+	Off        bool    `mapstructure:"off"`        // the scene turns the lights off
+	Brightness float64 `mapstructure:"brightness"` // percent; 0 keeps the light's
+	Mirek      int     `mapstructure:"mirek"`      // color temperature; 0 keeps the light's
+}
+
+// EnsureStep is the outcome for one declared zone or scene.
+type EnsureStep struct {
+	Kind   string // "zone" or "scene"
+	Name   string
+	Group  string // scenes: the room or zone they belong to
+	ID     string // empty for a zone or scene a dry run would create
+	Exists bool   // found on the bridge; nothing was changed
+
+	group  *ensureGroup
+	lights []string
+	spec   any
+}
+
+type ensureGroup struct {
+	id, rtype, name string
+	lights          []string
+	scenes          map[string]string // lowercase name → id
+}
+
+// ensureHome is what Ensure needs to know about the bridge.
+type ensureHome struct {
+	groups []*ensureGroup
+}
+
+// Ensure creates the zones and scenes of cfg the bridge does not have yet, matched
+// by name (zones) or by name within their group (scenes), so running it again
+// changes nothing. Existing zones and scenes are left as they are. Everything is
+// resolved before the first resource is created; with dryRun nothing is.
+func (h *Home) Ensure(ctx context.Context, cfg EnsureConfig, dryRun bool) ([]EnsureStep, error) {
+	home, err := h.ensureHome(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := home.plan(cfg)
+	if err != nil || dryRun {
+		return steps, err
+	}
+	for i := range steps {
+		if steps[i].Exists {
+			continue
+		}
+		body, err := json.Marshal(steps[i].body())
+		if err != nil {
+			return steps[:i], err
+		}
+		id, err := h.createResource(ctx, steps[i].Kind, body)
+		if err != nil {
+			return steps[:i], fmt.Errorf("create %s %q: %w", steps[i].Kind, steps[i].Name, err)
+		}
+		steps[i].ID = id
+		if steps[i].Kind == "zone" {
+			steps[i].group.id = id
+		}
+	}
+	return steps, nil
+}
+
+func (h *Home) ensureHome(ctx context.Context) (*ensureHome, error) {
+	devices, err := h.GetDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("devices: %w", err)
+	}
+	rooms, err := h.GetRooms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rooms: %w", err)
+	}
+	zones, err := h.GetZones(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("zones: %w", err)
+	}
+	scenes, err := h.GetScenes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scenes: %w", err)
+	}
+	return newEnsureHome(devices, rooms, zones, scenes), nil
+}
+
+func newEnsureHome(devices map[string]openhue.DeviceGet, rooms, zones map[string]openhue.RoomGet, scenes map[string]openhue.SceneGet) *ensureHome {
+	deviceLights := func(id string) []string {
+		var out []string
+		if d, ok := devices[id]; ok && d.Services != nil {
+			for _, s := range *d.Services {
+				if s.Rid != nil && s.Rtype != nil && *s.Rtype == "light" {
+					out = append(out, *s.Rid)
+				}
+			}
+		}
+		return out
+	}
+	home := &ensureHome{}
+	byID := make(map[string]*ensureGroup)
+	add := func(rtype string, groups map[string]openhue.RoomGet) {
+		for id, g := range groups {
+			eg := &ensureGroup{id: id, rtype: rtype, scenes: make(map[string]string)}
+			if g.Metadata != nil && g.Metadata.Name != nil {
+				eg.name = *g.Metadata.Name
+			}
+			if g.Children != nil {
+				for _, c := range *g.Children {
+					switch {
+					case c.Rid == nil || c.Rtype == nil:
+					case *c.Rtype == "device":
+						eg.lights = append(eg.lights, deviceLights(*c.Rid)...)
+					case *c.Rtype == "light":
+						eg.lights = append(eg.lights, *c.Rid)
+					}
+				}
+			}
+			home.groups = append(home.groups, eg)
+			byID[id] = eg
+		}
+	}
+	add("room", rooms)
+	add("zone", zones)
+	for id, s := range scenes {
+		if s.Group == nil || s.Group.Rid == nil || s.Metadata == nil || s.Metadata.Name == nil {
+			continue
+		}
+		if g, ok := byID[*s.Group.Rid]; ok {
+			g.scenes[strings.ToLower(*s.Metadata.Name)] = id
+		}
+	}
+	return home
+}
+
+// find returns the room or zone with id or name ref, restricted to rtype unless
+// it is empty.
+func (e *ensureHome) find(ref, rtype string) (*ensureGroup, error) {
+	var found []*ensureGroup
+	for _, g := range e.groups {
+		if rtype != "" && g.rtype != rtype {
+			continue
+		}
+		if g.id == ref && g.id != "" {
+			return g, nil
+		}
+		if strings.EqualFold(g.name, ref) {
+			found = append(found, g)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("%d rooms or zones are named %q; use the id", len(found), ref)
+}
+
+// plan resolves cfg against the bridge without changing anything.
+func (e *ensureHome) plan(cfg EnsureConfig) ([]EnsureStep, error) {
+	var steps []EnsureStep
+	for _, z := range cfg.Zones {
+		if z.Name == "" {
+			return nil, errors.New("ensure: zone without name")
+		}
+		if g, err := e.find(z.Name, "zone"); err != nil {
+			return nil, err
+		} else if g != nil {
+			steps = append(steps, EnsureStep{Kind: "zone", Name: z.Name, ID: g.id, Exists: true})
+			continue
+		}
+		lights := append([]string(nil), z.Lights...)
+		for _, ref := range z.Rooms {
+			room, err := e.find(ref, "room")
+			if err != nil {
+				return nil, err
+			}
+			if room == nil {
+				return nil, fmt.Errorf("ensure: zone %q: no room %q", z.Name, ref)
+			}
+			lights = append(lights, room.lights...)
+		}
+		if len(lights) == 0 {
+			return nil, fmt.Errorf("ensure: zone %q has no lights", z.Name)
+		}
+		g := &ensureGroup{rtype: "zone", name: z.Name, lights: dedupe(lights), scenes: make(map[string]string)}
+		e.groups = append(e.groups, g)
+		steps = append(steps, EnsureStep{Kind: "zone", Name: z.Name, group: g, lights: g.lights, spec: z})
+	}
+	for _, s := range cfg.Scenes {
+		if s.Name == "" || s.Group == "" {
+			return nil, errors.New("ensure: scene without name or group")
+		}
+		g, err := e.find(s.Group, "")
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			return nil, fmt.Errorf("ensure: scene %q: no room or zone %q", s.Name, s.Group)
+		}
+		if id, ok := g.scenes[strings.ToLower(s.Name)]; ok {
+			steps = append(steps, EnsureStep{Kind: "scene", Name: s.Name, Group: g.name, ID: id, Exists: true})
+			continue
+		}
+		if len(g.lights) == 0 {
+			return nil, fmt.Errorf("ensure: scene %q: %s %q has no lights", s.Name, g.rtype, g.name)
+		}
+		g.scenes[strings.ToLower(s.Name)] = "" // declared twice: created once
+		steps = append(steps, EnsureStep{Kind: "scene", Name: s.Name, Group: g.name, group: g, lights: g.lights, spec: s})
+	}
+	return steps, nil
+}
+
+// body is the CLIP v2 POST body creating the step's resource.
+func (s EnsureStep) body() any {
+	refs := make([]ResourceRef, len(s.lights))
+	for i, l := range s.lights {
+		refs[i] = ResourceRef{Rid: l, Rtype: "light"}
+	}
+	switch spec := s.spec.(type) {
+	case ZoneSpec:
+		archetype := spec.Archetype
+		if archetype == "" {
+			archetype = "other"
+		}
+		return map[string]any{
+			"type":     "zone",
+			"metadata": map[string]string{"name": spec.Name, "archetype": archetype},
+			"children": refs,
+		}
+	case SceneSpec:
+		action := map[string]any{"on": map[string]bool{"on": !spec.Off}}
+		if !spec.Off && spec.Brightness > 0 {
+			action["dimming"] = map[string]float64{"brightness": spec.Brightness}
+		}
+		if !spec.Off && spec.Mirek > 0 {
+			action["color_temperature"] = map[string]int{"mirek": spec.Mirek}
+		}
+		actions := make([]map[string]any, len(refs))
+		for i, r := range refs {
+			actions[i] = map[string]any{"target": r, "action": action}
+		}
+		return map[string]any{
+			"type":     "scene",
+			"metadata": map[string]string{"name": spec.Name},
+			"group":    ResourceRef{Rid: s.group.id, Rtype: s.group.rtype},
+			"actions":  actions,
+		}
+	}
+	return nil
+}
+
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
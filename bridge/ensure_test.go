@@ -0,0 +1,126 @@
+package bridge
+
+import (
+	"encoding/json"
+	"testing"
+
+	openhue "github.com/openhue/openhue-go"
+)
+
+func testEnsureHome(t *testing.T) *ensureHome {
+	t.Helper()
+	var (
+		devices map[string]openhue.DeviceGet
+		rooms   map[string]openhue.RoomGet
+		zones   map[string]openhue.RoomGet
+		scenes  map[string]openhue.SceneGet
+	)
+	for _, v := range []struct {
+		body string
+		out  any
+	}{
+		{`{"dev-1": {"services": [{"rid": "light-1", "rtype": "light"}, {"rid": "zb-1", "rtype": "zigbee_connectivity"}]},
+		   "dev-2": {"services": [{"rid": "light-2", "rtype": "light"}]}}`, &devices},
+		{`{"room-1": {"metadata": {"name": "Living room"}, "children": [{"rid": "dev-1", "rtype": "device"}]},
+		   "room-2": {"metadata": {"name": "Kitchen"}, "children": [{"rid": "dev-2", "rtype": "device"}]}}`, &rooms},
+		{`{"zone-1": {"metadata": {"name": "Upstairs"}, "children": [{"rid": "light-2", "rtype": "light"}]}}`, &zones},
+		{`{"scene-1": {"metadata": {"name": "Relax"}, "group": {"rid": "room-1", "rtype": "room"}}}`, &scenes},
+	} {
+		if err := json.Unmarshal([]byte(v.body), v.out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return newEnsureHome(devices, rooms, zones, scenes)
+}
+
+func TestEnsurePlan(t *testing.T) {
+	home := testEnsureHome(t)
+	steps, err := home.plan(EnsureConfig{
+		Zones: []ZoneSpec{
+			{Name: "upstairs"},
+			{Name: "Downstairs", Rooms: []string{"living room", "room-2"}, Lights: []string{"light-1"}},
+		},
+		Scenes: []SceneSpec{
+			{Name: "relax", Group: "Living room"},
+			{Name: "Evening", Group: "Downstairs", Brightness: 40, Mirek: 400},
+			{Name: "Off", Group: "zone-1", Off: true, Brightness: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind, name, id string
+		exists         bool
+	}{
+		{"zone", "upstairs", "zone-1", true},
+		{"zone", "Downstairs", "", false},
+		{"scene", "relax", "scene-1", true},
+		{"scene", "Evening", "", false},
+		{"scene", "Off", "", false},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(want))
+	}
+	for i, w := range want {
+		s := steps[i]
+		if s.Kind != w.kind || s.Name != w.name || s.ID != w.id || s.Exists != w.exists {
+			t.Errorf("step %d = %s %q id=%q exists=%v; want %s %q id=%q exists=%v", i, s.Kind, s.Name, s.ID, s.Exists, w.kind, w.name, w.id, w.exists)
+		}
+	}
+
+	// the new zone gets its id before the scene in it is created
+	steps[1].group.id = "zone-new"
+	b, err := json.Marshal(steps[3].body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scene struct {
+		Group   ResourceRef `json:"group"`
+		Actions []struct {
+			Target ResourceRef     `json:"target"`
+			Action json.RawMessage `json:"action"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(b, &scene); err != nil {
+		t.Fatal(err)
+	}
+	if scene.Group != (ResourceRef{Rid: "zone-new", Rtype: "zone"}) {
+		t.Errorf("scene group = %+v", scene.Group)
+	}
+	if len(scene.Actions) != 2 || scene.Actions[0].Target.Rid != "light-1" || scene.Actions[1].Target.Rid != "light-2" {
+		t.Fatalf("scene actions = %s, want light-1 and light-2 once each", b)
+	}
+	if got, want := string(scene.Actions[0].Action), `{"color_temperature":{"mirek":400},"dimming":{"brightness":40},"on":{"on":true}}`; got != want {
+		t.Errorf("action = %s, want %s", got, want)
+	}
+
+	b, _ = json.Marshal(steps[4].body())
+	if err := json.Unmarshal(b, &scene); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(scene.Actions[0].Action); got != `{"on":{"on":false}}` {
+		t.Errorf("off action = %s", got)
+	}
+}
+
+func TestEnsurePlanErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EnsureConfig
+	}{
+		{"unknown room", EnsureConfig{Zones: []ZoneSpec{{Name: "Z", Rooms: []string{"Attic"}}}}},
+		{"zone without lights", EnsureConfig{Zones: []ZoneSpec{{Name: "Z"}}}},
+		{"unknown group", EnsureConfig{Scenes: []SceneSpec{{Name: "S", Group: "Attic"}}}},
+		{"scene without group", EnsureConfig{Scenes: []SceneSpec{{Name: "S"}}}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := testEnsureHome(t).plan(tt.cfg); err == nil {
+				t.Error("plan() succeeded, want an error")
+			}
+		})
+	}
+}
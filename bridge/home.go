@@ -10,29 +10,115 @@ import (
 )
 
 type Home struct {
-	api *openhue.ClientWithResponses
-	*openhue.Home
+	api  *openhue.ClientWithResponses
+	keys *Keys
+	addr *Address
+
+	// raw access for endpoints the generated client doesn't cover
+	httpClient *http.Client
+	limit      *limitTransport
 }
 
-func NewHome(bridgeIP, apiKey string) (*Home, error) {
-	if bridgeIP == "" || apiKey == "" {
+func NewHome(addr *Address, keys *Keys) (*Home, error) {
+	if addr == nil || addr.Host() == "" || keys == nil || keys.Current() == "" {
 		return nil, errors.New("illegal arguments, bridgeIP and apiKey must be set")
 	}
 
-	base, err := openhue.NewHome(bridgeIP, apiKey)
+	limit := newLimitTransport(newKeyTransport(addr, keys), DefaultMaxConcurrent)
+	httpClient := &http.Client{Transport: limit}
+
+	// every request goes to the current address, so a re-discovered IP applies right away
+	useCurrentHost := func(ctx context.Context, req *http.Request) error {
+		req.URL.Host = addr.Host()
+		req.Host = req.URL.Host
+		return nil
+	}
+	client, err := openhue.NewClientWithResponses("https://"+addr.Host(), openhue.WithHTTPClient(httpClient), openhue.WithRequestEditorFn(useCurrentHost))
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := newClient(bridgeIP, apiKey)
+	// drop keep-alive connections to the old IP
+	addr.OnChange(func(string) { httpClient.CloseIdleConnections() })
+
+	return &Home{
+		api:        client,
+		keys:       keys,
+		addr:       addr,
+		httpClient: httpClient,
+		limit:      limit,
+	}, nil
+}
+
+// SetMaxConcurrent changes how many requests may be in flight at once; every
+// user of h (poller, command adapter, API) shares the limit. n <= 0 means
+// DefaultMaxConcurrent.
+func (h *Home) SetMaxConcurrent(n int) {
+	h.limit.setLimit(n)
+}
+
+// Stats returns request counts, errors and latencies per bridge endpoint.
+func (h *Home) Stats() []EndpointStats {
+	return h.limit.snapshot()
+}
+
+func (h *Home) GetDevices(ctx context.Context) (map[string]openhue.DeviceGet, error) {
+	resp, err := h.api.GetDevicesWithResponse(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Home{
-		api:  client,
-		Home: base,
-	}, nil
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+	devices := make(map[string]openhue.DeviceGet, len(data))
+
+	for _, device := range data {
+		devices[*device.Id] = device
+	}
+
+	return devices, nil
+}
+
+func (h *Home) GetDevice(ctx context.Context, id string) (*openhue.DeviceGet, error) {
+	resp, err := h.api.GetDeviceWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+
+	for _, device := range data {
+		return &device, nil
+	}
+
+	return nil, nil
+}
+
+func (h *Home) GetRooms(ctx context.Context) (map[string]openhue.RoomGet, error) {
+	resp, err := h.api.GetRoomsWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+	rooms := make(map[string]openhue.RoomGet, len(data))
+
+	for _, room := range data {
+		rooms[*room.Id] = room
+	}
+
+	return rooms, nil
 }
 
 func (h *Home) GetZones(ctx context.Context) (map[string]openhue.RoomGet, error) {
@@ -55,6 +141,26 @@ func (h *Home) GetZones(ctx context.Context) (map[string]openhue.RoomGet, error)
 	return zones, nil
 }
 
+func (h *Home) GetScenes(ctx context.Context) (map[string]openhue.SceneGet, error) {
+	resp, err := h.api.GetScenesWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+	scenes := make(map[string]openhue.SceneGet, len(data))
+
+	for _, scene := range data {
+		scenes[*scene.Id] = scene
+	}
+
+	return scenes, nil
+}
+
 func (h *Home) GetScene(ctx context.Context, id string) (*openhue.SceneGet, error) {
 	resp, err := h.api.GetSceneWithResponse(ctx, id)
 	if err != nil {
@@ -74,25 +180,119 @@ func (h *Home) GetScene(ctx context.Context, id string) (*openhue.SceneGet, erro
 	return nil, nil
 }
 
-// newClient creates a new ClientWithResponses for a given Bridge IP and API key.
-// This function will also skip SSL verification, as the Philips HUE Bridge exposes a self-signed certificate.
-func newClient(bridgeIP, apiKey string) (*openhue.ClientWithResponses, error) {
+func (h *Home) UpdateScene(ctx context.Context, id string, body openhue.ScenePut) error {
+	resp, err := h.api.UpdateSceneWithResponse(ctx, id, body)
+	if err != nil {
+		return err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return newApiError(resp)
+	}
 
-	var authFn openhue.RequestEditorFn
+	return nil
+}
 
-	if len(apiKey) > 0 {
-		authFn = func(ctx context.Context, req *http.Request) error {
-			req.Header.Set("hue-application-key", apiKey)
-			return nil
-		}
-	} else {
-		authFn = func(ctx context.Context, req *http.Request) error {
-			return nil
-		}
+func (h *Home) GetGroupedLights(ctx context.Context) (map[string]openhue.GroupedLightGet, error) {
+	resp, err := h.api.GetGroupedLightsWithResponse(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// skip SSL Verification
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+	lights := make(map[string]openhue.GroupedLightGet, len(data))
+
+	for _, light := range data {
+		lights[*light.Id] = light
+	}
+
+	return lights, nil
+}
 
-	return openhue.NewClientWithResponses("https://"+bridgeIP, openhue.WithRequestEditorFn(authFn))
+// GetDevicePowers returns the power state of every device, keyed by device_power id.
+func (h *Home) GetDevicePowers(ctx context.Context) (map[string]openhue.DevicePowerGet, error) {
+	resp, err := h.api.GetDevicePowersWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+	powers := make(map[string]openhue.DevicePowerGet, len(data))
+
+	for _, power := range data {
+		powers[*power.Id] = power
+	}
+
+	return powers, nil
+}
+
+func (h *Home) GetGroupedLight(ctx context.Context, id string) (*openhue.GroupedLightGet, error) {
+	resp, err := h.api.GetGroupedLightWithResponse(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return nil, newApiError(resp)
+	}
+
+	data := *(*resp.JSON200).Data
+
+	for _, light := range data {
+		return &light, nil
+	}
+
+	return nil, nil
+}
+
+func (h *Home) UpdateGroupedLight(ctx context.Context, id string, body openhue.GroupedLightPut) error {
+	resp, err := h.api.UpdateGroupedLightWithResponse(ctx, id, body)
+	if err != nil {
+		return err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return newApiError(resp)
+	}
+
+	return nil
+}
+
+func (h *Home) UpdateLight(ctx context.Context, id string, body openhue.LightPut) error {
+	resp, err := h.api.UpdateLightWithResponse(ctx, id, body)
+	if err != nil {
+		return err
+	}
+
+	if resp.HTTPResponse.StatusCode != http.StatusOK {
+		return newApiError(resp)
+	}
+
+	return nil
+}
+
+// newKeyTransport creates the transport used for all bridge requests with the given set of API keys.
+// This function will also skip SSL verification, as the Philips HUE Bridge exposes a self-signed certificate.
+func newKeyTransport(addr *Address, keys *Keys) http.RoundTripper {
+	// the key transport sets hue-application-key and fails over on 401/403
+	return keys.Transport(newTransport(addr))
+}
+
+// newTransport reaches the bridge at addr without an API key, e.g. for pairing.
+func newTransport(addr *Address) *http.Transport {
+	// skip SSL Verification
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	// proxying happens in addr.DialContext so the streamer and Home behave the same
+	transport.Proxy = nil
+	transport.DialContext = addr.DialContext
+	return transport
 }
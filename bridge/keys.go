@@ -0,0 +1,117 @@
+package bridge
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// Keys holds the hue-application-keys registered on the bridge, in order of
+// preference. When the active key is rejected (401/403, e.g. deleted from the
+// bridge) the next one takes over.
+type Keys struct {
+	mu   sync.RWMutex
+	keys []string
+	idx  int
+
+	// OnFailover (optional) is called with the index of the newly active key.
+	OnFailover func(index int)
+}
+
+func NewKeys(keys ...string) *Keys {
+	k := &Keys{}
+	for _, key := range keys {
+		if key != "" {
+			k.keys = append(k.keys, key)
+		}
+	}
+	return k
+}
+
+// Current returns the active key, or "" if none is configured.
+func (k *Keys) Current() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.idx >= len(k.keys) {
+		return ""
+	}
+	return k.keys[k.idx]
+}
+
+// Index returns the position of the active key (0 = primary).
+func (k *Keys) Index() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.idx
+}
+
+// Failover marks rejected as unusable. It reports whether a different key is now
+// active and worth retrying with.
+func (k *Keys) Failover(rejected string) bool {
+	k.mu.Lock()
+	if k.idx < len(k.keys) && k.keys[k.idx] != rejected {
+		// another request already failed over
+		k.mu.Unlock()
+		return true
+	}
+	if k.idx+1 >= len(k.keys) {
+		k.mu.Unlock()
+		slog.Error("hue api key rejected and no other key left", "index", k.idx)
+		return false
+	}
+	k.idx++
+	idx := k.idx
+	cb := k.OnFailover
+	k.mu.Unlock()
+
+	slog.Error("hue api key rejected by bridge; failing over to next key", "index", idx)
+	if cb != nil {
+		cb(idx)
+	}
+	return true
+}
+
+// Transport wraps base so every request carries the active key and is retried
+// with the next key when the bridge answers 401/403.
+func (k *Keys) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &keyTransport{keys: k, base: base}
+}
+
+type keyTransport struct {
+	keys *Keys
+	base http.RoundTripper
+}
+
+func (t *keyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		key := t.keys.Current()
+		r := req.Clone(req.Context())
+		r.Header.Set("hue-application-key", key)
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			return resp, nil
+		}
+		if req.Body != nil && req.GetBody == nil {
+			// body can't be replayed
+			return resp, nil
+		}
+		if !t.keys.Failover(key) {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+	}
+}
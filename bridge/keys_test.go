@@ -0,0 +1,61 @@
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeysTransport_FailsOverOnForbidden(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("hue-application-key") != "secondary" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var failedOver int
+	keys := NewKeys("primary", "secondary")
+	keys.OnFailover = func(idx int) { failedOver = idx }
+
+	client := &http.Client{Transport: keys.Transport(nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if keys.Current() != "secondary" {
+		t.Errorf("Current() = %q, want %q", keys.Current(), "secondary")
+	}
+	if failedOver != 1 {
+		t.Errorf("OnFailover index = %d, want 1", failedOver)
+	}
+}
+
+func TestKeysTransport_NoKeysLeft(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	keys := NewKeys("primary", "")
+	client := &http.Client{Transport: keys.Transport(nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if keys.Current() != "primary" {
+		t.Errorf("Current() = %q, want %q", keys.Current(), "primary")
+	}
+}
@@ -0,0 +1,126 @@
+package bridge
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxConcurrent is how many requests Home sends in parallel; the bridge
+// slows down and starts dropping requests beyond about three.
+const DefaultMaxConcurrent = 3
+
+// EndpointStats summarizes the requests to one endpoint since start.
+type EndpointStats struct {
+	Endpoint   string  `json:"endpoint"` // "PUT /clip/v2/resource/light/{id}"
+	Requests   uint64  `json:"requests"`
+	Errors     uint64  `json:"errors"` // transport errors and 4xx/5xx answers
+	AvgLatency float64 `json:"avg_latency_ms"`
+	MaxLatency float64 `json:"max_latency_ms"`
+	AvgWait    float64 `json:"avg_wait_ms"` // queued behind the concurrency limit
+}
+
+type endpointStats struct {
+	requests uint64
+	errors   uint64
+	latency  time.Duration
+	max      time.Duration
+	wait     time.Duration
+}
+
+// limitTransport bounds the requests in flight and records per-endpoint metrics.
+type limitTransport struct {
+	base http.RoundTripper
+	sem  atomic.Pointer[chan struct{}]
+
+	mu    sync.Mutex
+	stats map[string]*endpointStats
+}
+
+func newLimitTransport(base http.RoundTripper, n int) *limitTransport {
+	t := &limitTransport{base: base, stats: make(map[string]*endpointStats)}
+	t.setLimit(n)
+	return t
+}
+
+func (t *limitTransport) setLimit(n int) {
+	if n <= 0 {
+		n = DefaultMaxConcurrent
+	}
+	sem := make(chan struct{}, n)
+	t.sem.Store(&sem)
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	queued := time.Now()
+	sem := *t.sem.Load()
+	select {
+	case sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-sem }()
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	t.record(req.Method+" "+endpoint(req.URL.Path), start.Sub(queued), time.Since(start), err != nil || resp.StatusCode >= http.StatusBadRequest)
+	return resp, err
+}
+
+func (t *limitTransport) record(key string, wait, latency time.Duration, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.stats[key]
+	if !ok {
+		s = &endpointStats{}
+		t.stats[key] = s
+	}
+	s.requests++
+	if failed {
+		s.errors++
+	}
+	s.latency += latency
+	s.wait += wait
+	s.max = max(s.max, latency)
+}
+
+func (t *limitTransport) snapshot() []EndpointStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]EndpointStats, 0, len(t.stats))
+	for key, s := range t.stats {
+		out = append(out, EndpointStats{
+			Endpoint:   key,
+			Requests:   s.requests,
+			Errors:     s.errors,
+			AvgLatency: ms(s.latency) / float64(s.requests),
+			MaxLatency: ms(s.max),
+			AvgWait:    ms(s.wait) / float64(s.requests),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Endpoint < out[j].Endpoint })
+	return out
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// endpoint replaces resource ids and the v1 application key in path, e.g.
+// "/clip/v2/resource/light/<uuid>" → "/clip/v2/resource/light/{id}" and
+// "/api/<key>/lights" → "/api/{key}/lights".
+func endpoint(path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(segs) >= 2 && segs[0] == "api":
+		segs[1] = "{key}"
+	case len(segs) >= 4 && segs[0] == "clip" && segs[2] == "resource":
+		for i := 4; i < len(segs); i++ {
+			segs[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimitTransport_BoundsConcurrency(t *testing.T) {
+	var inflight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/clip/v2/resource/light/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	limit := newLimitTransport(http.DefaultTransport, 2)
+	client := &http.Client{Transport: limit}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := "/clip/v2/resource/light/abc"
+			if i == 0 {
+				path = "/clip/v2/resource/light/missing"
+			}
+			resp, err := client.Get(srv.URL + path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", p)
+	}
+	stats := limit.snapshot()
+	if len(stats) != 1 || stats[0].Endpoint != "GET /clip/v2/resource/light/{id}" {
+		t.Fatalf("stats = %+v, want one GET light endpoint", stats)
+	}
+	if stats[0].Requests != 8 || stats[0].Errors != 1 {
+		t.Errorf("requests/errors = %d/%d, want 8/1", stats[0].Requests, stats[0].Errors)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/clip/v2/resource/light/0b7e4d2a-91c3-4f6e-8d21-5a9c3b7e1f02", "/clip/v2/resource/light/{id}"},
+		{"/clip/v2/resource/device", "/clip/v2/resource/device"},
+		{"/api/secret-key", "/api/{key}"},
+		{"/api/secret-key/lights", "/api/{key}/lights"},
+		{"/eventstream/clip/v2", "/eventstream/clip/v2"},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			if got := endpoint(tt.path); got != tt.want {
+				t.Errorf("endpoint(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
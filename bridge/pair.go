@@ -0,0 +1,71 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrLinkButton is returned by Pair until the bridge's link button is pressed.
+var ErrLinkButton = errors.New("link button not pressed")
+
+// Credentials are what pairing with the bridge yields.
+type Credentials struct {
+	Username  string `json:"username"`  // application key, sent as hue-application-key
+	ClientKey string `json:"clientkey"` // entertainment streaming key
+}
+
+// Pair registers deviceType (e.g. "loxone-philips-hue#gateway") as an
+// application on the bridge. It fails with ErrLinkButton unless the link button
+// was pressed in the last 30 seconds, so callers retry while the user walks over.
+func Pair(ctx context.Context, addr *Address, deviceType string) (Credentials, error) {
+	body, err := json.Marshal(map[string]any{"devicetype": deviceType, "generateclientkey": true})
+	if err != nil {
+		return Credentials{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+addr.Host()+"/api", bytes.NewReader(body))
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c := &http.Client{Transport: newTransport(addr)}
+	resp, err := c.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("pair: %s", resp.Status)
+	}
+	return parsePairResponse(resp.Body)
+}
+
+// parsePairResponse reads the v1 style reply, e.g.
+// [{"error":{"type":101,"description":"link button not pressed"}}].
+func parsePairResponse(r io.Reader) (Credentials, error) {
+	var res []struct {
+		Success *Credentials `json:"success"`
+		Error   *struct {
+			Type        int    `json:"type"`
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return Credentials{}, fmt.Errorf("pair: %w", err)
+	}
+	switch {
+	case len(res) == 0:
+		return Credentials{}, errors.New("pair: empty response")
+	case res[0].Error != nil && res[0].Error.Type == 101:
+		return Credentials{}, ErrLinkButton
+	case res[0].Error != nil:
+		return Credentials{}, fmt.Errorf("pair: %s", res[0].Error.Description)
+	case res[0].Success == nil || res[0].Success.Username == "":
+		return Credentials{}, errors.New("pair: no application key in response")
+	}
+	return *res[0].Success, nil
+}
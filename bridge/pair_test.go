@@ -0,0 +1,46 @@
+package bridge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePairResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Credentials
+		wantErr error // nil with wantAny for errors without a sentinel
+		wantAny bool
+	}{
+		{
+			name: "paired",
+			body: `[{"success":{"username":"83b7780291a6ceffbe0bd049104df","clientkey":"33DDAD4A1CA09E2B9A5F6D0D6BF2D8C7"}}]`,
+			want: Credentials{Username: "83b7780291a6ceffbe0bd049104df", ClientKey: "33DDAD4A1CA09E2B9A5F6D0D6BF2D8C7"},
+		},
+		{name: "link button", body: `[{"error":{"type":101,"address":"","description":"link button not pressed"}}]`, wantErr: ErrLinkButton},
+		{name: "other error", body: `[{"error":{"type":7,"address":"/devicetype","description":"invalid value"}}]`, wantAny: true},
+		{name: "empty", body: `[]`, wantAny: true},
+		{name: "no key", body: `[{"success":{}}]`, wantAny: true},
+		{name: "not json", body: `<html>`, wantAny: true},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parsePairResponse(strings.NewReader(tt.body))
+			switch {
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("parsePairResponse() error = %v, want %v", err, tt.wantErr)
+			case tt.wantAny && err == nil:
+				t.Fatalf("parsePairResponse() = %+v, want an error", got)
+			case tt.wantErr == nil && !tt.wantAny && err != nil:
+				t.Fatalf("parsePairResponse() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parsePairResponse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
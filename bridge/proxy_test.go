@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAddressDialContext_ConnectProxy(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		conn, err := target.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("hello"))
+	}()
+
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	gotAuth := make(chan string, 1)
+	go func() {
+		conn, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != http.MethodConnect {
+			return
+		}
+		gotAuth <- req.Header.Get("Proxy-Authorization")
+		upstream, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			_, _ = conn.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+			return
+		}
+		defer upstream.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		_, _ = io.Copy(conn, upstream)
+	}()
+
+	addr := NewAddress("")
+	if err := addr.SetProxy("http://user:pass@" + proxy.Addr().String()); err != nil {
+		t.Fatalf("SetProxy() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := addr.DialContext(ctx, "tcp", target.Addr().String())
+	if err != nil {
+		t.Fatalf("DialContext() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	buf, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("read %q, want %q", buf, "hello")
+	}
+	if auth := <-gotAuth; auth != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Proxy-Authorization = %q, want %q", auth, "Basic dXNlcjpwYXNz")
+	}
+}
+
+func TestAddressSetProxy_InvalidScheme(t *testing.T) {
+	if err := NewAddress("").SetProxy("socks5://127.0.0.1:1080"); err == nil {
+		t.Error("SetProxy() expected error for socks5 scheme")
+	}
+}
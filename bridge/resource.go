@@ -0,0 +1,194 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ResourceRef points at another CLIP v2 resource.
+type ResourceRef struct {
+	Rid   string `json:"rid"`
+	Rtype string `json:"rtype"`
+}
+
+// Resource holds the fields shared by (most) CLIP v2 resources; Raw keeps the
+// full object for type-specific decoding.
+type Resource struct {
+	ID       string        `json:"id"`
+	IDv1     string        `json:"id_v1,omitempty"`
+	Type     string        `json:"type"`
+	Owner    *ResourceRef  `json:"owner,omitempty"`
+	Group    *ResourceRef  `json:"group,omitempty"`    // scenes
+	Children []ResourceRef `json:"children,omitempty"` // rooms, zones
+	Metadata *struct {
+		Name      string `json:"name"`
+		Archetype string `json:"archetype,omitempty"`
+	} `json:"metadata,omitempty"`
+	ProductData *struct {
+		ProductName string `json:"product_name"`
+	} `json:"product_data,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+// Name returns metadata.name, or "" if the resource has none.
+func (r *Resource) Name() string {
+	if r.Metadata == nil {
+		return ""
+	}
+	return r.Metadata.Name
+}
+
+// GetResource fetches a single resource of any type: GET /clip/v2/resource/<rtype>/<id>.
+func (h *Home) GetResource(ctx context.Context, rtype, id string) (*Resource, error) {
+	u := fmt.Sprintf("https://%s/clip/v2/resource/%s/%s", h.addr.Host(), url.PathEscape(rtype), url.PathEscape(id))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ApiError{StatusCode: resp.StatusCode}
+	}
+
+	var body struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode %s/%s: %w", rtype, id, err)
+	}
+	if len(body.Data) == 0 {
+		return nil, nil
+	}
+
+	var r Resource
+	if err := json.Unmarshal(body.Data[0], &r); err != nil {
+		return nil, fmt.Errorf("decode %s/%s: %w", rtype, id, err)
+	}
+	r.Raw = body.Data[0]
+	return &r, nil
+}
+
+// PutResource sends body unchanged to PUT /clip/v2/resource/<rtype>/<id>; an escape
+// hatch for fields the typed client does not cover.
+func (h *Home) PutResource(ctx context.Context, rtype, id string, body []byte) error {
+	return h.clip(ctx, http.MethodPut, rtype, id, body, nil)
+}
+
+// createResource posts body to /clip/v2/resource/<rtype> and returns the id the
+// bridge assigned.
+func (h *Home) createResource(ctx context.Context, rtype string, body []byte) (string, error) {
+	var refs []ResourceRef
+	if err := h.clip(ctx, http.MethodPost, rtype, "", body, &refs); err != nil {
+		return "", err
+	}
+	if len(refs) == 0 {
+		return "", fmt.Errorf("post %s: bridge returned no id", rtype)
+	}
+	return refs[0].Rid, nil
+}
+
+// clip sends a request to /clip/v2/resource/<rtype>[/<id>] and decodes the
+// "data" array of the response into out, if not nil.
+func (h *Home) clip(ctx context.Context, method, rtype, id string, body []byte, out any) error {
+	u := fmt.Sprintf("https://%s/clip/v2/resource/%s", h.addr.Host(), url.PathEscape(rtype))
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	op := strings.ToLower(method) + " " + rtype
+	if id != "" {
+		op += "/" + id
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("%s: %d: %s", op, resp.StatusCode, result.Errors[0].Description)
+		}
+		return &ApiError{StatusCode: resp.StatusCode}
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s: %s", op, result.Errors[0].Description)
+	}
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("decode %s: %w", op, err)
+	}
+	return nil
+}
+
+// Ping checks that the bridge answers CLIP v2 requests with the current key.
+func (h *Home) Ping(ctx context.Context) error {
+	u := fmt.Sprintf("https://%s/clip/v2/resource/bridge", h.addr.Host())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ApiError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Resources returns every CLIP v2 resource as the bridge sent it: GET /clip/v2/resource.
+func (h *Home) Resources(ctx context.Context) (json.RawMessage, error) {
+	u := fmt.Sprintf("https://%s/clip/v2/resource", h.addr.Host())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ApiError{StatusCode: resp.StatusCode}
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode resources: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,95 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// Limits are the documented capacities of the bridge's (v1) resource tables. Once
+// a table is full the bridge rejects new entries, and the Hue app often fails
+// without saying why.
+var Limits = map[string]int{
+	"lights":        63,
+	"sensors":       250,
+	"groups":        64,
+	"scenes":        200,
+	"rules":         250,
+	"schedules":     100,
+	"resourcelinks": 64,
+}
+
+// UsageWarnRatio is the fill level from which a table counts as nearly full.
+const UsageWarnRatio = 0.9
+
+// Usage is the fill level of one resource table.
+type Usage struct {
+	Resource string `json:"resource"`
+	Count    int    `json:"count"`
+	Limit    int    `json:"limit"`
+}
+
+// Ratio returns Count/Limit.
+func (u Usage) Ratio() float64 {
+	if u.Limit <= 0 {
+		return 0
+	}
+	return float64(u.Count) / float64(u.Limit)
+}
+
+// Near reports whether the table is at or above UsageWarnRatio.
+func (u Usage) Near() bool {
+	return u.Ratio() >= UsageWarnRatio
+}
+
+// Usage counts the entries of every table in Limits, sorted by resource name. It
+// reads the v1 full state since the limits are defined there (v2 has no rules).
+func (h *Home) Usage(ctx context.Context) ([]Usage, error) {
+	u := fmt.Sprintf("https://%s/api/%s", h.addr.Host(), url.PathEscape(h.keys.Current()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ApiError{StatusCode: resp.StatusCode}
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode full state: %w", err)
+	}
+	return countUsage(body)
+}
+
+func countUsage(body json.RawMessage) ([]Usage, error) {
+	// v1 answers errors as a 200 with an array, e.g. for an unknown key
+	var failure []struct {
+		Error *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &failure) == nil && len(failure) > 0 && failure[0].Error != nil {
+		return nil, fmt.Errorf("full state: %s", failure[0].Error.Description)
+	}
+
+	var state map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(body, &state); err != nil {
+		return nil, fmt.Errorf("decode full state: %w", err)
+	}
+	out := make([]Usage, 0, len(Limits))
+	for resource, limit := range Limits {
+		out = append(out, Usage{Resource: resource, Count: len(state[resource]), Limit: limit})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Resource < out[j].Resource })
+	return out, nil
+}
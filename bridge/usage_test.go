@@ -0,0 +1,56 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountUsage(t *testing.T) {
+	body := `{
+		"lights": {"1": {}, "2": {}},
+		"scenes": {"a": {}},
+		"rules": {},
+		"config": {"name": "Philips hue"}
+	}`
+	got, err := countUsage([]byte(body))
+	if err != nil {
+		t.Fatalf("countUsage() error = %v", err)
+	}
+	if len(got) != len(Limits) {
+		t.Fatalf("countUsage() returned %d tables, want %d", len(got), len(Limits))
+	}
+	counts := make(map[string]int)
+	for _, u := range got {
+		counts[u.Resource] = u.Count
+		if u.Limit != Limits[u.Resource] {
+			t.Errorf("%s limit = %d, want %d", u.Resource, u.Limit, Limits[u.Resource])
+		}
+	}
+	if counts["lights"] != 2 || counts["scenes"] != 1 || counts["rules"] != 0 {
+		t.Errorf("counts = %v, want lights=2 scenes=1 rules=0", counts)
+	}
+}
+
+func TestCountUsage_Error(t *testing.T) {
+	_, err := countUsage([]byte(`[{"error": {"type": 1, "address": "/", "description": "unauthorized user"}}]`))
+	if err == nil || !strings.Contains(err.Error(), "unauthorized user") {
+		t.Fatalf("countUsage() error = %v, want unauthorized user", err)
+	}
+}
+
+func TestUsageNear(t *testing.T) {
+	tests := []struct {
+		u    Usage
+		want bool
+	}{
+		{Usage{Count: 179, Limit: 200}, false},
+		{Usage{Count: 180, Limit: 200}, true},
+		{Usage{Count: 200, Limit: 200}, true},
+		{Usage{Count: 5, Limit: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.u.Near(); got != tt.want {
+			t.Errorf("%+v.Near() = %v, want %v", tt.u, got, tt.want)
+		}
+	}
+}
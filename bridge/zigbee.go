@@ -0,0 +1,107 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Zigbee connectivity states as reported by zigbee_connectivity.
+const (
+	ZigbeeConnected      = "connected"
+	ZigbeeDisconnected   = "disconnected"
+	ZigbeeIssue          = "connectivity_issue"
+	ZigbeeUnidirectional = "unidirectional_incoming"
+)
+
+// zigbeeIssueShare is the share of devices with issues from which Suggestion
+// gives advice.
+const zigbeeIssueShare = 0.2
+
+// ZigbeeConnectivity is the Zigbee link of one device. Only the bridge's own
+// entry carries Channel.
+type ZigbeeConnectivity struct {
+	ID    string `json:"id"`
+	Owner struct {
+		Rid   string `json:"rid"`
+		Rtype string `json:"rtype"`
+	} `json:"owner"`
+	Status     string `json:"status"`
+	MACAddress string `json:"mac_address"`
+	Channel    *struct {
+		Status string `json:"status"` // set, changing
+		Value  string `json:"value"`  // e.g. "channel_25"
+	} `json:"channel,omitempty"`
+}
+
+// GetZigbeeConnectivity lists the Zigbee link of every device, the bridge included.
+func (h *Home) GetZigbeeConnectivity(ctx context.Context) ([]ZigbeeConnectivity, error) {
+	var out []ZigbeeConnectivity
+	if err := h.clip(ctx, http.MethodGet, "zigbee_connectivity", "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ZigbeeSummary condenses the Zigbee links of a bridge.
+type ZigbeeSummary struct {
+	Channel  int            // 11..26; 0 when the bridge did not report it
+	Devices  int            // devices other than the bridge
+	ByStatus map[string]int // devices per status
+	Problems []ZigbeeConnectivity
+}
+
+// SummarizeZigbee counts links per status; Problems lists the devices that are
+// not connected, sorted by status and owner.
+func SummarizeZigbee(links []ZigbeeConnectivity) ZigbeeSummary {
+	s := ZigbeeSummary{ByStatus: make(map[string]int)}
+	for _, l := range links {
+		if l.Channel != nil { // the bridge itself
+			s.Channel, _ = strconv.Atoi(strings.TrimPrefix(l.Channel.Value, "channel_"))
+			continue
+		}
+		s.Devices++
+		s.ByStatus[l.Status]++
+		if l.Status != ZigbeeConnected {
+			s.Problems = append(s.Problems, l)
+		}
+	}
+	sort.Slice(s.Problems, func(i, j int) bool {
+		if s.Problems[i].Status != s.Problems[j].Status {
+			return s.Problems[i].Status < s.Problems[j].Status
+		}
+		return s.Problems[i].Owner.Rid < s.Problems[j].Owner.Rid
+	})
+	return s
+}
+
+// Suggestion returns advice when many devices have connectivity issues, or ""
+// when the network looks healthy.
+func (s ZigbeeSummary) Suggestion() string {
+	issues := s.ByStatus[ZigbeeIssue] + s.ByStatus[ZigbeeUnidirectional]
+	if s.Devices == 0 || issues < 2 || float64(issues)/float64(s.Devices) < zigbeeIssueShare {
+		return ""
+	}
+	advice := fmt.Sprintf("%d of %d devices report connectivity issues, which usually means interference or weak routes: ", issues, s.Devices)
+	if wifi := overlappingWiFi(s.Channel); wifi != 0 {
+		advice += fmt.Sprintf("Zigbee channel %d overlaps Wi-Fi channel %d, so move either (Zigbee 15, 20 or 25 sit between Wi-Fi 1, 6 and 11); ", s.Channel, wifi)
+	}
+	return advice + "add mains powered lights or plugs as routers near the affected devices and keep the bridge away from Wi-Fi access points and USB 3 devices"
+}
+
+// overlappingWiFi returns the 2.4 GHz Wi-Fi channel (1, 6 or 11) that overlaps
+// Zigbee channel ch, or 0 if none does.
+func overlappingWiFi(ch int) int {
+	switch {
+	case ch >= 11 && ch <= 14:
+		return 1
+	case ch >= 16 && ch <= 19:
+		return 6
+	case ch >= 21 && ch <= 24:
+		return 11
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package bridge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeZigbee(t *testing.T) {
+	body := `[
+		{"id": "z0", "owner": {"rid": "bridge-dev", "rtype": "device"}, "status": "connected", "channel": {"status": "set", "value": "channel_17"}},
+		{"id": "z1", "owner": {"rid": "dev-1", "rtype": "device"}, "status": "connected"},
+		{"id": "z2", "owner": {"rid": "dev-3", "rtype": "device"}, "status": "connectivity_issue"},
+		{"id": "z3", "owner": {"rid": "dev-2", "rtype": "device"}, "status": "connectivity_issue"},
+		{"id": "z4", "owner": {"rid": "dev-4", "rtype": "device"}, "status": "disconnected"}
+	]`
+	var links []ZigbeeConnectivity
+	if err := json.Unmarshal([]byte(body), &links); err != nil {
+		t.Fatal(err)
+	}
+	s := SummarizeZigbee(links)
+	if s.Channel != 17 || s.Devices != 4 {
+		t.Errorf("Channel, Devices = %d, %d; want 17, 4", s.Channel, s.Devices)
+	}
+	if s.ByStatus[ZigbeeIssue] != 2 || s.ByStatus[ZigbeeConnected] != 1 || s.ByStatus[ZigbeeDisconnected] != 1 {
+		t.Errorf("ByStatus = %v", s.ByStatus)
+	}
+	var problems []string
+	for _, p := range s.Problems {
+		problems = append(problems, p.Owner.Rid)
+	}
+	if got := strings.Join(problems, ","); got != "dev-2,dev-3,dev-4" {
+		t.Errorf("Problems = %s, want dev-2,dev-3,dev-4", got)
+	}
+	if got := s.Suggestion(); !strings.Contains(got, "overlaps Wi-Fi channel 6") {
+		t.Errorf("Suggestion() = %q, want the Wi-Fi overlap", got)
+	}
+
+	healthy := SummarizeZigbee(links[:2])
+	if got := healthy.Suggestion(); got != "" {
+		t.Errorf("healthy Suggestion() = %q, want none", got)
+	}
+}
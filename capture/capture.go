@@ -0,0 +1,150 @@
+// Package capture writes raw bridge events and Loxone datagrams to a rotating
+// file (--capture-raw), for debugging payloads without debug logging.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Path of the capture file; rotated files get ".1", ".2", ... appended.
+	Path string
+
+	// MaxSize is the size in bytes at which the file is rotated. Default 10 MiB.
+	MaxSize int64
+
+	// MaxFiles is the number of files kept, including the current one. Default 3.
+	MaxFiles int
+
+	// Redact lists secrets (API keys) replaced by "<redacted>".
+	Redact []string
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Writer appends one line per payload: "<time> <kind> <source> <payload>".
+// A nil Writer discards everything, so callers need no checks.
+type Writer struct {
+	cfg    Config
+	log    *slog.Logger
+	redact *strings.Replacer
+	now    func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func New(cfg Config) (*Writer, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("capture: Path required")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = 3
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	var pairs []string
+	for _, s := range cfg.Redact {
+		if s != "" {
+			pairs = append(pairs, s, "<redacted>")
+		}
+	}
+	w := &Writer{
+		cfg:    cfg,
+		log:    cfg.Logger.With("module", "capture", "path", cfg.Path),
+		redact: strings.NewReplacer(pairs...),
+		now:    time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("capture: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("capture: %w", err)
+	}
+	w.f, w.size = f, st.Size()
+	return nil
+}
+
+// Write records payload, e.g. Write("sse", "192.168.1.2", body). Newlines in
+// payload are escaped so each record stays on one line.
+func (w *Writer) Write(kind, source string, payload []byte) {
+	if w == nil {
+		return
+	}
+	p := strings.ReplaceAll(strings.ReplaceAll(string(payload), "\r", `\r`), "\n", `\n`)
+	line := w.now().Format(time.RFC3339Nano) + " " + kind + " " + source + " " + w.redact.Replace(p) + "\n"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return // closed, or reopening failed
+	}
+	if w.size > 0 && w.size+int64(len(line)) > w.cfg.MaxSize {
+		if err := w.rotate(); err != nil {
+			w.log.Error("capture rotation failed; capture stopped", "error", err)
+			return
+		}
+	}
+	n, err := w.f.WriteString(line)
+	w.size += int64(n)
+	if err != nil {
+		w.log.Warn("capture write failed", "error", err)
+	}
+}
+
+// rotate shifts path → path.1 → path.2 ..., dropping the oldest.
+func (w *Writer) rotate() error {
+	w.f.Close()
+	w.f = nil
+	for i := w.cfg.MaxFiles - 1; i > 0; i-- {
+		from := w.cfg.Path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", w.cfg.Path, i-1)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", w.cfg.Path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if w.cfg.MaxFiles == 1 {
+		if err := os.Remove(w.cfg.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return w.open()
+}
+
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
@@ -0,0 +1,55 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.log")
+	w, err := New(Config{Path: path, MaxSize: 200, MaxFiles: 2, Redact: []string{"secret-key"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	w.Write("sse", "10.0.0.2", []byte("[{\"key\": \"secret-key\"}]\n"))
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2026-01-02T03:04:05Z sse 10.0.0.2 [{\"key\": \"<redacted>\"}]\\n\n"
+	if string(b) != want {
+		t.Fatalf("capture = %q, want %q", b, want)
+	}
+
+	for range 10 {
+		w.Write("udp", "10.0.0.3:5000", []byte(strings.Repeat("x", 50)))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{path, path + ".1"} {
+		st, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Size() > 200 {
+			t.Errorf("%s is %d bytes, want at most 200", name, st.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("%s.2 exists beyond MaxFiles", path)
+	}
+}
+
+func TestNilWriter(t *testing.T) {
+	var w *Writer
+	w.Write("sse", "x", []byte("y"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
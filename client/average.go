@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+type AverageConfig struct {
+	// Channels are the sensor channels to average. Default temperature.
+	Channels []resource.Metric
+
+	// Weights per device id or lowercase device name; default 1, 0 leaves a
+	// sensor out (e.g. one mounted above a radiator).
+	Weights map[string]float64
+
+	// Zones maps a zone name to the rooms (by id or name) it spans; each zone is
+	// averaged over all of its rooms as /zone/<name>/<channel>_avg.
+	Zones map[string][]string
+
+	// Outlier is how far a reading may be from the median of its room or zone
+	// before it is ignored; only applied with three or more sensors. Default 3.
+	Outlier float64
+
+	// MaxAge drops readings of sensors that stopped reporting. Default 2h.
+	MaxAge time.Duration
+}
+
+// Averages is a MessageHook that follows sensor readings and adds the weighted
+// average of all sensors in the room as /room/<name>/<channel>_avg, e.g. for a
+// Loxone intelligent room controller that takes a single temperature.
+type Averages struct {
+	names *Poller
+	cfg   AverageConfig
+	zones map[string][]string // key: lowercase room id or name, value: zone names
+	now   func() time.Time
+
+	mu       sync.Mutex
+	readings map[resource.Metric]map[string]reading // key: channel, then device id
+}
+
+type reading struct {
+	value float64
+	at    time.Time
+}
+
+func NewAverages(names *Poller, cfg AverageConfig) *Averages {
+	if len(cfg.Channels) == 0 {
+		cfg.Channels = []resource.Metric{resource.MetricTemperature}
+	}
+	if cfg.Outlier <= 0 {
+		cfg.Outlier = 3
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 2 * time.Hour
+	}
+	a := &Averages{
+		names:    names,
+		cfg:      cfg,
+		zones:    make(map[string][]string),
+		now:      time.Now,
+		readings: make(map[resource.Metric]map[string]reading, len(cfg.Channels)),
+	}
+	for _, ch := range cfg.Channels {
+		a.readings[ch] = make(map[string]reading)
+	}
+	for zone, rooms := range cfg.Zones {
+		for _, room := range rooms {
+			key := strings.ToLower(room)
+			a.zones[key] = append(a.zones[key], zone)
+		}
+	}
+	for _, zs := range a.zones {
+		sort.Strings(zs)
+	}
+	return a
+}
+
+// ParseWeights converts {"hall sensor": "0.5"} to weights.
+func ParseWeights(raw map[string]string) (map[string]float64, error) {
+	out := make(map[string]float64, len(raw))
+	for sensor, v := range raw {
+		w, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid average_weights %s=%q: expected a number >= 0", sensor, v)
+		}
+		out[strings.ToLower(sensor)] = w
+	}
+	return out, nil
+}
+
+// averageChannel returns the channel the average of channel is sent on.
+func averageChannel(channel resource.Metric) resource.Metric {
+	return channel + "_avg"
+}
+
+func (a *Averages) Process(ctx context.Context, msg Message) ([]Message, error) {
+	readings, ok := a.readings[msg.Channel]
+	if !ok || !strings.HasPrefix(msg.Path, "/sensor/") {
+		return []Message{msg}, nil
+	}
+	v, err := strconv.ParseFloat(msg.Value, 64)
+	if err != nil {
+		return []Message{msg}, nil
+	}
+	inv := a.names.Snapshot()
+	room := inv.RoomID(string(msg.ID))
+	if room == "" {
+		return []Message{msg}, nil
+	}
+
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	readings[string(msg.ID)] = reading{value: v, at: now}
+
+	out := []Message{msg}
+	avg := msg
+	avg.Channel = averageChannel(msg.Channel)
+	if name := cleanName(inv.Alias(room)); name != "" {
+		if v, ok := a.average(inv, readings, now, func(r string) bool { return r == room }); ok {
+			avg.ID = resource.ID(room)
+			avg.Path = "/room/" + name + "/" + string(avg.Channel)
+			avg.Value = strconv.FormatFloat(v, 'f', 2, 64)
+			out = append(out, avg)
+		}
+	}
+	for _, zone := range a.zonesOf(inv, room) {
+		if v, ok := a.average(inv, readings, now, func(r string) bool { return a.inZone(inv, r, zone) }); ok {
+			avg.ID = ""
+			avg.Path = "/zone/" + cleanName(zone) + "/" + string(avg.Channel)
+			avg.Value = strconv.FormatFloat(v, 'f', 2, 64)
+			out = append(out, avg)
+		}
+	}
+	return out, nil
+}
+
+// average returns the weighted mean of the fresh readings of sensors in the
+// rooms matched by in, leaving out outliers. Callers hold a.mu.
+func (a *Averages) average(inv *Inventory, readings map[string]reading, now time.Time, in func(room string) bool) (float64, bool) {
+	type sample struct{ value, weight float64 }
+	var samples []sample
+	for id, r := range readings {
+		if now.Sub(r.at) > a.cfg.MaxAge {
+			delete(readings, id)
+			continue
+		}
+		if !in(inv.RoomID(id)) {
+			continue
+		}
+		if w := a.weight(inv, id); w > 0 {
+			samples = append(samples, sample{r.value, w})
+		}
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+	var median float64
+	if len(samples) >= 3 {
+		values := make([]float64, len(samples))
+		for i, s := range samples {
+			values[i] = s.value
+		}
+		sort.Float64s(values)
+		median = values[len(values)/2]
+		if len(values)%2 == 0 {
+			median = (median + values[len(values)/2-1]) / 2
+		}
+	}
+	var sum, weights float64
+	for _, s := range samples {
+		if len(samples) >= 3 && math.Abs(s.value-median) > a.cfg.Outlier {
+			continue
+		}
+		sum += s.value * s.weight
+		weights += s.weight
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return sum / weights, true
+}
+
+func (a *Averages) weight(inv *Inventory, id string) float64 {
+	if w, ok := a.cfg.Weights[strings.ToLower(id)]; ok {
+		return w
+	}
+	if w, ok := a.cfg.Weights[strings.ToLower(inv.Alias(id))]; ok {
+		return w
+	}
+	return 1
+}
+
+// zonesOf returns the configured zones spanning room.
+func (a *Averages) zonesOf(inv *Inventory, room string) []string {
+	zones := a.zones[strings.ToLower(room)]
+	if name := strings.ToLower(inv.Alias(room)); name != "" {
+		zones = append(zones[:len(zones):len(zones)], a.zones[name]...)
+	}
+	return zones
+}
+
+func (a *Averages) inZone(inv *Inventory, room, zone string) bool {
+	for _, z := range a.zonesOf(inv, room) {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
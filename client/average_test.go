@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func averagePoller() *Poller {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.names["room-1"] = Device{Name: "Living Room", Alias: "Living Room", Type: "room"}
+		inv.names["room-2"] = Device{Name: "Kitchen", Alias: "Kitchen", Type: "room"}
+		inv.names["s3"] = Device{Name: "Radiator", Alias: "Radiator", Type: "device"}
+		for id, room := range map[string]string{"s1": "room-1", "s2": "room-1", "s3": "room-1", "s4": "room-1", "s5": "room-2"} {
+			inv.rooms[id] = room
+		}
+	})
+	return p
+}
+
+func temperature(id, value string) Message {
+	return Message{Type: resource.TypeTemperature, ID: resource.ID(id), Path: "/sensor/" + id + "/temperature", Channel: resource.MetricTemperature, Value: value}
+}
+
+func TestAverages_Process(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      AverageConfig
+		readings []Message
+		want     []string // "<path> <value>" after the last reading, excluding the reading itself
+	}{
+		{
+			name:     "single sensor",
+			readings: []Message{temperature("s1", "21.00")},
+			want:     []string{"/room/living_room/temperature_avg 21.00"},
+		},
+		{
+			name:     "mean of the room",
+			readings: []Message{temperature("s1", "21.00"), temperature("s2", "22.50")},
+			want:     []string{"/room/living_room/temperature_avg 21.75"},
+		},
+		{
+			name:     "zone spans rooms",
+			cfg:      AverageConfig{Zones: map[string][]string{"Ground Floor": {"living room", "room-2"}}},
+			readings: []Message{temperature("s1", "21.00"), temperature("s2", "22.00"), temperature("s5", "24.00")},
+			want:     []string{"/room/kitchen/temperature_avg 24.00", "/zone/ground_floor/temperature_avg 22.33"},
+		},
+		{
+			name:     "weighted by name",
+			cfg:      AverageConfig{Weights: map[string]float64{"radiator": 0, "s2": 3}},
+			readings: []Message{temperature("s1", "20.00"), temperature("s3", "28.00"), temperature("s2", "24.00")},
+			want:     []string{"/room/living_room/temperature_avg 23.00"},
+		},
+		{
+			name:     "outlier dropped",
+			readings: []Message{temperature("s1", "21.00"), temperature("s2", "21.50"), temperature("s4", "22.00"), temperature("s3", "35.00")},
+			want:     []string{"/room/living_room/temperature_avg 21.50"},
+		},
+		{
+			name:     "unknown room passes through",
+			readings: []Message{temperature("s9", "21.00")},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := NewAverages(averagePoller(), tt.cfg)
+			var out []Message
+			for _, m := range tt.readings {
+				var err error
+				if out, err = a.Process(context.Background(), m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []string
+			for _, m := range out[1:] {
+				got = append(got, m.Path+" "+m.Value)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAverages_ForgetsSilentSensors(t *testing.T) {
+	a := NewAverages(averagePoller(), AverageConfig{MaxAge: time.Hour})
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	if _, err := a.Process(context.Background(), temperature("s1", "10.00")); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Hour)
+	out, err := a.Process(context.Background(), temperature("s2", "20.00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[1].Value != "20.00" {
+		t.Errorf("out = %+v, want the average of s2 only", out)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	if _, err := ParseWeights(map[string]string{"hall": "-1"}); err == nil {
+		t.Error("negative weight accepted")
+	}
+	w, err := ParseWeights(map[string]string{"Hall Sensor": " 0.5"})
+	if err != nil || w["hall sensor"] != 0.5 {
+		t.Errorf("ParseWeights() = %v, %v", w, err)
+	}
+}
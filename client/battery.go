@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type BatteryConfig struct {
+	// Sender receives /device/<id>/battery_low 0|1.
+	Sender gateway.Sender
+
+	// Threshold is the battery level (percent) at or below which a device is low.
+	// Default 20.
+	Threshold float64
+
+	// Hysteresis is how far above Threshold the level must recover before the
+	// alert clears, so a level flapping around the threshold alerts once. Default 5.
+	Hysteresis float64
+
+	// Repeat re-sends battery_low 1 of devices still low at this interval; 0 only
+	// sends it when the battery turns low.
+	Repeat time.Duration
+
+	// Poll (optional) returns the battery level per device id; events only cover
+	// changes, polling catches devices that were low before the gateway started.
+	Poll func(ctx context.Context) (map[string]float64, error)
+
+	// PollInterval between polls. Default 1h.
+	PollInterval time.Duration
+
+	// Bools (optional) renders the battery_low value; default 1/0.
+	Bools *udp.Bools
+}
+
+// Batteries turns battery levels into a per-device /device/<id>/battery_low
+// alert with hysteresis, instead of every sensor needing threshold logic in Loxone.
+type Batteries struct {
+	cfg BatteryConfig
+	now func() time.Time
+
+	mu  sync.Mutex
+	low map[string]time.Time // key: device id, value: last battery_low 1 sent
+}
+
+func NewBatteries(cfg BatteryConfig) *Batteries {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 20
+	}
+	if cfg.Hysteresis <= 0 {
+		cfg.Hysteresis = 5
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Hour
+	}
+	return &Batteries{cfg: cfg, now: time.Now, low: make(map[string]time.Time)}
+}
+
+// Observe records the battery level of device.
+func (b *Batteries) Observe(device string, level float64) {
+	if b == nil || device == "" {
+		return
+	}
+	b.mu.Lock()
+	_, low := b.low[device]
+	var report, value bool
+	switch {
+	case !low && level <= b.cfg.Threshold:
+		b.low[device] = b.now()
+		report, value = true, true
+	case low && level >= b.cfg.Threshold+b.cfg.Hysteresis:
+		delete(b.low, device)
+		report, value = true, false
+	}
+	b.mu.Unlock()
+
+	if report {
+		slog.Info("battery low changed", "device", device, "level", level, "low", value)
+		b.send(device, value)
+	}
+}
+
+// Run polls the levels and repeats the alerts of low devices until ctx is done.
+func (b *Batteries) Run(ctx context.Context) error {
+	b.poll(ctx)
+	interval := b.cfg.PollInterval
+	if b.cfg.Repeat > 0 && b.cfg.Repeat < interval {
+		interval = b.cfg.Repeat
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPoll := b.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if b.now().Sub(lastPoll) >= b.cfg.PollInterval {
+			b.poll(ctx)
+			lastPoll = b.now()
+		}
+		b.repeat()
+	}
+}
+
+func (b *Batteries) poll(ctx context.Context) {
+	if b.cfg.Poll == nil {
+		return
+	}
+	levels, err := b.cfg.Poll(ctx)
+	if err != nil {
+		slog.Warn("battery poll failed", "error", err.Error())
+		return
+	}
+	for device, level := range levels {
+		b.Observe(device, level)
+	}
+}
+
+// repeat re-sends the alert of devices that have been low for Repeat.
+func (b *Batteries) repeat() {
+	if b.cfg.Repeat <= 0 {
+		return
+	}
+	now := b.now()
+	var due []string
+	b.mu.Lock()
+	for device, sent := range b.low {
+		if now.Sub(sent) >= b.cfg.Repeat {
+			b.low[device] = now
+			due = append(due, device)
+		}
+	}
+	b.mu.Unlock()
+	for _, device := range due {
+		b.send(device, true)
+	}
+}
+
+func (b *Batteries) send(device string, low bool) {
+	if b.cfg.Sender != nil {
+		b.cfg.Sender.Send([]byte(fmt.Sprintf("/device/%s/battery_low %s", device, b.cfg.Bools.Format("battery_low", low))))
+	}
+}
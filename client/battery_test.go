@@ -0,0 +1,32 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBatteries(t *testing.T) {
+	now := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+	sender := &recordSender{}
+	b := NewBatteries(BatteryConfig{Sender: sender, Threshold: 20, Hysteresis: 5, Repeat: 24 * time.Hour})
+	b.now = func() time.Time { return now }
+
+	for _, level := range []float64{40, 20, 19, 22, 20, 24} { // flaps around the threshold
+		b.Observe("a", level)
+	}
+	now = now.Add(12 * time.Hour)
+	b.repeat()
+	now = now.Add(12 * time.Hour)
+	b.repeat()
+	b.Observe("a", 25)
+
+	want := []string{
+		"/device/a/battery_low 1",
+		"/device/a/battery_low 1", // repeated after a day
+		"/device/a/battery_low 0",
+	}
+	if !reflect.DeepEqual(sender.msgs, want) {
+		t.Errorf("msgs = %q, want %q", sender.msgs, want)
+	}
+}
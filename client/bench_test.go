@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The event→UDP hot path runs once per bridge event; on a Pi Zero it should
+// stay well below a millisecond per event with debug logging off. Run with
+//
+//	go test ./client -run '^$' -bench . -benchmem
+
+type discardSink struct{}
+
+func (discardSink) Write(Message) {}
+
+func loadPayloads(b *testing.B) map[string][]EventContainer {
+	b.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "events", "*.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	payloads := make(map[string][]EventContainer)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		// unknown types are logged at warn level, which is not the hot path
+		if strings.HasSuffix(name, ".golden") || name == "unknown" {
+			continue
+		}
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var containers []EventContainer
+		if err := json.Unmarshal(raw, &containers); err != nil {
+			b.Fatalf("%s: %v", file, err)
+		}
+		payloads[name] = containers
+	}
+	return payloads
+}
+
+func BenchmarkHandle(b *testing.B) {
+	for name, containers := range loadPayloads(b) {
+		containers := containers // capture range var
+		b.Run(name, func(b *testing.B) {
+			e := goldenStreamer(b, discardSink{})
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := e.handle(ctx, containers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeResource(b *testing.B) {
+	for name, containers := range loadPayloads(b) {
+		raw := containers[0].Data[0]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := decodeResource(raw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMessageBytes(b *testing.B) {
+	msg := Message{Path: "/sensor/00000001-1111-4222-8333-000000000001/temperature", Value: "21.50", Origin: OriginApp}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = msg.Bytes()
+	}
+}
+
+func BenchmarkPayloadDecode(b *testing.B) {
+	for name, containers := range loadPayloads(b) {
+		raw, err := json.Marshal(containers)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			p := &payload{}
+			b.ReportAllocs()
+			for b.Loop() {
+				p.reset()
+				p.appendLine(raw)
+				if err := p.decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
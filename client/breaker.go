@@ -0,0 +1,135 @@
+package client
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// DefaultLowPriority are the channels a Breaker drops during an event storm:
+// analog values that a later message supersedes anyway.
+var DefaultLowPriority = []resource.Metric{
+	resource.MetricBrightness, resource.MetricLightLevel, resource.MetricTemperature,
+	resource.MetricHumidity, resource.MetricPower, resource.MetricEnergy,
+}
+
+type BreakerConfig struct {
+	// Limit is the messages per second to Loxone above which the breaker opens.
+	Limit int
+
+	// Hold is how long the breaker stays open; it closes at the first second
+	// after Hold that is below Limit again. Default 10s.
+	Hold time.Duration
+
+	// Low lists the channels dropped while open. Nil means DefaultLowPriority.
+	Low []resource.Metric
+
+	// State (optional) exposes the breaker in the health and /gateway/event_storm.
+	State *gateway.State
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Breaker guards Loxone and the logs against event storms, e.g. a runaway
+// dynamic scene across dozens of lights: above Limit messages per second it
+// drops low-priority channels for a while, logging once when it opens and once
+// when it closes. A nil Breaker allows everything.
+type Breaker struct {
+	cfg BreakerConfig
+	log *slog.Logger
+	low map[resource.Metric]bool
+	now func() time.Time
+
+	mu      sync.Mutex
+	window  time.Time // start of the current second
+	count   int       // messages in the current second
+	until   time.Time // open until; zero while closed
+	dropped uint64    // during the current trip
+	status  gateway.BreakerStatus
+}
+
+func NewBreaker(cfg BreakerConfig) (*Breaker, error) {
+	if cfg.Limit <= 0 {
+		return nil, errors.New("breaker: Limit required")
+	}
+	if cfg.Hold <= 0 {
+		cfg.Hold = 10 * time.Second
+	}
+	if cfg.Low == nil {
+		cfg.Low = DefaultLowPriority
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	low := make(map[resource.Metric]bool, len(cfg.Low))
+	for _, m := range cfg.Low {
+		low[m] = true
+	}
+	return &Breaker{
+		cfg: cfg,
+		log: cfg.Logger.With("module", "breaker"),
+		low: low,
+		now: time.Now,
+	}, nil
+}
+
+// Allow counts msg and reports whether it may be sent.
+func (b *Breaker) Allow(msg Message) bool {
+	if b == nil {
+		return true
+	}
+	now := b.now()
+	b.mu.Lock()
+	if now.Sub(b.window) >= time.Second {
+		b.roll(now)
+	}
+	b.count++
+	if b.until.IsZero() && b.count > b.cfg.Limit {
+		b.until = now.Add(b.cfg.Hold)
+		b.status.Open = true
+		b.status.Trips++
+		b.log.Warn("event storm; dropping low-priority channels", "limit_per_second", b.cfg.Limit, "hold", b.cfg.Hold.String())
+		b.report()
+	}
+	allow := b.until.IsZero() || !b.low[msg.Channel]
+	if !allow {
+		b.dropped++
+		b.status.Dropped++
+	}
+	b.mu.Unlock()
+	return allow
+}
+
+// roll starts a new one-second window at now; an open breaker whose hold is
+// over closes if the last second stayed within the limit, or is held again.
+// While open the status is reported once per window.
+func (b *Breaker) roll(now time.Time) {
+	calm := b.count <= b.cfg.Limit || now.Sub(b.window) >= 2*time.Second // a silent second counts as calm
+	b.window, b.count = now, 0
+	if b.until.IsZero() {
+		return
+	}
+	if now.Before(b.until) || !calm {
+		if !now.Before(b.until) {
+			b.until = now.Add(b.cfg.Hold)
+		}
+		b.report()
+		return
+	}
+	b.log.Info("event storm over", "dropped", b.dropped)
+	b.until, b.dropped = time.Time{}, 0
+	b.status.Open = false
+	b.report()
+}
+
+// report must be called with b.mu held.
+func (b *Breaker) report() {
+	if b.cfg.State != nil {
+		b.cfg.State.SetBreaker(b.status)
+	}
+}
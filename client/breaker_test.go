@@ -0,0 +1,70 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	state := gateway.NewState(nil)
+	b, err := NewBreaker(BreakerConfig{Limit: 3, Hold: 5 * time.Second, State: state})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	brightness := Message{Path: "/group/gl-1/brightness", Channel: resource.MetricBrightness}
+	motion := Message{Path: "/sensor/dev-1/motion", Channel: resource.MetricMotion}
+
+	for i := 0; i < 3; i++ {
+		if !b.Allow(brightness) {
+			t.Fatalf("message %d within the limit dropped", i)
+		}
+	}
+	if b.Allow(brightness) {
+		t.Error("low-priority message above the limit allowed")
+	}
+	if !b.Allow(motion) {
+		t.Error("motion dropped during a storm")
+	}
+	if h := state.Health(); h.Breaker == nil || !h.Breaker.Open || h.Breaker.Trips != 1 {
+		t.Errorf("Health().Breaker = %+v, want open after 1 trip", h.Breaker)
+	}
+
+	// the storm outlasts the hold: the breaker stays open
+	for i := 0; i < 6; i++ {
+		now = now.Add(time.Second)
+		for j := 0; j < 5; j++ {
+			b.Allow(brightness)
+		}
+	}
+	if b.Allow(brightness) {
+		t.Error("breaker closed while the storm continued")
+	}
+	// the status is reported per second, so the current second is not counted yet
+	if h := state.Health(); !h.Breaker.Open || h.Breaker.Dropped != 1+5*5 {
+		t.Errorf("Health().Breaker = %+v, want open with %d dropped", h.Breaker, 1+5*5)
+	}
+
+	// calm seconds close it once the renewed hold is over
+	for i := 0; i < 6; i++ {
+		now = now.Add(time.Second)
+		b.Allow(motion)
+	}
+	if !b.Allow(brightness) {
+		t.Error("breaker still open after the storm")
+	}
+	if h := state.Health(); h.Breaker.Open || h.Breaker.Trips != 1 {
+		t.Errorf("Health().Breaker = %+v, want closed after 1 trip", h.Breaker)
+	}
+
+	var none *Breaker
+	if !none.Allow(brightness) {
+		t.Error("nil Breaker dropped a message")
+	}
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// CheckResources wraps next so a command for a grouped_light, scene, room or
+// zone the bridge does not have fails with a suggestion, e.g. for a typo in a
+// Loxone virtual output, instead of a bare 404 from the bridge. Ids missing
+// from the inventory are looked up on the bridge before they are rejected.
+func (p *Poller) CheckResources(next udp.CommandHandler) udp.CommandHandler {
+	return &resourceCheck{poller: p, next: next}
+}
+
+type resourceCheck struct {
+	poller  *Poller
+	next    udp.CommandHandler
+	fetched sync.Map // ids the bridge had that the inventory does not keep, e.g. zone scenes
+}
+
+func (c *resourceCheck) Apply(ctx context.Context, cmd udp.Command) error {
+	if err := c.check(ctx, cmd); err != nil {
+		return err
+	}
+	return c.next.Apply(ctx, cmd)
+}
+
+func (c *resourceCheck) check(ctx context.Context, cmd udp.Command) error {
+	inv := c.poller.Snapshot()
+	if names, scenes := inv.Len(); names+scenes == 0 {
+		return nil // not loaded yet; let the bridge decide
+	}
+	ids, tracked := inv.idsOf(cmd.Domain)
+	id := string(cmd.ID)
+	if !tracked || ids[id] {
+		return nil
+	}
+	if _, ok := c.fetched.Load(id); ok {
+		return nil
+	}
+	if r := c.poller.fetch(ctx, string(cmd.Domain), id); r != nil {
+		c.poller.insert(r)
+		c.fetched.Store(id, true)
+		return nil
+	}
+	if s := inv.closest(id, ids); s != "" {
+		return fmt.Errorf("unknown %s %s, did you mean %s?", cmd.Domain, id, s)
+	}
+	return fmt.Errorf("unknown %s %s", cmd.Domain, id)
+}
+
+// idsOf returns the ids the inventory holds for commands of domain t, and
+// whether it tracks that domain at all.
+func (inv *Inventory) idsOf(t resource.Type) (map[string]bool, bool) {
+	ids := make(map[string]bool)
+	switch t {
+	case resource.TypeGroupedLight:
+		for id := range inv.groups {
+			ids[id] = true
+		}
+	case resource.TypeScene:
+		for id := range inv.scenes {
+			ids[id] = true
+		}
+	case resource.TypeRoom, resource.TypeZone:
+		for id, d := range inv.names {
+			if d.Type == string(t) {
+				ids[id] = true
+			}
+		}
+	default:
+		return nil, false
+	}
+	return ids, true
+}
+
+// closest suggests the id nearest to ref, by id or by name, as "<id> (<alias>)".
+// A ref equal to a name (e.g. "Kitchen" instead of its id) always matches.
+func (inv *Inventory) closest(ref string, ids map[string]bool) string {
+	best, bestDist := "", len(ref)/3+1 // further than that is no typo
+	for id := range ids {
+		name := inv.displayName(id)
+		if strings.EqualFold(name, ref) || strings.EqualFold(cleanName(name), ref) {
+			best, bestDist = id, 0
+			break
+		}
+		d := levenshtein(strings.ToLower(ref), id)
+		if name != "" {
+			d = min(d, levenshtein(strings.ToLower(ref), strings.ToLower(name)))
+		}
+		if d < bestDist || (d == bestDist && best != "" && id < best) {
+			best, bestDist = id, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	if name := inv.displayName(best); name != "" {
+		return best + " (" + name + ")"
+	}
+	return best
+}
+
+// displayName is the alias of a room, zone or grouped_light owner, or the name
+// of a scene.
+func (inv *Inventory) displayName(id string) string {
+	if s, ok := inv.scenes[id]; ok {
+		return s.Name
+	}
+	if owner := inv.groups[id]; owner != "" {
+		return inv.Alias(owner)
+	}
+	return inv.Alias(id)
+}
+
+// levenshtein is the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func checkPoller() *Poller {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.names["0000000a-1111-4222-8333-00000000000a"] = Device{Name: "room", Alias: "Kitchen", Type: "room"}
+		inv.names["0000000b-1111-4222-8333-00000000000b"] = Device{Name: "zone", Alias: "Garden", Type: "zone"}
+		inv.groups["0000000f-1111-4222-8333-00000000000f"] = "0000000a-1111-4222-8333-00000000000a"
+		inv.scenes["0000000e-1111-4222-8333-00000000000e"] = Scene{ID: "0000000e-1111-4222-8333-00000000000e", Name: "Relax"}
+	})
+	return p
+}
+
+func TestCheckResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     udp.Command
+		wantErr string
+	}{
+		{name: "known grouped_light", cmd: udp.Command{Domain: "grouped_light", ID: "0000000f-1111-4222-8333-00000000000f", Action: "on"}},
+		{name: "known zone", cmd: udp.Command{Domain: "zone", ID: "0000000b-1111-4222-8333-00000000000b", Action: "on"}},
+		{name: "untracked domain", cmd: udp.Command{Domain: udp.DomainRaw, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Rtype: "light"}},
+		{name: "typo in id", cmd: udp.Command{Domain: "grouped_light", ID: "0000000f-1111-4222-8333-00000000001f", Action: "on"},
+			wantErr: "unknown grouped_light 0000000f-1111-4222-8333-00000000001f, did you mean 0000000f-1111-4222-8333-00000000000f (Kitchen)?"},
+		{name: "name instead of id", cmd: udp.Command{Domain: "scene", ID: "relax", Action: "recall"},
+			wantErr: "did you mean 0000000e-1111-4222-8333-00000000000e (Relax)?"},
+		{name: "room typo by name", cmd: udp.Command{Domain: "room", ID: "kitchn", Action: "on"},
+			wantErr: "did you mean 0000000a-1111-4222-8333-00000000000a (Kitchen)?"},
+		{name: "nothing close", cmd: udp.Command{Domain: "zone", ID: "x", Action: "on"}, wantErr: "unknown zone x"},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkPoller().CheckResources(nopHandler{}).Apply(context.Background(), tt.cmd)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Apply() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Apply() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckResources_EmptyInventory(t *testing.T) {
+	cmd := udp.Command{Domain: "grouped_light", ID: "anything", Action: "on"}
+	if err := testPoller().CheckResources(nopHandler{}).Apply(context.Background(), cmd); err != nil {
+		t.Fatalf("Apply() before the inventory loaded: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type DailyConfig struct {
+	// Sender receives /gateway/daily_report <json>.
+	Sender gateway.Sender
+
+	// Sinks (optional) receive the report as a message too, e.g. a webhook.
+	Sinks []Sink
+
+	// Stats (optional) returns the UDP client's counters (usually udp.Client.Stats).
+	Stats func() udp.ClientStats
+
+	// At is the local time of day the report is sent, as an offset from midnight.
+	At time.Duration
+}
+
+// DailyReport summarizes the last day for lightweight monitoring.
+type DailyReport struct {
+	Date       string `json:"date"`        // day the report was sent, YYYY-MM-DD
+	Offline    int    `json:"offline"`     // devices currently not connected
+	BatteryLow int    `json:"battery_low"` // devices currently reporting a low or critical battery
+	Events     uint64 `json:"events"`      // bridge events received since the last report
+	Dropped    uint64 `json:"dropped"`     // messages to Loxone dropped since the last report
+}
+
+// Daily collects the figures of the daily report from the event stream and
+// sends it once a day as a log line, /gateway/daily_report and to the sinks.
+type Daily struct {
+	cfg DailyConfig
+
+	mu          sync.Mutex
+	offline     map[string]bool // key: device id
+	batteryLow  map[string]bool // key: device id
+	events      uint64
+	lastDropped uint64
+}
+
+func NewDaily(cfg DailyConfig) *Daily {
+	d := &Daily{
+		cfg:        cfg,
+		offline:    make(map[string]bool),
+		batteryLow: make(map[string]bool),
+	}
+	if cfg.Stats != nil {
+		d.lastDropped = cfg.Stats().Dropped()
+	}
+	return d
+}
+
+// Event counts one decoded bridge event.
+func (d *Daily) Event() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.events++
+	d.mu.Unlock()
+}
+
+// Connectivity records whether device is connected.
+func (d *Daily) Connectivity(device string, connected bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if connected {
+		delete(d.offline, device)
+	} else {
+		d.offline[device] = true
+	}
+}
+
+// Battery records whether device reports a low battery.
+func (d *Daily) Battery(device string, low bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if low {
+		d.batteryLow[device] = true
+	} else {
+		delete(d.batteryLow, device)
+	}
+}
+
+// Run sends the report every day at cfg.At until ctx is done.
+func (d *Daily) Run(ctx context.Context) error {
+	for {
+		timer := time.NewTimer(time.Until(nextDaily(time.Now(), d.cfg.At)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case now := <-timer.C:
+			d.send(d.report(now))
+		}
+	}
+}
+
+// nextDaily returns the first time after now at offset at from a local midnight.
+func nextDaily(now time.Time, at time.Duration) time.Time {
+	y, m, day := now.Date()
+	next := time.Date(y, m, day, 0, 0, 0, 0, now.Location()).Add(at)
+	if !next.After(now) {
+		next = time.Date(y, m, day+1, 0, 0, 0, 0, now.Location()).Add(at)
+	}
+	return next
+}
+
+// report returns the figures since the last report and starts a new day.
+func (d *Daily) report(now time.Time) DailyReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := DailyReport{
+		Date:       now.Format(time.DateOnly),
+		Offline:    len(d.offline),
+		BatteryLow: len(d.batteryLow),
+		Events:     d.events,
+	}
+	d.events = 0
+	if d.cfg.Stats != nil {
+		dropped := d.cfg.Stats().Dropped()
+		r.Dropped = dropped - d.lastDropped
+		d.lastDropped = dropped
+	}
+	return r
+}
+
+func (d *Daily) send(r DailyReport) {
+	slog.Info("daily report", "date", r.Date, "offline", r.Offline, "battery_low", r.BatteryLow, "events", r.Events, "dropped", r.Dropped)
+	b, err := json.Marshal(r)
+	if err != nil {
+		slog.Error("daily report encoding failed", "error", err.Error())
+		return
+	}
+	msg := Message{Path: "/gateway/daily_report", Value: string(b), Channel: "daily_report", Time: time.Now()}
+	if d.cfg.Sender != nil {
+		d.cfg.Sender.Send(msg.Bytes())
+	}
+	for _, s := range d.cfg.Sinks {
+		s.Write(msg)
+	}
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func TestDaily(t *testing.T) {
+	stats := udp.ClientStats{DroppedQueue: 3}
+	sender := &recordSender{}
+	sink := &captureSink{}
+	d := NewDaily(DailyConfig{Sender: sender, Sinks: []Sink{sink}, Stats: func() udp.ClientStats { return stats }})
+
+	d.Event()
+	d.Event()
+	d.Connectivity("a", false)
+	d.Connectivity("b", false)
+	d.Connectivity("b", true)
+	d.Battery("c", true)
+	stats.DroppedQueue = 5
+
+	now := time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)
+	d.send(d.report(now))
+	want := `/gateway/daily_report {"date":"2025-03-01","offline":1,"battery_low":1,"events":2,"dropped":2}`
+	if len(sender.msgs) != 1 || sender.msgs[0] != want {
+		t.Errorf("msgs = %q, want [%q]", sender.msgs, want)
+	}
+	if len(sink.msgs) != 1 || sink.msgs[0] != want {
+		t.Errorf("sink msgs = %q, want [%q]", sink.msgs, want)
+	}
+
+	if r := d.report(now.Add(24 * time.Hour)); r.Events != 0 || r.Dropped != 0 || r.Offline != 1 {
+		t.Errorf("next report = %+v, want counters reset and states kept", r)
+	}
+}
+
+func TestNextDaily(t *testing.T) {
+	at := 7 * time.Hour
+	tests := []struct {
+		now, want time.Time
+	}{
+		{now: time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC), want: time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC)},
+		{now: time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC), want: time.Date(2025, 3, 2, 7, 0, 0, 0, time.UTC)},
+		{now: time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC), want: time.Date(2025, 4, 1, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := nextDaily(tt.now, at); !got.Equal(tt.want) {
+			t.Errorf("nextDaily(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Deadband suppresses analog values that moved less than a configured delta since
+// the last value forwarded on the same path, e.g. temperature ≥0.2 or light_level ≥5%.
+type Deadband struct {
+	rules map[string]deadbandRule // key: channel (temperature, light_level, ...)
+
+	mu   sync.Mutex
+	last map[string]float64 // key: outgoing path
+}
+
+type deadbandRule struct {
+	delta    float64
+	relative bool // delta is a fraction of the last value
+}
+
+// NewDeadband parses channel → delta pairs. A delta ending in "%" is relative to
+// the last forwarded value, anything else is absolute.
+func NewDeadband(cfg map[string]string) (*Deadband, error) {
+	d := &Deadband{
+		rules: make(map[string]deadbandRule, len(cfg)),
+		last:  make(map[string]float64),
+	}
+	for channel, raw := range cfg {
+		raw = strings.TrimSpace(raw)
+		rule := deadbandRule{}
+		if strings.HasSuffix(raw, "%") {
+			rule.relative = true
+			raw = strings.TrimSuffix(raw, "%")
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("deadband %s: invalid delta %q", channel, cfg[channel])
+		}
+		if rule.relative {
+			v /= 100
+		}
+		rule.delta = v
+		d.rules[channel] = rule
+	}
+	return d, nil
+}
+
+// Allow reports whether v should be forwarded on path and, if so, remembers it.
+func (d *Deadband) Allow(path, channel string, v float64) bool {
+	if d == nil {
+		return true
+	}
+	rule, ok := d.rules[channel]
+	if !ok {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	prev, seen := d.last[path]
+	if seen {
+		delta := rule.delta
+		if rule.relative {
+			delta *= math.Abs(prev)
+		}
+		// small epsilon so 21.2 after 21.0 counts as a 0.2 step
+		if math.Abs(v-prev) < delta-1e-9 {
+			return false
+		}
+	}
+	d.last[path] = v
+	return true
+}
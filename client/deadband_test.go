@@ -0,0 +1,41 @@
+package client
+
+import "testing"
+
+func TestDeadband_Allow(t *testing.T) {
+	d, err := NewDeadband(map[string]string{
+		"temperature": "0.2",
+		"light_level": "5%",
+	})
+	if err != nil {
+		t.Fatalf("NewDeadband() unexpected error: %v", err)
+	}
+
+	steps := []struct {
+		path    string
+		channel string
+		value   float64
+		want    bool
+	}{
+		{"/sensor/a/temperature", "temperature", 21.0, true},
+		{"/sensor/a/temperature", "temperature", 21.1, false},
+		{"/sensor/a/temperature", "temperature", 21.2, true},
+		{"/sensor/b/temperature", "temperature", 21.1, true},
+		{"/sensor/a/light_level", "light_level", 10000, true},
+		{"/sensor/a/light_level", "light_level", 10400, false},
+		{"/sensor/a/light_level", "light_level", 10500, true},
+		{"/sensor/a/motion", "motion", 1, true},
+		{"/sensor/a/motion", "motion", 1, true},
+	}
+	for i, s := range steps {
+		if got := d.Allow(s.path, s.channel, s.value); got != s.want {
+			t.Errorf("step %d: Allow(%s, %v) = %v, want %v", i, s.path, s.value, got, s.want)
+		}
+	}
+}
+
+func TestNewDeadband_Invalid(t *testing.T) {
+	if _, err := NewDeadband(map[string]string{"temperature": "abc"}); err == nil {
+		t.Fatalf("NewDeadband() expected error, got nil")
+	}
+}
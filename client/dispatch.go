@@ -0,0 +1,608 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Output receives the payloads for Loxone; *udp.Client implements it.
+type Output interface {
+	Send(b []byte)
+	SendCritical(ctx context.Context, b []byte) error
+}
+
+// Dispatcher turns bridge events into Loxone messages: it decodes each
+// resource, renders its paths and runs them through pauses, echo handling,
+// sampling and the hooks to Output and the sinks. The EventStreamer feeds it
+// from the event stream; it is not safe for concurrent use.
+type Dispatcher struct {
+	log        *slog.Logger
+	out        Output
+	levels     bool
+	poller     *Poller
+	state      *gateway.State
+	deadband   *Deadband
+	sampler    *Sampler
+	breaker    *Breaker
+	occupancy  *Occupancy
+	failsafe   *Failsafe
+	reporter   *Reporter
+	bools      *udp.Bools
+	guard      *PathGuard
+	pauses     *gateway.Pauses
+	echoes     *gateway.Echoes
+	echoMode   string
+	sources    *Sources
+	overrides  *Overrides
+	daily      *Daily
+	batteries  *Batteries
+	staleMode  string
+	staleAfter time.Duration
+	hooks      []MessageHook
+	sinks      []Sink
+	forward    map[string]bool // rooms, zones and devices sent; nil sends all
+
+	motionExclude map[string]bool // grouped_motion owner types/ids to skip
+	homeMotion    bool
+	homeLight     bool
+
+	critical        map[resource.Type]bool // resource types sent via Output.SendCritical
+	criticalTimeout time.Duration
+	entertainment   *gateway.Entertainment
+	curves          *curve.Curves
+
+	handlers map[resource.Type]EventHandler // see RegisterHandler
+
+	held     map[string]json.RawMessage // scene id → last event skipped while unresolved
+	resolved chan string                // ids the poller resolved since
+}
+
+// NewDispatcher builds the event handling of a streamer from the message
+// fields of cfg, without an event stream; Bridge and Keys are ignored. It needs
+// Poller, State and Output or UDPClient.
+func NewDispatcher(cfg StreamerConfig, opts ...DispatcherOption) (*Dispatcher, error) {
+	switch {
+	case cfg.Output == nil && cfg.UDPClient == nil:
+		return nil, errors.New("dispatcher: Output or UDPClient required")
+	case cfg.Poller == nil:
+		return nil, errors.New("dispatcher: Poller required")
+	case cfg.State == nil:
+		return nil, errors.New("dispatcher: State required")
+	}
+	return newDispatcher(cfg, buildOptions(opts)), nil
+}
+
+func newDispatcher(cfg StreamerConfig, o options) *Dispatcher {
+	out := cfg.Output
+	if out == nil {
+		out = cfg.UDPClient
+	}
+
+	exclude := cfg.MotionExclude
+	if exclude == nil {
+		exclude = []string{"bridge_home"}
+	}
+	motionExclude := make(map[string]bool, len(exclude))
+	for _, x := range exclude {
+		motionExclude[x] = true
+	}
+
+	critical := cfg.Critical
+	if critical == nil {
+		critical = []string{"contact", "tamper", "security_area_motion"}
+	}
+	criticalTypes := make(map[resource.Type]bool, len(critical))
+	for _, t := range critical {
+		criticalTypes[resource.Type(t)] = true
+	}
+	if cfg.CriticalTimeout <= 0 {
+		cfg.CriticalTimeout = 5 * time.Second
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 30 * time.Second
+	}
+
+	var forward map[string]bool
+	if len(cfg.Forward) > 0 {
+		forward = make(map[string]bool, len(cfg.Forward))
+		for _, id := range cfg.Forward {
+			forward[id] = true
+		}
+	}
+
+	// scenes created mid-run are replayed once the poller knows their group
+	resolved := make(chan string, pendingSize)
+	cfg.Poller.OnResolved(func(id string) {
+		select {
+		case resolved <- id:
+		default:
+		}
+	})
+
+	return &Dispatcher{
+		log:        o.logger,
+		out:        out,
+		levels:     cfg.Levels,
+		handlers:   make(map[resource.Type]EventHandler),
+		held:       make(map[string]json.RawMessage),
+		resolved:   resolved,
+		poller:     cfg.Poller,
+		state:      cfg.State,
+		deadband:   cfg.Deadband,
+		sampler:    cfg.Sampler,
+		breaker:    cfg.Breaker,
+		occupancy:  cfg.Occupancy,
+		failsafe:   cfg.Failsafe,
+		reporter:   cfg.Reporter,
+		bools:      cfg.Bools,
+		guard:      NewPathGuard(),
+		pauses:     cfg.Pauses,
+		echoes:     cfg.Echoes,
+		echoMode:   cfg.EchoMode,
+		sources:    cfg.Sources,
+		overrides:  cfg.Overrides,
+		daily:      cfg.Daily,
+		batteries:  cfg.Batteries,
+		staleMode:  cfg.StaleMode,
+		staleAfter: cfg.StaleAfter,
+		hooks:      cfg.Hooks,
+		sinks:      o.sinks,
+		forward:    forward,
+
+		motionExclude: motionExclude,
+		homeMotion:    cfg.HomeMotion,
+		homeLight:     cfg.HomeLight,
+
+		critical:        criticalTypes,
+		criticalTimeout: cfg.CriticalTimeout,
+		entertainment:   cfg.Entertainment,
+		curves:          cfg.Curves,
+	}
+}
+
+// Dispatch handles the containers of one event stream payload, then the held
+// events of resources resolved since.
+func (d *Dispatcher) Dispatch(ctx context.Context, containers []EventContainer) error {
+	if err := d.handle(ctx, containers); err != nil {
+		return err
+	}
+	return d.replayResolved(ctx)
+}
+
+func (d *Dispatcher) handle(ctx context.Context, containers []EventContainer) error {
+	for _, c := range containers {
+		ctx := d.markStale(ctx, c.CreationTime)
+		for _, raw := range c.Data {
+			ev, err := decodeResource(raw)
+			if err != nil {
+				return err
+			}
+			d.daily.Event()
+			if fn := d.handlerFor(ev.ResourceType()); fn != nil {
+				if err := fn(ctx, ev); err != nil {
+					d.log.Warn("event handler failed", "type", ev.ResourceType(), "id", ev.GetGeneric().ID, "error", err)
+				}
+				continue
+			}
+			d.handleDefault(ctx, ev, raw)
+		}
+	}
+	return nil
+}
+
+// EventHandler handles one decoded bridge resource, e.g. to publish it to MQTT.
+type EventHandler func(ctx context.Context, ev EventResource) error
+
+// RegisterHandler makes fn handle the events of resourceType (e.g. "motion")
+// instead of the built-in Loxone messages; "*" stands for every type without a
+// handler of its own. fn may call DefaultHandler to send them as well. Errors
+// are logged and do not stop the event stream. Register handlers before the
+// first event.
+func (d *Dispatcher) RegisterHandler(resourceType string, fn EventHandler) {
+	d.handlers[resource.Type(resourceType)] = fn
+}
+
+func (d *Dispatcher) handlerFor(t resource.Type) EventHandler {
+	if fn, ok := d.handlers[t]; ok {
+		return fn
+	}
+	return d.handlers["*"]
+}
+
+// DefaultHandler is the built-in handling of ev: the Loxone messages, the
+// occupancy, failsafe and daily report input and so on.
+func (d *Dispatcher) DefaultHandler(ctx context.Context, ev EventResource) error {
+	d.handleDefault(ctx, ev, nil)
+	return nil
+}
+
+// handleDefault handles ev decoded from raw; raw is only kept for scenes the
+// poller does not know yet, and re-encoded from ev if nil.
+func (d *Dispatcher) handleDefault(ctx context.Context, ev EventResource, raw json.RawMessage) {
+	parent := ev.GetGeneric().Owner
+
+	switch ee := ev.(type) {
+	case *LightEvent:
+		if ee.On != nil {
+			if d.debug(ctx) {
+				d.log.Debug("light event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "on", ee.On.On)
+			}
+		}
+	case *TamperEvent:
+		if len(ee.TamperReports) > 0 {
+			for _, report := range ee.TamperReports {
+				if d.debug(ctx) {
+					d.log.Debug("tamper event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "source", report.Source, "state", report.State)
+				}
+				d.sendBool(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/sensor/" + string(parent.ID) + "/tamper", Channel: "tamper"}, report.State == StateTampered)
+			}
+		}
+	case *ContactEvent:
+		if ee.ContactReport != nil {
+			if d.debug(ctx) {
+				d.log.Debug("contact event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "state", ee.ContactReport.State)
+			}
+			d.sendBool(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/contact/" + string(parent.ID) + "/state", Channel: "state"}, ee.ContactReport.State == StateContact)
+			d.occupancy.Signal(d.poller.RoomOf(string(parent.ID)), SignalContact, true)
+		}
+	case *MotionEvent:
+		if ee.Motion.MotionReport != nil {
+			if parent.ID == "" {
+				return
+			}
+			if d.debug(ctx) {
+				d.log.Debug("motion event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "motion", ee.Motion.MotionReport.Motion)
+			}
+			d.sendBool(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/sensor/" + string(parent.ID) + "/motion", Channel: "motion"}, ee.Motion.MotionReport.Motion)
+			d.occupancy.Signal(d.poller.RoomOf(string(parent.ID)), SignalMotion, ee.Motion.MotionReport.Motion)
+			d.failsafe.Motion(ctx, string(parent.ID), ee.Motion.MotionReport.Motion)
+		}
+
+	case *GroupedMotionEvent:
+		if ee.Motion.MotionReport != nil {
+			motion := ee.Motion.MotionReport.Motion
+			d.failsafe.Motion(ctx, string(parent.ID), motion)
+			if parent.Type == resource.TypeBridgeHome && d.homeMotion {
+				d.sendBool(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/home/motion", Channel: "motion"}, motion)
+				return
+			}
+			if d.motionExclude[string(parent.Type)] || d.motionExclude[string(parent.ID)] {
+				return
+			}
+			if d.debug(ctx) {
+				d.log.Debug("grouped motion event", "id", parent.ID, "group", d.poller.Lookup(ctx, parent), "grouped_motion", ee.Motion.MotionReport.Motion)
+			}
+			d.sendBool(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/group/" + string(parent.ID) + "/motion", Channel: "motion"}, motion)
+		}
+
+	case *SecurityAreaMotionEvent:
+		if ee.Motion.MotionReport != nil {
+			d.sendBool(ctx, Message{Type: ee.Type, ID: ee.ID, Path: "/security/" + string(ee.ID) + "/motion", Channel: "motion"}, ee.Motion.MotionReport.Motion)
+		}
+	case *LightLevelEvent:
+		if ee.Light.LightLevelReport != nil {
+			if d.debug(ctx) {
+				d.log.Debug("light level event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "light_level", ee.Light.LightLevelReport.LightLevel)
+			}
+
+			d.sendValue(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/sensor/" + string(parent.ID) + "/light_level", Channel: "light_level"}, 6, ee.Light.LightLevelReport.LightLevel)
+		}
+
+	case *GroupedLightLevelEvent:
+		if ee.Light.LightLevelReport != nil {
+			if d.debug(ctx) {
+				d.log.Debug("grouped light level event", "id", parent.ID, "group", d.poller.Lookup(ctx, parent), "light_level", ee.Light.LightLevelReport.LightLevel)
+			}
+
+			d.sendValue(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/group/" + string(parent.ID) + "/light_level", Channel: "light_level"}, 6, ee.Light.LightLevelReport.LightLevel)
+		}
+
+	case *TemperatureEvent:
+		if ee.Temperature.TemperatureReport != nil {
+			if d.debug(ctx) {
+				d.log.Debug("temperature event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "temperature", ee.Temperature.TemperatureReport.Temperature)
+			}
+
+			d.sendValue(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/sensor/" + string(parent.ID) + "/temperature", Channel: "temperature"}, 2, ee.Temperature.TemperatureReport.Temperature)
+		}
+	case *GroupedLightEvent:
+		if d.debug(ctx) {
+			d.log.Debug("grouped_light event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent))
+		}
+		if ee.On != nil && parent.Type == resource.TypeRoom {
+			d.occupancy.Signal(d.poller.GetAlias(string(parent.ID)), SignalLight, ee.On.On)
+		}
+		if parent.Type == resource.TypeBridgeHome {
+			if !d.homeLight {
+				return
+			}
+			if ee.On != nil {
+				d.sendBool(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/home/on", Channel: "on"}, ee.On.On)
+			}
+			if ee.Dimming != nil {
+				d.sendValue(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/home/brightness", Channel: "brightness"}, 0, d.curves.For(string(ee.ID)).ToLoxone(ee.Dimming.Brightness))
+			}
+			return
+		}
+		if ee.Dimming != nil {
+			d.sendValue(ctx, Message{Type: ee.Type, ID: ee.ID, Path: "/group/" + string(ee.ID) + "/brightness", Channel: "brightness", SinkOnly: !d.levels}, 0, d.curves.For(string(ee.ID)).ToLoxone(ee.Dimming.Brightness))
+		}
+	case *DevicePowerEvent:
+		if ee.PowerState != nil {
+			if d.debug(ctx) {
+				d.log.Debug("device power event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), "battery", ee.PowerState.BatteryLevel, "state", ee.PowerState.BatteryState)
+			}
+			d.sendValue(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/sensor/" + string(parent.ID) + "/battery", Channel: "battery", SinkOnly: !d.levels}, 0, ee.PowerState.BatteryLevel)
+			d.daily.Battery(string(parent.ID), ee.PowerState.BatteryState == "low" || ee.PowerState.BatteryState == "critical")
+			if ee.PowerState.BatteryState != "" { // mains powered devices report no battery
+				d.batteries.Observe(string(parent.ID), ee.PowerState.BatteryLevel)
+			}
+		}
+	case *PowerEvent:
+		id := parent.ID
+		if id == "" {
+			id = ee.ID
+		}
+		if w, ok := ee.Watts(); ok {
+			d.sendValue(ctx, Message{Type: ee.Type, ID: id, Path: "/plug/" + string(id) + "/power", Channel: "power"}, 1, w)
+		}
+		if kwh, ok := ee.KWh(); ok {
+			d.sendValue(ctx, Message{Type: ee.Type, ID: id, Path: "/plug/" + string(id) + "/energy", Channel: "energy"}, 3, kwh)
+		}
+	case *EntertainmentConfigurationEvent:
+		if ee.Status != "" {
+			active := ee.Status == "active"
+			if d.entertainment != nil {
+				d.entertainment.SetActive(string(ee.ID), active)
+			}
+			d.sendBool(ctx, Message{Type: ee.Type, ID: ee.ID, Path: "/entertainment/" + string(ee.ID) + "/active", Channel: "active"}, active)
+		}
+	case *ValueEvent:
+		if ee.Value != nil {
+			svc := valueServices[ee.Type]
+			if d.debug(ctx) {
+				d.log.Debug(string(ee.Type)+" event", "id", parent.ID, "device", d.poller.Lookup(ctx, parent), string(svc.Metric), *ee.Value)
+			}
+			d.sendValue(ctx, Message{Type: ee.Type, ID: parent.ID, Path: "/sensor/" + string(parent.ID) + "/" + string(svc.Metric), Channel: svc.Metric}, svc.Prec, *ee.Value)
+		}
+	case *ButtonEvent:
+		// accessories act on their room; remember the input to attribute the change
+		d.sources.Pressed(d.poller.Snapshot().RoomID(string(parent.ID)))
+		event := ee.Pressed()
+		ch, err := resource.ParseMetric(event)
+		if err != nil {
+			d.log.Debug("button event without a usable event", "id", ee.ID, "event", event)
+			return
+		}
+		d.log.Debug("button event", "id", ee.ID, "device", d.poller.Lookup(ctx, parent), "event", event)
+		// every press is a pulse; Loxone reacts to the message, not a change
+		d.sendBool(ctx, Message{Type: ee.Type, ID: ee.ID, Path: "/button/" + string(ee.ID) + "/" + event, Channel: ch}, true)
+	case *RelativeRotaryEvent:
+		d.sources.Pressed(d.poller.Snapshot().RoomID(string(parent.ID)))
+		rot := ee.Turned()
+		if rot == nil || rot.Steps == 0 {
+			return
+		}
+		d.log.Debug("relative_rotary event", "id", ee.ID, "device", d.poller.Lookup(ctx, parent), "direction", rot.Direction, "steps", rot.Steps, "duration_ms", rot.Duration)
+		// every turn is a delta, so never subject to the deadband
+		d.send(ctx, Message{Type: ee.Type, ID: ee.ID, Path: "/rotary/" + string(ee.ID) + "/steps", Channel: "steps", Value: strconv.Itoa(rot.SignedSteps())})
+	case *ZigbeeConnectivityEvent:
+		d.log.Debug("zigbee_connectivity event", "id", parent.ID, "state", ee.Status)
+		if ee.Status != "" {
+			d.daily.Connectivity(string(parent.ID), ee.Status == StatusConnected)
+		}
+
+	case *SceneEvent:
+		scene := d.poller.LookupScene(ctx, string(ee.ID))
+		d.log.Debug("scene event", "id", ee.ID, "status", ee.Status.Active, "scene", scene)
+		if scene == nil {
+			if raw == nil {
+				raw, _ = json.Marshal(ee)
+			}
+			d.held[string(ee.ID)] = append(json.RawMessage(nil), raw...)
+			return
+		}
+		// dynamic scenes report their status continuously; the sampler thins them out
+		if ee.Status.Active == "static" || ee.Status.Active == "dynamic_palette" {
+			d.send(ctx, Message{Type: ee.Type, ID: ee.ID, Path: "/scene/" + string(scene.GroupID) + "/on", Channel: "on", Value: string(ee.ID)})
+		}
+	case *UnknownEvent:
+		// keep for diagnostics or forward to a generic handler
+		// slog.Debug("unknown event", "type", d.Type, "raw", string(d.Raw))
+		d.log.Warn("unknown event", "type", ee.Type, "raw", string(ee.Raw))
+	case *MutedEvent:
+
+	default:
+		d.log.Debug("unhandled event", "type", ee.ResourceType())
+	}
+}
+
+// debug reports whether debug records are emitted, so the hot path skips name
+// lookups and payload copies that would only feed a discarded record.
+func (d *Dispatcher) debug(ctx context.Context) bool {
+	return d.log.Enabled(ctx, slog.LevelDebug)
+}
+
+// replayResolved handles the held events of resources the poller has resolved
+// since, so a scene created mid-run is not silently lost.
+func (d *Dispatcher) replayResolved(ctx context.Context) error {
+	for {
+		select {
+		case id := <-d.resolved:
+			raw, ok := d.held[id]
+			if !ok {
+				continue
+			}
+			delete(d.held, id)
+			d.log.Debug("replaying event of resolved resource", "id", id)
+			if err := d.handle(ctx, []EventContainer{{Data: []json.RawMessage{raw}}}); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// sendBool forwards a boolean as "1" or "0"; Loxone gets it in the configured
+// encoding.
+func (d *Dispatcher) sendBool(ctx context.Context, msg Message, v bool) {
+	msg.Value, msg.Bool = "0", true
+	if v {
+		msg.Value = "1"
+	}
+	d.send(ctx, msg)
+}
+
+// sendValue forwards an analog value with prec decimals unless it falls inside
+// the channel's deadband.
+func (d *Dispatcher) sendValue(ctx context.Context, msg Message, prec int, v float64) {
+	if !d.deadband.Allow(msg.Path, string(msg.Channel), v) {
+		d.log.Debug("value inside deadband; suppressed", "path", msg.Path, "value", v)
+		return
+	}
+	msg.Value = strconv.FormatFloat(v, 'f', prec, 64)
+	d.send(ctx, msg)
+}
+
+// send runs msg through the hooks and forwards whatever comes out to Loxone.
+func (d *Dispatcher) send(ctx context.Context, msg Message) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if !d.forwarded(msg) {
+		d.log.Debug("message not forwarded", "path", msg.Path, "value", msg.Value)
+		return
+	}
+	if d.paused(msg) {
+		d.log.Debug("message paused", "path", msg.Path, "value", msg.Value)
+		return
+	}
+	if stale(ctx) {
+		if d.staleMode == StaleDrop && !d.critical[msg.Type] {
+			d.log.Debug("stale message dropped", "path", msg.Path, "value", msg.Value)
+			return
+		}
+		msg.Stale = true
+	}
+	echo := d.echo(msg)
+	switch {
+	case echo && d.echoMode == EchoSuppress:
+		d.log.Debug("command echo suppressed", "path", msg.Path, "value", msg.Value)
+		return
+	case d.sources != nil && attributed(msg.Type):
+		room := lightRoom(d.poller.Snapshot(), msg)
+		msg.Origin = d.sources.Origin(room, echo)
+		d.overrides.Observe(d.poller.GetAlias(room), msg.Origin)
+	case echo && d.echoMode == EchoTag:
+		msg.Origin = OriginLoxone
+	}
+	if !d.critical[msg.Type] && !d.sampler.Allow(msg) {
+		d.log.Debug("message sampled out", "path", msg.Path, "value", msg.Value)
+		return
+	}
+	if !d.critical[msg.Type] && !d.breaker.Allow(msg) {
+		return // event storm; the breaker logs once per trip
+	}
+	msgs := []Message{msg}
+	for _, hook := range d.hooks {
+		var next []Message
+		for _, m := range msgs {
+			out, err := hook.Process(ctx, m)
+			if err != nil {
+				d.log.Error("message hook failed; forwarding unchanged", "path", m.Path, "error", err.Error())
+				out = []Message{m}
+			}
+			next = append(next, out...)
+		}
+		msgs = next
+	}
+	for _, m := range msgs {
+		if err := d.guard.Check(m); err != nil {
+			d.state.Alert("duplicate_path", err)
+		}
+		switch {
+		case m.SinkOnly: // not for Loxone, see StreamerConfig.Levels
+		case d.critical[m.Type]:
+			d.sendCritical(ctx, m)
+		default:
+			d.out.Send(m.Encode(d.bools))
+		}
+		d.reporter.Observe(m)
+		for _, s := range d.sinks {
+			s.Write(m)
+		}
+	}
+}
+
+// messageRoom returns the room or zone msg belongs to, or "". Group messages
+// belong to their room or zone, scenes to the room they were recalled in.
+func messageRoom(inv *Inventory, msg Message) string {
+	id := string(msg.ID)
+	room := inv.RoomID(id)
+	switch {
+	case msg.Type == resource.TypeScene:
+		if s, ok := inv.Scene(id); ok {
+			room = s.GroupID
+		}
+	case inv.GroupOwner(id) != "":
+		room = inv.GroupOwner(id)
+	case room == "":
+		if d, ok := inv.Device(id); ok && (d.Type == "room" || d.Type == "zone") {
+			room = id
+		}
+	}
+	return room
+}
+
+// forwarded reports whether msg is sent under Forward: its device, or the room
+// or zone it belongs to, is listed. Messages without an id, e.g. /home/motion,
+// are always sent.
+func (d *Dispatcher) forwarded(msg Message) bool {
+	if d.forward == nil || msg.ID == "" || d.forward[string(msg.ID)] {
+		return true
+	}
+	room := messageRoom(d.poller.Snapshot(), msg)
+	return room != "" && d.forward[room]
+}
+
+// paused reports whether msg belongs to a paused room or device.
+func (d *Dispatcher) paused(msg Message) bool {
+	if d.pauses == nil || msg.ID == "" {
+		return false
+	}
+	inv := d.poller.Snapshot()
+	id := string(msg.ID)
+	room := messageRoom(inv, msg)
+	scopes := []string{gateway.Scope(gateway.ScopeDevice, id)}
+	if alias := inv.Alias(id); alias != "" {
+		scopes = append(scopes, gateway.Scope(gateway.ScopeDevice, alias))
+	}
+	if room != "" {
+		scopes = append(scopes, gateway.Scope(gateway.ScopeRoom, room))
+		if alias := inv.Alias(room); alias != "" {
+			scopes = append(scopes, gateway.Scope(gateway.ScopeRoom, alias))
+		}
+	}
+	return d.pauses.Paused(scopes...)
+}
+
+// sendCritical delivers an alarm-grade message synchronously (bounded by
+// criticalTimeout) and raises a gateway alert when it cannot be written.
+func (d *Dispatcher) sendCritical(ctx context.Context, m Message) {
+	ctx, cancel := context.WithTimeout(ctx, d.criticalTimeout)
+	defer cancel()
+	if err := d.out.SendCritical(ctx, m.Encode(d.bools)); err != nil {
+		d.state.Alert("critical_send_failed", fmt.Errorf("%s: %w", m.Path, err))
+	}
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// recordOutput keeps the payloads a Dispatcher sends to Loxone.
+type recordOutput struct {
+	mu       sync.Mutex
+	queued   []string
+	critical []string
+}
+
+func (o *recordOutput) Send(b []byte) {
+	o.mu.Lock()
+	o.queued = append(o.queued, string(b))
+	o.mu.Unlock()
+}
+
+func (o *recordOutput) SendCritical(_ context.Context, b []byte) error {
+	o.mu.Lock()
+	o.critical = append(o.critical, string(b))
+	o.mu.Unlock()
+	return nil
+}
+
+func loadFixture(t *testing.T, name string) []EventContainer {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "events", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []EventContainer
+	if err := json.Unmarshal(raw, &containers); err != nil {
+		t.Fatal(err)
+	}
+	return containers
+}
+
+// TestDispatcherPayloads locks in the exact datagrams Loxone receives per event.
+func TestDispatcherPayloads(t *testing.T) {
+	const device = "00000001-1111-4222-8333-000000000001"
+	motionOff := func(t *testing.T) []EventContainer {
+		c := loadFixture(t, "motion")
+		c[0].Data[0] = json.RawMessage(strings.ReplaceAll(string(c[0].Data[0]), `"motion": true`, `"motion": false`))
+		return c
+	}
+
+	tests := []struct {
+		name         string
+		events       func(t *testing.T) []EventContainer
+		cfg          func(cfg *StreamerConfig)
+		wantQueued   []string
+		wantCritical []string
+	}{
+		{
+			name:       "motion on",
+			events:     func(t *testing.T) []EventContainer { return loadFixture(t, "motion") },
+			wantQueued: []string{"/sensor/" + device + "/motion 1"},
+		},
+		{
+			name:       "motion off",
+			events:     motionOff,
+			wantQueued: []string{"/sensor/" + device + "/motion 0"},
+		},
+		{
+			name:         "contact is critical",
+			events:       func(t *testing.T) []EventContainer { return loadFixture(t, "contact") },
+			wantCritical: []string{"/contact/" + device + "/state 0"},
+		},
+		{
+			name:       "temperature",
+			events:     func(t *testing.T) []EventContainer { return loadFixture(t, "temperature") },
+			wantQueued: []string{"/sensor/" + device + "/temperature 21.37"},
+		},
+		{
+			name:   "grouped light brightness",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "grouped_light") },
+			cfg: func(cfg *StreamerConfig) {
+				cfg.Levels = true
+			},
+			wantQueued: []string{"/group/0000000f-1111-4222-8333-00000000000f/brightness 42"},
+		},
+		{
+			name:   "grouped light brightness without levels",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "grouped_light") },
+		},
+		{
+			name:   "bool words",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "motion") },
+			cfg: func(cfg *StreamerConfig) {
+				cfg.Bools, _ = udp.NewBools(udp.BoolWords, nil)
+			},
+			wantQueued: []string{"/sensor/" + device + "/motion true"},
+		},
+		{
+			name:   "stale mark",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "contact") },
+			cfg: func(cfg *StreamerConfig) {
+				cfg.StaleMode = StaleMark
+			},
+			wantCritical: []string{"/contact/" + device + "/state 0 stale=1"},
+		},
+		{
+			name:   "forward device",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "motion") },
+			cfg: func(cfg *StreamerConfig) {
+				cfg.Forward = []string{device}
+			},
+			wantQueued: []string{"/sensor/" + device + "/motion 1"},
+		},
+		{
+			name:   "forward room of device",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "motion") },
+			cfg: func(cfg *StreamerConfig) {
+				cfg.Poller.update(func(inv *Inventory) { inv.rooms[device] = "00000002-1111-4222-8333-000000000002" })
+				cfg.Forward = []string{"00000002-1111-4222-8333-000000000002"}
+			},
+			wantQueued: []string{"/sensor/" + device + "/motion 1"},
+		},
+		{
+			name:   "forward leaves out others",
+			events: func(t *testing.T) []EventContainer { return loadFixture(t, "grouped_light") },
+			cfg: func(cfg *StreamerConfig) {
+				cfg.Forward = []string{device}
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out := &recordOutput{}
+			cfg := StreamerConfig{Output: out, Poller: testPoller(), State: gateway.NewState(nil)}
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			d, err := NewDispatcher(cfg)
+			if err != nil {
+				t.Fatalf("NewDispatcher() error = %v", err)
+			}
+			if err := d.Dispatch(context.Background(), tt.events(t)); err != nil {
+				t.Fatalf("Dispatch() error = %v", err)
+			}
+			if strings.Join(out.queued, "|") != strings.Join(tt.wantQueued, "|") {
+				t.Errorf("queued = %q, want %q", out.queued, tt.wantQueued)
+			}
+			if strings.Join(out.critical, "|") != strings.Join(tt.wantCritical, "|") {
+				t.Errorf("critical = %q, want %q", out.critical, tt.wantCritical)
+			}
+		})
+	}
+}
+
+// The boolean encoding is for Loxone only; the sinks keep 1/0.
+func TestDispatcherBoolEncoding(t *testing.T) {
+	t.Parallel()
+
+	out, sink := &recordOutput{}, &messageSink{}
+	bools, err := udp.NewBools(udp.BoolOnOff, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDispatcher(StreamerConfig{Output: out, Poller: testPoller(), State: gateway.NewState(nil), Bools: bools}, WithSink(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Dispatch(context.Background(), loadFixture(t, "motion")); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	want := "/sensor/00000001-1111-4222-8333-000000000001/motion ON"
+	if len(out.queued) != 1 || out.queued[0] != want {
+		t.Errorf("queued = %q, want [%q]", out.queued, want)
+	}
+	if len(sink.msgs) != 1 || sink.msgs[0].Value != "1" {
+		t.Errorf("sink got %+v, want motion 1", sink.msgs)
+	}
+}
+
+func TestNewDispatcherRequired(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewDispatcher(StreamerConfig{Poller: testPoller(), State: gateway.NewState(nil)}); err == nil {
+		t.Error("NewDispatcher() without Output error = nil, want error")
+	}
+}
+
+func TestDispatcherRegisterHandler(t *testing.T) {
+	t.Parallel()
+
+	out := &recordOutput{}
+	d, err := NewDispatcher(StreamerConfig{Output: out, Poller: testPoller(), State: gateway.NewState(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	d.RegisterHandler("motion", func(ctx context.Context, ev EventResource) error {
+		seen = append(seen, string(ev.ResourceType()))
+		return errors.New("sink down") // logged; later events still flow
+	})
+	d.RegisterHandler("*", func(ctx context.Context, ev EventResource) error {
+		seen = append(seen, "*"+string(ev.ResourceType()))
+		return d.DefaultHandler(ctx, ev)
+	})
+
+	events := append(loadFixture(t, "motion"), loadFixture(t, "temperature")...)
+	if err := d.Dispatch(context.Background(), events); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if got, want := strings.Join(seen, ","), "motion,*temperature"; got != want {
+		t.Errorf("handlers saw %s, want %s", got, want)
+	}
+	want := []string{"/sensor/00000001-1111-4222-8333-000000000001/temperature 21.37"}
+	if strings.Join(out.queued, "|") != strings.Join(want, "|") {
+		t.Errorf("queued = %q, want %q", out.queued, want)
+	}
+}
@@ -0,0 +1,28 @@
+package client
+
+// Echo modes: what happens to events caused by a recent Loxone command.
+const (
+	EchoOff      = "off"      // forward as any other event
+	EchoTag      = "tag"      // forward with origin=loxone
+	EchoSuppress = "suppress" // drop
+)
+
+// OriginLoxone marks a message as the feedback of a Loxone command.
+const OriginLoxone = "loxone"
+
+// echo reports whether msg is the light state feedback of a recent Loxone
+// command. Messages also match a command on their room or zone (scenes: the room
+// they belong to), on a room or zone sharing lights with it, or on one of its
+// lights: all of those change the group's state too.
+func (d *Dispatcher) echo(msg Message) bool {
+	if d.echoes == nil || msg.ID == "" || !attributed(msg.Type) {
+		return false
+	}
+	inv := d.poller.Snapshot()
+	ids := []string{string(msg.ID)}
+	if room := lightRoom(inv, msg); room != "" {
+		ids = append(ids, inv.Overlapping(room)...)
+		ids = append(ids, inv.Lights(room)...)
+	}
+	return d.echoes.Recent(ids...)
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type originSink struct {
+	mu      sync.Mutex
+	origins []string
+}
+
+func (s *originSink) Write(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.origins = append(s.origins, msg.Origin)
+}
+
+type nopHandler struct{}
+
+func (nopHandler) Apply(ctx context.Context, cmd udp.Command) error { return nil }
+
+func TestEcho(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "events", "grouped_light.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []EventContainer
+	if err := json.Unmarshal(raw, &containers); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		command bool
+		want    []string // origins of the forwarded messages
+	}{
+		{name: "no command", mode: EchoTag, want: []string{""}},
+		{name: "tag", mode: EchoTag, command: true, want: []string{OriginLoxone}},
+		{name: "suppress", mode: EchoSuppress, command: true, want: nil},
+		{name: "off", mode: EchoOff, command: true, want: []string{""}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sink := &originSink{}
+			e := goldenStreamer(t, sink)
+			e.echoes = gateway.NewEchoes(0, nil)
+			e.echoMode = tt.mode
+			if tt.command {
+				cmd := udp.Command{Domain: "grouped_light", ID: "0000000f-1111-4222-8333-00000000000f", Action: "on", Value: udp.BoolValue(true)}
+				if err := e.echoes.Track(nopHandler{}).Apply(context.Background(), cmd); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := e.handle(context.Background(), containers); err != nil {
+				t.Fatal(err)
+			}
+			if len(sink.origins) != len(tt.want) {
+				t.Fatalf("origins = %q, want %q", sink.origins, tt.want)
+			}
+			for i := range tt.want {
+				if sink.origins[i] != tt.want[i] {
+					t.Errorf("origins = %q, want %q", sink.origins, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEcho_OverlappingGroups(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "events", "grouped_light.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []EventContainer
+	if err := json.Unmarshal(raw, &containers); err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		room   = "00000002-1111-4222-8333-000000000002" // owns the grouped_light of the event
+		zone   = "00000003-1111-4222-8333-000000000003" // shares the lamp with the room
+		other  = "00000004-1111-4222-8333-000000000004"
+		lamp   = "0000000a-1111-4222-8333-00000000000a"
+		zoneGL = "00000010-1111-4222-8333-000000000010"
+		otherL = "00000011-1111-4222-8333-000000000011"
+	)
+	tests := []struct {
+		name string
+		cmd  udp.Command
+		want string
+	}{
+		{name: "zone sharing a light", cmd: udp.Command{Domain: "grouped_light", ID: zoneGL, Action: "on", Value: udp.BoolValue(true)}, want: OriginLoxone},
+		{name: "member light", cmd: udp.Command{Domain: "light", ID: lamp, Action: "on", Value: udp.BoolValue(true)}, want: OriginLoxone},
+		{name: "unrelated room", cmd: udp.Command{Domain: "light", ID: otherL, Action: "on", Value: udp.BoolValue(true)}, want: OriginApp},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sink := &originSink{}
+			e := goldenStreamer(t, sink)
+			e.poller.update(func(inv *Inventory) {
+				inv.setName(room, "room", "Kitchen", nil, "room")
+				inv.setName(zone, "zone", "Downstairs", nil, "zone")
+				inv.setName(other, "room", "Hall", nil, "room")
+				inv.lights[room] = []string{lamp}
+				inv.lights[zone] = []string{lamp}
+				inv.lights[other] = []string{otherL}
+				inv.groups["0000000f-1111-4222-8333-00000000000f"] = room
+				inv.groups[zoneGL] = zone
+			})
+			e.echoes = gateway.NewEchoes(0, e.poller)
+			e.sources = NewSources(0)
+			if err := e.echoes.Track(nopHandler{}).Apply(context.Background(), tt.cmd); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.handle(context.Background(), containers); err != nil {
+				t.Fatal(err)
+			}
+			if len(sink.origins) == 0 || sink.origins[0] != tt.want {
+				t.Errorf("origins = %q, want %q first", sink.origins, tt.want)
+			}
+		})
+	}
+}
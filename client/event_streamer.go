@@ -2,37 +2,282 @@ package client
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log/slog"
+	"io"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/capture"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
 	"github.com/samvdb/loxone-philips-hue/udp"
 	"golang.org/x/net/http2"
 )
 
-const backoffMax = 30 * time.Second
+const (
+	defaultBackoffMax   = 30 * time.Second
+	defaultAlertAfter   = 5
+	defaultMaxEventSize = 2 << 20
+)
+
+// warmupTimeout bounds how long events are held back waiting for the poller's
+// initial inventory and the UDP client's first dial.
+const warmupTimeout = 15 * time.Second
+
+// maxEarlyEvents bounds the SSE events held during warmup; the oldest are dropped.
+const maxEarlyEvents = 256
+
+type StreamerConfig struct {
+	Bridge    *bridge.Address
+	Keys      *bridge.Keys
+	UDPClient *udp.Client
+	Poller    *Poller
+	State     *gateway.State
+
+	// Levels also sends group brightness and battery levels to Loxone; the
+	// sinks get them either way.
+	Levels bool
+
+	// Output (optional) receives the Loxone payloads in place of UDPClient,
+	// e.g. a recorder in tests.
+	Output Output
+
+	// Deadband (optional) suppresses small analog changes.
+	Deadband *Deadband
+
+	// Sampler (optional) limits repeated messages per resource type, e.g. the
+	// status of dynamic scenes. Critical types are never sampled.
+	Sampler *Sampler
+
+	// Breaker (optional) drops low-priority channels during event storms.
+	// Critical types are never dropped.
+	Breaker *Breaker
+
+	// Occupancy (optional) aggregates motion/contact/light activity per room.
+	Occupancy *Occupancy
+
+	// Failsafe (optional) runs motion lighting rules while Loxone is down.
+	Failsafe *Failsafe
+
+	// Reporter (optional) re-sends analog values to Loxone on a fixed cadence.
+	Reporter *Reporter
+
+	// Bools (optional) renders boolean values for Loxone; default 1/0. The
+	// sinks always get 1/0.
+	Bools *udp.Bools
+
+	// Pauses (optional) suppresses messages of paused rooms and devices.
+	Pauses *gateway.Pauses
+
+	// Echoes (optional) recognizes the feedback of recent Loxone commands;
+	// EchoMode (EchoOff, EchoTag or EchoSuppress) decides what happens to it.
+	Echoes   *gateway.Echoes
+	EchoMode string
+
+	// Sources (optional) tags light state changes with their origin; it relies
+	// on Echoes to recognize gateway commands.
+	Sources *Sources
+
+	// Overrides (optional) reports rooms changed by hand; it needs Sources.
+	Overrides *Overrides
+
+	// Daily (optional) collects the figures of the daily report.
+	Daily *Daily
+
+	// Batteries (optional) raises /device/<id>/battery_low from battery levels.
+	Batteries *Batteries
+
+	// StaleMode (StaleOff, StaleMark or StaleDrop) handles events created more
+	// than StaleAfter ago. Default StaleOff; StaleAfter defaults to 30s.
+	StaleMode  string
+	StaleAfter time.Duration
+
+	// MaxEventSize is the longest event stream line (bytes) accepted; larger
+	// events end the connection. Default 2 MiB.
+	MaxEventSize int
+
+	// Capture (optional) records every raw event stream payload.
+	Capture *capture.Writer
+
+	// Resume (optional) skips events handled before a restart.
+	Resume *Resume
+
+	// Hooks (optional) run in order on every outgoing message.
+	Hooks []MessageHook
+
+	// Forward lists the rooms, zones and devices (by id) whose messages are
+	// sent; a room or zone includes its devices and scenes. Messages of the
+	// gateway itself are always sent. Empty forwards everything.
+	Forward []string
+
+	// MotionExclude lists grouped_motion owners (an rtype such as "zone" or a
+	// resource id) that are not forwarded. Nil means ["bridge_home"].
+	MotionExclude []string
+
+	// HomeMotion publishes bridge_home grouped motion as /home/motion, regardless
+	// of MotionExclude.
+	HomeMotion bool
+
+	// HomeLight publishes the bridge_home grouped light as /home/on and
+	// /home/brightness: whether any light in the home is on, without
+	// aggregating every light.
+	HomeLight bool
+
+	// Critical lists resource types whose messages bypass the UDP queue and its
+	// drop policy. Nil means ["contact", "tamper", "security_area_motion"] (burglar alarm inputs).
+	Critical []string
+
+	// CriticalTimeout bounds how long a critical send may block. Default 5s.
+	CriticalTimeout time.Duration
 
-func NewStreamer(ctx context.Context, bridgeIP string, hueAPIKey string, udpClient *udp.Client, poller *Poller) EventStreamer {
+	// Entertainment (optional) is told when entertainment sessions start and stop.
+	Entertainment *gateway.Entertainment
 
+	// Curves (optional) maps brightness feedback back to Loxone dimmer values.
+	Curves *curve.Curves
+
+	// AlertAfter is the number of consecutive failed connects after which the
+	// stream is reported unhealthy and a gateway alert is raised. Default 5.
+	AlertAfter int
+}
+
+// newStreamClient returns the HTTP/2 client used for the bridge event stream.
+func newStreamClient(bridgeAddr *bridge.Address, keys *bridge.Keys) *http.Client {
 	tlsCfg := &tls.Config{InsecureSkipVerify: true}
-	client := &http.Client{Transport: &http2.Transport{TLSClientConfig: tlsCfg}}
+	// the key transport sets hue-application-key and fails over on 401/403
+	return &http.Client{Transport: keys.Transport(&http2.Transport{
+		TLSClientConfig: tlsCfg,
+		// resolve/dial like bridge.Home so hostnames and custom DNS behave the same
+		DialTLSContext: func(ctx context.Context, network, address string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := bridgeAddr.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn := tls.Client(conn, cfg)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		},
+	})}
+}
 
-	return EventStreamer{
-		httpClient: client,
-		url:        fmt.Sprintf("https://%s/eventstream/clip/v2", bridgeIP),
-		apiKey:     hueAPIKey,
-		udpClient:  udpClient,
-		poller:     poller,
+// Record copies the raw event stream to w until ctx is done, e.g. for support
+// bundles. It returns nil when ctx ends the recording.
+func Record(ctx context.Context, bridgeAddr *bridge.Address, keys *bridge.Keys, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/eventstream/clip/v2", bridgeAddr.Host()), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := newStreamClient(bridgeAddr, keys).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// NewStreamer checks cfg and builds a streamer; opts add sinks and tune the
+// event stream connection.
+func NewStreamer(ctx context.Context, cfg StreamerConfig, opts ...StreamerOption) (*EventStreamer, error) {
+	switch {
+	case cfg.Bridge == nil:
+		return nil, errors.New("streamer: Bridge required")
+	case cfg.Keys == nil:
+		return nil, errors.New("streamer: Keys required")
+	case cfg.UDPClient == nil && cfg.Output == nil:
+		return nil, errors.New("streamer: UDPClient or Output required")
+	case cfg.Poller == nil:
+		return nil, errors.New("streamer: Poller required")
+	case cfg.State == nil:
+		return nil, errors.New("streamer: State required")
+	}
+	o := buildOptions(opts)
+
+	client := newStreamClient(cfg.Bridge, cfg.Keys)
+	if o.httpClient != nil {
+		c := *o.httpClient
+		base := c.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		c.Transport = cfg.Keys.Transport(base)
+		client = &c
+	}
+
+	// a new bridge IP needs fresh connections and a new stream
+	restart := make(chan struct{}, 1)
+	cfg.Bridge.OnChange(func(string) {
+		client.CloseIdleConnections()
+		select {
+		case restart <- struct{}{}:
+		default:
+		}
+	})
+
+	if cfg.AlertAfter <= 0 {
+		cfg.AlertAfter = defaultAlertAfter
+	}
+	if cfg.MaxEventSize <= 0 {
+		cfg.MaxEventSize = defaultMaxEventSize
 	}
 
+	e := &EventStreamer{
+		Dispatcher: newDispatcher(cfg, o),
+		httpClient: client,
+		bridge:     cfg.Bridge,
+		restart:    restart,
+		udpClient:  cfg.UDPClient,
+		maxEvent:   cfg.MaxEventSize,
+		capture:    cfg.Capture,
+		resume:     cfg.Resume,
+		backoffMax: o.backoffMax,
+		alertAfter: cfg.AlertAfter,
+		ready:      make(chan struct{}),
+	}
+	cfg.Poller.OnPolled(e.polled)
+	return e, nil
 }
 
 func (e *EventStreamer) Run(ctx context.Context) error {
 	backoff := time.Second
+	failures := 0
+
+	// Connect right away but hold events until the inventory is loaded and Loxone
+	// was dialled, so early events carry names and are not lost.
+	go func() {
+		warmCtx, cancel := context.WithTimeout(ctx, warmupTimeout)
+		defer cancel()
+		err := e.poller.WaitReady(warmCtx)
+		if err == nil && e.udpClient != nil {
+			select {
+			case <-e.udpClient.Ready():
+			case <-warmCtx.Done():
+				err = warmCtx.Err()
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			e.log.Warn("gateway not ready; forwarding events anyway", "timeout", warmupTimeout.String())
+		}
+		close(e.ready)
+		e.flushEarly(ctx)
+	}()
 
 	for {
 		// Exit immediately if we're asked to stop.
@@ -40,25 +285,41 @@ func (e *EventStreamer) Run(ctx context.Context) error {
 			return err
 		}
 
+		e.connected = false
 		err := e.streamOnce(ctx)
 		if ctx.Err() != nil {
 			// Context cancelled while streaming or during request.
 			return ctx.Err()
 		}
+		if e.connected {
+			// the stream was up; a later drop starts a fresh series
+			failures = 0
+			backoff = time.Second
+		}
 		if err == nil {
 			// Clean close from server; reset backoff and continue.
 			backoff = time.Second
 			continue
 		}
 
-		slog.Error(fmt.Sprintf("stream error: %v (reconnecting in %s)", err, backoff))
+		if bridgeUnavailable(err) {
+			e.state.SetBridgeOnline(false)
+		}
+
+		failures++
+		if failures == e.alertAfter {
+			e.state.SetEventStreamOK(false)
+			e.state.Alert("event_stream_down", err)
+		}
+
+		e.log.Error(fmt.Sprintf("stream error: %v (reconnecting in %s)", err, backoff), "failures", failures)
 		if err := sleepContext(ctx, backoff); err != nil {
 			return err // ctx cancelled during backoff
 		}
-		if backoff < backoffMax {
+		if backoff < e.backoffMax {
 			backoff *= 2
-			if backoff > backoffMax {
-				backoff = backoffMax
+			if backoff > e.backoffMax {
+				backoff = e.backoffMax
 			}
 		}
 	}
@@ -66,12 +327,33 @@ func (e *EventStreamer) Run(ctx context.Context) error {
 }
 
 func (e *EventStreamer) streamOnce(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", e.url, nil)
+	// an address change while no stream ran is covered by dialling the new host
+	// below; a stale signal must not cancel this stream
+	select {
+	case <-e.restart:
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-e.restart:
+			e.log.Info("bridge address changed; reconnecting event stream")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	url := fmt.Sprintf("https://%s/eventstream/clip/v2", e.bridge.Host())
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Accept", "text/event-stream")
-	req.Header.Set("hue-application-key", e.apiKey)
+	if id := e.resume.LastEventID(); id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
 
 	resp, err := e.httpClient.Do(req)
 	if err != nil {
@@ -80,157 +362,154 @@ func (e *EventStreamer) streamOnce(ctx context.Context) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %s", resp.Status)
+		return &statusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	slog.Info("Listening for Philips Hue Events...")
+	e.connected = true
+	e.resume.Connected()
+	e.state.SetBridgeOnline(true)
+	e.state.SetEventStreamOK(true)
+	e.log.Info("Listening for Philips Hue Events...")
 
 	scanner := bufio.NewScanner(resp.Body)
-	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024) // allow big events
+	scanner.Buffer(make([]byte, 0, min(64*1024, e.maxEvent)), e.maxEvent) // allow big events
 
-	var buf []byte
+	p := getPayload()
+	defer putPayload(p)
 
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := scanner.Bytes()
 
 		// SSE format: blank line separates events; "data:" lines carry payload
 		if len(line) == 0 {
-			if len(buf) > 0 {
-				// parse one complete SSE event payload (JSON array of containers)
-				var containers []EventContainer
-				if err := json.Unmarshal(buf, &containers); err != nil {
-					slog.Error(fmt.Sprintf("bad JSON: %s (err: %v)", string(buf), err))
-				} else {
-					err := e.handle(ctx, containers)
-					if err != nil {
-						return err
-					}
+			if len(p.buf) > 0 {
+				e.handleMu.Lock()
+				err := e.handlePayload(ctx, p)
+				e.handleMu.Unlock()
+				if err != nil {
+					return err
 				}
-				buf = buf[:0]
+				p.reset()
 			}
 			continue
 		}
 
-		if len(line) >= 5 && line[:5] == "data:" {
-			// strip "data:" and optional leading space
-			payload := line[5:]
-			if len(payload) > 0 && payload[0] == ' ' {
-				payload = payload[1:]
-			}
-			// SSE may split data across multiple "data:" lines; join with \n
-			if len(buf) > 0 {
-				buf = append(buf, '\n')
-			}
-			buf = append(buf, payload...)
+		if data, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			// strip optional leading space
+			p.appendLine(bytes.TrimPrefix(data, []byte(" ")))
+		} else if id, ok := bytes.CutPrefix(line, []byte("id:")); ok {
+			p.id = string(bytes.TrimSpace(id))
 		}
 	}
 
 	return scanner.Err()
 }
 
-func (e *EventStreamer) handle(ctx context.Context, containers []EventContainer) error {
-	for _, c := range containers {
-		for _, raw := range c.Data {
-			ev, err := decodeResource(raw)
-			if err != nil {
-				return err
-			}
+// statusError is returned when the event stream answers with a non-200 status.
+type statusError struct {
+	StatusCode int
+	Status     string
+}
 
-			parent := ev.GetGeneric().Owner
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
 
-			switch ee := ev.(type) {
-			case *LightEvent:
-				if ee.On != nil {
-					slog.Debug("light event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "on", ee.On.On)
-				}
-			case *TamperEvent:
-				if len(ee.TamperReports) > 0 {
-					for _, report := range ee.TamperReports {
-						slog.Debug("tamper event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "source", report.Source, "state", report.State)
-					}
-				}
-			case *ContactEvent:
-				if ee.ContactReport != nil {
-					slog.Debug("contact event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "state", ee.ContactReport.State)
-					state := 0
-					if ee.ContactReport.State == StateContact {
-						state = 1
-					}
-					e.udpClient.Send([]byte(fmt.Sprintf("/contact/%s/state %b", parent.ID, state)))
-				}
-			case *MotionEvent:
-				if ee.Motion.MotionReport != nil {
-					if parent.ID == "" {
-						continue
-					}
-					slog.Debug("motion event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "motion", ee.Motion.MotionReport.Motion)
-					value := 0
-					// convert to 1 or 0
-					if ee.Motion.MotionReport.Motion {
-						value = 1
-					}
-					e.udpClient.Send([]byte(fmt.Sprintf("/sensor/%s/motion %b", parent.ID, value)))
-				}
+// bridgeUnavailable reports whether err indicates the bridge itself is gone
+// (restarting, updating firmware) rather than a problem with one event.
+func bridgeUnavailable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= http.StatusInternalServerError
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
 
-			case *GroupedMotionEvent:
-				if ee.Motion.MotionReport != nil {
-					if parent.Type == "bridge_home" {
-						continue
-					}
-					slog.Debug("grouped motion event", "id", parent.ID, "group", e.poller.GetDevice(parent.ID), "grouped_motion", ee.Motion.MotionReport.Motion)
-					value := 0
-					// convert to 1 or 0
-					if ee.Motion.MotionReport.Motion {
-						value = 1
-					}
-					e.udpClient.Send([]byte(fmt.Sprintf("/group/%s/motion %b", parent.ID, value)))
-				}
+// handlePayload parses one complete SSE event payload (JSON array of containers)
+// and handles it.
+func (e *EventStreamer) handlePayload(ctx context.Context, p *payload) error {
+	e.capture.Write("sse", e.bridge.Host(), p.buf)
+	if err := p.decode(); err != nil {
+		e.log.Error("bad event payload; see --capture-raw", "bytes", len(p.buf), "error", err)
+	} else {
+		if err := e.handleReady(ctx, p.id, e.resume.Filter(p.containers)); err != nil {
+			return err
+		}
+	}
+	return e.replayResolved(ctx)
+}
 
-			case *LightLevelEvent:
-				if ee.Light.LightLevelReport != nil {
-					slog.Debug("light level event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "light_level", ee.Light.LightLevelReport.LightLevel)
+// polled handles a resource the poller fetched because the bridge sends no
+// events for it, as if it came from the event stream.
+func (e *EventStreamer) polled(ctx context.Context, raw json.RawMessage) {
+	e.handleMu.Lock()
+	defer e.handleMu.Unlock()
+	if err := e.handleReady(ctx, "", []EventContainer{{Type: EventTypeUpdate, Data: []json.RawMessage{raw}}}); err != nil {
+		e.log.Warn("polled resource not handled", "error", err)
+	}
+}
 
-					e.udpClient.Send([]byte(fmt.Sprintf("/sensor/%s/light_level %f", parent.ID, ee.Light.LightLevelReport.LightLevel)))
-				}
+// heldEvent is an SSE event held during warmup; eventID is its SSE id.
+type heldEvent struct {
+	eventID    string
+	containers []EventContainer
+}
 
-			case *GroupedLightLevelEvent:
-				if ee.Light.LightLevelReport != nil {
-					slog.Debug("grouped light level event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "light_level", ee.Light.LightLevelReport.LightLevel)
+// handleReady holds containers until the warmup in Run finished and then replays
+// them in order ahead of the current ones. Containers are marked handled for
+// Resume once they are dispatched, not while held.
+func (e *EventStreamer) handleReady(ctx context.Context, eventID string, containers []EventContainer) error {
+	if e.ready != nil {
+		select {
+		case <-e.ready:
+		default:
+			if len(e.early) == maxEarlyEvents {
+				e.early = e.early[1:]
+				e.earlyDropped++
+			}
+			// containers are reused by the next event
+			e.early = append(e.early, heldEvent{eventID: eventID, containers: cloneContainers(containers)})
+			return nil
+		}
+	}
+	if err := e.replayEarly(ctx); err != nil {
+		return err
+	}
+	return e.dispatch(ctx, eventID, containers)
+}
 
-					e.udpClient.Send([]byte(fmt.Sprintf("/sensor/%s/grouped_light_level %f", parent.ID, ee.Light.LightLevelReport.LightLevel)))
-				}
+// dispatch handles containers and records them, the SSE event eventID, with Resume.
+func (e *EventStreamer) dispatch(ctx context.Context, eventID string, containers []EventContainer) error {
+	if err := e.handle(ctx, containers); err != nil {
+		return err
+	}
+	e.resume.Mark(eventID, containers)
+	return nil
+}
 
-			case *TemperatureEvent:
-				if ee.Temperature.TemperatureReport != nil {
-					slog.Debug("temperature event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "temperature", ee.Temperature.TemperatureReport.Temperature)
+// flushEarly forwards the held containers as soon as the warmup is over rather
+// than with the next event, which may be minutes away on a quiet system.
+func (e *EventStreamer) flushEarly(ctx context.Context) {
+	e.handleMu.Lock()
+	defer e.handleMu.Unlock()
+	if err := e.replayEarly(ctx); err != nil {
+		e.log.Warn("events held during warmup not handled", "error", err)
+	}
+}
 
-					e.udpClient.Send([]byte(fmt.Sprintf("/sensor/%s/temperature %.2f", parent.ID, ee.Temperature.TemperatureReport.Temperature)))
-				}
-			case *GroupedLightEvent:
-				slog.Debug("grouped_light event", "id", parent.ID, "device", e.poller.GetDevice(parent.ID), "raw", string(raw))
-			case *ZigbeeConnectivityEvent:
-				slog.Debug("zigbee_connectivity event", "id", parent.ID, "state", ee.Status)
-
-			case *SceneEvent:
-				scene := e.poller.GetScene(ee.ID)
-				slog.Debug("scene event", "id", ee.ID, "status", ee.Status.Active, "scene", scene)
-				if scene == nil {
-					continue
-				}
-				if ee.Status.Active == "static" {
-					e.udpClient.Send([]byte(fmt.Sprintf("/scene/%s/on %s", scene.GroupID, ee.ID)))
-				}
-			case *UnknownEvent:
-				// keep for diagnostics or forward to a generic handler
-				// slog.Debug("unknown event", "type", e.Type, "raw", string(e.Raw))
-				slog.Warn("unknown event", "type", ee.Type, "raw", string(ee.Raw))
-			case *MutedEvent:
-
-			default:
-				slog.Debug("unhandled event", "type", ee.ResourceType())
-			}
+// replayEarly handles the held containers in order; the caller holds handleMu.
+func (e *EventStreamer) replayEarly(ctx context.Context) error {
+	if len(e.early) == 0 {
+		return nil
+	}
+	e.log.Info("replaying events held during warmup", "events", len(e.early), "dropped", e.earlyDropped)
+	early := e.early
+	e.early, e.earlyDropped = nil, 0
+	for _, h := range early {
+		if err := e.dispatch(ctx, h.eventID, h.containers); err != nil {
+			return err
 		}
-
 	}
 	return nil
 }
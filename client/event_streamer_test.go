@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+)
+
+func TestHandleReady_HoldsEventsUntilReady(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "events", "motion.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []EventContainer
+	if err := json.Unmarshal(raw, &containers); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &captureSink{}
+	e := goldenStreamer(t, sink)
+	ready := make(chan struct{})
+	e.ready = ready
+
+	for i := 0; i < maxEarlyEvents+2; i++ {
+		if err := e.handleReady(context.Background(), "", containers); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sink.msgs) != 0 {
+		t.Fatalf("%d messages forwarded before ready", len(sink.msgs))
+	}
+
+	close(ready)
+	if err := e.handleReady(context.Background(), "", containers); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(sink.msgs), maxEarlyEvents+1; got != want {
+		t.Errorf("forwarded %d messages after ready, want %d (held events bounded, then the current one)", got, want)
+	}
+	if len(e.early) != 0 {
+		t.Errorf("%d events still held", len(e.early))
+	}
+}
+
+func TestFlushEarly_ForwardsHeldEventsOnReady(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "events", "motion.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []EventContainer
+	if err := json.Unmarshal(raw, &containers); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &captureSink{}
+	e := goldenStreamer(t, sink)
+	e.resume = mustResume(t, filepath.Join(t.TempDir(), "resume.json"))
+	ready := make(chan struct{})
+	e.ready = ready
+	if err := e.handleReady(context.Background(), "1714564800:0", containers); err != nil {
+		t.Fatal(err)
+	}
+	if id := e.resume.LastEventID(); id != "" {
+		t.Errorf("held event marked handled as %q", id)
+	}
+
+	// no further event arrives
+	close(ready)
+	e.flushEarly(context.Background())
+	if len(sink.msgs) != 1 {
+		t.Errorf("forwarded %d messages once ready, want 1", len(sink.msgs))
+	}
+	if id := e.resume.LastEventID(); id != "1714564800:0" {
+		t.Errorf("LastEventID() = %q after dispatch", id)
+	}
+	if len(e.early) != 0 {
+		t.Errorf("%d events still held", len(e.early))
+	}
+}
+
+func TestStreamOnce_IgnoresStaleRestart(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	e := goldenStreamer(t, &captureSink{})
+	e.httpClient = srv.Client()
+	e.bridge = bridge.NewAddress(strings.TrimPrefix(srv.URL, "https://"))
+
+	// the bridge moved while no stream was running
+	e.restart <- struct{}{}
+
+	if err := e.streamOnce(context.Background()); err != nil {
+		t.Fatalf("streamOnce() = %v, want a clean close", err)
+	}
+	if !e.connected {
+		t.Error("stream never connected")
+	}
+}
+
+func TestNewStreamer_RequiresConfig(t *testing.T) {
+	if _, err := NewStreamer(context.Background(), StreamerConfig{}); err == nil {
+		t.Error("NewStreamer() with an empty config succeeded")
+	}
+	if _, err := NewPoller(context.Background(), nil); err == nil {
+		t.Error("NewPoller() without a bridge succeeded")
+	}
+}
@@ -4,8 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/capture"
+	"github.com/samvdb/loxone-philips-hue/resource"
 	"github.com/samvdb/loxone-philips-hue/udp"
 )
 
@@ -20,7 +26,7 @@ type EventContainer struct {
 }
 
 type EventResource interface {
-	ResourceType() string
+	ResourceType() resource.Type
 	GetGeneric() *GenericEvent
 }
 
@@ -31,11 +37,24 @@ type OnEvent struct {
 }
 
 type EventStreamer struct {
+	*Dispatcher
+
 	httpClient *http.Client
-	url        string
-	apiKey     string
+	bridge     *bridge.Address
+	restart    chan struct{} // signalled when the bridge address changes
 	udpClient  *udp.Client
-	poller     *Poller
+	maxEvent   int // bytes
+	capture    *capture.Writer
+	resume     *Resume
+
+	backoffMax time.Duration
+	alertAfter int  // consecutive failures before the stream is reported down
+	connected  bool // set by streamOnce once the bridge accepted the stream
+
+	handleMu     sync.Mutex    // serializes the event stream and polled resources
+	ready        chan struct{} // closed when the warmup in Run is over
+	early        []heldEvent   // SSE events held until ready
+	earlyDropped int
 }
 
 const (
@@ -43,14 +62,14 @@ const (
 )
 
 type Owner struct {
-	ID   string `json:"rid"`
-	Type string `json:"rtype"`
+	ID   resource.ID   `json:"rid"`
+	Type resource.Type `json:"rtype"`
 }
 
 type GenericEvent struct {
-	ID    string `json:"id"`
-	Type  string `json:"type"`
-	Owner Owner  `json:"owner"`
+	ID    resource.ID   `json:"id"`
+	Type  resource.Type `json:"type"`
+	Owner Owner         `json:"owner"`
 }
 
 func (e *GenericEvent) GetGeneric() *GenericEvent {
@@ -65,7 +84,7 @@ type LightEvent struct {
 	} `json:"on,omitempty"`
 }
 
-func (e *LightEvent) ResourceType() string { return e.Type }
+func (e *LightEvent) ResourceType() resource.Type { return e.Type }
 
 type ContactEvent struct {
 	*GenericEvent
@@ -75,7 +94,7 @@ type ContactEvent struct {
 	} `json:"contact_report,omitempty"`
 }
 
-func (e *ContactEvent) ResourceType() string { return e.Type }
+func (e *ContactEvent) ResourceType() resource.Type { return e.Type }
 
 type TamperEvent struct {
 	*GenericEvent
@@ -86,7 +105,7 @@ type TamperEvent struct {
 	} `json:"tamper_reports,omitempty"`
 }
 
-func (e *TamperEvent) ResourceType() string { return e.Type }
+func (e *TamperEvent) ResourceType() resource.Type { return e.Type }
 
 type ZigbeeConnectivityEvent struct {
 	*GenericEvent
@@ -94,7 +113,7 @@ type ZigbeeConnectivityEvent struct {
 	Status ConnectedStatus `json:"status"`
 }
 
-func (e *ZigbeeConnectivityEvent) ResourceType() string { return e.Type }
+func (e *ZigbeeConnectivityEvent) ResourceType() resource.Type { return e.Type }
 
 type SceneEvent struct {
 	*GenericEvent
@@ -105,17 +124,40 @@ type SceneEvent struct {
 	} `json:"status"`
 }
 
-func (e *SceneEvent) ResourceType() string { return e.Type }
+func (e *SceneEvent) ResourceType() resource.Type { return e.Type }
 
 type GroupedLightEvent struct {
 	*GenericEvent
-	IDv1    string `json:"id_v1"`
-	Dimming struct {
+	IDv1 string `json:"id_v1"`
+	On   *struct {
+		On bool `json:"on"`
+	} `json:"on,omitempty"`
+	Dimming *struct {
 		Brightness float64 `json:"brightness"`
-	} `json:"dimming"`
+	} `json:"dimming,omitempty"`
+}
+
+func (e *GroupedLightEvent) ResourceType() resource.Type { return e.Type }
+
+type DevicePowerEvent struct {
+	*GenericEvent
+	IDv1       string `json:"id_v1"`
+	PowerState *struct {
+		BatteryState string  `json:"battery_state"` // normal | low | critical
+		BatteryLevel float64 `json:"battery_level"` // 0..100
+	} `json:"power_state,omitempty"`
 }
 
-func (e *GroupedLightEvent) ResourceType() string { return e.Type }
+func (e *DevicePowerEvent) ResourceType() resource.Type { return e.Type }
+
+// EntertainmentConfigurationEvent reports an entertainment session starting or
+// stopping; while "active" the bridge ignores regular commands for its lights.
+type EntertainmentConfigurationEvent struct {
+	*GenericEvent
+	Status string `json:"status,omitempty"` // active | inactive
+}
+
+func (e *EntertainmentConfigurationEvent) ResourceType() resource.Type { return e.Type }
 
 type MotionEvent struct {
 	*GenericEvent
@@ -130,12 +172,92 @@ type MotionEvent struct {
 	} `json:"motion"`
 }
 
-func (e *MotionEvent) ResourceType() string { return e.Type }
+func (e *MotionEvent) ResourceType() resource.Type { return e.Type }
+
+// ButtonEvent is a press on a Hue accessory such as a dimmer switch or smart button.
+type ButtonEvent struct {
+	*GenericEvent
+	Button *struct {
+		ButtonReport *struct {
+			Updated time.Time `json:"updated"`
+			Event   string    `json:"event"` // initial_press, repeat, short_release, long_press, ...
+		} `json:"button_report"`
+		LastEvent string `json:"last_event,omitempty"` // deprecated; all older firmware sends
+	} `json:"button,omitempty"`
+}
+
+func (e *ButtonEvent) ResourceType() resource.Type { return e.Type }
+
+// ButtonEvents are the button events forwarded as /button/<id>/<event>.
+var ButtonEvents = []string{"initial_press", "repeat", "short_release", "long_press", "long_release", "double_short_release"}
+
+// Pressed returns the reported event, e.g. "short_release", or "" if there is none.
+func (e *ButtonEvent) Pressed() string {
+	switch {
+	case e.Button == nil:
+		return ""
+	case e.Button.ButtonReport != nil && e.Button.ButtonReport.Event != "":
+		return e.Button.ButtonReport.Event
+	}
+	return e.Button.LastEvent
+}
+
+// RelativeRotaryEvent is a turn of a tap dial or lutron aurora knob.
+type RelativeRotaryEvent struct {
+	*GenericEvent
+	RelativeRotary *struct {
+		RotaryReport *struct {
+			Updated  time.Time `json:"updated"`
+			Action   string    `json:"action"` // start, repeat
+			Rotation *Rotation `json:"rotation,omitempty"`
+		} `json:"rotary_report"`
+		LastEvent *struct {
+			Action   string    `json:"action"`
+			Rotation *Rotation `json:"rotation,omitempty"`
+		} `json:"last_event,omitempty"` // deprecated; all older firmware sends
+	} `json:"relative_rotary,omitempty"`
+}
+
+func (e *RelativeRotaryEvent) ResourceType() resource.Type { return e.Type }
+
+// Rotation is one step of turning a rotary.
+type Rotation struct {
+	Direction string `json:"direction"` // clock_wise, counter_clock_wise
+	Steps     int    `json:"steps"`
+	Duration  int    `json:"duration"` // ms
+}
+
+// Turned returns the reported rotation, or nil if there is none.
+func (e *RelativeRotaryEvent) Turned() *Rotation {
+	switch r := e.RelativeRotary; {
+	case r == nil:
+		return nil
+	case r.RotaryReport != nil && r.RotaryReport.Rotation != nil:
+		return r.RotaryReport.Rotation
+	case r.LastEvent != nil:
+		return r.LastEvent.Rotation
+	}
+	return nil
+}
+
+// SignedSteps are the steps turned, negative counterclockwise.
+func (r *Rotation) SignedSteps() int {
+	if r.Direction == "counter_clock_wise" {
+		return -r.Steps
+	}
+	return r.Steps
+}
 
 type GroupedMotionEvent struct {
 	*MotionEvent
 }
 
+// SecurityAreaMotionEvent is motion in a Hue Secure (MotionAware) security area
+// while it is armed; its id is the area.
+type SecurityAreaMotionEvent struct {
+	*MotionEvent
+}
+
 type LightLevelEvent struct {
 	*GenericEvent
 	IDv1    string `json:"id_v1"`
@@ -146,16 +268,18 @@ type LightLevelEvent struct {
 			//Light level in 10000*log10(lux) +1 measured by sensor. Logarithmic scale used because the human eye adjusts to light levels and small changes at low lux levels are more noticeable than at high lux levels. This allows use of linear scale configuration sliders.
 			LightLevel float64 `json:"light_level"`
 		} `json:"light_level_report"`
-	} `json:"motion"`
+	} `json:"light"`
 }
 
-func (e *LightLevelEvent) ResourceType() string { return e.Type }
+func (e *LightLevelEvent) ResourceType() resource.Type { return e.Type }
 
+// GroupedLightLevelEvent is the combined light level of a room or zone; its owner
+// is the group.
 type GroupedLightLevelEvent struct {
 	*LightLevelEvent
 }
 
-func (e *GroupedLightLevelEvent) ResourceType() string { return e.Type }
+func (e *GroupedLightLevelEvent) ResourceType() resource.Type { return e.Type }
 
 type TemperatureEvent struct {
 	*GenericEvent
@@ -169,7 +293,67 @@ type TemperatureEvent struct {
 	} `json:"temperature"`
 }
 
-func (e *TemperatureEvent) ResourceType() string { return e.Type }
+func (e *TemperatureEvent) ResourceType() resource.Type { return e.Type }
+
+// PowerEvent is any resource reporting electrical power or energy (smart plugs,
+// third-party Zigbee meters exposed by the bridge). The bridge has no stable
+// resource type for these yet, so it is matched by its "power" or "energy"
+// objects and every field is optional.
+type PowerEvent struct {
+	*GenericEvent
+	Power *struct {
+		PowerReport *struct {
+			Power flexFloat `json:"power"` // W
+		} `json:"power_report"`
+	} `json:"power,omitempty"`
+	Energy *struct {
+		EnergyReport *struct {
+			Energy flexFloat `json:"energy"` // kWh
+		} `json:"energy_report"`
+	} `json:"energy,omitempty"`
+}
+
+func (e *PowerEvent) ResourceType() resource.Type { return e.Type }
+
+// Watts returns the reported power, if any.
+func (e *PowerEvent) Watts() (float64, bool) {
+	if e.Power == nil || e.Power.PowerReport == nil {
+		return 0, false
+	}
+	return e.Power.PowerReport.Power.Get()
+}
+
+// KWh returns the reported energy total, if any.
+func (e *PowerEvent) KWh() (float64, bool) {
+	if e.Energy == nil || e.Energy.EnergyReport == nil {
+		return 0, false
+	}
+	return e.Energy.EnergyReport.Energy.Get()
+}
+
+// flexFloat accepts a JSON number or a numeric string and ignores anything else,
+// so a firmware changing the representation does not fail the whole event.
+type flexFloat struct {
+	v  float64
+	ok bool
+}
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		f.v, f.ok = v, true
+	}
+	return nil
+}
+
+func (f flexFloat) Get() (float64, bool) { return f.v, f.ok }
+
+func (f flexFloat) MarshalJSON() ([]byte, error) {
+	if !f.ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.v)
+}
 
 type ContactState string
 
@@ -181,8 +365,10 @@ const (
 type ConnectedStatus string
 
 const (
-	StatusConnected    ConnectedStatus = "connected"
-	StatusDisconnected ConnectedStatus = "connectivity_issue"
+	StatusConnected              ConnectedStatus = "connected"
+	StatusDisconnected           ConnectedStatus = "disconnected"
+	StatusConnectivityIssue      ConnectedStatus = "connectivity_issue"
+	StatusUnidirectionalIncoming ConnectedStatus = "unidirectional_incoming"
 )
 
 type TamperState string
@@ -192,9 +378,11 @@ const (
 	StateNotTampered TamperState = "not_tampered"
 )
 
-// Minimal probe to read only the "type" field.
+// Minimal probe to read the "type" field and whether the resource reports power.
 type typeProbe struct {
-	Type string `json:"type"`
+	Type   resource.Type   `json:"type"`
+	Power  json.RawMessage `json:"power"`
+	Energy json.RawMessage `json:"energy"`
 }
 
 // Decode one raw data object into a concrete EventResource.
@@ -256,6 +444,13 @@ func decodeResource(b []byte) (EventResource, error) {
 		}
 		return &ev, nil
 
+	case "security_area_motion":
+		var ev SecurityAreaMotionEvent
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return nil, fmt.Errorf("security_area_motion: %w", err)
+		}
+		return &ev, nil
+
 	case "light_level":
 		var ev LightLevelEvent
 		if err := json.Unmarshal(b, &ev); err != nil {
@@ -264,7 +459,7 @@ func decodeResource(b []byte) (EventResource, error) {
 		return &ev, nil
 
 	case "grouped_light_level":
-		var ev GroupedLightEvent
+		var ev GroupedLightLevelEvent
 		if err := json.Unmarshal(b, &ev); err != nil {
 			return nil, fmt.Errorf("grouped_light_level: %w", err)
 		}
@@ -275,6 +470,30 @@ func decodeResource(b []byte) (EventResource, error) {
 			return nil, fmt.Errorf("temperature: %w", err)
 		}
 		return &ev, nil
+	case "device_power":
+		var ev DevicePowerEvent
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return nil, fmt.Errorf("device_power: %w", err)
+		}
+		return &ev, nil
+	case "entertainment_configuration":
+		var ev EntertainmentConfigurationEvent
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return nil, fmt.Errorf("entertainment_configuration: %w", err)
+		}
+		return &ev, nil
+	case "button":
+		var ev ButtonEvent
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return nil, fmt.Errorf("button: %w", err)
+		}
+		return &ev, nil
+	case "relative_rotary":
+		var ev RelativeRotaryEvent
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return nil, fmt.Errorf("relative_rotary: %w", err)
+		}
+		return &ev, nil
 	case "geofence_client":
 		var ev MutedEvent
 		if err := json.Unmarshal(b, &ev); err != nil {
@@ -282,19 +501,28 @@ func decodeResource(b []byte) (EventResource, error) {
 		}
 		return &ev, nil
 
-	// add other resource types here: "motion", "button", "temperature", ...
+	// add other resource types here
 	default:
+		if _, ok := valueServices[tp.Type]; ok {
+			return decodeValue(b, tp.Type)
+		}
+		if tp.Power != nil || tp.Energy != nil {
+			var ev PowerEvent
+			if err := json.Unmarshal(b, &ev); err == nil && ev.GenericEvent != nil {
+				return &ev, nil
+			}
+		}
 		// Unknown type? Return a raw wrapper so you don’t lose data.
 		return &UnknownEvent{Raw: b, Type: tp.Type}, nil
 	}
 }
 
 type UnknownEvent struct {
-	Type string
+	Type resource.Type
 	Raw  []byte
 }
 
-func (e *UnknownEvent) ResourceType() string { return e.Type }
+func (e *UnknownEvent) ResourceType() resource.Type { return e.Type }
 
 func (e *UnknownEvent) GetGeneric() *GenericEvent {
 	return &GenericEvent{}
@@ -302,8 +530,8 @@ func (e *UnknownEvent) GetGeneric() *GenericEvent {
 
 type MutedEvent struct {
 	*GenericEvent
-	Type string
+	Type resource.Type
 	Raw  []byte
 }
 
-func (e *MutedEvent) ResourceType() string { return e.Type }
+func (e *MutedEvent) ResourceType() resource.Type { return e.Type }
@@ -0,0 +1,171 @@
+package client
+
+import "testing"
+
+func TestDecodeGroupedLightLevel(t *testing.T) {
+	raw := []byte(`{
+		"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11",
+		"type": "grouped_light_level",
+		"owner": {"rid": "0b7e4d2a-91c3-4f6e-8d21-5a9c3b7e1f02", "rtype": "room"},
+		"enabled": true,
+		"light": {"light_level_report": {"changed": "2025-01-01T00:00:00Z", "light_level": 18000}}
+	}`)
+
+	ev, err := decodeResource(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gll, ok := ev.(*GroupedLightLevelEvent)
+	if !ok {
+		t.Fatalf("decoded %T, want *GroupedLightLevelEvent", ev)
+	}
+	if gll.Light.LightLevelReport == nil || gll.Light.LightLevelReport.LightLevel != 18000 {
+		t.Fatalf("light level not decoded: %+v", gll.Light)
+	}
+}
+
+func TestDecodeButton(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "button_report", raw: `{"button_report": {"event": "long_release", "updated": "2025-01-01T00:00:00Z"}, "last_event": "repeat"}`, want: "long_release"},
+		{name: "last_event only", raw: `{"last_event": "initial_press"}`, want: "initial_press"},
+		{name: "no event", raw: `{}`, want: ""},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			raw := []byte(`{"id": "0000001d-1111-4222-8333-00000000001d", "type": "button", "owner": {"rid": "00000001-1111-4222-8333-000000000001", "rtype": "device"}, "button": ` + tt.raw + `}`)
+			ev, err := decodeResource(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, ok := ev.(*ButtonEvent)
+			if !ok {
+				t.Fatalf("decoded %T, want *ButtonEvent", ev)
+			}
+			if got := b.Pressed(); got != tt.want {
+				t.Errorf("Pressed() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsMalformedID(t *testing.T) {
+	raw := []byte(`{"id": "not-a-uuid", "type": "motion", "owner": {"rid": "x", "rtype": "device"}}`)
+	if _, err := decodeResource(raw); err == nil {
+		t.Fatal("decodeResource() accepted a malformed id")
+	}
+}
+
+func TestDecodePower(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		watts     float64
+		wattsOK   bool
+		kwh       float64
+		kwhOK     bool
+		wantPower bool
+	}{
+		{
+			name:      "number",
+			raw:       `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "power_measurement", "power": {"power_report": {"power": 12.5}}}`,
+			watts:     12.5,
+			wattsOK:   true,
+			wantPower: true,
+		},
+		{
+			name:      "string values and unknown fields",
+			raw:       `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "plug_meter", "power": {"power_report": {"power": "3", "unit": "W"}, "extra": [1]}, "energy": {"energy_report": {"energy": "1.25"}}}`,
+			watts:     3,
+			wattsOK:   true,
+			kwh:       1.25,
+			kwhOK:     true,
+			wantPower: true,
+		},
+		{
+			name:      "garbage value",
+			raw:       `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "power_measurement", "power": {"power_report": {"power": {"value": 1}}}}`,
+			wantPower: true,
+		},
+		{
+			name: "no power object",
+			raw:  `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "something_new"}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ev, err := decodeResource([]byte(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, ok := ev.(*PowerEvent)
+			if ok != tt.wantPower {
+				t.Fatalf("decoded %T", ev)
+			}
+			if !ok {
+				return
+			}
+			if w, ok := p.Watts(); w != tt.watts || ok != tt.wattsOK {
+				t.Errorf("Watts() = %v, %v, want %v, %v", w, ok, tt.watts, tt.wattsOK)
+			}
+			if e, ok := p.KWh(); e != tt.kwh || ok != tt.kwhOK {
+				t.Errorf("KWh() = %v, %v, want %v, %v", e, ok, tt.kwh, tt.kwhOK)
+			}
+		})
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	reported := 41.5
+	tests := []struct {
+		name  string
+		raw   string
+		value *float64
+		err   bool
+	}{
+		{
+			name:  "report",
+			raw:   `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "relative_humidity", "relative_humidity": {"relative_humidity": 40, "relative_humidity_valid": true, "relative_humidity_report": {"changed": "2025-01-01T00:00:00Z", "relative_humidity": 41.5}}}`,
+			value: &reported,
+		},
+		{
+			name: "no report",
+			raw:  `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "relative_humidity", "relative_humidity": {"relative_humidity_valid": false}}`,
+		},
+		{
+			name: "not a number",
+			raw:  `{"id": "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11", "type": "relative_humidity", "relative_humidity": {"relative_humidity_report": {"relative_humidity": "wet"}}}`,
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ev, err := decodeResource([]byte(tt.raw))
+			if tt.err {
+				if err == nil {
+					t.Fatal("decodeResource() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, ok := ev.(*ValueEvent)
+			if !ok {
+				t.Fatalf("decoded %T, want *ValueEvent", ev)
+			}
+			if (v.Value == nil) != (tt.value == nil) || (v.Value != nil && *v.Value != *tt.value) {
+				t.Errorf("value = %v, want %v", v.Value, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// FailsafeRule switches a room or zone on when motion is seen and off again
+// For after the last motion, e.g.
+// {"failsafe": [{"motion": "hall sensor", "group": "hall", "for": "5m"}]}.
+type FailsafeRule struct {
+	Motion string        `mapstructure:"motion"` // motion sensor or room/zone (grouped motion), by id or name
+	Group  string        `mapstructure:"group"`  // room, zone or grouped_light, by id or name
+	For    time.Duration `mapstructure:"for"`    // default 5m
+}
+
+type FailsafeConfig struct {
+	Rules []FailsafeRule
+
+	// Names resolves the rules' sensors and groups.
+	Names *Poller
+
+	// Handler applies the on/off commands. It must be the full command chain,
+	// not the queue, so read-only mode, HA standby, the command windows and the
+	// failure tracking hold for them.
+	Handler udp.CommandHandler
+
+	// Down reports whether Loxone is unreachable; rules only fire while it is.
+	Down func() bool
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Failsafe runs a few motion lighting rules in place of Loxone while the
+// Miniserver is down, so basic lighting keeps working during an outage. It
+// only switches off what it switched on, and leaves lights alone once Loxone
+// is back.
+type Failsafe struct {
+	cfg FailsafeConfig
+	log *slog.Logger
+	now func() time.Time
+
+	mu   sync.Mutex
+	held map[string]time.Time // key: grouped_light id, value: when to switch off
+}
+
+// ValidateFailsafeRules checks rules read from the configuration.
+func ValidateFailsafeRules(rules []FailsafeRule) error {
+	for i, r := range rules {
+		if r.Motion == "" || r.Group == "" {
+			return fmt.Errorf("failsafe rule %d: motion and group are required", i+1)
+		}
+		if r.For < 0 {
+			return fmt.Errorf("failsafe rule %d: negative for", i+1)
+		}
+	}
+	return nil
+}
+
+func NewFailsafe(cfg FailsafeConfig) (*Failsafe, error) {
+	if cfg.Names == nil || cfg.Handler == nil || cfg.Down == nil {
+		return nil, fmt.Errorf("failsafe: Names, Handler and Down are required")
+	}
+	if err := ValidateFailsafeRules(cfg.Rules); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Rules {
+		if cfg.Rules[i].For == 0 {
+			cfg.Rules[i].For = 5 * time.Minute
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Failsafe{
+		cfg:  cfg,
+		log:  cfg.Logger.With("module", "failsafe"),
+		now:  time.Now,
+		held: make(map[string]time.Time),
+	}, nil
+}
+
+// Motion feeds a motion report of a sensor device, room or zone.
+func (f *Failsafe) Motion(ctx context.Context, id string, motion bool) {
+	if f == nil || !motion || !f.cfg.Down() {
+		return
+	}
+	inv := f.cfg.Names.Snapshot()
+	for _, r := range f.cfg.Rules {
+		if !matches(inv, id, r.Motion) && !matches(inv, inv.RoomID(id), r.Motion) {
+			continue
+		}
+		group := groupedLight(inv, r.Group)
+		if group == "" {
+			f.log.Warn("failsafe group not found", "group", r.Group)
+			continue
+		}
+		until := f.now().Add(r.For)
+		f.mu.Lock()
+		_, on := f.held[group]
+		if !on || until.After(f.held[group]) {
+			f.held[group] = until
+		}
+		f.mu.Unlock()
+		if !on {
+			f.log.Info("failsafe: motion while loxone is down; switching on", "motion", r.Motion, "group", r.Group, "for", r.For)
+			f.apply(ctx, group, true)
+		}
+	}
+}
+
+// Run switches off expired groups until ctx is done.
+func (f *Failsafe) Run(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			f.expire(ctx)
+		}
+	}
+}
+
+func (f *Failsafe) expire(ctx context.Context) {
+	now := f.now()
+	down := f.cfg.Down()
+	var off []string
+	f.mu.Lock()
+	for group, until := range f.held {
+		switch {
+		case !down:
+			delete(f.held, group) // Loxone is back and owns the lights again
+		case now.After(until):
+			delete(f.held, group)
+			off = append(off, group)
+		}
+	}
+	f.mu.Unlock()
+	for _, group := range off {
+		f.log.Info("failsafe: no motion; switching off", "grouped_light", group)
+		f.apply(ctx, group, false)
+	}
+}
+
+func (f *Failsafe) apply(ctx context.Context, group string, on bool) {
+	cmd := udp.Command{Domain: resource.TypeGroupedLight, ID: resource.ID(group), Action: "on", Value: udp.BoolValue(on)}
+	if err := f.cfg.Handler.Apply(udp.WithSource(ctx, "failsafe"), cmd); err != nil {
+		f.log.Error("failsafe command failed", "grouped_light", group, "on", on, "error", err)
+	}
+}
+
+// matches reports whether ref names id, by id or name.
+func matches(inv *Inventory, id, ref string) bool {
+	return id != "" && (id == ref || strings.EqualFold(inv.Alias(id), ref))
+}
+
+// groupedLight resolves a grouped_light id, or a room or zone by id or name, to
+// the grouped_light controlling it.
+func groupedLight(inv *Inventory, ref string) string {
+	if inv.GroupOwner(ref) != "" {
+		return ref
+	}
+	for id, owner := range inv.groups {
+		if matches(inv, owner, ref) {
+			return id
+		}
+	}
+	return ""
+}
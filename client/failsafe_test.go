@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type recordHandler struct {
+	mu   sync.Mutex
+	cmds []udp.Command
+}
+
+func (h *recordHandler) Apply(ctx context.Context, cmd udp.Command) error {
+	h.mu.Lock()
+	h.cmds = append(h.cmds, cmd)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *recordHandler) values() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]string, 0, len(h.cmds))
+	for _, c := range h.cmds {
+		out = append(out, string(c.ID)+" "+c.Value.Raw)
+	}
+	return out
+}
+
+func failsafePoller() *Poller {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.names["room-1"] = Device{Name: "Hall", Alias: "Hall", Type: "room"}
+		inv.names["m1"] = Device{Name: "Hall sensor", Alias: "Hall sensor", Type: "device"}
+		inv.rooms["m1"] = "room-1"
+		inv.rooms["m2"] = "room-1"
+		inv.groups["gl-1"] = "room-1"
+	})
+	return p
+}
+
+func TestFailsafe(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  FailsafeRule
+		id    string
+		up    bool          // Loxone reachable when the motion arrives
+		after time.Duration // time passed before expire
+		back  bool          // Loxone back before expire
+		want  []string
+	}{
+		{name: "on while down", rule: FailsafeRule{Motion: "hall sensor", Group: "hall"}, id: "m1", after: time.Minute, want: []string{"gl-1 true"}},
+		{name: "off after for", rule: FailsafeRule{Motion: "m1", Group: "gl-1", For: time.Minute}, id: "m1", after: 2 * time.Minute, want: []string{"gl-1 true", "gl-1 false"}},
+		{name: "room matches its sensors", rule: FailsafeRule{Motion: "hall", Group: "hall", For: time.Minute}, id: "m2", after: 2 * time.Minute, want: []string{"gl-1 true", "gl-1 false"}},
+		{name: "left alone once loxone is back", rule: FailsafeRule{Motion: "m1", Group: "hall", For: time.Minute}, id: "m1", after: 2 * time.Minute, back: true, want: []string{"gl-1 true"}},
+		{name: "ignored while up", rule: FailsafeRule{Motion: "m1", Group: "hall"}, id: "m1", up: true, after: 10 * time.Minute, want: []string{}},
+		{name: "other sensor", rule: FailsafeRule{Motion: "m1", Group: "hall"}, id: "m3", want: []string{}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := &recordHandler{}
+			down := !tt.up
+			f, err := NewFailsafe(FailsafeConfig{
+				Rules:   []FailsafeRule{tt.rule},
+				Names:   failsafePoller(),
+				Handler: h,
+				Down:    func() bool { return down },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Unix(1700000000, 0)
+			f.now = func() time.Time { return now }
+
+			ctx := context.Background()
+			f.Motion(ctx, tt.id, true)
+			f.Motion(ctx, tt.id, true) // repeated motion does not switch on twice
+			now = now.Add(tt.after)
+			if tt.back {
+				down = false
+			}
+			f.expire(ctx)
+
+			got := h.values()
+			if len(got) != len(tt.want) {
+				t.Fatalf("commands = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("commands = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFailsafeRules(t *testing.T) {
+	if err := ValidateFailsafeRules([]FailsafeRule{{Motion: "m1", Group: "hall"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateFailsafeRules([]FailsafeRule{{Motion: "m1"}}); err == nil {
+		t.Fatal("expected an error for a rule without group")
+	}
+}
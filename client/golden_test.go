@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/events")
+
+// golden is what one captured SSE payload decodes to and what reaches Loxone.
+type golden struct {
+	Decoded   []decoded `json:"decoded"`
+	Forwarded []string  `json:"forwarded"` // "<path> <value>"
+}
+
+type decoded struct {
+	GoType string        `json:"go_type"`
+	Event  EventResource `json:"event"`
+}
+
+type captureSink struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (s *captureSink) Write(msg Message) {
+	s.mu.Lock()
+	s.msgs = append(s.msgs, msg.Path+" "+msg.Value)
+	s.mu.Unlock()
+}
+
+// goldenStreamer returns a streamer writing to a local UDP socket whose inventory
+// knows the scene of testdata/events/scene.json.
+func goldenStreamer(t testing.TB, sink Sink) *EventStreamer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	udpClient, err := udp.NewClient(t.Context(), udp.ClientConfig{Remote: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { udpClient.Close() })
+
+	poller := testPoller()
+	poller.update(func(inv *Inventory) {
+		inv.scenes["0000000e-1111-4222-8333-00000000000e"] = Scene{ID: "0000000e-1111-4222-8333-00000000000e", GroupID: "00000002-1111-4222-8333-000000000002"}
+	})
+	e, err := NewStreamer(t.Context(), StreamerConfig{
+		Bridge:     bridge.NewAddress("127.0.0.1"),
+		Keys:       bridge.NewKeys("key"),
+		UDPClient:  udpClient,
+		Poller:     poller,
+		State:      gateway.NewState(nil),
+		HomeMotion: true,
+		HomeLight:  true,
+	}, WithSink(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(e.ready) // no warmup without Run
+	return e
+}
+
+func TestGoldenEvents(t *testing.T) {
+	payloads, err := filepath.Glob(filepath.Join("testdata", "events", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) == 0 {
+		t.Fatal("no payloads in testdata/events")
+	}
+	for _, payload := range payloads {
+		if strings.HasSuffix(payload, ".golden.json") {
+			continue
+		}
+		payload := payload // capture range var
+		t.Run(strings.TrimSuffix(filepath.Base(payload), ".json"), func(t *testing.T) {
+			raw, err := os.ReadFile(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var containers []EventContainer
+			if err := json.Unmarshal(raw, &containers); err != nil {
+				t.Fatal(err)
+			}
+
+			var got golden
+			for _, c := range containers {
+				for _, data := range c.Data {
+					ev, err := decodeResource(data)
+					if err != nil {
+						t.Fatalf("decode: %v", err)
+					}
+					got.Decoded = append(got.Decoded, decoded{GoType: fmt.Sprintf("%T", ev), Event: ev})
+				}
+			}
+			sink := &captureSink{}
+			if err := goldenStreamer(t, sink).handle(context.Background(), containers); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+			got.Forwarded = append([]string{}, sink.msgs...)
+
+			out, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, '\n')
+			file := strings.TrimSuffix(payload, ".json") + ".golden.json"
+			if *update {
+				if err := os.WriteFile(file, out, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("%v (run go test ./client -run TestGoldenEvents -update)", err)
+			}
+			if string(out) != string(want) {
+				t.Errorf("%s differs from the golden file:\ngot:\n%s\nwant:\n%s", payload, out, want)
+			}
+		})
+	}
+}
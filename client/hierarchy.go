@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Path styles for outgoing messages.
+const (
+	PathStyleIDs          = "ids"          // /sensor/<id>/temperature
+	PathStyleHierarchical = "hierarchical" // /<level>/<room>/<device>/temperature
+)
+
+// Hierarchy is a MessageHook that rewrites resource paths by room and device name,
+// e.g. /sensor/<id>/temperature → /gf/living/hall_sensor/temperature, matching how
+// Loxone organizes rooms so virtual inputs can use wildcards. Group paths become
+// /<level>/<room>/<metric> and scene paths /<level>/<room>/scene. Messages whose
+// room or device is not known keep their id path.
+type Hierarchy struct {
+	names  *Poller
+	levels map[string]string // key: room id or lowercase room name, value: e.g. "gf"
+	rooms  map[string]string // key: room id or lowercase room name, value: Loxone room name
+}
+
+// NewHierarchy creates the hook; levels maps rooms (by id or name) to the path
+// segments placed in front of them, e.g. {"living room": "gf"}. Rooms without a
+// level start at the room name. rooms renames Hue rooms (by id or name) to the
+// Loxone room they belong to, e.g. {"living room": "Woonkamer"}, so paths match
+// the Loxone room names; several Hue rooms may map to one Loxone room.
+func NewHierarchy(names *Poller, levels, rooms map[string]string) *Hierarchy {
+	l := make(map[string]string, len(levels))
+	for room, level := range levels {
+		l[strings.ToLower(room)] = strings.Trim(level, "/")
+	}
+	m := make(map[string]string, len(rooms))
+	for room, name := range rooms {
+		if cleanName(name) != "" {
+			m[strings.ToLower(room)] = name
+		}
+	}
+	return &Hierarchy{names: names, levels: l, rooms: m}
+}
+
+func (h *Hierarchy) Process(ctx context.Context, msg Message) ([]Message, error) {
+	if p := h.path(msg); p != "" {
+		msg.Path = p
+	}
+	return []Message{msg}, nil
+}
+
+func (h *Hierarchy) path(msg Message) string {
+	inv := h.names.Snapshot()
+	id := string(msg.ID)
+	kind, _, _ := strings.Cut(strings.TrimPrefix(msg.Path, "/"), "/")
+	switch kind {
+	case "sensor", "contact":
+		device := cleanName(inv.Alias(id))
+		if device == "" {
+			return ""
+		}
+		return h.join(inv, inv.RoomID(id), device, string(msg.Channel))
+	case "group":
+		group := id
+		if owner := inv.GroupOwner(id); owner != "" {
+			group = owner
+		}
+		return h.join(inv, group, string(msg.Channel))
+	case "scene":
+		s, ok := inv.Scene(id)
+		if !ok {
+			return ""
+		}
+		return h.join(inv, s.GroupID, "scene")
+	}
+	return ""
+}
+
+// join builds /<level>/<room>/<rest...>, or returns "" if the room has no name.
+func (h *Hierarchy) join(inv *Inventory, roomID string, rest ...string) string {
+	name := inv.Alias(roomID)
+	if roomID == "" || cleanName(name) == "" {
+		return ""
+	}
+	var segs []string
+	level, ok := h.levels[roomID]
+	if !ok {
+		level = h.levels[strings.ToLower(name)]
+	}
+	if level != "" {
+		segs = append(segs, level)
+	}
+	if loxone, ok := h.rooms[roomID]; ok {
+		name = loxone
+	} else if loxone, ok := h.rooms[strings.ToLower(name)]; ok {
+		name = loxone
+	}
+	segs = append(segs, cleanName(name))
+	segs = append(segs, rest...)
+	return "/" + strings.Join(segs, "/")
+}
+
+// Collisions lists hierarchical paths that more than one room, zone or device
+// would map to (e.g. two sensors with the same name in one room), using the
+// current inventory.
+func (h *Hierarchy) Collisions() []string {
+	inv := h.names.Snapshot()
+	owners := make(map[string][]string) // key: path prefix
+	for id, d := range inv.names {
+		var p string
+		switch d.Type {
+		case "room", "zone":
+			p = h.join(inv, id)
+		default:
+			if room := inv.RoomID(id); room != "" && cleanName(d.Alias) != "" {
+				p = h.join(inv, room, cleanName(d.Alias))
+			}
+		}
+		if p != "" {
+			owners[p] = append(owners[p], id)
+		}
+	}
+	var out []string
+	for p, ids := range owners {
+		if len(ids) > 1 {
+			sort.Strings(ids)
+			out = append(out, fmt.Sprintf("%s is used by %s", p, strings.Join(ids, ", ")))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestHierarchy(t *testing.T) {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.setName("room-1", "room", "Living Room", nil, "room")
+		inv.setName("room-2", "room", "Attic", nil, "room")
+		inv.setName("dev-1", "Hue motion sensor", "Hall Sensor", nil, "device")
+		inv.setName("dev-2", "Hue motion sensor", "Unassigned", nil, "device")
+		inv.rooms["dev-1"] = "room-1"
+		inv.groups["gl-1"] = "room-1"
+		inv.scenes["scene-1"] = Scene{ID: "scene-1", GroupID: "room-2"}
+	})
+	h := NewHierarchy(p, map[string]string{"Living Room": "gf", "room-2": "/2f/"}, nil)
+
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"sensor", Message{Path: "/sensor/dev-1/temperature", ID: "dev-1", Channel: resource.MetricTemperature}, "/gf/living_room/hall_sensor/temperature"},
+		{"grouped light", Message{Path: "/group/gl-1/brightness", ID: "gl-1", Channel: resource.MetricBrightness}, "/gf/living_room/brightness"},
+		{"group", Message{Path: "/group/room-1/motion", ID: "room-1", Channel: resource.MetricMotion}, "/gf/living_room/motion"},
+		{"scene by room id", Message{Path: "/scene/room-2/on", ID: "scene-1", Channel: resource.MetricOn}, "/2f/attic/scene"},
+		{"device without room", Message{Path: "/sensor/dev-2/motion", ID: "dev-2", Channel: resource.MetricMotion}, "/sensor/dev-2/motion"},
+		{"unknown device", Message{Path: "/sensor/dev-9/motion", ID: "dev-9", Channel: resource.MetricMotion}, "/sensor/dev-9/motion"},
+		{"home", Message{Path: "/home/motion", Channel: resource.MetricMotion}, "/home/motion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := h.Process(context.Background(), tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) != 1 || out[0].Path != tt.want {
+				t.Errorf("Process() = %+v, want path %s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestHierarchy_Collisions(t *testing.T) {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.setName("room-1", "room", "Hall", nil, "room")
+		inv.setName("zone-1", "zone", "hall", nil, "zone")
+		inv.setName("dev-1", "Hue motion sensor", "Sensor", nil, "device")
+		inv.setName("dev-2", "Hue motion sensor", "sensor!", nil, "device")
+		inv.setName("dev-3", "Hue motion sensor", "Other", nil, "device")
+		inv.rooms["dev-1"] = "room-1"
+		inv.rooms["dev-2"] = "room-1"
+		inv.rooms["dev-3"] = "room-1"
+	})
+
+	got := NewHierarchy(p, nil, nil).Collisions()
+	want := []string{"/hall is used by room-1, zone-1", "/hall/sensor is used by dev-1, dev-2"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Collisions() = %q, want %q", got, want)
+	}
+}
+
+func TestHierarchy_LoxoneRooms(t *testing.T) {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.setName("room-1", "room", "Living Room", nil, "room")
+		inv.setName("room-2", "room", "Kitchen", nil, "room")
+		inv.setName("room-3", "room", "Attic", nil, "room")
+		inv.setName("dev-1", "Hue motion sensor", "Hall Sensor", nil, "device")
+		inv.setName("dev-2", "Hue motion sensor", "Hall Sensor", nil, "device")
+		inv.rooms["dev-1"] = "room-1"
+		inv.rooms["dev-2"] = "room-2"
+		inv.groups["gl-3"] = "room-3"
+	})
+	h := NewHierarchy(p, map[string]string{"living room": "gf"}, map[string]string{
+		"Living Room": "Woonkamer",
+		"room-2":      "Woonkamer",
+		"attic":       "!!", // no usable name; keeps the Hue name
+	})
+
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"by name, level by hue name", Message{Path: "/sensor/dev-1/motion", ID: "dev-1", Channel: resource.MetricMotion}, "/gf/woonkamer/hall_sensor/motion"},
+		{"by id", Message{Path: "/sensor/dev-2/motion", ID: "dev-2", Channel: resource.MetricMotion}, "/woonkamer/hall_sensor/motion"},
+		{"unusable name", Message{Path: "/group/gl-3/brightness", ID: "gl-3", Channel: resource.MetricBrightness}, "/attic/brightness"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := h.Process(context.Background(), tt.msg)
+			if len(out) != 1 || out[0].Path != tt.want {
+				t.Errorf("Process() = %+v, want path %s", out, tt.want)
+			}
+		})
+	}
+
+	// two Hue rooms merged into one Loxone room can collide
+	got := NewHierarchy(p, nil, map[string]string{"room-1": "Woonkamer", "room-2": "Woonkamer"}).Collisions()
+	want := []string{"/woonkamer is used by room-1, room-2", "/woonkamer/hall_sensor is used by dev-1, dev-2"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Collisions() = %q, want %q", got, want)
+	}
+}
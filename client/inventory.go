@@ -0,0 +1,160 @@
+package client
+
+import (
+	"slices"
+	"sort"
+)
+
+// Inventory is an immutable snapshot of the bridge resources the poller knows about.
+// Writers build a new Inventory and swap it in, so readers never take a lock and a
+// snapshot stays consistent for as long as it is held.
+type Inventory struct {
+	names     map[string]Device // key: resource id
+	scenes    map[string]Scene
+	rooms     map[string]string   // key: device id, value: room id
+	lights    map[string][]string // key: room, zone or device id, value: light service ids
+	groups    map[string]string   // key: grouped_light id, value: owning room or zone id
+	overrides map[string]string   // key: resource id, value: configured alias; shared, never modified
+}
+
+func newInventory(overrides map[string]string) *Inventory {
+	return &Inventory{
+		names:     make(map[string]Device),
+		scenes:    make(map[string]Scene),
+		rooms:     make(map[string]string),
+		lights:    make(map[string][]string),
+		groups:    make(map[string]string),
+		overrides: overrides,
+	}
+}
+
+// clone returns a copy that can be modified without affecting readers of inv.
+func (inv *Inventory) clone() *Inventory {
+	c := &Inventory{
+		names:     make(map[string]Device, len(inv.names)),
+		scenes:    make(map[string]Scene, len(inv.scenes)),
+		rooms:     make(map[string]string, len(inv.rooms)),
+		lights:    make(map[string][]string, len(inv.lights)),
+		groups:    make(map[string]string, len(inv.groups)),
+		overrides: inv.overrides,
+	}
+	for k, v := range inv.groups {
+		c.groups[k] = v
+	}
+	for k, v := range inv.lights {
+		c.lights[k] = v // slices are replaced, never appended to in place
+	}
+	for k, v := range inv.names {
+		c.names[k] = v
+	}
+	for k, v := range inv.scenes {
+		c.scenes[k] = v
+	}
+	for k, v := range inv.rooms {
+		c.rooms[k] = v
+	}
+	return c
+}
+
+func (inv *Inventory) setName(key, name string, alias string, idv1 *string, t string) {
+	if key == "" || name == "" {
+		return
+	}
+	idv := ""
+	if idv1 != nil {
+		idv = *idv1
+	}
+	// configured names win over bridge metadata, which family members tend to rename
+	if o, ok := inv.overrides[key]; ok {
+		alias = o
+	}
+	inv.names[key] = Device{Name: name, Alias: alias, IDv1: idv, Type: t}
+}
+
+func (inv *Inventory) setArchetype(key, archetype string) {
+	if d, ok := inv.names[key]; ok {
+		d.Archetype = archetype
+		inv.names[key] = d
+	}
+}
+
+// Device returns the device (or room, zone, ...) stored under id.
+func (inv *Inventory) Device(id string) (Device, bool) {
+	d, ok := inv.names[id]
+	return d, ok
+}
+
+// Scene returns the scene with the given id.
+func (inv *Inventory) Scene(id string) (Scene, bool) {
+	s, ok := inv.scenes[id]
+	return s, ok
+}
+
+// RoomID returns the id of the room the device is assigned to, or "".
+func (inv *Inventory) RoomID(deviceID string) string {
+	return inv.rooms[deviceID]
+}
+
+// GroupOwner returns the room or zone id owning a grouped_light, or "".
+func (inv *Inventory) GroupOwner(groupedLightID string) string {
+	return inv.groups[groupedLightID]
+}
+
+// Alias returns the user-given name of id, or "".
+func (inv *Inventory) Alias(id string) string {
+	return inv.names[id].Alias
+}
+
+// Len returns the number of named resources and scenes in the snapshot.
+func (inv *Inventory) Len() (names, scenes int) {
+	return len(inv.names), len(inv.scenes)
+}
+
+// Has reports whether id is a known device, room, zone or scene.
+func (inv *Inventory) Has(id string) bool {
+	if _, ok := inv.names[id]; ok {
+		return true
+	}
+	_, ok := inv.scenes[id]
+	return ok
+}
+
+// Lights returns the light service ids of a room, zone or device.
+func (inv *Inventory) Lights(id string) []string {
+	return append([]string(nil), inv.lights[id]...)
+}
+
+// Overlapping returns the rooms and zones sharing at least one light with group,
+// group included, sorted.
+func (inv *Inventory) Overlapping(group string) []string {
+	members := inv.lights[group]
+	out := []string{group}
+	for id, lights := range inv.lights {
+		if id == group {
+			continue
+		}
+		if t := inv.names[id].Type; t != "room" && t != "zone" {
+			continue
+		}
+		if slices.ContainsFunc(lights, func(l string) bool { return slices.Contains(members, l) }) {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// addMembers records the lights of group (room or zone) given its children, which
+// are devices for rooms and light services (or devices) for zones.
+func (inv *Inventory) addMembers(group string, children []Owner) {
+	var lights []string
+	for _, c := range children {
+		switch c.Type {
+		case "device":
+			lights = append(lights, inv.lights[string(c.ID)]...)
+		case "light":
+			lights = append(lights, string(c.ID))
+		}
+	}
+	inv.lights[group] = lights
+}
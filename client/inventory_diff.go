@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// InventoryChange is one resource added to, removed from or renamed in the
+// inventory.
+type InventoryChange struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"` // room, zone, scene or the cleaned device product, e.g. hue_motion_sensor
+	Name    string `json:"name"`
+	OldName string `json:"old_name,omitempty"` // renamed only
+}
+
+// InventoryDiff is how the inventory changed, e.g. after pairing a device, so
+// Loxone virtual inputs can be updated in time.
+type InventoryDiff struct {
+	Added   []InventoryChange `json:"added,omitempty"`
+	Removed []InventoryChange `json:"removed,omitempty"`
+	Renamed []InventoryChange `json:"renamed,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (d InventoryDiff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Renamed) == 0
+}
+
+// Message renders d for the sinks as /gateway/inventory_changed with the diff
+// as JSON value, like the daily report.
+func (d InventoryDiff) Message() Message {
+	b, _ := json.Marshal(d) // plain strings; cannot fail
+	return Message{Path: "/gateway/inventory_changed", Value: string(b), Channel: "inventory_changed", Time: time.Now()}
+}
+
+// diffInventory compares the devices, rooms, zones and scenes of two snapshots.
+func diffInventory(prev, next *Inventory) InventoryDiff {
+	var d InventoryDiff
+	for id, n := range next.names {
+		p, ok := prev.names[id]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, InventoryChange{ID: id, Kind: n.Type, Name: n.Alias})
+		case p.Alias != n.Alias:
+			d.Renamed = append(d.Renamed, InventoryChange{ID: id, Kind: n.Type, Name: n.Alias, OldName: p.Alias})
+		}
+	}
+	for id, p := range prev.names {
+		if _, ok := next.names[id]; !ok {
+			d.Removed = append(d.Removed, InventoryChange{ID: id, Kind: p.Type, Name: p.Alias})
+		}
+	}
+	for id, n := range next.scenes {
+		p, ok := prev.scenes[id]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, InventoryChange{ID: id, Kind: "scene", Name: n.Name})
+		case p.Name != n.Name:
+			d.Renamed = append(d.Renamed, InventoryChange{ID: id, Kind: "scene", Name: n.Name, OldName: p.Name})
+		}
+	}
+	for id, p := range prev.scenes {
+		if _, ok := next.scenes[id]; !ok {
+			d.Removed = append(d.Removed, InventoryChange{ID: id, Kind: "scene", Name: p.Name})
+		}
+	}
+	for _, cs := range [][]InventoryChange{d.Added, d.Removed, d.Renamed} {
+		sort.Slice(cs, func(i, j int) bool { return cs[i].ID < cs[j].ID })
+	}
+	return d
+}
+
+// OnInventoryChanged registers fn to be called whenever a refresh or a resource
+// fetched on demand changes the inventory. The initial load is not reported.
+func (p *Poller) OnInventoryChanged(fn func(InventoryDiff)) {
+	p.mu.Lock()
+	p.changed = append(p.changed, fn)
+	p.mu.Unlock()
+}
+
+// notifyChanged logs and reports the difference between two snapshots; fns are
+// the OnInventoryChanged callbacks, read under p.mu by the caller.
+func (p *Poller) notifyChanged(fns []func(InventoryDiff), prev, next *Inventory) {
+	if names, scenes := prev.Len(); names+scenes == 0 {
+		return // initial load
+	}
+	d := diffInventory(prev, next)
+	if d.Empty() {
+		return
+	}
+	p.log.Info("inventory changed", "added", len(d.Added), "removed", len(d.Removed), "renamed", len(d.Renamed))
+	for _, c := range d.Added {
+		p.log.Info("inventory: added", "id", c.ID, "kind", c.Kind, "name", c.Name)
+	}
+	for _, c := range d.Removed {
+		p.log.Info("inventory: removed", "id", c.ID, "kind", c.Kind, "name", c.Name)
+	}
+	for _, c := range d.Renamed {
+		p.log.Info("inventory: renamed", "id", c.ID, "kind", c.Kind, "from", c.OldName, "to", c.Name)
+	}
+	for _, fn := range fns {
+		fn(d)
+	}
+}
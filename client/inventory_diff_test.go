@@ -0,0 +1,64 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffInventory(t *testing.T) {
+	prev := newInventory(nil)
+	prev.names["room-1"] = Device{Type: "room", Alias: "Kitchen"}
+	prev.names["light-1"] = Device{Type: "hue_bulb", Alias: "Desk"}
+	prev.scenes["scene-1"] = Scene{ID: "scene-1", Name: "Relax"}
+	prev.scenes["scene-2"] = Scene{ID: "scene-2", Name: "Read"}
+
+	next := prev.clone()
+	next.names["room-1"] = Device{Type: "room", Alias: "Cooking"}
+	delete(next.names, "light-1")
+	next.names["motion-1"] = Device{Type: "hue_motion_sensor", Alias: "Hall"}
+	next.scenes["scene-2"] = Scene{ID: "scene-2", Name: "Reading"}
+	next.scenes["scene-3"] = Scene{ID: "scene-3", Name: "Night"}
+
+	got := diffInventory(prev, next)
+	want := InventoryDiff{
+		Added: []InventoryChange{
+			{ID: "motion-1", Kind: "hue_motion_sensor", Name: "Hall"},
+			{ID: "scene-3", Kind: "scene", Name: "Night"},
+		},
+		Removed: []InventoryChange{{ID: "light-1", Kind: "hue_bulb", Name: "Desk"}},
+		Renamed: []InventoryChange{
+			{ID: "room-1", Kind: "room", Name: "Cooking", OldName: "Kitchen"},
+			{ID: "scene-2", Kind: "scene", Name: "Reading", OldName: "Read"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffInventory() = %+v, want %+v", got, want)
+	}
+	if !diffInventory(next, next.clone()).Empty() {
+		t.Error("diffInventory() of equal snapshots is not empty")
+	}
+}
+
+func TestPoller_OnInventoryChanged(t *testing.T) {
+	p := testPoller()
+	var diffs []InventoryDiff
+	p.OnInventoryChanged(func(d InventoryDiff) { diffs = append(diffs, d) })
+
+	p.insert(kitchen(t)) // initial load
+	if len(diffs) != 0 {
+		t.Fatalf("initial load reported %d diffs", len(diffs))
+	}
+
+	p.insert(kitchen(t)) // unchanged
+	p.update(func(inv *Inventory) { inv.names["room-1"] = Device{Type: "room", Alias: "Cooking"} })
+	if len(diffs) != 1 {
+		t.Fatalf("got %d diffs, want 1", len(diffs))
+	}
+	want := []InventoryChange{{ID: "room-1", Kind: "room", Name: "Cooking", OldName: "Kitchen"}}
+	if !reflect.DeepEqual(diffs[0].Renamed, want) {
+		t.Errorf("Renamed = %+v, want %+v", diffs[0].Renamed, want)
+	}
+	if m := diffs[0].Message(); m.Path != "/gateway/inventory_changed" || m.Value == "" {
+		t.Errorf("Message() = %+v", m)
+	}
+}
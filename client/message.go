@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Message is one value forwarded to Loxone as "<path> <value>",
+// e.g. "/sensor/<id>/temperature 21.50".
+type Message struct {
+	Path     string          `json:"path"`
+	Value    string          `json:"value"`
+	Type     resource.Type   `json:"type,omitempty"`     // hue resource type that produced it (motion, temperature, ...)
+	ID       resource.ID     `json:"id,omitempty"`       // hue owner id
+	Channel  resource.Metric `json:"channel,omitempty"`  // last path segment (motion, temperature, state, ...)
+	Time     time.Time       `json:"time"`               // when the event was received
+	Origin   string          `json:"origin,omitempty"`   // "loxone", "accessory" or "app" when attributed
+	Stale    bool            `json:"stale,omitempty"`    // the bridge created the event long ago (--stale-events mark)
+	Restored bool            `json:"restored,omitempty"` // re-sent from the persisted values at startup (--persist-mark)
+
+	// SinkOnly messages go to the sinks but not to Loxone, see StreamerConfig.Levels.
+	SinkOnly bool `json:"-"`
+
+	// Bool marks Value as a boolean, always "1" or "0"; only the datagram for
+	// Loxone takes the configured encoding, see Encode.
+	Bool bool `json:"-"`
+}
+
+// Encode renders the UDP payload like Bytes, with a boolean value in bools'
+// encoding for the channel.
+func (m Message) Encode(bools *udp.Bools) []byte {
+	if m.Bool && (m.Value == "1" || m.Value == "0") {
+		m.Value = bools.Format(string(m.Channel), m.Value == "1")
+	}
+	return m.Bytes()
+}
+
+// Bytes renders the UDP payload; tags follow the value as " origin=<origin>"
+// " stale=1" and " restored=1", which Loxone command recognitions ignore.
+func (m Message) Bytes() []byte {
+	b := make([]byte, 0, len(m.Path)+len(m.Value)+len(m.Origin)+32)
+	b = append(b, m.Path...)
+	b = append(b, ' ')
+	b = append(b, m.Value...)
+	if m.Origin != "" {
+		b = append(b, " origin="...)
+		b = append(b, m.Origin...)
+	}
+	if m.Stale {
+		b = append(b, " stale=1"...)
+	}
+	if m.Restored {
+		b = append(b, " restored=1"...)
+	}
+	return b
+}
+
+// Sink receives every outgoing message next to the Loxone UDP client. Write must not
+// block; slow sinks queue internally.
+type Sink interface {
+	Write(msg Message)
+}
+
+// MessageHook can transform, suppress or multiply outgoing messages. Returning
+// nil drops the message; returning []Message{msg} forwards it unchanged.
+type MessageHook interface {
+	Process(ctx context.Context, msg Message) ([]Message, error)
+}
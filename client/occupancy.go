@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// OccupancySignal is a kind of activity that hints at a room being occupied.
+type OccupancySignal int
+
+const (
+	SignalMotion  OccupancySignal = iota // motion sensor; held while motion is reported
+	SignalContact                        // door/window contact changed
+	SignalLight                          // room lights switched on
+)
+
+// signalWeight is how much a fresh signal contributes to the room score.
+var signalWeight = map[OccupancySignal]float64{
+	SignalMotion:  1.0,
+	SignalContact: 0.6,
+	SignalLight:   0.5,
+}
+
+// occupiedThreshold is the score at which a room counts as occupied.
+const occupiedThreshold = 0.5
+
+type OccupancyConfig struct {
+	// Sender receives /room/<name>/occupied 0|1 on every change.
+	Sender gateway.Sender
+
+	// Decay is how long a signal keeps contributing after it was last seen. Default 10m.
+	Decay time.Duration
+
+	// Bools (optional) renders the occupied value; default 1/0.
+	Bools *udp.Bools
+}
+
+// Occupancy combines motion, contact and grouped_light activity per room into a
+// decaying score and reports a boolean /room/<name>/occupied to Loxone.
+type Occupancy struct {
+	cfg OccupancyConfig
+
+	mu    sync.Mutex
+	rooms map[string]*roomOccupancy // key: room name
+}
+
+type roomOccupancy struct {
+	last     map[OccupancySignal]time.Time
+	held     map[OccupancySignal]bool
+	occupied bool
+}
+
+func NewOccupancy(cfg OccupancyConfig) *Occupancy {
+	if cfg.Decay <= 0 {
+		cfg.Decay = 10 * time.Minute
+	}
+	return &Occupancy{
+		cfg:   cfg,
+		rooms: make(map[string]*roomOccupancy),
+	}
+}
+
+// Signal records activity in room. active=false releases a held signal (motion cleared,
+// lights off) so it starts decaying.
+func (o *Occupancy) Signal(room string, kind OccupancySignal, active bool) {
+	if o == nil || room == "" {
+		return
+	}
+	now := time.Now()
+
+	o.mu.Lock()
+	r, ok := o.rooms[room]
+	if !ok {
+		r = &roomOccupancy{
+			last: make(map[OccupancySignal]time.Time),
+			held: make(map[OccupancySignal]bool),
+		}
+		o.rooms[room] = r
+	}
+	switch kind {
+	case SignalMotion:
+		r.held[kind] = active
+		r.last[kind] = now
+	default:
+		// contact and light are pulses: only the change itself counts
+		if active {
+			r.last[kind] = now
+		}
+	}
+	o.mu.Unlock()
+
+	o.evaluate(now)
+}
+
+// Run re-evaluates all rooms periodically so decayed rooms flip to unoccupied.
+func (o *Occupancy) Run(ctx context.Context) error {
+	interval := o.cfg.Decay / 20
+	if interval < time.Second {
+		interval = time.Second
+	}
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			o.evaluate(now)
+		}
+	}
+}
+
+func (o *Occupancy) evaluate(now time.Time) {
+	type change struct {
+		room     string
+		occupied bool
+		score    float64
+	}
+	var changes []change
+
+	o.mu.Lock()
+	for name, r := range o.rooms {
+		score := r.score(now, o.cfg.Decay)
+		occupied := score >= occupiedThreshold
+		if occupied != r.occupied {
+			r.occupied = occupied
+			changes = append(changes, change{room: name, occupied: occupied, score: score})
+		}
+	}
+	o.mu.Unlock()
+
+	for _, c := range changes {
+		slog.Debug("room occupancy changed", "room", c.room, "occupied", c.occupied, "score", c.score)
+		if o.cfg.Sender != nil {
+			o.cfg.Sender.Send([]byte(fmt.Sprintf("/room/%s/occupied %s", cleanName(c.room), o.cfg.Bools.Format("occupied", c.occupied))))
+		}
+	}
+}
+
+func (r *roomOccupancy) score(now time.Time, decay time.Duration) float64 {
+	var score float64
+	for kind, last := range r.last {
+		w := signalWeight[kind]
+		if r.held[kind] {
+			score += w
+			continue
+		}
+		remaining := 1 - float64(now.Sub(last))/float64(decay)
+		if remaining > 0 {
+			score += w * remaining
+		}
+	}
+	return score
+}
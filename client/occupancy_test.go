@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+type recordSender struct {
+	msgs []string
+}
+
+func (r *recordSender) Send(b []byte) {
+	r.msgs = append(r.msgs, string(b))
+}
+
+func TestOccupancy_MotionHoldsAndDecays(t *testing.T) {
+	sender := &recordSender{}
+	o := NewOccupancy(OccupancyConfig{Sender: sender, Decay: time.Minute})
+
+	o.Signal("Living Room", SignalMotion, true)
+	o.Signal("Living Room", SignalMotion, false)
+	if len(sender.msgs) != 1 || sender.msgs[0] != "/room/living_room/occupied 1" {
+		t.Fatalf("after motion msgs = %v, want [/room/living_room/occupied 1]", sender.msgs)
+	}
+
+	o.evaluate(time.Now().Add(2 * time.Minute))
+	if len(sender.msgs) != 2 || sender.msgs[1] != "/room/living_room/occupied 0" {
+		t.Fatalf("after decay msgs = %v, want occupied 0 appended", sender.msgs)
+	}
+}
+
+func TestOccupancy_LightAloneIsBrief(t *testing.T) {
+	sender := &recordSender{}
+	o := NewOccupancy(OccupancyConfig{Sender: sender, Decay: time.Minute})
+
+	o.Signal("Hall", SignalLight, true)
+	o.evaluate(time.Now().Add(10 * time.Second))
+	if len(sender.msgs) != 2 {
+		t.Fatalf("msgs = %v, want occupied 1 then 0", sender.msgs)
+	}
+}
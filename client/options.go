@@ -0,0 +1,89 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Options tune NewStreamer, NewDispatcher and the pollers. Each constructor
+// takes its own option type, so an option it would not use does not compile:
+// WithLogger fits all of them, WithSink the streamer and the dispatcher, and
+// WithHTTPClient and WithBackoff only the streamer.
+
+// StreamerOption tunes NewStreamer.
+type StreamerOption interface {
+	apply(*options)
+	streamer()
+}
+
+// DispatcherOption tunes NewDispatcher; every DispatcherOption also tunes
+// NewStreamer.
+type DispatcherOption interface {
+	StreamerOption
+	dispatcher()
+}
+
+// PollerOption tunes NewPoller and NewOfflinePoller.
+type PollerOption interface {
+	apply(*options)
+	poller()
+}
+
+// Option tunes every constructor of this package.
+type Option interface {
+	DispatcherOption
+	PollerOption
+}
+
+type option func(*options)
+
+func (f option) apply(o *options) { f(o) }
+func (option) streamer()          {}
+func (option) dispatcher()        {}
+func (option) poller()            {}
+
+type options struct {
+	logger     *slog.Logger
+	httpClient *http.Client
+	backoffMax time.Duration
+	sinks      []Sink
+}
+
+func buildOptions[O interface{ apply(*options) }](opts []O) options {
+	o := options{logger: slog.Default(), backoffMax: defaultBackoffMax}
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+	return o
+}
+
+// WithLogger sets the logger of the streamer, dispatcher or poller. Default
+// slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return option(func(o *options) {
+		if l != nil {
+			o.logger = l
+		}
+	})
+}
+
+// WithHTTPClient replaces the client of the event stream, e.g. to go through a
+// proxy; the bridge application key is still added.
+func WithHTTPClient(c *http.Client) StreamerOption {
+	return option(func(o *options) { o.httpClient = c })
+}
+
+// WithBackoff caps the delay between reconnect attempts. Default 30s.
+func WithBackoff(max time.Duration) StreamerOption {
+	return option(func(o *options) {
+		if max > 0 {
+			o.backoffMax = max
+		}
+	})
+}
+
+// WithSink adds a sink that receives every outgoing message next to Loxone.
+func WithSink(s Sink) DispatcherOption {
+	return option(func(o *options) { o.sinks = append(o.sinks, s) })
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type OverrideConfig struct {
+	// Sender receives /room/<name>/override 0|1 on every change.
+	Sender gateway.Sender
+
+	// Timeout is how long a manual change keeps the room overridden. Default 1h.
+	Timeout time.Duration
+
+	// Timeouts (optional) overrides Timeout per room name (case-insensitive).
+	Timeouts map[string]time.Duration
+
+	// Bools (optional) renders the override value; default 1/0.
+	Bools *udp.Bools
+}
+
+// Overrides tracks rooms whose lights were changed by hand (Hue app or
+// accessory) and reports /room/<name>/override 1 until the timeout passes or
+// Loxone reasserts control with a command of its own, so Loxone logic can hold
+// back instead of undoing the change.
+type Overrides struct {
+	cfg OverrideConfig
+	now func() time.Time
+
+	mu    sync.Mutex
+	rooms map[string]time.Time // key: room name, value: override expiry
+}
+
+func NewOverrides(cfg OverrideConfig) *Overrides {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Hour
+	}
+	timeouts := make(map[string]time.Duration, len(cfg.Timeouts))
+	for room, d := range cfg.Timeouts {
+		timeouts[strings.ToLower(room)] = d
+	}
+	cfg.Timeouts = timeouts
+	return &Overrides{cfg: cfg, now: time.Now, rooms: make(map[string]time.Time)}
+}
+
+// ParseOverrideTimeouts converts {"kitchen": "2h"} to durations.
+func ParseOverrideTimeouts(raw map[string]string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(raw))
+	for room, v := range raw {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid override_timeouts %s=%q: expected a positive duration", room, v)
+		}
+		out[room] = d
+	}
+	return out, nil
+}
+
+// Observe records a light change of the given origin in room: manual changes
+// start (or extend) the override, Loxone commands end it.
+func (o *Overrides) Observe(room, origin string) {
+	if o == nil || room == "" {
+		return
+	}
+	now := o.now()
+	o.mu.Lock()
+	_, active := o.rooms[room]
+	switch origin {
+	case OriginApp, OriginAccessory:
+		timeout, ok := o.cfg.Timeouts[strings.ToLower(room)]
+		if !ok {
+			timeout = o.cfg.Timeout
+		}
+		o.rooms[room] = now.Add(timeout)
+	case OriginLoxone:
+		delete(o.rooms, room)
+	}
+	_, overridden := o.rooms[room]
+	o.mu.Unlock()
+
+	if overridden != active {
+		o.report(room, overridden)
+	}
+}
+
+// Run clears expired overrides until ctx is done.
+func (o *Overrides) Run(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			o.expire()
+		}
+	}
+}
+
+func (o *Overrides) expire() {
+	now := o.now()
+	var expired []string
+	o.mu.Lock()
+	for room, until := range o.rooms {
+		if !now.Before(until) {
+			delete(o.rooms, room)
+			expired = append(expired, room)
+		}
+	}
+	o.mu.Unlock()
+	for _, room := range expired {
+		o.report(room, false)
+	}
+}
+
+func (o *Overrides) report(room string, overridden bool) {
+	slog.Debug("room override changed", "room", room, "override", overridden)
+	if o.cfg.Sender != nil {
+		o.cfg.Sender.Send([]byte(fmt.Sprintf("/room/%s/override %s", cleanName(room), o.cfg.Bools.Format("override", overridden))))
+	}
+}
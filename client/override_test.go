@@ -0,0 +1,33 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOverrides(t *testing.T) {
+	now := time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)
+	sender := &recordSender{}
+	o := NewOverrides(OverrideConfig{Sender: sender, Timeout: time.Hour, Timeouts: map[string]time.Duration{"Kitchen": 10 * time.Minute}})
+	o.now = func() time.Time { return now }
+
+	o.Observe("Living Room", OriginApp)
+	o.Observe("Living Room", OriginAccessory) // extends, no repeat
+	o.Observe("Living Room", OriginLoxone)    // Loxone reasserts control
+	o.Observe("Kitchen", OriginAccessory)
+	o.Observe("Hall", OriginLoxone) // not overridden, nothing to clear
+
+	now = now.Add(11 * time.Minute)
+	o.expire()
+
+	want := []string{
+		"/room/living_room/override 1",
+		"/room/living_room/override 0",
+		"/room/kitchen/override 1",
+		"/room/kitchen/override 0",
+	}
+	if !reflect.DeepEqual(sender.msgs, want) {
+		t.Errorf("msgs = %q, want %q", sender.msgs, want)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// PathGuard notices when messages from two different resources leave on the same
+// path (alias collision, script or template mistake), which would silently mix
+// their values in one Loxone input.
+type PathGuard struct {
+	mu       sync.Mutex
+	sources  map[string]string // key: outgoing path, value: "<type>/<id>"
+	reported map[string]bool
+}
+
+func NewPathGuard() *PathGuard {
+	return &PathGuard{
+		sources:  make(map[string]string),
+		reported: make(map[string]bool),
+	}
+}
+
+// Check records the source of msg and returns an error the first time its path
+// is seen from a different source. Scenes share their group's path by design and
+// messages without an id are not tracked.
+func (g *PathGuard) Check(msg Message) error {
+	if g == nil || msg.ID == "" || msg.Type == resource.TypeScene {
+		return nil
+	}
+	src := string(msg.Type) + "/" + string(msg.ID)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	prev, ok := g.sources[msg.Path]
+	if !ok {
+		g.sources[msg.Path] = src
+		return nil
+	}
+	if prev == src || g.reported[msg.Path] {
+		return nil
+	}
+	g.reported[msg.Path] = true
+	return fmt.Errorf("duplicate path %s: sent by %s and %s", msg.Path, prev, src)
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestPathGuard(t *testing.T) {
+	g := NewPathGuard()
+	a := Message{Path: "/gf/hall/sensor/motion", Type: resource.TypeMotion, ID: "dev-1"}
+	b := Message{Path: "/gf/hall/sensor/motion", Type: resource.TypeMotion, ID: "dev-2"}
+
+	if err := g.Check(a); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	if err := g.Check(a); err != nil {
+		t.Fatalf("same source again: %v", err)
+	}
+	if err := g.Check(b); err == nil {
+		t.Fatal("second source on the same path was not reported")
+	}
+	if err := g.Check(b); err != nil {
+		t.Errorf("collision reported twice: %v", err)
+	}
+
+	scene := Message{Path: "/scene/room-1/on", Type: resource.TypeScene}
+	for _, id := range []resource.ID{"scene-1", "scene-2"} {
+		scene.ID = id
+		if err := g.Check(scene); err != nil {
+			t.Errorf("scenes of one group: %v", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledPayload caps the buffers kept for reuse so one oversized event does
+// not pin its memory for the lifetime of the process.
+const maxPooledPayload = 1 << 20
+
+var payloads = sync.Pool{New: func() any { return new(payload) }}
+
+// payload is one SSE event: the joined data lines and the containers decoded
+// from them. Decoding into a recycled payload reuses the backing arrays of the
+// previous event's json.RawMessage values, so anything kept past the next event
+// must be copied (see cloneContainers).
+type payload struct {
+	id         string // SSE event id, e.g. "1700000000:0"
+	buf        []byte
+	containers []EventContainer
+}
+
+func getPayload() *payload {
+	return payloads.Get().(*payload)
+}
+
+func putPayload(p *payload) {
+	if cap(p.buf) > maxPooledPayload {
+		return
+	}
+	p.reset()
+	payloads.Put(p)
+}
+
+func (p *payload) reset() {
+	p.id = ""
+	p.buf = p.buf[:0]
+}
+
+// appendLine adds the payload of one "data:" line; SSE joins multi-line data with \n.
+func (p *payload) appendLine(data []byte) {
+	if len(p.buf) > 0 {
+		p.buf = append(p.buf, '\n')
+	}
+	p.buf = append(p.buf, data...)
+}
+
+// decode unmarshals the JSON array in buf into containers. Every container
+// the slice ever held is cleared first, keeping only its Data backing array:
+// encoding/json does not zero reused slice elements, so fields missing from
+// this event would otherwise keep the previous event's values.
+func (p *payload) decode() error {
+	all := p.containers[:cap(p.containers)]
+	for i := range all {
+		all[i] = EventContainer{Data: all[i].Data[:0]}
+	}
+	p.containers = p.containers[:0]
+	if err := json.Unmarshal(p.buf, &p.containers); err != nil {
+		p.containers = p.containers[:0]
+		return err
+	}
+	return nil
+}
+
+// cloneContainers deep-copies containers decoded into a pooled payload.
+func cloneContainers(cs []EventContainer) []EventContainer {
+	out := make([]EventContainer, len(cs))
+	for i, c := range cs {
+		out[i] = c
+		out[i].Data = make([]json.RawMessage, len(c.Data))
+		for j, raw := range c.Data {
+			out[i].Data[j] = append(json.RawMessage(nil), raw...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPayload_DecodeReusesWithoutLeaking(t *testing.T) {
+	events := []string{
+		`[{"id":"a","type":"update","owner":{"rid":"x"},"data":[{"id":"1","type":"light","long":"aaaaaaaaaaaaaaaa"},{"id":"2","type":"light"}]},{"id":"b","type":"add","data":[]}]`,
+		`[{"id":"c","type":"update","data":[{"id":"3","type":"motion"}]}]`,
+	}
+	p := &payload{}
+	for _, ev := range events {
+		p.reset()
+		p.appendLine([]byte(ev))
+		if err := p.decode(); err != nil {
+			t.Fatalf("decode(%s): %v", ev, err)
+		}
+		var want []EventContainer
+		if err := json.Unmarshal([]byte(ev), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.containers, want) {
+			t.Errorf("decode(%s) = %+v, want %+v", ev, p.containers, want)
+		}
+	}
+}
+
+func TestPayload_JoinsDataLines(t *testing.T) {
+	p := &payload{}
+	p.appendLine([]byte(`[{"id":"a",`))
+	p.appendLine([]byte(`"data":[{"id":"1"}]}]`))
+	if err := p.decode(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.containers) != 1 || string(p.containers[0].Data[0]) != `{"id":"1"}` {
+		t.Errorf("containers = %+v", p.containers)
+	}
+}
+
+func TestPayload_DecodeRejectsNonArray(t *testing.T) {
+	for _, ev := range []string{`{"id":"a"}`, `[{"id":`, ``} {
+		p := &payload{}
+		p.appendLine([]byte(ev))
+		if err := p.decode(); err == nil {
+			t.Errorf("decode(%q) succeeded", ev)
+		}
+	}
+}
+
+func TestCloneContainers_SurvivesReuse(t *testing.T) {
+	p := &payload{}
+	p.appendLine([]byte(`[{"id":"a","data":[{"id":"1"}]}]`))
+	if err := p.decode(); err != nil {
+		t.Fatal(err)
+	}
+	kept := cloneContainers(p.containers)
+
+	p.reset()
+	p.appendLine([]byte(`[{"id":"b","data":[{"id":"2"}]}]`))
+	if err := p.decode(); err != nil {
+		t.Fatal(err)
+	}
+	if kept[0].ID != "a" || string(kept[0].Data[0]) != `{"id":"1"}` {
+		t.Errorf("clone changed after reuse: %+v", kept)
+	}
+}
@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// DefaultPersistChannels are the state-like channels restored by a Persist
+// unless configured otherwise. Motion, contact and tamper are left out so a
+// restore cannot trigger lighting or alarm logic.
+var DefaultPersistChannels = []resource.Metric{
+	resource.MetricOn, resource.MetricDimmable, resource.MetricBrightness,
+	resource.MetricTemperature, resource.MetricLightLevel, resource.MetricHumidity,
+	resource.MetricBattery, resource.MetricPower, resource.MetricEnergy,
+}
+
+type PersistConfig struct {
+	// File keeps the last value of every persisted path across restarts.
+	File string
+
+	// Sender receives the restored values.
+	Sender gateway.Sender
+
+	// Channels opts channels into persistence. Nil means DefaultPersistChannels.
+	Channels []resource.Metric
+
+	// Quiet is how long after startup the values are restored; paths that got a
+	// live value meanwhile are skipped. Default 10s.
+	Quiet time.Duration
+
+	// Mark tags restored values with " restored=1".
+	Mark bool
+
+	// Bools (optional) renders restored boolean values; default 1/0.
+	Bools *udp.Bools
+
+	// MaxAge skips persisted values older than this. Default 24h.
+	MaxAge time.Duration
+
+	// Interval between writes of File. Default 30s.
+	Interval time.Duration
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Persist remembers the last value of every channel and re-sends them after a
+// restart, so Loxone virtual inputs do not sit at their power-on defaults until
+// the next change. It is a Sink.
+type Persist struct {
+	cfg      PersistConfig
+	log      *slog.Logger
+	channels map[resource.Metric]bool
+	now      func() time.Time
+
+	mu    sync.Mutex
+	last  map[string]persistedValue // key: outgoing path
+	live  map[string]bool           // paths sent since startup
+	dirty bool
+}
+
+type persistedValue struct {
+	Value   string          `json:"value"`
+	Time    time.Time       `json:"time"`
+	Channel resource.Metric `json:"channel,omitempty"`
+	Bool    bool            `json:"bool,omitempty"` // Value is "1" or "0", see Message.Bool
+}
+
+// NewPersist loads cfg.File if it exists.
+func NewPersist(cfg PersistConfig) (*Persist, error) {
+	if cfg.File == "" {
+		return nil, errors.New("persist: File required")
+	}
+	if cfg.Channels == nil {
+		cfg.Channels = DefaultPersistChannels
+	}
+	if cfg.Quiet <= 0 {
+		cfg.Quiet = 10 * time.Second
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 24 * time.Hour
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	channels := make(map[resource.Metric]bool, len(cfg.Channels))
+	for _, c := range cfg.Channels {
+		channels[c] = true
+	}
+	p := &Persist{
+		cfg:      cfg,
+		log:      cfg.Logger.With("module", "persist", "file", cfg.File),
+		channels: channels,
+		now:      time.Now,
+		last:     make(map[string]persistedValue),
+		live:     make(map[string]bool),
+	}
+	b, err := os.ReadFile(cfg.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &p.last); err != nil {
+		// a damaged file must not keep the gateway from starting
+		p.log.Warn("persisted values unreadable; starting empty", "error", err)
+		p.last = make(map[string]persistedValue)
+	}
+	return p, nil
+}
+
+// Write remembers msg if its channel is persisted and it went to Loxone.
+func (p *Persist) Write(msg Message) {
+	if msg.SinkOnly || !p.channels[msg.Channel] {
+		return
+	}
+	t := msg.Time
+	if t.IsZero() {
+		t = p.now()
+	}
+	p.mu.Lock()
+	p.last[msg.Path] = persistedValue{Value: msg.Value, Time: t, Channel: msg.Channel, Bool: msg.Bool}
+	p.live[msg.Path] = true
+	p.dirty = true
+	p.mu.Unlock()
+}
+
+// Run restores the persisted values after the quiet period and saves the
+// current ones periodically and when ctx is done.
+func (p *Persist) Run(ctx context.Context) error {
+	restore := time.NewTimer(p.cfg.Quiet)
+	defer restore.Stop()
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := p.save(); err != nil {
+				p.log.Error("saving persisted values failed", "error", err)
+			}
+			return ctx.Err()
+		case <-restore.C:
+			p.restore()
+		case <-ticker.C:
+			if err := p.save(); err != nil {
+				p.log.Warn("saving persisted values failed", "error", err)
+			}
+		}
+	}
+}
+
+// restore sends every persisted value that has not been superseded by a live
+// one since startup.
+func (p *Persist) restore() {
+	cutoff := p.now().Add(-p.cfg.MaxAge)
+	p.mu.Lock()
+	var msgs []Message
+	for path, v := range p.last {
+		if p.live[path] || v.Time.Before(cutoff) {
+			continue
+		}
+		msgs = append(msgs, Message{Path: path, Value: v.Value, Channel: v.Channel, Bool: v.Bool, Time: v.Time, Restored: p.cfg.Mark})
+	}
+	p.mu.Unlock()
+
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Path < msgs[j].Path })
+	p.log.Info("restoring persisted values", "values", len(msgs))
+	if p.cfg.Sender == nil {
+		return
+	}
+	for _, m := range msgs {
+		p.cfg.Sender.Send(m.Encode(p.cfg.Bools))
+	}
+}
+
+// save writes the values to File if they changed, via a temporary file so a
+// crash never leaves it half written.
+func (p *Persist) save() error {
+	p.mu.Lock()
+	if !p.dirty {
+		p.mu.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(p.last)
+	p.dirty = false
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(p.cfg.File, b); err != nil {
+		p.mu.Lock()
+		p.dirty = true // retry with the next save
+		p.mu.Unlock()
+		return fmt.Errorf("persist: %w", err)
+	}
+	return nil
+}
+
+func writeFileAtomic(file string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
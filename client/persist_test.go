@@ -0,0 +1,89 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func TestPersist_RestoreAfterRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "values.json")
+	now := time.Now()
+
+	first, err := NewPersist(PersistConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Write(Message{Path: "/light/l1/on", Channel: resource.MetricOn, Value: "1", Time: now})
+	first.Write(Message{Path: "/sensor/s1/temperature", Channel: resource.MetricTemperature, Value: "21.50", Time: now})
+	first.Write(Message{Path: "/sensor/s2/temperature", Channel: resource.MetricTemperature, Value: "19.00", Time: now.Add(-48 * time.Hour)})
+	first.Write(Message{Path: "/sensor/s1/motion", Channel: resource.MetricMotion, Value: "1", Time: now})
+	first.Write(Message{Path: "/sensor/s1/battery", Channel: resource.MetricBattery, Value: "80", Time: now, SinkOnly: true})
+	if err := first.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &recordSender{}
+	second, err := NewPersist(PersistConfig{File: file, Sender: sender, Mark: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Write(Message{Path: "/light/l1/on", Channel: resource.MetricOn, Value: "0", Time: now}) // live before the quiet period ended
+	second.restore()
+
+	want := []string{"/sensor/s1/temperature 21.50 restored=1"}
+	if !slices.Equal(sender.msgs, want) {
+		t.Fatalf("restored = %v, want %v", sender.msgs, want)
+	}
+}
+
+func TestPersist_RestoreBoolEncoding(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "values.json")
+	first, err := NewPersist(PersistConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Write(Message{Path: "/light/l1/on", Channel: resource.MetricOn, Value: "1", Bool: true, Time: time.Now()})
+	if err := first.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	bools, err := udp.NewBools(udp.BoolWords, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &recordSender{}
+	second, err := NewPersist(PersistConfig{File: file, Sender: sender, Bools: bools})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.restore()
+
+	want := []string{"/light/l1/on true"}
+	if !slices.Equal(sender.msgs, want) {
+		t.Fatalf("restored = %v, want %v", sender.msgs, want)
+	}
+}
+
+func TestPersist_DamagedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "values.json")
+	if err := os.WriteFile(file, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPersist(PersistConfig{File: file})
+	if err != nil {
+		t.Fatalf("NewPersist() error = %v, want a fresh start", err)
+	}
+	p.Write(Message{Path: "/light/l1/on", Channel: resource.MetricOn, Value: "1"})
+	if err := p.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPersist(PersistConfig{File: file}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// PollJob fetches one resource periodically, for values the bridge sends no
+// events for (e.g. some third-party device attributes), e.g.
+// {"poller": {"resources": [{"type": "device_power", "id": "<id>", "interval": "1m", "max_interval": "15m"}]}}.
+type PollJob struct {
+	Type        string        `mapstructure:"type"`
+	ID          string        `mapstructure:"id"`
+	Interval    time.Duration `mapstructure:"interval"`
+	MaxInterval time.Duration `mapstructure:"max_interval"` // back off up to this while unchanged; 0 keeps Interval
+	Timeout     time.Duration `mapstructure:"timeout"`      // deadline of each fetch; default 30s
+}
+
+// next returns the delay after a fetch: Interval after a change, doubled up to
+// MaxInterval while the resource stays the same.
+func (j PollJob) next(cur time.Duration, changed bool) time.Duration {
+	if changed || j.MaxInterval <= j.Interval || cur < j.Interval {
+		return j.Interval
+	}
+	return min(cur*2, j.MaxInterval)
+}
+
+// OnPolled registers fn to be called with every polled resource that changed
+// since its previous fetch.
+func (p *Poller) OnPolled(fn func(ctx context.Context, raw json.RawMessage)) {
+	p.mu.Lock()
+	p.polled = append(p.polled, fn)
+	p.mu.Unlock()
+}
+
+// poll runs job until ctx is done.
+func (p *Poller) poll(ctx context.Context, wg *sync.WaitGroup, job PollJob) {
+	if p.home == nil || job.Interval <= 0 {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var last json.RawMessage
+		delay := job.Interval
+		for {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			runCtx, cancel := context.WithTimeout(ctx, Job{Timeout: job.Timeout}.timeout())
+			r, err := p.home.GetResource(runCtx, job.Type, job.ID)
+			cancel()
+			if err != nil || r == nil {
+				if ctx.Err() == nil {
+					p.log.Warn("poll failed", "type", job.Type, "id", job.ID, "error", err)
+				}
+				delay = job.Interval
+				continue
+			}
+			changed := !bytes.Equal(r.Raw, last)
+			delay = job.next(delay, changed)
+			if !changed {
+				continue
+			}
+			last = r.Raw
+			p.mu.Lock()
+			fns := p.polled
+			p.mu.Unlock()
+			for _, fn := range fns {
+				fn(ctx, r.Raw)
+			}
+		}
+	}()
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPollJobNext(t *testing.T) {
+	adaptive := PollJob{Interval: time.Minute, MaxInterval: 5 * time.Minute}
+	tests := []struct {
+		name    string
+		job     PollJob
+		cur     time.Duration
+		changed bool
+		want    time.Duration
+	}{
+		{name: "fixed", job: PollJob{Interval: time.Minute}, cur: time.Minute, want: time.Minute},
+		{name: "unchanged backs off", job: adaptive, cur: time.Minute, want: 2 * time.Minute},
+		{name: "capped", job: adaptive, cur: 4 * time.Minute, want: 5 * time.Minute},
+		{name: "change resets", job: adaptive, cur: 5 * time.Minute, changed: true, want: time.Minute},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.job.next(tt.cur, tt.changed); got != tt.want {
+				t.Errorf("next(%s, %v) = %s, want %s", tt.cur, tt.changed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamerPolled(t *testing.T) {
+	sink := &captureSink{}
+	e := goldenStreamer(t, sink)
+	raw := json.RawMessage(`{
+		"id": "00000017-1111-4222-8333-000000000017",
+		"owner": {"rid": "00000001-1111-4222-8333-000000000001", "rtype": "device"},
+		"temperature": {"temperature_valid": true, "temperature_report": {"changed": "2025-03-01T10:00:00.000Z", "temperature": 19.5}},
+		"type": "temperature"
+	}`)
+	e.polled(t.Context(), raw)
+
+	want := "/sensor/00000001-1111-4222-8333-000000000001/temperature 19.50"
+	if len(sink.msgs) != 1 || sink.msgs[0] != want {
+		t.Fatalf("messages = %v, want [%s]", sink.msgs, want)
+	}
+}
@@ -2,32 +2,48 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	openhue "github.com/openhue/openhue-go"
 	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/resource"
 )
 
+// Poller owns the bridge inventory and is shared by the streamer, the command
+// adapter and the admin surfaces. Lookups read an atomically published snapshot;
+// refreshes and on-demand inserts copy it, so the hot path never waits on a write.
 type Poller struct {
-	home    *bridge.Home
-	homeIP  string
-	homeKey string
-	// name index like the Python 'names' map; we try v1 id if available, else fallback.
-	mu     sync.RWMutex
-	names  map[string]Device // key: id_v1 ("/lights/1") OR "<rtype>/<uuid>"
-	scenes map[string]Scene
+	log  *slog.Logger
+	home *bridge.Home
+	inv  atomic.Pointer[Inventory]
 
-	lastRefresh     time.Time
-	refreshInterval time.Duration
+	mu     sync.Mutex           // serializes writers and guards misses, queued and the callbacks
+	misses map[string]time.Time // ids the bridge did not know, to avoid refetching
+
+	pending  chan Owner      // ids missing from the inventory, resolved by Run
+	queued   map[string]bool // ids in pending
+	resolved []func(id string)
+	polled   []func(ctx context.Context, raw json.RawMessage)
+	changed  []func(InventoryDiff)
+
+	ready     chan struct{} // closed once the first refresh finished
+	readyOnce sync.Once
+
+	schedule Schedule
 }
 
 type Device struct {
-	Name  string
-	Type  string
-	Alias string
-	IDv1  string
+	Name      string
+	Type      string
+	Alias     string
+	IDv1      string
+	Archetype string // devices only, e.g. "plug"
 }
 
 type Scene struct {
@@ -45,61 +61,155 @@ func (d *Device) toString() string {
 	return fmt.Sprintf("%s %s - %s ", d.IDv1, d.Name, d.Alias)
 }
 
-func NewPoller(ctx context.Context, bridgeIP string, hueAPIKey string) *Poller {
+// NewOfflinePoller creates a poller without a bridge whose inventory stays
+// empty, for running the message pipeline offline (see SelfTest).
+func NewOfflinePoller(opts ...PollerOption) *Poller {
+	return newPoller(nil, buildOptions(opts))
+}
 
-	return &Poller{
-		homeIP:          bridgeIP,
-		homeKey:         hueAPIKey,
-		names:           make(map[string]Device),
-		scenes:          make(map[string]Scene),
-		refreshInterval: time.Hour,
+// NewPoller creates a poller for home; Run loads the inventory.
+func NewPoller(ctx context.Context, home *bridge.Home, opts ...PollerOption) (*Poller, error) {
+	if home == nil {
+		return nil, errors.New("poller: bridge home required")
 	}
+	return newPoller(home, buildOptions(opts)), nil
 }
 
-func (p *Poller) Run(ctx context.Context) error {
-	home, err := bridge.NewHome(p.homeIP, p.homeKey)
+func newPoller(home *bridge.Home, o options) *Poller {
+	p := &Poller{
+		log:      o.logger,
+		home:     home,
+		misses:   make(map[string]time.Time),
+		pending:  make(chan Owner, pendingSize),
+		queued:   make(map[string]bool),
+		ready:    make(chan struct{}),
+		schedule: DefaultSchedule(),
+	}
+	p.inv.Store(newInventory(nil))
+	return p
+}
 
-	if err != nil {
-		return err
+// Snapshot returns the current inventory. It is never nil and never modified, so
+// callers can hold on to it for a consistent view across several lookups.
+func (p *Poller) Snapshot() *Inventory {
+	return p.inv.Load()
+}
+
+// SetNameOverrides sets configured aliases (resource id → name) for devices, rooms
+// and zones. They take precedence over the names stored on the bridge.
+func (p *Poller) SetNameOverrides(overrides map[string]string) {
+	o := make(map[string]string, len(overrides))
+	for id, name := range overrides {
+		o[id] = name
 	}
+	p.update(func(inv *Inventory) {
+		inv.overrides = o
+		for id, name := range o {
+			if d, ok := inv.names[id]; ok {
+				d.Alias = name
+				inv.names[id] = d
+			}
+		}
+	})
+}
 
-	p.home = home
+// update applies fn to a copy of the inventory and publishes the result.
+func (p *Poller) update(fn func(inv *Inventory)) {
+	p.mu.Lock()
+	prev := p.inv.Load()
+	next := prev.clone()
+	fn(next)
+	p.inv.Store(next)
+	changed := p.changed
+	p.mu.Unlock()
+	p.notifyChanged(changed, prev, next)
+}
 
-	slog.Debug(fmt.Sprintf("poller started at %s", time.Now()))
+// Run loads the inventory and then runs the scheduled jobs until ctx is done.
+func (p *Poller) Run(ctx context.Context) error {
+	p.log.Debug(fmt.Sprintf("poller started at %s", time.Now()))
+	s := p.schedule
 
-	if time.Since(p.lastRefresh) >= p.refreshInterval {
-		if err := p.refreshNames(ctx); err != nil {
-			slog.Warn("refresh names", "err", err)
-		} else {
-			slog.Info("names refreshed")
-		}
-		p.lastRefresh = time.Now()
+	refreshCtx, cancel := context.WithTimeout(ctx, s.Names.timeout())
+	if err := p.Refresh(refreshCtx); err != nil {
+		p.log.Warn("refresh names", "err", err)
+	}
+	cancel()
+	p.readyOnce.Do(func() { close(p.ready) })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.resolvePending(ctx)
+	}()
+	every(ctx, &wg, "names", s.Names, p.Refresh)
+	every(ctx, &wg, "resync", s.Resync, s.OnResync)
+	every(ctx, &wg, "health", s.Health, s.OnHealth)
+	for _, job := range s.Resources {
+		p.poll(ctx, &wg, job)
 	}
+	wg.Wait()
+	return nil
+}
 
+// Refresh reloads the full inventory from the bridge right away.
+func (p *Poller) Refresh(ctx context.Context) error {
+	if err := p.refreshNames(ctx); err != nil {
+		return err
+	}
+	p.log.Info("names refreshed")
 	return nil
 }
 
+// WaitReady blocks until the initial inventory has been loaded (or failed to load)
+// or ctx is done.
+func (p *Poller) WaitReady(ctx context.Context) error {
+	select {
+	case <-p.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refreshNames builds a complete new inventory and publishes it only once every
+// resource type loaded, so a failed refresh keeps the previous snapshot.
 func (p *Poller) refreshNames(ctx context.Context) error {
-	devices, err := p.home.GetDevices()
+	inv := newInventory(p.Snapshot().overrides)
+
+	devices, err := p.home.GetDevices(ctx)
 	if err != nil {
 		return err
 	}
 	for _, device := range devices {
-		slog.Info("device", "id", *device.Id, "productName", *device.ProductData.ProductName, "alias", *device.Metadata.Name)
-		p.setName(*device.Id, *device.ProductData.ProductName, *device.Metadata.Name, device.IdV1, cleanName(*device.ProductData.ProductName))
+		p.log.Info("device", "id", *device.Id, "productName", *device.ProductData.ProductName, "alias", *device.Metadata.Name)
+		inv.setName(*device.Id, *device.ProductData.ProductName, *device.Metadata.Name, device.IdV1, cleanName(*device.ProductData.ProductName))
+		if device.Metadata.Archetype != nil {
+			inv.setArchetype(*device.Id, string(*device.Metadata.Archetype))
+		}
+		inv.addMembers(*device.Id, refs(device.Services))
 	}
 
-	rooms, err := p.home.GetRooms()
+	rooms, err := p.home.GetRooms(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, r := range rooms {
-		slog.Info("room", "id", *r.Id, "name", *r.Metadata.Name)
-		p.setName(*r.Id, "room", *r.Metadata.Name, r.IdV1, "room")
+		p.log.Info("room", "id", *r.Id, "name", *r.Metadata.Name)
+		inv.setName(*r.Id, "room", *r.Metadata.Name, r.IdV1, "room")
+		if r.Children != nil {
+			for _, child := range *r.Children {
+				if child.Rid != nil {
+					inv.rooms[*child.Rid] = *r.Id
+				}
+			}
+		}
+		inv.addMembers(*r.Id, refs(r.Children))
 	}
 
-	scenes, err := p.home.GetScenes()
+	scenes, err := p.home.GetScenes(ctx)
 	if err != nil {
 		return err
 	}
@@ -108,8 +218,8 @@ func (p *Poller) refreshNames(ctx context.Context) error {
 		gName := ""
 		switch *r.Group.Rtype {
 		case "room":
-			gName = p.GetAlias(*r.Group.Rid)
-			p.scenes[*r.Id] = Scene{
+			gName = inv.Alias(*r.Group.Rid)
+			inv.scenes[*r.Id] = Scene{
 				Name:    *r.Metadata.Name,
 				ID:      *r.Id,
 				IDv1:    *r.IdV1,
@@ -117,7 +227,7 @@ func (p *Poller) refreshNames(ctx context.Context) error {
 				GroupID: *r.Group.Rid,
 			}
 		}
-		slog.Info("scene", "id", *r.Id, "name", *r.Metadata.Name, "type", *r.Group.Rtype, "group_name", gName)
+		p.log.Info("scene", "id", *r.Id, "name", *r.Metadata.Name, "type", *r.Group.Rtype, "group_name", gName)
 	}
 
 	zones, err := p.home.GetZones(ctx)
@@ -126,57 +236,231 @@ func (p *Poller) refreshNames(ctx context.Context) error {
 	}
 
 	for _, r := range zones {
-		slog.Info("zone", "id", *r.Id, "name", *r.Metadata.Name)
+		p.log.Info("zone", "id", *r.Id, "name", *r.Metadata.Name)
+		inv.setName(*r.Id, "zone", *r.Metadata.Name, r.IdV1, "zone")
+		inv.addMembers(*r.Id, refs(r.Children))
 	}
 
-	grouped, err := p.home.GetGroupedLights()
+	grouped, err := p.home.GetGroupedLights(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, g := range grouped {
+		if g.Id != nil && g.Owner != nil && g.Owner.Rid != nil {
+			inv.groups[*g.Id] = *g.Owner.Rid
+		}
 		switch *g.Owner.Rtype {
 		case "room":
 			for _, rr := range rooms {
 				if *rr.Id == *g.Owner.Rid {
-					slog.Info("grouped_light", "group_id", *g.Id, "room_id", *rr.Id, "room", *rr.Metadata.Name)
+					p.log.Info("grouped_light", "group_id", *g.Id, "room_id", *rr.Id, "room", *rr.Metadata.Name)
 					continue
 				}
 			}
 		case "zone":
 			for _, rr := range zones {
 				if *rr.Id == *g.Owner.Rid {
-					slog.Info("grouped_light", "group_id", *g.Id, "zone_id", *rr.Id, "zone", *rr.Metadata.Name)
+					p.log.Info("grouped_light", "group_id", *g.Id, "zone_id", *rr.Id, "zone", *rr.Metadata.Name)
 					continue
 				}
 			}
-			slog.Warn("grouped_light zone", "zone", *g.Id)
+			p.log.Warn("grouped_light zone", "zone", *g.Id)
 		case "bridge_home":
 		default:
 			return fmt.Errorf("unknown group type: %s", *g.Owner.Rtype)
 		}
 	}
+
+	p.mu.Lock()
+	prev := p.inv.Load()
+	p.inv.Store(inv)
+	changed := p.changed
+	p.mu.Unlock()
+	p.notifyChanged(changed, prev, inv)
 	return nil
 }
 
-func (p *Poller) setName(key, name string, alias string, idv1 *string, t string) {
-	if key == "" || name == "" {
+// refs converts openhue resource identifiers, skipping incomplete ones.
+func refs(ids *[]openhue.ResourceIdentifier) []Owner {
+	if ids == nil {
+		return nil
+	}
+	out := make([]Owner, 0, len(*ids))
+	for _, id := range *ids {
+		if id.Rid != nil && id.Rtype != nil {
+			out = append(out, Owner{ID: resource.ID(*id.Rid), Type: resource.Type(*id.Rtype)})
+		}
+	}
+	return out
+}
+
+// Lights returns the light service ids of a room or zone.
+func (p *Poller) Lights(groupID string) []string {
+	return p.Snapshot().Lights(groupID)
+}
+
+// GroupOwner returns the room or zone id owning a grouped_light, or "".
+func (p *Poller) GroupOwner(groupedLightID string) string {
+	return p.Snapshot().GroupOwner(groupedLightID)
+}
+
+// missRetry is how long an id the bridge did not know is left alone before it is fetched again.
+const missRetry = 5 * time.Minute
+
+// pendingSize bounds the ids waiting for resolution; further misses are dropped
+// until the queue drains (the next full refresh picks them up anyway).
+const pendingSize = 64
+
+// Lookup is GetDevice for the hot path: an id that is not in the inventory yet
+// (e.g. paired after the last refresh) returns "" right away and is queued for
+// resolution in the background.
+func (p *Poller) Lookup(ctx context.Context, owner Owner) string {
+	if s := p.GetDevice(string(owner.ID)); s != "" || owner.Type == "" {
+		return s
+	}
+	p.enqueue(owner)
+	return ""
+}
+
+// LookupScene is GetScene with background resolution of scenes created after the
+// last refresh; see Lookup.
+func (p *Poller) LookupScene(ctx context.Context, id string) *Scene {
+	if s := p.GetScene(id); s != nil {
+		return s
+	}
+	p.enqueue(Owner{ID: resource.ID(id), Type: resource.TypeScene})
+	return nil
+}
+
+// OnResolved registers fn to be called with the id of every queued resource once
+// it has been added to the inventory, so callers can re-emit what they skipped.
+func (p *Poller) OnResolved(fn func(id string)) {
+	p.mu.Lock()
+	p.resolved = append(p.resolved, fn)
+	p.mu.Unlock()
+}
+
+func (p *Poller) enqueue(owner Owner) {
+	id := string(owner.ID)
+	if id == "" {
 		return
 	}
 	p.mu.Lock()
-	idv := ""
-	if idv1 != nil {
-		idv = *idv1
+	defer p.mu.Unlock()
+	if p.queued[id] {
+		return
+	}
+	select {
+	case p.pending <- owner:
+		p.queued[id] = true
+	default:
+		p.log.Debug("resolution queue full; id left for the next refresh", "id", id)
+	}
+}
+
+// resolvePending fetches queued ids until ctx is done.
+func (p *Poller) resolvePending(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case owner := <-p.pending:
+			p.resolve(ctx, owner)
+		}
+	}
+}
+
+func (p *Poller) resolve(ctx context.Context, owner Owner) {
+	id := string(owner.ID)
+	r := p.fetch(ctx, string(owner.Type), id)
+
+	p.mu.Lock()
+	delete(p.queued, id)
+	callbacks := append([]func(string){}, p.resolved...)
+	p.mu.Unlock()
+
+	if r == nil {
+		return
+	}
+	p.insert(r)
+	p.log.Info("resolved resource added after the last refresh", "type", owner.Type, "id", id, "name", r.Name())
+	for _, fn := range callbacks {
+		fn(id)
+	}
+}
+
+// fetch loads a single resource from the bridge, remembering misses so an id the
+// bridge does not know is not requested on every event.
+func (p *Poller) fetch(ctx context.Context, rtype, id string) *bridge.Resource {
+	if p.home == nil || id == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	if t, ok := p.misses[id]; ok && time.Since(t) < missRetry {
+		p.mu.Unlock()
+		return nil
+	}
+	p.misses[id] = time.Now()
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	r, err := p.home.GetResource(ctx, rtype, id)
+	if err != nil || r == nil {
+		p.log.Debug("resource lookup failed", "type", rtype, "id", id, "err", err)
+		return nil
 	}
-	p.names[key] = Device{Name: name, Alias: alias, IDv1: idv, Type: t}
+
+	p.mu.Lock()
+	delete(p.misses, id)
 	p.mu.Unlock()
+	return r
+}
+
+// insert adds a single fetched resource to the cache, mirroring refreshNames.
+func (p *Poller) insert(r *bridge.Resource) {
+	idv1 := &r.IDv1
+	switch r.Type {
+	case "device":
+		product := ""
+		if r.ProductData != nil {
+			product = r.ProductData.ProductName
+		}
+		p.log.Info("device", "id", r.ID, "productName", product, "alias", r.Name())
+		p.update(func(inv *Inventory) {
+			inv.setName(r.ID, product, r.Name(), idv1, cleanName(product))
+			if r.Metadata != nil {
+				inv.setArchetype(r.ID, r.Metadata.Archetype)
+			}
+		})
+	case "scene":
+		if r.Group == nil || r.Group.Rtype != "room" {
+			return
+		}
+		p.update(func(inv *Inventory) {
+			gName := inv.Alias(r.Group.Rid)
+			p.log.Info("scene", "id", r.ID, "name", r.Name(), "type", r.Group.Rtype, "group_name", gName)
+			inv.scenes[r.ID] = Scene{
+				Name:    r.Name(),
+				ID:      r.ID,
+				IDv1:    r.IDv1,
+				Group:   gName,
+				GroupID: r.Group.Rid,
+			}
+		})
+	default:
+		p.log.Info(r.Type, "id", r.ID, "name", r.Name())
+		p.update(func(inv *Inventory) { inv.setName(r.ID, r.Type, r.Name(), idv1, r.Type) })
+	}
 }
 
 func (p *Poller) GetDevice(key string) string {
 	if key == "" {
 		return ""
 	}
-	if d, ok := p.names[key]; ok {
+	if d, ok := p.Snapshot().Device(key); ok {
 		return d.toString()
 	}
 	return ""
@@ -186,30 +470,39 @@ func (p *Poller) GetScene(key string) *Scene {
 	if key == "" {
 		return nil
 	}
-	if d, ok := p.scenes[key]; ok {
+	if d, ok := p.Snapshot().Scene(key); ok {
 		return &d
 	}
 	return nil
 }
 
+// SceneGroup returns the id of the room or zone a scene belongs to, or "".
+func (p *Poller) SceneGroup(sceneID string) string {
+	if s := p.GetScene(sceneID); s != nil {
+		return s.GroupID
+	}
+	return ""
+}
+
 func (p *Poller) GetName(key string) string {
 	if key == "" {
 		return ""
 	}
-	if d, ok := p.names[key]; ok {
-		return d.Name
-	}
-	return ""
+	d, _ := p.Snapshot().Device(key)
+	return d.Name
+}
+
+// RoomOf returns the name of the room the device belongs to, or "" if it isn't assigned.
+func (p *Poller) RoomOf(deviceID string) string {
+	inv := p.Snapshot()
+	return inv.Alias(inv.RoomID(deviceID))
 }
 
 func (p *Poller) GetAlias(key string) string {
 	if key == "" {
 		return ""
 	}
-	if d, ok := p.names[key]; ok {
-		return d.Alias
-	}
-	return ""
+	return p.Snapshot().Alias(key)
 }
 
 // func (p *Poller) nameFor(r openhue.Resource, fallback string) string {
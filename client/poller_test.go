@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// testPoller returns a poller without a bridge, filled by the test.
+func testPoller() *Poller {
+	return newPoller(nil, buildOptions[PollerOption](nil))
+}
+
+func kitchen(t *testing.T) *bridge.Resource {
+	t.Helper()
+	var r bridge.Resource
+	if err := json.Unmarshal([]byte(`{"id":"room-1","type":"room","metadata":{"name":"Kitchen"}}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	return &r
+}
+
+func TestPoller_SnapshotIsImmutable(t *testing.T) {
+	p := testPoller()
+	before := p.Snapshot()
+
+	p.insert(kitchen(t))
+
+	if _, ok := before.Device("room-1"); ok {
+		t.Error("old snapshot sees inserted resource")
+	}
+	if got := p.GetAlias("room-1"); got != "Kitchen" {
+		t.Errorf("GetAlias() = %q, want %q", got, "Kitchen")
+	}
+}
+
+func TestPoller_ConcurrentLookups(t *testing.T) {
+	p := testPoller()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				_ = p.GetAlias("room-1")
+				_ = p.RoomOf("device-1")
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		p.insert(kitchen(t))
+	}
+	wg.Wait()
+}
+
+func TestPoller_NameOverrides(t *testing.T) {
+	p := testPoller()
+	p.insert(kitchen(t))
+
+	p.SetNameOverrides(map[string]string{"room-1": "Keuken"})
+	if got := p.GetAlias("room-1"); got != "Keuken" {
+		t.Errorf("GetAlias() after override = %q, want %q", got, "Keuken")
+	}
+
+	// resources fetched later get the override as well
+	p.insert(kitchen(t))
+	if got := p.GetAlias("room-1"); got != "Keuken" {
+		t.Errorf("GetAlias() after re-insert = %q, want %q", got, "Keuken")
+	}
+}
+
+func TestInventory_Lights(t *testing.T) {
+	inv := newInventory(nil)
+	inv.addMembers("device-1", []Owner{{ID: "light-1", Type: "light"}, {ID: "zigbee-1", Type: "zigbee_connectivity"}})
+	inv.addMembers("device-2", []Owner{{ID: "light-2", Type: "light"}})
+	inv.addMembers("room-1", []Owner{{ID: "device-1", Type: "device"}, {ID: "device-2", Type: "device"}})
+	inv.addMembers("zone-1", []Owner{{ID: "light-2", Type: "light"}})
+
+	if got := inv.Lights("room-1"); len(got) != 2 || got[0] != "light-1" || got[1] != "light-2" {
+		t.Errorf("Lights(room-1) = %v, want [light-1 light-2]", got)
+	}
+	if got := inv.Lights("zone-1"); len(got) != 1 || got[0] != "light-2" {
+		t.Errorf("Lights(zone-1) = %v, want [light-2]", got)
+	}
+}
+
+func TestPoller_ResolvesMissesInBackground(t *testing.T) {
+	const id = "6f1c7a10-2b9e-4c8e-9a55-3e2d1f0b4c11"
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/clip/v2/resource/device/"+id {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"data":[{"id":%q,"type":"device","metadata":{"name":"Hall sensor"},"product_data":{"product_name":"Hue motion sensor"}}]}`, id)
+	}))
+	defer srv.Close()
+
+	home, err := bridge.NewHome(bridge.NewAddress(srv.Listener.Addr().String()), bridge.NewKeys("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPoller(context.Background(), home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved := make(chan string, 1)
+	p.OnResolved(func(id string) { resolved <- id })
+
+	owner := Owner{ID: id, Type: resource.TypeDevice}
+	if got := p.Lookup(context.Background(), owner); got != "" {
+		t.Fatalf("Lookup() = %q before resolution, want \"\"", got)
+	}
+	p.Lookup(context.Background(), owner) // queued once
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go p.resolvePending(ctx)
+
+	select {
+	case got := <-resolved:
+		if got != id {
+			t.Fatalf("resolved %q, want %q", got, id)
+		}
+	case <-ctx.Done():
+		t.Fatal("id was not resolved")
+	}
+	if p.GetAlias(id) != "Hall sensor" {
+		t.Errorf("GetAlias() = %q after resolution, want Hall sensor", p.GetAlias(id))
+	}
+	if len(p.pending) != 0 {
+		t.Errorf("%d ids still pending; duplicates should be queued once", len(p.pending))
+	}
+}
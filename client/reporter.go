@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// DefaultReportChannels are the analog channels re-sent by a Reporter unless
+// configured otherwise.
+var DefaultReportChannels = []resource.Metric{resource.MetricTemperature, resource.MetricLightLevel, resource.MetricBattery}
+
+type ReporterConfig struct {
+	// Sender receives the periodic "<path> <value>" datagrams.
+	Sender gateway.Sender
+
+	// Interval is the reporting cadence. Default 60s.
+	Interval time.Duration
+
+	// Channels opts channels into periodic reporting. Nil means DefaultReportChannels.
+	Channels []resource.Metric
+
+	// Bools (optional) renders boolean values; default 1/0.
+	Bools *udp.Bools
+}
+
+// Reporter re-sends the last value of selected analog channels on a fixed cadence,
+// next to the change-driven messages, so Loxone statistics get evenly spaced
+// samples even while a value does not move.
+type Reporter struct {
+	cfg      ReporterConfig
+	channels map[resource.Metric]bool
+
+	mu   sync.Mutex
+	last map[string]Message // key: outgoing path
+}
+
+func NewReporter(cfg ReporterConfig) *Reporter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Channels == nil {
+		cfg.Channels = DefaultReportChannels
+	}
+	channels := make(map[resource.Metric]bool, len(cfg.Channels))
+	for _, c := range cfg.Channels {
+		channels[c] = true
+	}
+	return &Reporter{
+		cfg:      cfg,
+		channels: channels,
+		last:     make(map[string]Message),
+	}
+}
+
+// Observe remembers msg if its channel is reported and it went to Loxone.
+func (r *Reporter) Observe(msg Message) {
+	if r == nil || msg.SinkOnly || !r.channels[msg.Channel] {
+		return
+	}
+	msg.Origin, msg.Stale = "", false // a periodic report carries the value, not its tags
+	r.mu.Lock()
+	r.last[msg.Path] = msg
+	r.mu.Unlock()
+}
+
+// Run sends every remembered value once per interval until ctx is done.
+func (r *Reporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.report()
+		}
+	}
+}
+
+func (r *Reporter) report() {
+	r.mu.Lock()
+	msgs := make([]Message, 0, len(r.last))
+	for _, m := range r.last {
+		msgs = append(msgs, m)
+	}
+	r.mu.Unlock()
+
+	if len(msgs) == 0 || r.cfg.Sender == nil {
+		return
+	}
+	slog.Debug("periodic report", "values", len(msgs))
+	for _, m := range msgs {
+		r.cfg.Sender.Send(m.Encode(r.cfg.Bools))
+	}
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestReporter_ResendsOptedInChannels(t *testing.T) {
+	sender := &recordSender{}
+	r := NewReporter(ReporterConfig{Sender: sender, Channels: []resource.Metric{resource.MetricTemperature}})
+
+	r.Observe(Message{Path: "/sensor/a/temperature", Value: "21.00", Channel: resource.MetricTemperature})
+	r.Observe(Message{Path: "/sensor/a/temperature", Value: "21.50", Channel: resource.MetricTemperature})
+	r.Observe(Message{Path: "/sensor/b/temperature", Value: "19.00", Channel: resource.MetricTemperature})
+	r.Observe(Message{Path: "/sensor/c/temperature", Value: "18.00", Channel: resource.MetricTemperature, SinkOnly: true})
+	r.Observe(Message{Path: "/sensor/a/light_level", Value: "12000", Channel: resource.MetricLightLevel})
+
+	r.report()
+	r.report()
+	sort.Strings(sender.msgs)
+	want := []string{
+		"/sensor/a/temperature 21.50", "/sensor/a/temperature 21.50",
+		"/sensor/b/temperature 19.00", "/sensor/b/temperature 19.00",
+	}
+	if len(sender.msgs) != len(want) {
+		t.Fatalf("msgs = %v, want %v", sender.msgs, want)
+	}
+	for i := range want {
+		if sender.msgs[i] != want[i] {
+			t.Fatalf("msgs = %v, want %v", sender.msgs, want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type ResumeConfig struct {
+	// File keeps the position in the event stream across restarts.
+	File string
+
+	// MaxAge ignores a persisted position older than this: after a long outage
+	// nothing the bridge sends is a replay. It also bounds how far back an event
+	// may date before it is taken for a bridge clock change rather than a
+	// replay. Default 10m.
+	MaxAge time.Duration
+
+	// Interval between writes of File. Default 5s.
+	Interval time.Duration
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Resume remembers the last event stream event handled, so events the bridge
+// replays after a quick restart (e.g. during an upgrade) are not forwarded
+// twice, which would pulse motion inputs in Loxone again. A nil Resume skips
+// nothing.
+type Resume struct {
+	cfg ResumeConfig
+	log *slog.Logger
+	now func() time.Time
+
+	mu          sync.Mutex
+	pos         resumePosition
+	dirty       bool
+	skipped     int
+	replayUntil time.Time // end of the replay window; zero when closed
+}
+
+// replayWindow bounds how long after a (re)connect the bridge may still be
+// replaying events the gateway already handled.
+const replayWindow = 10 * time.Second
+
+// resumePosition is the persisted state. Bridge creation times have a
+// resolution of one second, so the ids of the containers handled within the
+// newest second are kept as well.
+type resumePosition struct {
+	EventID string    `json:"event_id,omitempty"` // SSE id of the last event
+	Time    time.Time `json:"creationtime"`       // newest container creation time
+	IDs     []string  `json:"ids,omitempty"`      // containers created at Time
+	Saved   time.Time `json:"saved"`
+}
+
+// NewResume loads cfg.File if it exists.
+func NewResume(cfg ResumeConfig) (*Resume, error) {
+	if cfg.File == "" {
+		return nil, errors.New("resume: File required")
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 10 * time.Minute
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	r := &Resume{
+		cfg: cfg,
+		log: cfg.Logger.With("module", "resume", "file", cfg.File),
+		now: time.Now,
+	}
+	b, err := os.ReadFile(cfg.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pos resumePosition
+	switch err := json.Unmarshal(b, &pos); {
+	case err != nil:
+		// a damaged file must not keep the gateway from starting
+		r.log.Warn("event stream position unreadable; starting without", "error", err)
+	case r.now().Sub(pos.Saved) > cfg.MaxAge:
+		r.log.Info("event stream position too old; starting without", "saved", pos.Saved)
+	default:
+		r.pos = pos
+	}
+	return r, nil
+}
+
+// LastEventID is the SSE id of the last event handled, for the Last-Event-ID
+// header of the next connection.
+func (r *Resume) LastEventID() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pos.EventID
+}
+
+// Connected opens the replay window: the bridge resends missed events right
+// after the event stream (re)connects, so only then are containers filtered.
+func (r *Resume) Connected() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replayUntil = r.now().Add(replayWindow)
+}
+
+// Filter returns containers without the ones handled before, while the replay
+// window after a (re)connect is open; the first event without a replay closes
+// it. It returns cs itself when nothing is skipped; containers without a
+// creation time (e.g. polled resources) are always kept.
+func (r *Resume) Filter(cs []EventContainer) []EventContainer {
+	if r == nil {
+		return cs
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replayUntil.IsZero() {
+		return cs
+	}
+	if r.now().After(r.replayUntil) {
+		r.replayUntil = time.Time{}
+		return cs
+	}
+	var out []EventContainer
+	for i, c := range cs {
+		if !r.handled(c) {
+			if out != nil {
+				out = append(out, c)
+			}
+			continue
+		}
+		if out == nil {
+			// cs belongs to a pooled payload; never move its elements around
+			out = append(make([]EventContainer, 0, len(cs)), cs[:i]...)
+		}
+		r.skipped++
+		r.log.Debug("skipping replayed event", "id", c.ID, "creationtime", c.CreationTime)
+	}
+	if out == nil {
+		// live events follow the replay
+		r.replayUntil = time.Time{}
+		return cs
+	}
+	r.log.Info("skipped events handled before the restart", "events", len(cs)-len(out), "total", r.skipped)
+	return out
+}
+
+func (r *Resume) handled(c EventContainer) bool {
+	if c.CreationTime.IsZero() || r.pos.Time.IsZero() {
+		return false
+	}
+	switch {
+	case c.CreationTime.After(r.pos.Time):
+		return false
+	case c.CreationTime.Equal(r.pos.Time):
+		return slices.Contains(r.pos.IDs, c.ID)
+	}
+	// far older than anything handled: the bridge clock was set back
+	return r.pos.Time.Sub(c.CreationTime) <= r.cfg.MaxAge
+}
+
+// Mark records containers, the event with SSE id eventID, as handled.
+func (r *Resume) Mark(eventID string, cs []EventContainer) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if eventID != "" {
+		r.pos.EventID = eventID
+		r.dirty = true
+	}
+	for _, c := range cs {
+		switch {
+		case c.CreationTime.IsZero():
+			continue
+		case c.CreationTime.Equal(r.pos.Time):
+			r.pos.IDs = append(r.pos.IDs, c.ID)
+		case c.CreationTime.After(r.pos.Time) || r.pos.Time.Sub(c.CreationTime) > r.cfg.MaxAge:
+			r.pos.Time, r.pos.IDs = c.CreationTime, []string{c.ID}
+		default:
+			continue // older than the newest handled; nothing to remember
+		}
+		r.dirty = true
+	}
+}
+
+// Run saves the position periodically and when ctx is done.
+func (r *Resume) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.save(); err != nil {
+				r.log.Error("saving event stream position failed", "error", err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.save(); err != nil {
+				r.log.Warn("saving event stream position failed", "error", err)
+			}
+		}
+	}
+}
+
+func (r *Resume) save() error {
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	pos := r.pos
+	pos.Saved = r.now()
+	b, err := json.Marshal(pos)
+	r.dirty = false
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.cfg.File, b); err != nil {
+		r.mu.Lock()
+		r.dirty = true // retry with the next save
+		r.mu.Unlock()
+		return fmt.Errorf("resume: %w", err)
+	}
+	return nil
+}
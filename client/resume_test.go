@@ -0,0 +1,125 @@
+package client
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func containerIDs(cs []EventContainer) []string {
+	ids := make([]string, len(cs))
+	for i, c := range cs {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestResume_SkipsReplayAfterRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "resume.json")
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := NewResume(ResumeConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Mark("1714564799:0", []EventContainer{{ID: "a", CreationTime: t0.Add(-time.Second)}})
+	first.Mark("1714564800:0", []EventContainer{{ID: "b", CreationTime: t0}, {ID: "c", CreationTime: t0}})
+	if err := first.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewResume(ResumeConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := second.LastEventID(); got != "1714564800:0" {
+		t.Errorf("LastEventID() = %q", got)
+	}
+	second.Connected()
+	replay := []EventContainer{
+		{ID: "a", CreationTime: t0.Add(-time.Second)},
+		{ID: "b", CreationTime: t0},
+		{ID: "d", CreationTime: t0}, // same second, not handled yet
+		{ID: "e", CreationTime: t0.Add(time.Second)},
+		{ID: "polled"},
+		{ID: "clock", CreationTime: t0.Add(-time.Hour)}, // bridge clock set back
+	}
+	got := containerIDs(second.Filter(replay))
+	if want := []string{"d", "e", "polled", "clock"}; !slices.Equal(got, want) {
+		t.Errorf("Filter() = %v, want %v", got, want)
+	}
+	if replay[1].ID != "b" {
+		t.Error("Filter() modified its argument")
+	}
+
+	live := []EventContainer{{ID: "f", CreationTime: t0.Add(2 * time.Second)}}
+	if got := second.Filter(live); &got[0] != &live[0] {
+		t.Error("Filter() copied containers although none was skipped")
+	}
+
+	// after the replay, a container dated back (bridge clock drift) is live
+	drifted := []EventContainer{{ID: "g", CreationTime: t0}}
+	if got := second.Filter(drifted); len(got) != 1 {
+		t.Errorf("Filter() skipped %v outside the replay window", containerIDs(drifted))
+	}
+}
+
+func TestResume_ReplayWindow(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "resume.json")
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := t0
+
+	r := mustResume(t, file)
+	r.now = func() time.Time { return now }
+	r.Mark("1", []EventContainer{{ID: "a", CreationTime: t0}})
+	replay := []EventContainer{{ID: "a", CreationTime: t0}}
+
+	if got := r.Filter(replay); len(got) != 1 {
+		t.Error("Filter() skipped before the stream connected")
+	}
+	r.Connected()
+	if got := r.Filter(replay); len(got) != 0 {
+		t.Error("Filter() kept a replay right after connecting")
+	}
+	now = now.Add(replayWindow + time.Second)
+	if got := r.Filter(replay); len(got) != 1 {
+		t.Error("Filter() skipped after the replay window")
+	}
+	r.Connected() // reconnect
+	if got := r.Filter(replay); len(got) != 0 {
+		t.Error("Filter() kept a replay after reconnecting")
+	}
+}
+
+func TestResume_IgnoresOldPosition(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "resume.json")
+	t0 := time.Now().Add(-time.Hour)
+
+	first, err := NewResume(ResumeConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.now = func() time.Time { return t0 }
+	first.Mark("1", []EventContainer{{ID: "a", CreationTime: t0}})
+	if err := first.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var nilResume *Resume
+	for name, r := range map[string]*Resume{"nil": nilResume, "old": mustResume(t, file)} {
+		cs := []EventContainer{{ID: "a", CreationTime: t0}}
+		if got := r.Filter(cs); len(got) != 1 {
+			t.Errorf("%s: Filter() skipped %v", name, cs)
+		}
+	}
+}
+
+func mustResume(t *testing.T, file string) *Resume {
+	t.Helper()
+	r, err := NewResume(ResumeConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
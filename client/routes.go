@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Route prefixes the paths of resources matching Hue metadata, e.g. every device
+// with archetype "plug" under /plug. Empty fields match everything; a route needs
+// at least one criterion.
+type Route struct {
+	Archetype string `mapstructure:"archetype"` // device archetype, e.g. "plug"
+	Product   string `mapstructure:"product"`   // product name, e.g. "Hue smart plug"
+	Room      string `mapstructure:"room"`      // room name or id
+	Prefix    string `mapstructure:"prefix"`    // e.g. "/plug"
+}
+
+// Router is a MessageHook that applies the first matching Route, so devices added
+// later are routed by what they are instead of by id. It runs after the path style
+// hook: /sensor/<id>/battery → /plug/sensor/<id>/battery.
+type Router struct {
+	names  *Poller
+	routes []Route
+}
+
+func NewRouter(names *Poller, routes []Route) (*Router, error) {
+	r := &Router{names: names}
+	for _, rt := range routes {
+		rt.Prefix = "/" + strings.Trim(rt.Prefix, "/")
+		if rt.Prefix == "/" {
+			return nil, errors.New("route without prefix")
+		}
+		if rt.Archetype == "" && rt.Product == "" && rt.Room == "" {
+			return nil, errors.New("route " + rt.Prefix + " matches nothing: set archetype, product or room")
+		}
+		r.routes = append(r.routes, rt)
+	}
+	return r, nil
+}
+
+func (r *Router) Process(ctx context.Context, msg Message) ([]Message, error) {
+	if prefix := r.prefix(msg); prefix != "" {
+		msg.Path = prefix + msg.Path
+	}
+	return []Message{msg}, nil
+}
+
+func (r *Router) prefix(msg Message) string {
+	if len(r.routes) == 0 || msg.ID == "" {
+		return ""
+	}
+	inv := r.names.Snapshot()
+	id := string(msg.ID)
+	device, _ := inv.Device(id)
+	room := inv.RoomID(id)
+	if owner := inv.GroupOwner(id); owner != "" {
+		room = owner
+	}
+	for _, rt := range r.routes {
+		if rt.Archetype != "" && !strings.EqualFold(rt.Archetype, device.Archetype) {
+			continue
+		}
+		if rt.Product != "" && !strings.EqualFold(rt.Product, device.Name) {
+			continue
+		}
+		if rt.Room != "" && !strings.EqualFold(rt.Room, room) && !strings.EqualFold(rt.Room, inv.Alias(room)) {
+			continue
+		}
+		return rt.Prefix
+	}
+	return ""
+}
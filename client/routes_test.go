@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestRouter(t *testing.T) {
+	p := testPoller()
+	p.update(func(inv *Inventory) {
+		inv.setName("room-1", "room", "Garage", nil, "room")
+		inv.setName("plug-1", "Hue smart plug", "Heater", nil, "device")
+		inv.setArchetype("plug-1", "plug")
+		inv.setName("sensor-1", "Hue motion sensor", "Door", nil, "device")
+		inv.setName("sensor-2", "Hue motion sensor", "Hall", nil, "device")
+		inv.rooms["sensor-1"] = "room-1"
+	})
+	r, err := NewRouter(p, []Route{
+		{Archetype: "plug", Prefix: "/plug/"},
+		{Room: "garage", Prefix: "garage"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   resource.ID
+		path string
+		want string
+	}{
+		{"plug-1", "/sensor/plug-1/battery", "/plug/sensor/plug-1/battery"},
+		{"sensor-1", "/sensor/sensor-1/motion", "/garage/sensor/sensor-1/motion"},
+		{"sensor-2", "/sensor/sensor-2/motion", "/sensor/sensor-2/motion"},
+		{"", "/home/motion", "/home/motion"},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			out, err := r.Process(t.Context(), Message{ID: tt.id, Path: tt.path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out[0].Path != tt.want {
+				t.Errorf("path = %q, want %q", out[0].Path, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRouter_RejectsEmptyRoutes(t *testing.T) {
+	if _, err := NewRouter(nil, []Route{{Prefix: "/plug"}}); err == nil {
+		t.Error("route without criteria accepted")
+	}
+	if _, err := NewRouter(nil, []Route{{Archetype: "plug"}}); err == nil {
+		t.Error("route without prefix accepted")
+	}
+}
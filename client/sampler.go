@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SampleChanges forwards a path only when its value differs from the last one.
+const SampleChanges = "changes"
+
+// DefaultSampling tames dynamic scenes, which report their status continuously.
+var DefaultSampling = map[string]string{"scene": "10s"}
+
+// Sampler limits how often messages of a resource type are forwarded. Per path it
+// either drops unchanged values ("changes") or repeats an unchanged value at most
+// once per interval ("10s"); a changed value always passes.
+type Sampler struct {
+	rules map[string]sampleRule // key: message type (scene, light, ...)
+
+	mu   sync.Mutex
+	last map[string]sampled // key: outgoing path
+}
+
+type sampleRule struct {
+	every   time.Duration // 0: changes only
+	changes bool
+}
+
+type sampled struct {
+	value string
+	at    time.Time
+}
+
+// NewSampler parses type → "changes" | duration pairs on top of DefaultSampling;
+// "0s" or "off" disables sampling for a type.
+func NewSampler(cfg map[string]string) (*Sampler, error) {
+	s := &Sampler{
+		rules: make(map[string]sampleRule),
+		last:  make(map[string]sampled),
+	}
+	merged := make(map[string]string, len(DefaultSampling)+len(cfg))
+	for t, v := range DefaultSampling {
+		merged[t] = v
+	}
+	for t, v := range cfg {
+		merged[t] = v
+	}
+	for t, raw := range merged {
+		raw = strings.ToLower(strings.TrimSpace(raw))
+		switch raw {
+		case SampleChanges:
+			s.rules[t] = sampleRule{changes: true}
+			continue
+		case "off":
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("sampling %s: invalid value %q: expected %s, off or a duration", t, raw, SampleChanges)
+		}
+		if d > 0 {
+			s.rules[t] = sampleRule{every: d}
+		}
+	}
+	return s, nil
+}
+
+// Allow reports whether msg should be forwarded and, if so, remembers it.
+func (s *Sampler) Allow(msg Message) bool {
+	if s == nil {
+		return true
+	}
+	rule, ok := s.rules[string(msg.Type)]
+	if !ok {
+		return true
+	}
+	now := msg.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, seen := s.last[msg.Path]
+	if seen && prev.value == msg.Value && (rule.changes || now.Sub(prev.at) < rule.every) {
+		return false
+	}
+	s.last[msg.Path] = sampled{value: msg.Value, at: now}
+	return true
+}
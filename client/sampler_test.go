@@ -0,0 +1,54 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestSampler(t *testing.T) {
+	s, err := NewSampler(map[string]string{"light": SampleChanges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Now()
+	msg := func(typ resource.Type, path, value string, after time.Duration) Message {
+		return Message{Type: typ, Path: path, Value: value, Time: t0.Add(after)}
+	}
+
+	steps := []struct {
+		name string
+		msg  Message
+		want bool
+	}{
+		{"first scene", msg("scene", "/scene/g1/on", "s1", 0), true},
+		{"repeat within 10s", msg("scene", "/scene/g1/on", "s1", 3*time.Second), false},
+		{"other scene passes", msg("scene", "/scene/g1/on", "s2", 4*time.Second), true},
+		{"repeat after 10s", msg("scene", "/scene/g1/on", "s2", 15*time.Second), true},
+		{"light first", msg("light", "/light/l1/on", "1", 0), true},
+		{"light unchanged", msg("light", "/light/l1/on", "1", time.Hour), false},
+		{"light changed", msg("light", "/light/l1/on", "0", time.Hour), true},
+		{"unsampled type", msg("motion", "/sensor/m1/motion", "1", 0), true},
+		{"unsampled repeat", msg("motion", "/sensor/m1/motion", "1", 0), true},
+	}
+	for _, st := range steps {
+		if got := s.Allow(st.msg); got != st.want {
+			t.Errorf("%s: Allow() = %v, want %v", st.name, got, st.want)
+		}
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	s, err := NewSampler(map[string]string{"scene": "off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Message{Type: "scene", Path: "/scene/g1/on", Value: "s1"}
+	if !s.Allow(m) || !s.Allow(m) {
+		t.Error("scene sampling not disabled by off")
+	}
+	if _, err := NewSampler(map[string]string{"scene": "often"}); err == nil {
+		t.Error("NewSampler(often) error = nil, want error")
+	}
+}
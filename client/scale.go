@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// Scale is a MessageHook that multiplies analog values by a per-channel factor
+// and rounds them, for Loxone virtual inputs that only take integers, e.g.
+// {"temperature": "10"} sends 21.5°C as 215. Non-numeric values pass unchanged.
+type Scale struct {
+	factors map[resource.Metric]float64 // key: channel
+}
+
+// NewScale parses channel → factor pairs.
+func NewScale(cfg map[string]string) (*Scale, error) {
+	s := &Scale{factors: make(map[resource.Metric]float64, len(cfg))}
+	for channel, raw := range cfg {
+		m, err := resource.ParseMetric(channel)
+		if err != nil {
+			return nil, fmt.Errorf("scale: %w", err)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || f <= 0 {
+			return nil, fmt.Errorf("scale %s: invalid factor %q", channel, raw)
+		}
+		s.factors[m] = f
+	}
+	return s, nil
+}
+
+// Factor returns the factor for channel, or 0 if its values are not scaled.
+func (s *Scale) Factor(channel resource.Metric) float64 {
+	if s == nil {
+		return 0
+	}
+	return s.factors[channel]
+}
+
+func (s *Scale) Process(ctx context.Context, msg Message) ([]Message, error) {
+	f := s.Factor(msg.Channel)
+	if f == 0 {
+		return []Message{msg}, nil
+	}
+	v, err := strconv.ParseFloat(msg.Value, 64)
+	if err != nil {
+		return []Message{msg}, nil
+	}
+	msg.Value = strconv.FormatFloat(math.Round(v*f), 'f', 0, 64)
+	return []Message{msg}, nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestScale(t *testing.T) {
+	s, err := NewScale(map[string]string{"temperature": "10", "light_level": "0.01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		channel string
+		value   string
+		want    string
+	}{
+		{"temperature", "21.50", "215"},
+		{"temperature", "-3.26", "-33"},
+		{"light_level", "18000.000000", "180"},
+		{"battery", "87", "87"},
+		{"temperature", "n/a", "n/a"},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.channel+"="+tt.value, func(t *testing.T) {
+			t.Parallel()
+			out, err := s.Process(t.Context(), Message{Channel: resource.Metric(tt.channel), Value: tt.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out[0].Value != tt.want {
+				t.Errorf("value = %q, want %q", out[0].Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewScale_Invalid(t *testing.T) {
+	for _, cfg := range []map[string]string{{"temperature": "ten"}, {"temperature": "0"}, {"Temp C": "10"}} {
+		if _, err := NewScale(cfg); err == nil {
+			t.Errorf("NewScale(%v) accepted", cfg)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// defaultJobTimeout bounds a single run of a job without its own Timeout.
+const defaultJobTimeout = 30 * time.Second
+
+// Job configures one periodic poller task.
+type Job struct {
+	Interval time.Duration `mapstructure:"interval"` // 0 disables the job
+	Jitter   float64       `mapstructure:"jitter"`   // spread as a fraction of Interval, 0..1
+	Timeout  time.Duration `mapstructure:"timeout"`  // deadline of each run; default 30s
+}
+
+// delay returns Interval spread by ±Jitter, so several gateways (or jobs) do not
+// hit the bridge in lockstep.
+func (j Job) delay() time.Duration {
+	if j.Jitter <= 0 {
+		return j.Interval
+	}
+	spread := float64(j.Interval) * min(j.Jitter, 1) * (rand.Float64()*2 - 1)
+	return max(j.Interval+time.Duration(spread), time.Second)
+}
+
+func (j Job) timeout() time.Duration {
+	if j.Timeout <= 0 {
+		return defaultJobTimeout
+	}
+	return j.Timeout
+}
+
+// Schedule holds the poller's periodic jobs.
+type Schedule struct {
+	Names  Job `mapstructure:"names"`  // inventory refresh
+	Resync Job `mapstructure:"resync"` // state resync, runs OnResync
+	Health Job `mapstructure:"health"` // bridge health probe, runs OnHealth
+
+	// Resources are polled individually and fed to OnPolled subscribers.
+	Resources []PollJob `mapstructure:"resources"`
+
+	OnResync func(ctx context.Context) error `mapstructure:"-"`
+	OnHealth func(ctx context.Context) error `mapstructure:"-"`
+}
+
+// DefaultSchedule refreshes names hourly and probes the bridge every minute;
+// resync is off.
+func DefaultSchedule() Schedule {
+	return Schedule{
+		Names:  Job{Interval: time.Hour, Jitter: 0.1, Timeout: defaultJobTimeout},
+		Health: Job{Interval: time.Minute, Jitter: 0.1, Timeout: 10 * time.Second},
+	}
+}
+
+// SetSchedule replaces the job schedule; call it before Run.
+func (p *Poller) SetSchedule(s Schedule) {
+	p.schedule = s
+}
+
+// every runs fn every job interval until ctx is done.
+func every(ctx context.Context, wg *sync.WaitGroup, name string, job Job, fn func(context.Context) error) {
+	if job.Interval <= 0 || fn == nil {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			timer := time.NewTimer(job.delay())
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			runCtx, cancel := context.WithTimeout(ctx, job.timeout())
+			err := fn(runCtx)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("poller job failed", "job", name, "error", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      Job
+		min, max time.Duration
+	}{
+		{name: "no jitter", job: Job{Interval: time.Minute}, min: time.Minute, max: time.Minute},
+		{name: "10% jitter", job: Job{Interval: 100 * time.Second, Jitter: 0.1}, min: 90 * time.Second, max: 110 * time.Second},
+		{name: "jitter capped at 100%", job: Job{Interval: 10 * time.Second, Jitter: 5}, min: time.Second, max: 20 * time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			for range 100 {
+				if d := tt.job.delay(); d < tt.min || d > tt.max {
+					t.Fatalf("delay() = %s, want %s..%s", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import "github.com/samvdb/loxone-philips-hue/resource"
+
+// PathSpec describes one message path the gateway can emit to Loxone.
+type PathSpec struct {
+	Path        string   `json:"path"`            // template, "<id>" is a hue resource id
+	Source      string   `json:"source"`          // hue resource type, or "gateway"
+	Channel     string   `json:"channel"`         // last path segment
+	Value       string   `json:"value"`           // bool (0|1, see --bool-encoding), int, float or string
+	Min         *float64 `json:"min,omitempty"`   // inclusive, numeric values only
+	Max         *float64 `json:"max,omitempty"`   // inclusive, numeric values only
+	Unit        string   `json:"unit,omitempty"`  // e.g. "°C", "%"
+	Scale       float64  `json:"scale,omitempty"` // values are multiplied by scale and rounded
+	Description string   `json:"description"`
+}
+
+// SchemaOptions mirrors the settings that add or remove paths.
+type SchemaOptions struct {
+	Events     bool // event streaming enabled (--mode events|both)
+	Levels     bool // group brightness and battery levels sent (--loxone-levels)
+	HomeMotion bool
+	HomeLight  bool
+	Occupancy  bool
+	Overrides  bool
+	Daily      bool // --daily-report set
+	BatteryLow bool // --battery-low set
+	Usage      bool // bridge usage polling enabled
+	Breaker    bool // --event-storm-limit set
+	Inventory  bool // --inventory-changed set
+	Scale      *Scale
+
+	Averages []resource.Metric // sensor channels averaged per room (--room-averages)
+	AvgZones bool              // average_zones configured
+}
+
+func span(min, max float64) (*float64, *float64) { return &min, &max }
+
+// Schema lists the paths the gateway emits with opts, in a stable order.
+func Schema(opts SchemaOptions) []PathSpec {
+	specs := []PathSpec{
+		{Path: "/gateway/version", Source: "gateway", Channel: "version", Value: "string", Description: "gateway version, sent at startup and on announce"},
+		{Path: "/gateway/bridge_online", Source: "gateway", Channel: "bridge_online", Value: "bool", Description: "Hue bridge reachability"},
+		{Path: "/gateway/event_stream_ok", Source: "gateway", Channel: "event_stream_ok", Value: "bool", Description: "0 after --event-stream-alert-after consecutive failed reconnects"},
+		{Path: "/gateway/udp_dropped", Source: "gateway", Channel: "udp_dropped", Value: "int", Description: "messages to Loxone dropped since start, reported every minute"},
+		{Path: "/gateway/leader", Source: "gateway", Channel: "leader", Value: "bool", Description: "1 when this instance took over as HA leader (--ha-peers)"},
+		{Path: "/gateway/apikey_failover", Source: "gateway", Channel: "apikey_failover", Value: "bool", Description: "1 while the secondary API key is in use"},
+		{Path: "/gateway/vacation", Source: "gateway", Channel: "vacation", Value: "bool", Description: "vacation mode"},
+		{Path: "/gateway/night", Source: "gateway", Channel: "night", Value: "bool", Description: "night mode"},
+		{Path: "/gateway/alert", Source: "gateway", Channel: "alert", Value: "string", Description: "kind of the last gateway alert, e.g. critical_send_failed"},
+		{Path: "/gateway/config_ok", Source: "gateway", Channel: "config_ok", Value: "bool", Description: "0 when the configuration references resources missing on the bridge"},
+	}
+	if !opts.Events {
+		return specs
+	}
+
+	battery, batteryMax := span(0, 100)
+	brightness, brightnessMax := span(0, 100)
+	level, levelMax := span(0, 65535)
+	temp, tempMax := span(-40, 60)
+	humidity, humidityMax := span(0, 100)
+
+	specs = append(specs,
+		PathSpec{Path: "/contact/<id>/state", Source: "contact", Channel: "state", Value: "bool", Description: "1 when closed (contact), 0 when open"},
+		PathSpec{Path: "/sensor/<id>/tamper", Source: "tamper", Channel: "tamper", Value: "bool", Description: "1 when the sensor was tampered with"},
+		PathSpec{Path: "/sensor/<id>/motion", Source: "motion", Channel: "motion", Value: "bool", Description: "motion detected"},
+		PathSpec{Path: "/security/<id>/motion", Source: "security_area_motion", Channel: "motion", Value: "bool", Description: "motion in an armed Hue Secure area"},
+		PathSpec{Path: "/group/<id>/motion", Source: "grouped_motion", Channel: "motion", Value: "bool", Description: "motion anywhere in the room or zone"},
+		PathSpec{Path: "/sensor/<id>/light_level", Source: "light_level", Channel: "light_level", Value: "float", Min: level, Max: levelMax, Description: "hue light level, 10000*log10(lux)+1"},
+		PathSpec{Path: "/group/<id>/light_level", Source: "grouped_light_level", Channel: "light_level", Value: "float", Min: level, Max: levelMax, Description: "hue light level of the room or zone, 10000*log10(lux)+1"},
+		PathSpec{Path: "/sensor/<id>/temperature", Source: "temperature", Channel: "temperature", Value: "float", Min: temp, Max: tempMax, Unit: "°C", Description: "temperature"},
+		PathSpec{Path: "/sensor/<id>/humidity", Source: "relative_humidity", Channel: "humidity", Value: "float", Min: humidity, Max: humidityMax, Unit: "%", Description: "relative humidity of third-party sensors exposed by the bridge"},
+		PathSpec{Path: "/plug/<id>/power", Source: "power", Channel: "power", Value: "float", Unit: "W", Description: "power draw of a plug or meter that reports it"},
+		PathSpec{Path: "/plug/<id>/energy", Source: "power", Channel: "energy", Value: "float", Unit: "kWh", Description: "energy total of a plug or meter that reports it"},
+		PathSpec{Path: "/entertainment/<id>/active", Source: "entertainment_configuration", Channel: "active", Value: "bool", Description: "entertainment session streaming"},
+		PathSpec{Path: "/scene/<id>/on", Source: "scene", Channel: "on", Value: "string", Description: "id of the scene recalled in room <id>"},
+	)
+	if opts.Levels {
+		specs = append(specs,
+			PathSpec{Path: "/group/<id>/brightness", Source: "grouped_light", Channel: "brightness", Value: "int", Min: brightness, Max: brightnessMax, Unit: "%", Description: "grouped light brightness"},
+			PathSpec{Path: "/sensor/<id>/battery", Source: "device_power", Channel: "battery", Value: "int", Min: battery, Max: batteryMax, Unit: "%", Description: "battery level"},
+		)
+	}
+	for _, ev := range ButtonEvents {
+		specs = append(specs, PathSpec{Path: "/button/<id>/" + ev, Source: "button", Channel: ev, Value: "bool", Description: "1 on every " + ev + " of a dimmer switch, smart button or wall switch module button"})
+	}
+	specs = append(specs, PathSpec{Path: "/rotary/<id>/steps", Source: "relative_rotary", Channel: "steps", Value: "int", Description: "steps of one turn of a tap dial, negative counterclockwise"})
+	for _, ch := range opts.Averages {
+		specs = append(specs, averageSpecs(specs, ch, opts.AvgZones)...)
+	}
+	for i := range specs {
+		scale(&specs[i], opts.Scale.Factor(resource.Metric(specs[i].Channel)))
+	}
+	if opts.HomeMotion {
+		specs = append(specs, PathSpec{Path: "/home/motion", Source: "grouped_motion", Channel: "motion", Value: "bool", Description: "motion anywhere in the home"})
+	}
+	if opts.HomeLight {
+		specs = append(specs,
+			PathSpec{Path: "/home/on", Source: "grouped_light", Channel: "on", Value: "bool", Description: "any light in the home on"},
+			PathSpec{Path: "/home/brightness", Source: "grouped_light", Channel: "brightness", Value: "int", Min: brightness, Max: brightnessMax, Unit: "%", Description: "brightness of the lights on in the home"},
+		)
+	}
+	if opts.Occupancy {
+		specs = append(specs, PathSpec{Path: "/room/<name>/occupied", Source: "gateway", Channel: "occupied", Value: "bool", Description: "room occupancy derived from motion, contact and light activity"})
+	}
+	if opts.Overrides {
+		specs = append(specs, PathSpec{Path: "/room/<name>/override", Source: "gateway", Channel: "override", Value: "bool", Description: "1 while the room lights were changed in the Hue app or with an accessory (--override-timeout)"})
+	}
+	if opts.BatteryLow {
+		specs = append(specs, PathSpec{Path: "/device/<id>/battery_low", Source: "device_power", Channel: "battery_low", Value: "bool", Description: "1 when the battery level fell to --battery-low, 0 once it recovered"})
+	}
+	if opts.Daily {
+		specs = append(specs, PathSpec{Path: "/gateway/daily_report", Source: "gateway", Channel: "daily_report", Value: "string", Description: "daily JSON summary: offline devices, low batteries, events and dropped messages"})
+	}
+	if opts.Usage {
+		specs = append(specs,
+			PathSpec{Path: "/gateway/usage/<resource>", Source: "gateway", Channel: "<resource>", Value: "int", Description: "entries in a bridge table (lights, sensors, groups, scenes, rules, schedules, resourcelinks)"},
+			PathSpec{Path: "/gateway/usage_near", Source: "gateway", Channel: "usage_near", Value: "bool", Description: "1 while a bridge table is at 90% of its limit or more"},
+		)
+	}
+	if opts.Breaker {
+		specs = append(specs, PathSpec{Path: "/gateway/event_storm", Source: "gateway", Channel: "event_storm", Value: "bool", Description: "1 while low-priority channels are dropped because of --event-storm-limit"})
+	}
+	if opts.Inventory {
+		specs = append(specs, PathSpec{Path: "/gateway/inventory_changed", Source: "gateway", Channel: "inventory_changed", Value: "bool", Description: "pulses 1 when lights, rooms, zones or scenes were added, removed or renamed on the bridge"})
+	}
+	return specs
+}
+
+// averageSpecs derives the room (and zone) average specs of a sensor channel.
+func averageSpecs(specs []PathSpec, ch resource.Metric, zones bool) []PathSpec {
+	var out []PathSpec
+	for _, s := range specs {
+		if s.Path != "/sensor/<id>/"+string(ch) {
+			continue
+		}
+		s.Source = "gateway"
+		s.Channel = string(averageChannel(ch))
+		s.Value = "float"
+		room := s
+		room.Path = "/room/<name>/" + s.Channel
+		room.Description = "average " + string(ch) + " of the sensors in the room, outliers left out"
+		out = append(out, room)
+		if zones {
+			zone := s
+			zone.Path = "/zone/<name>/" + s.Channel
+			zone.Description = "average " + string(ch) + " of the rooms in the zone (average_zones)"
+			out = append(out, zone)
+		}
+	}
+	return out
+}
+
+// scale documents a Scale factor on an analog spec.
+func scale(s *PathSpec, factor float64) {
+	if factor == 0 || (s.Value != "float" && s.Value != "int") {
+		return
+	}
+	s.Scale = factor
+	s.Value = "int"
+	if s.Min != nil {
+		s.Min, s.Max = span(*s.Min*factor, *s.Max*factor)
+	}
+}
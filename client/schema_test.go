@@ -0,0 +1,61 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestSchema(t *testing.T) {
+	has := func(specs []PathSpec, path string) bool {
+		for _, s := range specs {
+			if s.Path == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	commandsOnly := Schema(SchemaOptions{})
+	if has(commandsOnly, "/sensor/<id>/motion") {
+		t.Error("commands-only schema lists event paths")
+	}
+	if !has(commandsOnly, "/gateway/bridge_online") {
+		t.Error("schema misses gateway paths")
+	}
+
+	if has(Schema(SchemaOptions{Events: true}), "/sensor/<id>/battery") {
+		t.Error("schema lists battery levels without --loxone-levels")
+	}
+	if !has(Schema(SchemaOptions{Events: true, Levels: true}), "/sensor/<id>/battery") {
+		t.Error("schema misses battery levels with --loxone-levels")
+	}
+
+	full := Schema(SchemaOptions{Events: true, HomeMotion: true, HomeLight: true, Occupancy: true, Averages: []resource.Metric{resource.MetricTemperature}, AvgZones: true})
+	for _, path := range []string{"/sensor/<id>/motion", "/home/motion", "/home/on", "/home/brightness", "/room/<name>/occupied", "/room/<name>/temperature_avg", "/zone/<name>/temperature_avg"} {
+		if !has(full, path) {
+			t.Errorf("schema misses %s", path)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range full {
+		if seen[s.Path] {
+			t.Errorf("duplicate path %s", s.Path)
+		}
+		seen[s.Path] = true
+		if (s.Min == nil) != (s.Max == nil) {
+			t.Errorf("%s: min and max must be set together", s.Path)
+		}
+	}
+
+	s, err := NewScale(map[string]string{"temperature": "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, spec := range Schema(SchemaOptions{Events: true, Scale: s}) {
+		if spec.Path == "/sensor/<id>/temperature" && (spec.Scale != 10 || spec.Value != "int" || *spec.Max != 600) {
+			t.Errorf("scaled temperature spec = %+v", spec)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Sample resource ids used by the selftest events.
+const (
+	SampleDevice = "00000000-0000-4000-8000-00000000de01"
+	SampleRoom   = "00000000-0000-4000-8000-0000000000a1"
+	SampleScene  = "00000000-0000-4000-8000-0000000000c1"
+)
+
+// sampleEvents holds one representative event per forwarded resource type.
+var sampleEvents = []struct {
+	Type resource.Type
+	Data string
+}{
+	{resource.TypeMotion, `{"id": "00000000-0000-4000-8000-000000000001", "type": "motion", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "motion": {"motion_report": {"changed": "2025-01-01T00:00:00Z", "motion": true}}}`},
+	{resource.TypeContact, `{"id": "00000000-0000-4000-8000-000000000002", "type": "contact", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "contact_report": {"changed": "2025-01-01T00:00:00Z", "state": "no_contact"}}`},
+	{resource.TypeTamper, `{"id": "00000000-0000-4000-8000-000000000003", "type": "tamper", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "tamper_reports": [{"changed": "2025-01-01T00:00:00Z", "source": "battery_door", "state": "tampered"}]}`},
+	{resource.TypeTemperature, `{"id": "00000000-0000-4000-8000-000000000004", "type": "temperature", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "temperature": {"temperature_report": {"changed": "2025-01-01T00:00:00Z", "temperature": 21.37}}}`},
+	{resource.TypeLightLevel, `{"id": "00000000-0000-4000-8000-000000000005", "type": "light_level", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "light": {"light_level_report": {"changed": "2025-01-01T00:00:00Z", "light_level": 18000}}}`},
+	{resource.TypeDevicePower, `{"id": "00000000-0000-4000-8000-000000000006", "type": "device_power", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "power_state": {"battery_state": "normal", "battery_level": 80}}`},
+	{"power_measurement", `{"id": "00000000-0000-4000-8000-000000000007", "type": "power_measurement", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "power": {"power_report": {"power": 12.5}}, "energy": {"energy_report": {"energy": 1.25}}}`},
+	{resource.TypeGroupedLight, `{"id": "00000000-0000-4000-8000-000000000008", "type": "grouped_light", "owner": {"rid": "` + SampleRoom + `", "rtype": "room"}, "on": {"on": true}, "dimming": {"brightness": 42.5}}`},
+	{resource.TypeGroupedMotion, `{"id": "00000000-0000-4000-8000-000000000009", "type": "grouped_motion", "owner": {"rid": "` + SampleRoom + `", "rtype": "room"}, "motion": {"motion_report": {"changed": "2025-01-01T00:00:00Z", "motion": true}}}`},
+	{resource.TypeGroupedLightLevel, `{"id": "00000000-0000-4000-8000-00000000000a", "type": "grouped_light_level", "owner": {"rid": "` + SampleRoom + `", "rtype": "room"}, "light": {"light_level_report": {"changed": "2025-01-01T00:00:00Z", "light_level": 18000}}}`},
+	{resource.TypeRelativeHumidity, `{"id": "00000000-0000-4000-8000-00000000000d", "type": "relative_humidity", "owner": {"rid": "` + SampleDevice + `", "rtype": "device"}, "relative_humidity": {"relative_humidity_report": {"changed": "2025-01-01T00:00:00Z", "relative_humidity": 48.5}}}`},
+	{resource.TypeSecurityAreaMotion, `{"id": "00000000-0000-4000-8000-00000000000b", "type": "security_area_motion", "motion": {"motion_report": {"changed": "2025-01-01T00:00:00Z", "motion": true}}}`},
+	{resource.TypeEntertainmentConfiguration, `{"id": "00000000-0000-4000-8000-00000000000c", "type": "entertainment_configuration", "status": "active"}`},
+	{resource.TypeScene, `{"id": "` + SampleScene + `", "type": "scene", "owner": {"rid": "` + SampleRoom + `", "rtype": "room"}, "status": {"active": "static"}}`},
+}
+
+// SelfTestConfig is the part of the streamer configuration that shapes
+// outgoing datagrams.
+type SelfTestConfig struct {
+	// Poller is the inventory the hooks were built with, usually NewOfflinePoller.
+	Poller *Poller
+
+	Hooks      []MessageHook
+	Bools      *udp.Bools
+	Curves     *curve.Curves
+	Critical   []string
+	HomeMotion bool
+	HomeLight  bool
+	Levels     bool
+}
+
+// SelfTestResult is what one sample event turned into.
+type SelfTestResult struct {
+	Type      resource.Type
+	Datagrams []string // exactly as sent to Loxone
+}
+
+// SelfTest runs one representative event per resource type through the
+// streamer pipeline (hooks, encodings, curves) and returns the datagrams that
+// would reach Loxone, so configuration mistakes show up before deployment.
+func SelfTest(ctx context.Context, cfg SelfTestConfig) ([]SelfTestResult, error) {
+	if cfg.Poller == nil {
+		return nil, errors.New("selftest: poller required")
+	}
+	cfg.Poller.update(func(inv *Inventory) {
+		inv.scenes[SampleScene] = Scene{ID: SampleScene, Name: "sample", GroupID: SampleRoom}
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	sender, err := udp.NewClient(ctx, udp.ClientConfig{Remote: conn.LocalAddr().String()})
+	if err != nil {
+		return nil, err
+	}
+	defer sender.Close()
+
+	e, err := NewStreamer(ctx, StreamerConfig{
+		Bridge:     bridge.NewAddress("127.0.0.1"),
+		Keys:       bridge.NewKeys("selftest"),
+		UDPClient:  sender,
+		Levels:     cfg.Levels,
+		Poller:     cfg.Poller,
+		State:      gateway.NewState(nil),
+		Bools:      cfg.Bools,
+		Curves:     cfg.Curves,
+		Critical:   cfg.Critical,
+		HomeMotion: cfg.HomeMotion,
+		HomeLight:  cfg.HomeLight,
+		Hooks:      cfg.Hooks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SelfTestResult, 0, len(sampleEvents))
+	buf := make([]byte, 64*1024)
+	for _, s := range sampleEvents {
+		if err := e.handle(ctx, []EventContainer{{CreationTime: time.Now(), Type: "update", Data: []json.RawMessage{json.RawMessage(s.Data)}}}); err != nil {
+			return nil, fmt.Errorf("selftest %s: %w", s.Type, err)
+		}
+		r := SelfTestResult{Type: s.Type}
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			r.Datagrams = append(r.Datagrams, string(buf[:n]))
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
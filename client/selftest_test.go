@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	scale, err := NewScale(map[string]string{"temperature": "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := SelfTest(context.Background(), SelfTestConfig{Poller: NewOfflinePoller(), Hooks: []MessageHook{scale}, Levels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(sampleEvents) {
+		t.Fatalf("%d results, want one per sample (%d)", len(results), len(sampleEvents))
+	}
+	for _, r := range results {
+		if len(r.Datagrams) == 0 {
+			t.Errorf("%s: nothing sent", r.Type)
+		}
+		if r.Type == "temperature" && (len(r.Datagrams) != 1 || !strings.HasSuffix(r.Datagrams[0], "/temperature 214")) {
+			t.Errorf("temperature datagrams = %q, want the scaled value", r.Datagrams)
+		}
+	}
+}
+
+func TestSelfTest_LevelsOff(t *testing.T) {
+	results, err := SelfTest(context.Background(), SelfTestConfig{Poller: NewOfflinePoller()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Type == "device_power" && len(r.Datagrams) != 0 {
+			t.Errorf("device_power datagrams = %q, want none without Levels", r.Datagrams)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// Origins of a light state change beside OriginLoxone.
+const (
+	OriginAccessory = "accessory" // a Hue dimmer switch, button or dial in the same room
+	OriginApp       = "app"       // neither: the Hue app, a Hue automation or a voice assistant
+)
+
+// Sources attributes light state changes to whoever caused them, so Loxone can
+// treat manual overrides differently: a gateway command (OriginLoxone), a Hue
+// accessory pressed in the same room shortly before (OriginAccessory), or else
+// the Hue app (OriginApp).
+type Sources struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pressed map[string]time.Time // key: room id, value: last accessory input
+}
+
+// NewSources returns a tracker that attributes changes within window of an
+// accessory input; zero means 3s.
+func NewSources(window time.Duration) *Sources {
+	if window <= 0 {
+		window = 3 * time.Second
+	}
+	return &Sources{window: window, now: time.Now, pressed: make(map[string]time.Time)}
+}
+
+// Pressed records accessory input in room.
+func (s *Sources) Pressed(room string) {
+	if s == nil || room == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pressed[room] = s.now()
+}
+
+// Origin returns the origin of a change in room; echo is whether it follows a
+// gateway command.
+func (s *Sources) Origin(room string, echo bool) string {
+	if echo {
+		return OriginLoxone
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.pressed[room]; ok && s.now().Sub(t) <= s.window {
+		return OriginAccessory
+	}
+	return OriginApp
+}
+
+// attributed reports whether messages of type t carry a light state change.
+func attributed(t resource.Type) bool {
+	return t == resource.TypeGroupedLight || t == resource.TypeLight || t == resource.TypeScene
+}
+
+// lightRoom returns the room or zone a light state message belongs to.
+func lightRoom(inv *Inventory, msg Message) string {
+	id := string(msg.ID)
+	if msg.Type == resource.TypeScene {
+		if s, ok := inv.Scene(id); ok {
+			return s.GroupID
+		}
+	}
+	if room := inv.GroupOwner(id); room != "" {
+		return room
+	}
+	return inv.RoomID(id)
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSources(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSources(2 * time.Second)
+	s.now = func() time.Time { return now }
+	s.Pressed("kitchen")
+
+	tests := []struct {
+		name  string
+		room  string
+		echo  bool
+		after time.Duration
+		want  string
+	}{
+		{name: "command", room: "kitchen", echo: true, want: OriginLoxone},
+		{name: "accessory", room: "kitchen", want: OriginAccessory},
+		{name: "other room", room: "hall", want: OriginApp},
+		{name: "accessory expired", room: "kitchen", after: 3 * time.Second, want: OriginApp},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sources{window: s.window, pressed: s.pressed, now: func() time.Time { return now.Add(tt.after) }}
+			if got := s.Origin(tt.room, tt.echo); got != tt.want {
+				t.Errorf("Origin(%q, %v) = %q, want %q", tt.room, tt.echo, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Stale modes: what happens to events the bridge created longer ago than the
+// threshold, e.g. a burst replayed after a reconnect.
+const (
+	StaleOff  = "off"  // forward as any other event
+	StaleMark = "mark" // forward with stale=1
+	StaleDrop = "drop" // drop, except critical types, which are marked
+)
+
+type staleKey struct{}
+
+// markStale returns ctx marked as carrying a stale event if created is older
+// than the threshold.
+func (d *Dispatcher) markStale(ctx context.Context, created time.Time) context.Context {
+	if d.staleMode == StaleOff || d.staleMode == "" || created.IsZero() || time.Since(created) <= d.staleAfter {
+		return ctx
+	}
+	return context.WithValue(ctx, staleKey{}, created)
+}
+
+// stale reports whether ctx carries a stale event.
+func stale(ctx context.Context) bool {
+	_, ok := ctx.Value(staleKey{}).(time.Time)
+	return ok
+}
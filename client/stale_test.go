@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type messageSink struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+func (s *messageSink) Write(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, msg)
+}
+
+func TestStaleEvents(t *testing.T) {
+	load := func(name string, created time.Time) []EventContainer {
+		raw, err := os.ReadFile(filepath.Join("testdata", "events", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var containers []EventContainer
+		if err := json.Unmarshal(raw, &containers); err != nil {
+			t.Fatal(err)
+		}
+		for i := range containers {
+			containers[i].CreationTime = created
+		}
+		return containers
+	}
+
+	tests := []struct {
+		name  string
+		mode  string
+		file  string
+		age   time.Duration
+		want  int // messages forwarded
+		stale bool
+	}{
+		{name: "fresh", mode: StaleDrop, file: "motion.json", age: time.Second, want: 1},
+		{name: "off", mode: StaleOff, file: "motion.json", age: time.Hour, want: 1},
+		{name: "mark", mode: StaleMark, file: "motion.json", age: time.Hour, want: 1, stale: true},
+		{name: "drop", mode: StaleDrop, file: "motion.json", age: time.Hour, want: 0},
+		{name: "drop critical", mode: StaleDrop, file: "contact.json", age: time.Hour, want: 1, stale: true},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sink := &messageSink{}
+			e := goldenStreamer(t, sink)
+			e.staleMode = tt.mode
+			if err := e.handle(context.Background(), load(tt.file, time.Now().Add(-tt.age))); err != nil {
+				t.Fatal(err)
+			}
+			if len(sink.msgs) != tt.want {
+				t.Fatalf("forwarded %d messages, want %d", len(sink.msgs), tt.want)
+			}
+			for _, m := range sink.msgs {
+				if m.Stale != tt.stale {
+					t.Errorf("%s stale = %v, want %v", m.Path, m.Stale, tt.stale)
+				}
+			}
+		})
+	}
+}
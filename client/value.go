@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// valueService describes a sensor service reporting a single number, shaped
+// like the bridge's own temperature service:
+//
+//	{"type": "<t>", "<t>": {"<t>_report": {"<t>": 45.2, "changed": "..."}}}
+//
+// Third-party Zigbee sensors exposed by the bridge use this shape for values
+// Hue has no dedicated resource for; supporting another one only takes an
+// entry in valueServices.
+type valueService struct {
+	Metric resource.Metric // forwarded as /sensor/<owner>/<metric>
+	Prec   int             // decimals sent to Loxone
+}
+
+var valueServices = map[resource.Type]valueService{
+	resource.TypeRelativeHumidity: {Metric: resource.MetricHumidity, Prec: 1},
+}
+
+// ValueEvent is an update of one of the valueServices.
+type ValueEvent struct {
+	*GenericEvent
+	Value *float64 `json:"value,omitempty"` // nil if the update carries no report
+}
+
+func (e *ValueEvent) ResourceType() resource.Type { return e.Type }
+
+// decodeValue decodes a resource of one of the valueServices.
+func decodeValue(b []byte, t resource.Type) (*ValueEvent, error) {
+	var ev ValueEvent
+	if err := json.Unmarshal(b, &ev.GenericEvent); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	service, ok := body[string(t)]
+	if !ok {
+		return &ev, nil
+	}
+	var fields map[string]json.RawMessage // also holds <t>_valid and the deprecated plain value
+	if err := json.Unmarshal(service, &fields); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	report, ok := fields[string(t)+"_report"]
+	if !ok {
+		return &ev, nil
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(report, &values); err != nil {
+		return nil, fmt.Errorf("%s report: %w", t, err)
+	}
+	raw, ok := values[string(t)]
+	if !ok {
+		return &ev, nil
+	}
+	var v float64
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("%s report: %w", t, err)
+	}
+	ev.Value = &v
+	return &ev, nil
+}
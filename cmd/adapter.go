@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/hue"
+	"github.com/samvdb/loxone-philips-hue/udp"
+	"github.com/spf13/viper"
+)
+
+// newHueAdapter builds the command adapter with the configured brightness curves,
+// dim floors, transitions, bool encodings and composite channels; the gateway and apply-file share it.
+func newHueAdapter(home *bridge.Home, poller *client.Poller, bools *udp.Bools) (*hue.Adapter, *curve.Curves, error) {
+	adapter, err := hue.NewAdapter(home, poller, slog.Default())
+	if err != nil {
+		return nil, nil, fmt.Errorf("hue adapter: %w", err)
+	}
+	// e.g. {"brightness_curves": {"<grouped_light id>": "perceptual"}}
+	curves, err := curve.New(flagBrightnessCurve, viper.GetStringMapString("brightness_curves"))
+	if err != nil {
+		return nil, nil, err
+	}
+	// e.g. {"min_dims": {"<grouped_light id>": "5:off"}}
+	floors, err := curve.NewFloors(flagMinDim, viper.GetStringMapString("min_dims"))
+	if err != nil {
+		return nil, nil, err
+	}
+	adapter.UseCurves(curves, floors)
+	// e.g. {"transitions": {"<room, zone or grouped_light id>": "800ms"}}
+	transitions, err := hue.NewTransitions(flagTransition, viper.GetStringMapString("transitions"))
+	if err != nil {
+		return nil, nil, err
+	}
+	adapter.UseTransitions(transitions)
+	adapter.UseBools(bools)
+	// e.g. {"composites": {"living_all": [{"target": "grouped_light/<id>"}, {"target": "light/<id>", "scale": 0.8}]}}
+	var members map[string][]hue.CompositeMember
+	if err := viper.UnmarshalKey("composites", &members); err != nil {
+		return nil, nil, fmt.Errorf("composites: %w", err)
+	}
+	composites, err := hue.NewComposites(members)
+	if err != nil {
+		return nil, nil, err
+	}
+	adapter.UseComposites(composites)
+	return adapter, curves, nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/udp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var flagApplyDryRun bool
+
+var applyCmd = &cobra.Command{
+	Use:   "apply-file <file.csv|file.json>",
+	Short: "Apply a file of commands to the bridge, e.g. powerup behaviors and default scenes when commissioning",
+	Long: `apply-file reads commands and applies them one by one with the same adapter
+the gateway uses for Loxone commands (curves, dim floors, transitions).
+
+CSV has the columns resource, action, value and an optional transition; a
+header row and lines starting with # are skipped:
+
+  resource,action,value,transition
+  grouped_light/<id>,on,true,
+  grouped_light/<id>,dimmable,40,2s
+  scene/<id>,on,true,
+  light/<id>,put,"{""powerup"": {""preset"": ""safety""}}",
+
+JSON is a list of the same fields; value may be a string or, for put, an object:
+
+  [{"resource": "light/<id>", "action": "put", "value": {"powerup": {"preset": "safety"}}}]
+
+"put" sends value as a raw CLIP v2 body to any resource type. Every line is
+validated before the first command is sent.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rows, err := readApplyFile(args[0])
+		if err != nil {
+			return err
+		}
+		cmds := make([]udp.Command, 0, len(rows))
+		for _, row := range rows {
+			c, err := row.command()
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", args[0], row.Line, err)
+			}
+			cmds = append(cmds, c)
+		}
+		if flagApplyDryRun {
+			for _, c := range cmds {
+				fmt.Printf("%s %s\n", c.Key(), c.Value)
+			}
+			return nil
+		}
+
+		if flagPhilipsHueIP == "" && flagPhilipsHueHost == "" && flagBridgeID == "" {
+			return fmt.Errorf("apply-file requires --philips-hue-ip (or --philips-hue-host / --philips-hue-bridge-id)")
+		}
+		ctx := cmd.Context()
+		addr, err := bridgeAddress(ctx)
+		if err != nil {
+			return err
+		}
+		home, err := bridge.NewHome(addr, bridge.NewKeys(flagPhilipsHueApiKey, flagPhilipsHueApiKey2))
+		if err != nil {
+			return err
+		}
+		poller, err := client.NewPoller(ctx, home)
+		if err != nil {
+			return err
+		}
+		poller.SetNameOverrides(viper.GetStringMapString("names"))
+		refreshCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err = poller.Refresh(refreshCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("load inventory: %w", err)
+		}
+		bools, err := udp.NewBools(flagBoolEncoding, viper.GetStringMapString("bool_encodings"))
+		if err != nil {
+			return err
+		}
+		adapter, _, err := newHueAdapter(home, poller, bools)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for i, c := range cmds {
+			applyCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+			err := adapter.Apply(applyCtx, c)
+			cancel()
+			if err != nil {
+				failed++
+				fmt.Printf("line %d: %s failed: %v\n", rows[i].Line, c.Key(), err)
+				continue
+			}
+			fmt.Printf("line %d: %s ok\n", rows[i].Line, c.Key())
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d commands failed", failed, len(cmds))
+		}
+		return nil
+	},
+}
+
+func init() {
+	applyCmd.Flags().BoolVar(&flagApplyDryRun, "dry-run", false, "Validate the file and print the commands without sending them")
+	rootCmd.AddCommand(applyCmd)
+}
+
+// applyRow is one command of an apply-file.
+type applyRow struct {
+	Resource   string          `json:"resource"` // "<rtype>/<id>"
+	Action     string          `json:"action"`
+	Value      json.RawMessage `json:"value"`
+	Transition string          `json:"transition"`
+	Line       int             `json:"-"` // 1-based line (CSV) or entry (JSON)
+}
+
+// command validates the row like a Loxone command; "put" becomes a raw command.
+func (r applyRow) command() (udp.Command, error) {
+	rtype, id, ok := strings.Cut(r.Resource, "/")
+	if !ok || rtype == "" || id == "" {
+		return udp.Command{}, fmt.Errorf("invalid resource %q: expected <rtype>/<id>", r.Resource)
+	}
+	value := string(r.Value)
+	var s string
+	if json.Unmarshal(r.Value, &s) == nil {
+		value = s
+	}
+	if r.Action == "put" {
+		return udp.NewRawCommand(rtype, id, []byte(value))
+	}
+	return udp.NewCommand(rtype, id, r.Action, value, r.Transition)
+}
+
+// readApplyFile reads a CSV or, by extension, JSON apply-file.
+func readApplyFile(path string) ([]applyRow, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var rows []applyRow
+		if err := json.Unmarshal(b, &rows); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i := range rows {
+			rows[i].Line = i + 1
+		}
+		return rows, nil
+	}
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var rows []applyRow
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		line, _ := r.FieldPos(0)
+		if len(rec) < 3 || len(rec) > 4 {
+			return nil, fmt.Errorf("%s:%d: expected resource,action,value[,transition]", path, line)
+		}
+		if strings.EqualFold(rec[0], "resource") {
+			continue // header
+		}
+		value, _ := json.Marshal(rec[2])
+		row := applyRow{Resource: rec[0], Action: rec[1], Value: value, Line: line}
+		if len(rec) == 4 {
+			row.Transition = rec[3]
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+)
+
+// batteryLevels polls the battery level of every battery powered device, keyed
+// by device id like the device_power events.
+func batteryLevels(home *bridge.Home) func(ctx context.Context) (map[string]float64, error) {
+	return func(ctx context.Context) (map[string]float64, error) {
+		powers, err := home.GetDevicePowers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		levels := make(map[string]float64, len(powers))
+		for _, p := range powers {
+			if p.Owner == nil || p.Owner.Rid == nil || p.PowerState == nil || p.PowerState.BatteryLevel == nil {
+				continue
+			}
+			levels[*p.Owner.Rid] = float64(*p.PowerState.BatteryLevel)
+		}
+		return levels, nil
+	}
+}
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagBehaviorScript   string
+	flagBehaviorName     string
+	flagBehaviorConfig   string
+	flagBehaviorDisabled bool
+	flagBehaviorEnable   bool
+	flagBehaviorDisable  bool
+	flagBehaviorMotion   string
+	flagBehaviorScene    string
+	flagBehaviorOffAfter time.Duration
+)
+
+var behaviorsCmd = &cobra.Command{
+	Use:   "behaviors",
+	Short: "List and edit the automations the bridge runs by itself, e.g. motion sensors recalling a scene",
+	Long: `behaviors manages the bridge's behavior instances. Unlike Loxone logic they keep
+working while the gateway or the Miniserver is down, which makes them a good
+fallback for motion lighting.
+
+A configuration is easiest to start from one made in the Hue app:
+
+  behaviors show <id> > hall.json
+  behaviors create --script <script> --name "Hall fallback" --config hall.json
+
+A motion sensor recalling a scene can also be created without one:
+
+  behaviors create --script <motion script> --name "Hall fallback" \
+    --motion <sensor device id> --scene <scene id> --off-after 5m
+
+The same operations are available on /api/behaviors when --api-listen is set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listBehaviors(cmd.Context())
+	},
+}
+
+var behaviorsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List behavior instances",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listBehaviors(cmd.Context())
+	},
+}
+
+var behaviorsScriptsCmd = &cobra.Command{
+	Use:   "scripts",
+	Short: "List the scripts behaviors can be created from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, home, cancel, err := behaviorsHome(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		scripts, err := home.GetBehaviorScripts(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCATEGORY\tDESCRIPTION")
+		for _, s := range scripts {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.Metadata.Name, s.Metadata.Category, s.Description)
+		}
+		return w.Flush()
+	},
+}
+
+var behaviorsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Print the configuration of a behavior instance as JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, home, cancel, err := behaviorsHome(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		b, err := findBehavior(ctx, home, args[0])
+		if err != nil {
+			return err
+		}
+		var out any
+		if err := json.Unmarshal(b.Configuration, &out); err != nil {
+			return fmt.Errorf("behavior %s: %w", b.ID, err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	},
+}
+
+var behaviorsCreateCmd = &cobra.Command{
+	Use:   "create --script <id|name> --name <name> (--config <file.json|-> | --motion <device> --scene <scene>)",
+	Short: "Create a behavior instance",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		motion := flagBehaviorMotion != "" || flagBehaviorScene != ""
+		switch {
+		case flagBehaviorScript == "" || flagBehaviorName == "":
+			return fmt.Errorf("create requires --script and --name")
+		case motion && flagBehaviorConfig != "":
+			return fmt.Errorf("--config and --motion/--scene are mutually exclusive")
+		case motion && (flagBehaviorMotion == "" || flagBehaviorScene == ""):
+			return fmt.Errorf("--motion and --scene go together")
+		case !motion && flagBehaviorConfig == "":
+			return fmt.Errorf("create requires --config or --motion and --scene")
+		}
+		ctx, home, cancel, err := behaviorsHome(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		var config json.RawMessage
+		if motion {
+			config, err = motionSceneConfig(ctx, home)
+		} else {
+			config, err = readBehaviorConfig(flagBehaviorConfig)
+		}
+		if err != nil {
+			return err
+		}
+		script, err := findScript(ctx, home, flagBehaviorScript)
+		if err != nil {
+			return err
+		}
+		id, err := home.CreateBehavior(ctx, bridge.BehaviorInstance{
+			ScriptID:      script,
+			Enabled:       !flagBehaviorDisabled,
+			Metadata:      bridge.BehaviorMetadata{Name: flagBehaviorName},
+			Configuration: config,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+		return nil
+	},
+}
+
+var behaviorsUpdateCmd = &cobra.Command{
+	Use:   "update <id> [--name <name>] [--config <file.json|->] [--enable|--disable]",
+	Short: "Rename, reconfigure, enable or disable a behavior instance",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var u bridge.BehaviorUpdate
+		switch {
+		case flagBehaviorEnable && flagBehaviorDisable:
+			return fmt.Errorf("--enable and --disable are mutually exclusive")
+		case flagBehaviorEnable, flagBehaviorDisable:
+			u.Enabled = &flagBehaviorEnable
+		}
+		if flagBehaviorName != "" {
+			u.Metadata = &bridge.BehaviorMetadata{Name: flagBehaviorName}
+		}
+		if flagBehaviorConfig != "" {
+			config, err := readBehaviorConfig(flagBehaviorConfig)
+			if err != nil {
+				return err
+			}
+			u.Configuration = config
+		}
+		if u.Enabled == nil && u.Metadata == nil && u.Configuration == nil {
+			return fmt.Errorf("nothing to update: set --name, --config, --enable or --disable")
+		}
+		ctx, home, cancel, err := behaviorsHome(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		b, err := findBehavior(ctx, home, args[0])
+		if err != nil {
+			return err
+		}
+		return home.UpdateBehavior(ctx, b.ID, u)
+	},
+}
+
+var behaviorsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete a behavior instance",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, home, cancel, err := behaviorsHome(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		b, err := findBehavior(ctx, home, args[0])
+		if err != nil {
+			return err
+		}
+		return home.DeleteBehavior(ctx, b.ID)
+	},
+}
+
+func init() {
+	behaviorsCreateCmd.Flags().StringVar(&flagBehaviorScript, "script", "", "Script id or name, see behaviors scripts")
+	behaviorsCreateCmd.Flags().StringVar(&flagBehaviorName, "name", "", "Name shown in the Hue app")
+	behaviorsCreateCmd.Flags().StringVar(&flagBehaviorConfig, "config", "", "JSON file with the configuration, - for stdin")
+	behaviorsCreateCmd.Flags().BoolVar(&flagBehaviorDisabled, "disabled", false, "Create the behavior disabled")
+	behaviorsCreateCmd.Flags().StringVar(&flagBehaviorMotion, "motion", "", "Device id of a motion sensor; with --scene instead of --config")
+	behaviorsCreateCmd.Flags().StringVar(&flagBehaviorScene, "scene", "", "Scene id the motion sensor recalls")
+	behaviorsCreateCmd.Flags().DurationVar(&flagBehaviorOffAfter, "off-after", 0, "Turn the scene's room or zone off after this long without motion; 0 leaves it on")
+	behaviorsUpdateCmd.Flags().StringVar(&flagBehaviorName, "name", "", "New name")
+	behaviorsUpdateCmd.Flags().StringVar(&flagBehaviorConfig, "config", "", "JSON file with the new configuration, - for stdin")
+	behaviorsUpdateCmd.Flags().BoolVar(&flagBehaviorEnable, "enable", false, "Enable the behavior")
+	behaviorsUpdateCmd.Flags().BoolVar(&flagBehaviorDisable, "disable", false, "Disable the behavior")
+
+	behaviorsCmd.AddCommand(behaviorsListCmd, behaviorsScriptsCmd, behaviorsShowCmd, behaviorsCreateCmd, behaviorsUpdateCmd, behaviorsDeleteCmd)
+	rootCmd.AddCommand(behaviorsCmd)
+}
+
+// behaviorsHome connects to the bridge for one behaviors operation.
+func behaviorsHome(parent context.Context) (context.Context, *bridge.Home, context.CancelFunc, error) {
+	if flagPhilipsHueIP == "" && flagPhilipsHueHost == "" && flagBridgeID == "" {
+		return nil, nil, nil, fmt.Errorf("behaviors requires --philips-hue-ip (or --philips-hue-host / --philips-hue-bridge-id)")
+	}
+	ctx, cancel := context.WithTimeout(parent, 30*time.Second)
+	addr, err := bridgeAddress(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	home, err := bridge.NewHome(addr, bridge.NewKeys(flagPhilipsHueApiKey, flagPhilipsHueApiKey2))
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return ctx, home, cancel, nil
+}
+
+func listBehaviors(parent context.Context) error {
+	ctx, home, cancel, err := behaviorsHome(parent)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	list, err := home.GetBehaviors(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tENABLED\tSTATUS\tSCRIPT")
+	for _, b := range list {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", b.ID, b.Metadata.Name, b.Enabled, b.Status, b.ScriptID)
+	}
+	return w.Flush()
+}
+
+// findBehavior returns the behavior instance with the given id or name.
+func findBehavior(ctx context.Context, home *bridge.Home, ref string) (bridge.BehaviorInstance, error) {
+	list, err := home.GetBehaviors(ctx)
+	if err != nil {
+		return bridge.BehaviorInstance{}, err
+	}
+	var byName []bridge.BehaviorInstance
+	for _, b := range list {
+		if b.ID == ref {
+			return b, nil
+		}
+		if strings.EqualFold(b.Metadata.Name, ref) {
+			byName = append(byName, b)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return bridge.BehaviorInstance{}, fmt.Errorf("no behavior %q", ref)
+	case 1:
+		return byName[0], nil
+	}
+	return bridge.BehaviorInstance{}, fmt.Errorf("%d behaviors are named %q; use the id", len(byName), ref)
+}
+
+// findScript resolves a script id or name to its id.
+func findScript(ctx context.Context, home *bridge.Home, ref string) (string, error) {
+	scripts, err := home.GetBehaviorScripts(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range scripts {
+		if s.ID == ref || strings.EqualFold(s.Metadata.Name, ref) {
+			return s.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no behavior script %q (see behaviors scripts)", ref)
+}
+
+// motionSceneConfig builds the configuration of --motion and --scene; the
+// scene's room or zone is looked up on the bridge.
+func motionSceneConfig(ctx context.Context, home *bridge.Home) (json.RawMessage, error) {
+	scene, err := home.GetScene(ctx, flagBehaviorScene)
+	if err != nil {
+		return nil, fmt.Errorf("scene %s: %w", flagBehaviorScene, err)
+	}
+	if scene == nil || scene.Group == nil || scene.Group.Rid == nil || scene.Group.Rtype == nil {
+		return nil, fmt.Errorf("scene %s not found", flagBehaviorScene)
+	}
+	return bridge.MotionScene{
+		Sensor:   flagBehaviorMotion,
+		Group:    bridge.ResourceRef{Rid: *scene.Group.Rid, Rtype: string(*scene.Group.Rtype)},
+		Scene:    flagBehaviorScene,
+		OffAfter: flagBehaviorOffAfter,
+	}.Configuration()
+}
+
+func readBehaviorConfig(file string) (json.RawMessage, error) {
+	var b []byte
+	var err error
+	if file == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("%s: configuration is not valid JSON", file)
+	}
+	return json.RawMessage(b), nil
+}
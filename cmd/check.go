@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/script"
+	"github.com/spf13/viper"
+)
+
+// configReference is a bridge resource id the configuration depends on.
+type configReference struct {
+	Source string // config key that mentions it
+	ID     string
+}
+
+var resourceIDPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// configReferences collects every resource id mentioned in the configuration.
+func configReferences() []configReference {
+	var refs []configReference
+	for id := range viper.GetStringMapString("names") {
+		refs = append(refs, configReference{Source: "names", ID: id})
+	}
+	for _, id := range flagForward {
+		refs = append(refs, configReference{Source: "forward", ID: id})
+	}
+	for _, x := range flagMotionExclude {
+		if resourceIDPattern.MatchString(x) {
+			refs = append(refs, configReference{Source: "grouped-motion-exclude", ID: x})
+		}
+	}
+	var rules []script.Rule
+	if err := viper.UnmarshalKey("scripts", &rules); err == nil {
+		for _, r := range rules {
+			for _, id := range resourceIDPattern.FindAllString(r.Path, -1) {
+				refs = append(refs, configReference{Source: "scripts " + r.File, ID: id})
+			}
+		}
+	}
+	return refs
+}
+
+// checkConfig waits for the first inventory and reports configured resources the
+// bridge does not know (deleted, re-paired, ...) and outgoing paths shared by
+// several resources, so they are noticed at startup instead of when Loxone shows
+// a wrong value. With --strict-paths a path collision stops the gateway.
+func checkConfig(ctx context.Context, poller *client.Poller, state *gateway.State) error {
+	refs := configReferences()
+	hierarchical := flagPathStyle == client.PathStyleHierarchical
+	if len(refs) == 0 && !hierarchical {
+		return nil
+	}
+	if err := poller.WaitReady(ctx); err != nil {
+		return nil
+	}
+	inv := poller.Snapshot()
+	if names, scenes := inv.Len(); names+scenes == 0 {
+		slog.Warn("inventory empty; skipping configuration check")
+		return nil
+	}
+
+	var issues []string
+	for _, ref := range refs {
+		if !inv.Has(ref.ID) {
+			issues = append(issues, fmt.Sprintf("%s: %s not found on the bridge", ref.Source, ref.ID))
+		}
+	}
+	for _, issue := range issues {
+		slog.Error("configuration references a missing resource", "issue", issue)
+	}
+	var collisions []string
+	if hierarchical {
+		collisions = client.NewHierarchy(poller, viper.GetStringMapString("path_levels"), viper.GetStringMapString("loxone_rooms")).Collisions()
+	}
+	for _, c := range collisions {
+		slog.Error("several resources map to the same outgoing path; rename one of them", "collision", c)
+	}
+	issues = append(issues, collisions...)
+	if len(issues) == 0 {
+		slog.Info("configuration matches bridge inventory", "references", len(refs))
+	}
+	state.SetConfigIssues(issues)
+
+	if flagStrictPaths && len(collisions) > 0 {
+		return fmt.Errorf("%d duplicate outgoing paths (--strict-paths)", len(collisions))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var flagEnsureDryRun bool
+
+// ensureCmd creates the zones and scenes declared under "ensure" in the config
+// file (see bridge.EnsureConfig) that the bridge does not have yet.
+var ensureCmd = &cobra.Command{
+	Use:   "ensure",
+	Short: "Create the zones and scenes declared in the config that are missing on the bridge",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagPhilipsHueIP == "" && flagPhilipsHueHost == "" && flagBridgeID == "" {
+			return fmt.Errorf("ensure requires --philips-hue-ip (or --philips-hue-host / --philips-hue-bridge-id)")
+		}
+		var cfg bridge.EnsureConfig
+		if err := viper.UnmarshalKey("ensure", &cfg); err != nil {
+			return fmt.Errorf("ensure config: %w", err)
+		}
+		if len(cfg.Zones)+len(cfg.Scenes) == 0 {
+			return fmt.Errorf("no zones or scenes declared under \"ensure\" in the config file")
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		addr, err := bridgeAddress(ctx)
+		if err != nil {
+			return err
+		}
+		home, err := bridge.NewHome(addr, bridge.NewKeys(flagPhilipsHueApiKey, flagPhilipsHueApiKey2))
+		if err != nil {
+			return err
+		}
+		steps, ensureErr := home.Ensure(ctx, cfg, flagEnsureDryRun)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tNAME\tGROUP\tID\tRESULT\t")
+		for _, s := range steps {
+			result := "created"
+			switch {
+			case s.Exists:
+				result = "exists"
+			case flagEnsureDryRun:
+				result = "would create"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", s.Kind, s.Name, s.Group, s.ID, result)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return ensureErr
+	},
+}
+
+func init() {
+	ensureCmd.Flags().BoolVar(&flagEnsureDryRun, "dry-run", false, "Only show what would be created")
+	rootCmd.AddCommand(ensureCmd)
+}
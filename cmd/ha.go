@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/samvdb/loxone-philips-hue/ha"
+)
+
+// newElector returns the HA leader elector, or nil when --ha-peers is not set and
+// this instance always leads.
+func newElector(onChange func(leader bool)) (*ha.Elector, error) {
+	if len(flagHAPeers) == 0 {
+		return nil, nil
+	}
+	id := flagHAID
+	if id == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		id = host
+	}
+	return ha.New(ha.Config{
+		ID:       id,
+		Listen:   flagHAListen,
+		Peers:    flagHAPeers,
+		Interval: flagHAInterval,
+		OnChange: onChange,
+		Logger:   slog.Default(),
+	})
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagHistorySince  string
+	flagHistoryUntil  string
+	flagHistoryTarget string
+	flagHistorySource string
+	flagHistoryLimit  int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the commands applied to the bridge, with their source and outcome",
+	Long: `history lists audited commands, e.g. who turned the lights off at 3am:
+
+  history --since 12h --target Kitchen
+
+It reads --audit-file when set, otherwise it asks the gateway running with
+--api-listen on this host, which only remembers the most recent commands.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := map[string]string{
+			"since":  flagHistorySince,
+			"until":  flagHistoryUntil,
+			"target": flagHistoryTarget,
+			"source": flagHistorySource,
+		}
+		if flagHistoryLimit > 0 {
+			params["limit"] = fmt.Sprint(flagHistoryLimit)
+		}
+
+		var recs []gateway.AuditRecord
+		switch {
+		case flagAuditFile != "":
+			q, err := gateway.ParseAuditQuery(func(key string) string { return params[key] }, time.Now())
+			if err != nil {
+				return err
+			}
+			if recs, err = gateway.ReadAudit(flagAuditFile, q); err != nil {
+				return err
+			}
+		case flagAPIListen != "":
+			var err error
+			if recs, err = fetchHistory(cmd.Context(), params); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("history requires --audit-file or --api-listen")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tSOURCE\tTARGET\tCOMMAND\tBEFORE\tAFTER\tOUTCOME")
+		for _, rec := range recs {
+			target := rec.Domain + "/" + rec.ID
+			if rec.Name != "" {
+				target += " (" + rec.Name + ")"
+			}
+			outcome := rec.Outcome
+			if rec.Error != "" {
+				outcome += ": " + rec.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s\t%s\t%s\n", rec.Time.Local().Format(time.DateTime), rec.Source, target,
+				rec.Action, rec.Value, strings.Join(rec.Before, ", "), strings.Join(rec.After, ", "), outcome)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&flagHistorySince, "since", "", "Only commands after this time: RFC 3339 or a duration back from now, e.g. 12h")
+	historyCmd.Flags().StringVar(&flagHistoryUntil, "until", "", "Only commands before this time: RFC 3339 or a duration back from now")
+	historyCmd.Flags().StringVar(&flagHistoryTarget, "target", "", "Only commands for this resource id or name")
+	historyCmd.Flags().StringVar(&flagHistorySource, "source", "", "Only commands from sources starting with this, e.g. udp:192.168.1.77, api: or failsafe")
+	historyCmd.Flags().IntVar(&flagHistoryLimit, "limit", 0, "Show only the newest n commands (0 shows all)")
+	rootCmd.AddCommand(historyCmd)
+}
+
+// fetchHistory queries GET /api/history of the gateway on this host.
+func fetchHistory(ctx context.Context, params map[string]string) ([]gateway.AuditRecord, error) {
+	u, err := localAPI("/api/history")
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	for k, v := range params {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gateway not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway history: %s", resp.Status)
+	}
+	var recs []gateway.AuditRecord
+	if err := json.NewDecoder(resp.Body).Decode(&recs); err != nil {
+		return nil, fmt.Errorf("gateway history: %w", err)
+	}
+	return recs, nil
+}
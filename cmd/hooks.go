@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/script"
+	"github.com/samvdb/loxone-philips-hue/udp"
+	"github.com/spf13/viper"
+)
+
+// buildHooks returns the configured message hooks in pipeline order; scripts
+// send their commands to handler, the full command chain so read-only mode
+// and HA standby hold for them. The gateway and selftest share it.
+func buildHooks(poller *client.Poller, handler udp.CommandHandler) ([]client.MessageHook, error) {
+	// e.g. {"scripts": [{"type": "temperature", "file": "scripts/round.star"}]}
+	var hooks []client.MessageHook
+	var scriptRules []script.Rule
+	if err := viper.UnmarshalKey("scripts", &scriptRules); err != nil {
+		return nil, fmt.Errorf("scripts: %w", err)
+	}
+	if len(scriptRules) > 0 {
+		engine, err := script.New(script.Config{
+			Rules:   scriptRules,
+			Handler: handler,
+			Logger:  slog.Default(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, engine)
+	}
+
+	// before scale, which then also applies to the averages
+	if len(flagRoomAverages) > 0 {
+		cfg, err := averageConfig()
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, client.NewAverages(poller, cfg))
+	}
+
+	// e.g. {"scale": {"temperature": "10"}} for integer-only Loxone inputs
+	scale, err := client.NewScale(viper.GetStringMapString("scale"))
+	if err != nil {
+		return nil, err
+	}
+	hooks = append(hooks, scale)
+
+	// runs after the scripts, which keep matching id paths;
+	// e.g. {"path_levels": {"living room": "gf", "attic": "2f"}}
+	// and {"loxone_rooms": {"living room": "Woonkamer", "<room id>": "Bureau"}}
+	if flagPathStyle == client.PathStyleHierarchical {
+		hooks = append(hooks, client.NewHierarchy(poller, viper.GetStringMapString("path_levels"), viper.GetStringMapString("loxone_rooms")))
+	}
+
+	// e.g. {"routes": [{"archetype": "plug", "prefix": "/plug"}, {"room": "garage", "prefix": "/garage"}]}
+	var routes []client.Route
+	if err := viper.UnmarshalKey("routes", &routes); err != nil {
+		return nil, fmt.Errorf("routes: %w", err)
+	}
+	if len(routes) > 0 {
+		router, err := client.NewRouter(poller, routes)
+		if err != nil {
+			return nil, fmt.Errorf("routes: %w", err)
+		}
+		hooks = append(hooks, router)
+	}
+	return hooks, nil
+}
+
+// averageConfig reads --room-averages and, e.g.
+// {"average_weights": {"radiator sensor": "0"}, "average_zones": {"upstairs": ["bedroom", "bath"]}}
+func averageConfig() (client.AverageConfig, error) {
+	cfg := client.AverageConfig{
+		Zones:   viper.GetStringMapStringSlice("average_zones"),
+		Outlier: flagAverageOutlier,
+	}
+	if flagAverageOutlier <= 0 {
+		return cfg, fmt.Errorf("invalid --average-outlier %v: expected a positive number", flagAverageOutlier)
+	}
+	for _, ch := range flagRoomAverages {
+		m, err := resource.ParseMetric(ch)
+		if err != nil {
+			return cfg, fmt.Errorf("--room-averages: %w", err)
+		}
+		cfg.Channels = append(cfg.Channels, m)
+	}
+	weights, err := client.ParseWeights(viper.GetStringMapString("average_weights"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Weights = weights
+	return cfg, nil
+}
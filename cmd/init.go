@@ -0,0 +1,592 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/loxone"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagInitOut         string
+	flagInitTemplateDir string
+	flagInitForce       bool
+)
+
+// initCmd walks a new installation through discovery, pairing and the Loxone
+// side, and writes everything the gateway and Loxone Config need.
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Discover and pair the bridge, then write a config file and Loxone templates",
+	Long: `init asks a few questions and writes, in one go:
+
+  config.yaml       the gateway config with the bridge, its application key, the Loxone target
+                    and the picked rooms and sensors as the resources to forward
+  VIU_Hue.xml       a Virtual UDP Input template with the picked rooms, sensors and gateway status
+  VO_Hue.xml        a Virtual Output template to switch and dim the picked rooms and recall their scenes
+
+Import both templates in Loxone Config and start the gateway with --config config.yaml.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputsFile := filepath.Join(flagInitTemplateDir, "VIU_Hue.xml")
+		outputsFile := filepath.Join(flagInitTemplateDir, "VO_Hue.xml")
+		if !flagInitForce {
+			for _, f := range []string{flagInitOut, inputsFile, outputsFile} {
+				if _, err := os.Stat(f); err == nil {
+					return fmt.Errorf("%s exists; use --force to overwrite it", f)
+				}
+			}
+		}
+		ctx := cmd.Context()
+		p := newPrompter(cmd.InOrStdin(), cmd.OutOrStdout())
+
+		found, err := chooseBridge(ctx, p)
+		if err != nil {
+			return err
+		}
+		addr := bridge.NewAddress(found.IP)
+		creds, err := pairBridge(ctx, p, addr)
+		if err != nil {
+			return err
+		}
+
+		cfg := initConfigFile{bridge: found, creds: creds, mode: modeBoth}
+		if cfg.loxoneIP, err = p.ask("Loxone Miniserver IP", flagLoxoneIP); err != nil {
+			return err
+		}
+		if cfg.loxonePort, err = p.askPort("UDP port the Miniserver listens on for Hue events", flagLoxoneUdpPort); err != nil {
+			return err
+		}
+		if cfg.listenPort, err = p.askPort("UDP port the gateway listens on for Loxone commands", cfg.loxonePort); err != nil {
+			return err
+		}
+		if cfg.gatewayIP, err = p.ask("IP of this gateway as the Miniserver sees it", outboundIP(cfg.loxoneIP, cfg.loxonePort)); err != nil {
+			return err
+		}
+
+		home, err := bridge.NewHome(addr, bridge.NewKeys(creds.Username))
+		if err != nil {
+			return err
+		}
+		inv, err := loadInitInventory(ctx, home)
+		if err != nil {
+			return fmt.Errorf("read bridge inventory: %w", err)
+		}
+		rooms, err := p.pick("Rooms to add to the Loxone templates", inv.roomLabels())
+		if err != nil {
+			return err
+		}
+		sensors, err := p.pick("Sensors to add to the Loxone templates", inv.sensorLabels())
+		if err != nil {
+			return err
+		}
+		for _, i := range rooms {
+			cfg.rooms = append(cfg.rooms, inv.rooms[i])
+		}
+		for _, i := range sensors {
+			cfg.sensors = append(cfg.sensors, inv.sensors[i])
+		}
+		// leaving something out limits what the gateway forwards; picking
+		// nothing at all leaves the choice for later
+		picked := len(rooms) + len(sensors)
+		cfg.forward = picked > 0 && picked < len(inv.rooms)+len(inv.sensors)
+
+		if err := writeInitFile(flagInitOut, 0o600, cfg.write); err != nil {
+			return err
+		}
+		inputs, outputs := cfg.templates()
+		if err := writeInitFile(inputsFile, 0o644, func(w io.Writer) error {
+			return loxone.WriteInputs(w, "Hue", cfg.loxonePort, inputs)
+		}); err != nil {
+			return err
+		}
+		if err := writeInitFile(outputsFile, 0o644, func(w io.Writer) error {
+			return loxone.WriteOutputs(w, "Hue", cfg.gatewayIP, cfg.listenPort, outputs)
+		}); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(p.out, "\nWrote %s, %s (%d inputs) and %s (%d outputs).\n", flagInitOut, inputsFile, len(inputs), outputsFile, len(outputs))
+		if cfg.forward {
+			fmt.Fprintln(p.out, "The gateway forwards only the picked rooms and sensors; remove forward from the config to forward everything.")
+		} else {
+			fmt.Fprintln(p.out, "The gateway forwards every resource.")
+		}
+		fmt.Fprintln(p.out, "Next steps:")
+		fmt.Fprintf(p.out, "  1. Import %s as a Virtual UDP Input and %s as a Virtual Output template in Loxone Config.\n", inputsFile, outputsFile)
+		fmt.Fprintf(p.out, "  2. Start the gateway: loxone-philips-hue --config %s\n", flagInitOut)
+		return nil
+	},
+}
+
+func init() {
+	initCmd.Flags().StringVar(&flagInitOut, "out", "config.yaml", "Config file to write")
+	initCmd.Flags().StringVar(&flagInitTemplateDir, "template-dir", ".", "Directory for the Loxone templates VIU_Hue.xml and VO_Hue.xml")
+	initCmd.Flags().BoolVar(&flagInitForce, "force", false, "Overwrite existing files")
+	rootCmd.AddCommand(initCmd)
+}
+
+// prompter asks questions on the terminal.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewScanner(in), out: out}
+}
+
+// ask returns the answer to question, def when it is left empty.
+func (p *prompter) ask(question, def string) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return "", err
+			}
+			return "", io.ErrUnexpectedEOF
+		}
+		answer := strings.TrimSpace(p.in.Text())
+		if answer == "" {
+			answer = def
+		}
+		if answer != "" {
+			return answer, nil
+		}
+	}
+}
+
+func (p *prompter) askPort(question string, def int) (int, error) {
+	for {
+		answer, err := p.ask(question, strconv.Itoa(def))
+		if err != nil {
+			return 0, err
+		}
+		port, err := strconv.Atoi(answer)
+		if err == nil && port > 0 && port <= 65535 {
+			return port, nil
+		}
+		fmt.Fprintf(p.out, "%q is not a port number\n", answer)
+	}
+}
+
+// pick lists items and returns the indexes chosen, e.g. "all", "none" or "1,3-5".
+func (p *prompter) pick(title string, items []string) ([]int, error) {
+	if len(items) == 0 {
+		fmt.Fprintf(p.out, "%s: none found\n", title)
+		return nil, nil
+	}
+	fmt.Fprintf(p.out, "\n%s:\n", title)
+	for i, item := range items {
+		fmt.Fprintf(p.out, "  %2d. %s\n", i+1, item)
+	}
+	for {
+		answer, err := p.ask("Pick all, none or numbers like 1,3-5", "all")
+		if err != nil {
+			return nil, err
+		}
+		picked, err := parsePick(answer, len(items))
+		if err == nil {
+			return picked, nil
+		}
+		fmt.Fprintln(p.out, err)
+	}
+}
+
+// parsePick reads a pick answer for n items into sorted 0-based indexes.
+func parsePick(answer string, n int) ([]int, error) {
+	switch strings.ToLower(answer) {
+	case "all":
+		picked := make([]int, n)
+		for i := range picked {
+			picked[i] = i
+		}
+		return picked, nil
+	case "none":
+		return nil, nil
+	}
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(answer, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			to = from
+		}
+		lo, err1 := strconv.Atoi(strings.TrimSpace(from))
+		hi, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || lo < 1 || hi > n || lo > hi {
+			return nil, fmt.Errorf("%q is not a number or range between 1 and %d", part, n)
+		}
+		for i := lo; i <= hi; i++ {
+			seen[i-1] = true
+		}
+	}
+	picked := make([]int, 0, len(seen))
+	for i := range seen {
+		picked = append(picked, i)
+	}
+	sort.Ints(picked)
+	return picked, nil
+}
+
+// chooseBridge discovers the bridges on the network and lets the user pick one,
+// or type its IP when none answers.
+func chooseBridge(ctx context.Context, p *prompter) (bridge.Found, error) {
+	fmt.Fprintln(p.out, "Looking for Hue bridges...")
+	found, err := bridge.DiscoverAll(ctx, 5*time.Second)
+	if err != nil || len(found) == 0 {
+		fmt.Fprintln(p.out, "No bridge found on the network.")
+		ip, err := p.ask("Hue bridge IP", flagPhilipsHueIP)
+		return bridge.Found{IP: ip}, err
+	}
+	if len(found) == 1 {
+		fmt.Fprintf(p.out, "Found bridge %s at %s.\n", found[0].ID, found[0].IP)
+		return found[0], nil
+	}
+	labels := make([]string, len(found))
+	for i, b := range found {
+		labels[i] = b.ID + " at " + b.IP
+	}
+	fmt.Fprintln(p.out, "Found these bridges:")
+	for i, label := range labels {
+		fmt.Fprintf(p.out, "  %2d. %s\n", i+1, label)
+	}
+	for {
+		answer, err := p.ask("Bridge to use", "1")
+		if err != nil {
+			return bridge.Found{}, err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(found) {
+			return found[i-1], nil
+		}
+		fmt.Fprintf(p.out, "%q is not between 1 and %d\n", answer, len(found))
+	}
+}
+
+// pairBridge registers the gateway on the bridge, polling while the user
+// presses the link button.
+func pairBridge(ctx context.Context, p *prompter, addr *bridge.Address) (bridge.Credentials, error) {
+	const wait = 30 * time.Second
+	if _, err := p.ask("Press the link button on the bridge, then press enter", "ok"); err != nil {
+		return bridge.Credentials{}, err
+	}
+	for {
+		creds, err := waitForLinkButton(ctx, addr, wait)
+		if err == nil {
+			fmt.Fprintln(p.out, "Paired.")
+			return creds, nil
+		}
+		if !errors.Is(err, bridge.ErrLinkButton) {
+			return bridge.Credentials{}, err
+		}
+		fmt.Fprintf(p.out, "The link button was not pressed within %s.\n", wait)
+		if _, err := p.ask("Press it again, then press enter", "ok"); err != nil {
+			return bridge.Credentials{}, err
+		}
+	}
+}
+
+func waitForLinkButton(ctx context.Context, addr *bridge.Address, wait time.Duration) (bridge.Credentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		creds, err := bridge.Pair(ctx, addr, "loxone-philips-hue#gateway")
+		if !errors.Is(err, bridge.ErrLinkButton) {
+			return creds, err
+		}
+		select {
+		case <-ctx.Done():
+			return bridge.Credentials{}, bridge.ErrLinkButton
+		case <-ticker.C:
+		}
+	}
+}
+
+// outboundIP returns the local address used to reach host:port, as a default
+// for the address Loxone sends commands to. No packet is sent.
+func outboundIP(host string, port int) string {
+	conn, err := net.Dial("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if a, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return a.IP.String()
+	}
+	return ""
+}
+
+type initRoom struct {
+	id, name     string
+	groupedLight string
+	services     []openhue.ResourceIdentifier
+	scenes       []initNamed
+}
+
+type initSensor struct {
+	id, name string
+	specs    []client.PathSpec
+}
+
+type initNamed struct{ id, name string }
+
+type initInventory struct {
+	rooms   []initRoom
+	sensors []initSensor
+}
+
+// loadInitInventory reads the rooms with their scenes, and the devices with a
+// sensor service the gateway forwards, sorted by name.
+func loadInitInventory(ctx context.Context, home *bridge.Home) (initInventory, error) {
+	rooms, err := home.GetRooms(ctx)
+	if err != nil {
+		return initInventory{}, err
+	}
+	devices, err := home.GetDevices(ctx)
+	if err != nil {
+		return initInventory{}, err
+	}
+	scenes, err := home.GetScenes(ctx)
+	if err != nil {
+		return initInventory{}, err
+	}
+
+	var inv initInventory
+	byID := make(map[string]int)
+	for id, r := range rooms {
+		room := initRoom{id: id, name: id}
+		if r.Metadata != nil && r.Metadata.Name != nil {
+			room.name = *r.Metadata.Name
+		}
+		if r.Services != nil {
+			room.services = *r.Services
+			for _, s := range room.services {
+				if s.Rid != nil && s.Rtype != nil && *s.Rtype == "grouped_light" {
+					room.groupedLight = *s.Rid
+				}
+			}
+		}
+		inv.rooms = append(inv.rooms, room)
+	}
+	sort.Slice(inv.rooms, func(i, j int) bool { return inv.rooms[i].name < inv.rooms[j].name })
+	for i, r := range inv.rooms {
+		byID[r.id] = i
+	}
+	for id, s := range scenes {
+		if s.Group == nil || s.Group.Rid == nil || s.Metadata == nil || s.Metadata.Name == nil {
+			continue
+		}
+		if i, ok := byID[*s.Group.Rid]; ok {
+			inv.rooms[i].scenes = append(inv.rooms[i].scenes, initNamed{id: id, name: *s.Metadata.Name})
+		}
+	}
+	for i := range inv.rooms {
+		sort.Slice(inv.rooms[i].scenes, func(a, b int) bool { return inv.rooms[i].scenes[a].name < inv.rooms[i].scenes[b].name })
+	}
+
+	// sensor paths carry the device id, e.g. "/sensor/<id>/motion"
+	var sensorSpecs []client.PathSpec
+	for _, spec := range client.Schema(client.SchemaOptions{Events: true}) {
+		if strings.HasPrefix(spec.Path, "/sensor/<id>/") || strings.HasPrefix(spec.Path, "/contact/<id>/") {
+			sensorSpecs = append(sensorSpecs, spec)
+		}
+	}
+	for id, d := range devices {
+		if d.Services == nil {
+			continue
+		}
+		sensor := initSensor{id: id, name: id}
+		if d.Metadata != nil && d.Metadata.Name != nil {
+			sensor.name = *d.Metadata.Name
+		}
+		for _, spec := range sensorSpecs {
+			for _, s := range *d.Services {
+				if s.Rtype != nil && string(*s.Rtype) == spec.Source {
+					sensor.specs = append(sensor.specs, spec)
+					break
+				}
+			}
+		}
+		if len(sensor.specs) > 0 {
+			inv.sensors = append(inv.sensors, sensor)
+		}
+	}
+	sort.Slice(inv.sensors, func(i, j int) bool { return inv.sensors[i].name < inv.sensors[j].name })
+	return inv, nil
+}
+
+func (inv initInventory) roomLabels() []string {
+	labels := make([]string, len(inv.rooms))
+	for i, r := range inv.rooms {
+		labels[i] = fmt.Sprintf("%s (%d scenes)", r.name, len(r.scenes))
+	}
+	return labels
+}
+
+func (inv initInventory) sensorLabels() []string {
+	labels := make([]string, len(inv.sensors))
+	for i, s := range inv.sensors {
+		channels := make([]string, len(s.specs))
+		for j, spec := range s.specs {
+			channels[j] = spec.Channel
+		}
+		labels[i] = fmt.Sprintf("%s (%s)", s.name, strings.Join(channels, ", "))
+	}
+	return labels
+}
+
+// initConfigFile is what init writes.
+type initConfigFile struct {
+	bridge     bridge.Found
+	creds      bridge.Credentials
+	mode       string
+	loxoneIP   string
+	loxonePort int
+	listenPort int
+	gatewayIP  string
+	rooms      []initRoom
+	sensors    []initSensor
+	forward    bool // only the picked rooms and sensors are forwarded
+}
+
+// write writes the config as commented YAML; strings are quoted so ids and
+// keys never turn into numbers.
+func (c initConfigFile) write(w io.Writer) error {
+	q := strconv.Quote
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by loxone-philips-hue init on %s.\n", time.Now().Format(time.DateOnly))
+	fmt.Fprintln(&b, "# Every flag can be set here with underscores, e.g. --loxone-udp-port as loxone_udp_port.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "# Miniserver receiving the Hue events (Virtual UDP Input VIU_Hue.xml).")
+	fmt.Fprintf(&b, "loxone_ip: %s\n", q(c.loxoneIP))
+	fmt.Fprintf(&b, "loxone_udp_port: %d\n", c.loxonePort)
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "# Port for commands from Loxone (Virtual Output VO_Hue.xml sends to %s:%d).\n", c.gatewayIP, c.listenPort)
+	fmt.Fprintf(&b, "listen_udp_port: %d\n", c.listenPort)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "# Hue bridge. With the bridge id set the gateway finds the bridge again when its IP changes.")
+	fmt.Fprintf(&b, "philips_hue_ip: %s\n", q(c.bridge.IP))
+	if c.bridge.ID != "" {
+		fmt.Fprintf(&b, "philips_hue_bridge_id: %s\n", q(c.bridge.ID))
+	}
+	fmt.Fprintln(&b, "# Application key from pairing; keep this file private.")
+	fmt.Fprintf(&b, "philips_hue_apikey: %s\n", q(c.creds.Username))
+	if c.creds.ClientKey != "" {
+		fmt.Fprintf(&b, "# Entertainment streaming key, not used by the gateway: %s\n", c.creds.ClientKey)
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "# events (Hue -> Loxone), commands (Loxone -> Hue) or both.")
+	fmt.Fprintf(&b, "mode: %s\n", q(c.mode))
+	if len(c.rooms)+len(c.sensors) > 0 {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "# Names used in logs and for room paths; rename freely.")
+		fmt.Fprintln(&b, "names:")
+		for _, r := range c.rooms {
+			fmt.Fprintf(&b, "  %s: %s\n", q(r.id), q(r.name))
+		}
+		for _, s := range c.sensors {
+			fmt.Fprintf(&b, "  %s: %s\n", q(s.id), q(s.name))
+		}
+	}
+	if c.forward {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "# Only these rooms (with their lights, sensors and scenes) and sensors are")
+		fmt.Fprintln(&b, "# forwarded to Loxone; remove the list to forward every resource.")
+		fmt.Fprintln(&b, "forward:")
+		for _, r := range c.rooms {
+			fmt.Fprintf(&b, "  - %s # %s\n", q(r.id), r.name)
+		}
+		for _, s := range c.sensors {
+			fmt.Fprintf(&b, "  - %s # %s\n", q(s.id), s.name)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// templates returns the virtual inputs and outputs for the picked rooms and
+// sensors, plus the gateway's own status paths.
+func (c initConfigFile) templates() ([]loxone.Input, []loxone.Output) {
+	var inputs []loxone.Input
+	var outputs []loxone.Output
+	add := func(title, path string, spec client.PathSpec) {
+		inputs = append(inputs, loxone.Input{Title: title, Path: path, Analog: spec.Value != "bool"})
+	}
+	schema := client.Schema(client.SchemaOptions{Events: true})
+	for _, spec := range schema {
+		if spec.Source == "gateway" && !strings.Contains(spec.Path, "<") && spec.Value != "string" {
+			add("Hue gateway "+spec.Channel, spec.Path, spec)
+		}
+	}
+	for _, r := range c.rooms {
+		for _, spec := range schema {
+			if !strings.HasPrefix(spec.Path, "/group/<id>/") || spec.Value == "string" {
+				continue
+			}
+			for _, s := range r.services {
+				if s.Rid == nil || s.Rtype == nil || string(*s.Rtype) != spec.Source {
+					continue
+				}
+				// grouped light paths carry the grouped_light id, the others the room id
+				id := r.id
+				if spec.Source == "grouped_light" {
+					id = *s.Rid
+				}
+				add(r.name+" "+spec.Channel, strings.Replace(spec.Path, "<id>", id, 1), spec)
+			}
+		}
+		if r.groupedLight != "" {
+			outputs = append(outputs,
+				loxone.Output{Title: r.name + " on", On: "/grouped_light/" + r.groupedLight + "/on 1", Off: "/grouped_light/" + r.groupedLight + "/on 0"},
+				loxone.Output{Title: r.name + " brightness", On: "/grouped_light/" + r.groupedLight + "/dimmable <v>", Analog: true},
+			)
+		}
+		for _, s := range r.scenes {
+			outputs = append(outputs, loxone.Output{Title: r.name + " " + s.name, On: "/scene/" + s.id + "/recall active"})
+		}
+	}
+	for _, s := range c.sensors {
+		for _, spec := range s.specs {
+			add(s.name+" "+spec.Channel, strings.Replace(spec.Path, "<id>", s.id, 1), spec)
+		}
+	}
+	return inputs, outputs
+}
+
+// writeInitFile writes path through fn, replacing it only once fn succeeded.
+func writeInitFile(path string, perm os.FileMode, fn func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
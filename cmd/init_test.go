@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInitConfigFileForward(t *testing.T) {
+	cfg := initConfigFile{
+		mode:    modeBoth,
+		rooms:   []initRoom{{id: "5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0", name: "Kitchen"}},
+		sensors: []initSensor{{id: "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", name: "Hall sensor"}},
+	}
+	var all strings.Builder
+	if err := cfg.write(&all); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(all.String(), "forward:") {
+		t.Errorf("write() with everything picked has a forward list:\n%s", all.String())
+	}
+
+	cfg.forward = true
+	var some strings.Builder
+	if err := cfg.write(&some); err != nil {
+		t.Fatal(err)
+	}
+	want := "forward:\n" +
+		"  - \"5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0\" # Kitchen\n" +
+		"  - \"0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d\" # Hall sensor\n"
+	if !strings.Contains(some.String(), want) {
+		t.Errorf("write() = \n%s\nwant it to contain\n%s", some.String(), want)
+	}
+}
@@ -5,9 +5,17 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/samvdb/loxone-philips-hue/api"
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/capture"
 	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/ha"
 	"github.com/samvdb/loxone-philips-hue/hue"
+	"github.com/samvdb/loxone-philips-hue/resource"
 	"github.com/samvdb/loxone-philips-hue/udp"
+	"github.com/samvdb/loxone-philips-hue/version"
 
 	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
@@ -24,24 +32,111 @@ import (
 )
 
 var (
-	cfgFile              string
-	flagLoxoneIP         string
-	flagLoxoneUdpPort    int
-	flagPhilipsHueIP     string
-	flagPhilipsHueApiKey string
-	debug                bool
+	cfgFile                 string
+	flagLoxoneIP            string
+	flagLoxoneUdpPort       int
+	flagLoxoneLevels        bool
+	flagListenUDPPort       int
+	flagPhilipsHueIP        string
+	flagPhilipsHueHost      string
+	flagDNSServer           string
+	flagHueProxy            string
+	flagPhilipsHueApiKey    string
+	flagPhilipsHueApiKey2   string
+	flagCommandQueueAge     time.Duration
+	flagOccupancyDecay      time.Duration
+	flagRoomAverages        []string
+	flagAverageOutlier      float64
+	flagReportInterval      time.Duration
+	flagReportChannels      []string
+	flagPathStyle           string
+	flagStrictPaths         bool
+	flagBoolEncoding        string
+	flagMotionExclude       []string
+	flagForward             []string
+	flagHomeMotion          bool
+	flagHomeLight           bool
+	flagCriticalTypes       []string
+	flagDeferEntertainment  bool
+	flagAPIListen           string
+	flagPprof               bool
+	flagCommandTimeout      time.Duration
+	flagCommandTimeouts     map[string]string
+	flagCommandGrammar      string
+	flagGrammarSources      map[string]string
+	flagBrightnessCurve     string
+	flagMinDim              string
+	flagTransition          time.Duration
+	flagUsageInterval       time.Duration
+	flagBridgeMaxConcurrent int
+	flagStreamBackoffMax    time.Duration
+	flagStreamAlertAfter    int
+	flagHAID                string
+	flagHAListen            string
+	flagHAPeers             []string
+	flagHAInterval          time.Duration
+	flagReadOnly            bool
+	flagEcho                string
+	flagEchoWindow          time.Duration
+	flagSourceAttribution   bool
+	flagOverrideTimeout     time.Duration
+	flagDailyReport         string
+	flagBatteryLow          float64
+	flagBatteryLowRepeat    time.Duration
+	flagStaleEvents         string
+	flagStaleAfter          time.Duration
+	flagMaxEventSize        int
+	flagUDPQueueSize        int
+	flagCommandWorkers      int
+	flagInventoryRefresh    time.Duration
+	flagUDPDropAlert        float64
+	flagUDPMaxConnAge       time.Duration
+	flagUDPBind             string
+	flagEventStormLimit     int
+	flagEventStormHold      time.Duration
+	flagEventStormDrop      []string
+	flagLoxoneProbePort     int
+	flagLoxoneDownAfter     time.Duration
+	flagTimezone            string
+	flagPersistFile         string
+	flagPersistQuiet        time.Duration
+	flagPersistMark         bool
+	flagPersistChannels     []string
+	flagEventResumeFile     string
+	flagEventResumeMaxAge   time.Duration
+	flagInventoryChanged    bool
+	flagCaptureRaw          string
+	flagCaptureMaxSize      int64
+	flagCaptureFiles        int
+	flagAuditFile           string
+	flagAuditState          bool
+	flagMode                string
+	flagBridgeID            string
+	flagDiscoveryInterval   time.Duration
+	debug                   bool
+
+	// logLevel can be changed at runtime via /gateway/loglevel
+	logLevel = new(slog.LevelVar)
+
+	logBuffer *api.LogBuffer
 )
 
 var rootCmd = &cobra.Command{
 	Use: "",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		level := slog.LevelInfo
+		logLevel.Set(slog.LevelInfo)
 		if debug {
-			level = slog.LevelDebug
+			logLevel.Set(slog.LevelDebug)
 		}
-		logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+		// recent lines are kept for /api/logs and support bundles
+		logBuffer = api.NewLogBuffer(1000, slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+		logger := slog.New(logBuffer)
 		slog.SetDefault(logger)
+		slog.Info("starting loxone-philips-hue", "version", version.Get().String())
+		if err := validateConfig(); err != nil {
+			return err
+		}
 		return Run(cmd)
 	},
 }
@@ -58,15 +153,167 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
 	rootCmd.PersistentFlags().StringVar(&flagLoxoneIP, "loxone-ip", "", "Loxone IP")
 	rootCmd.PersistentFlags().IntVar(&flagLoxoneUdpPort, "loxone-udp-port", 1234, "Loxone's UDP server port")
+	rootCmd.PersistentFlags().BoolVar(&flagLoxoneLevels, "loxone-levels", false, "Also send /group/<id>/brightness and /sensor/<id>/battery to Loxone; sinks always get them")
+	rootCmd.PersistentFlags().IntVar(&flagListenUDPPort, "listen-udp-port", 0, "UDP port the gateway receives Loxone commands on (0 uses --loxone-udp-port)")
 	rootCmd.PersistentFlags().StringVar(&flagPhilipsHueIP, "philips-hue-ip", "", "Philips Hue IP")
+	rootCmd.PersistentFlags().StringVar(&flagPhilipsHueHost, "philips-hue-host", "", "Philips Hue hostname (e.g. hue.local); takes precedence over --philips-hue-ip")
+	rootCmd.PersistentFlags().StringVar(&flagDNSServer, "dns-server", "", "DNS server (ip[:port]) used to resolve --philips-hue-host instead of the system resolver")
+	rootCmd.PersistentFlags().StringVar(&flagHueProxy, "hue-proxy", "", "HTTP(S) proxy URL for bridge connections; defaults to HTTPS_PROXY/NO_PROXY")
+	rootCmd.PersistentFlags().StringVar(&flagBridgeID, "philips-hue-bridge-id", "", "Philips Hue bridge id; the IP is discovered (and re-discovered) via mDNS")
+	rootCmd.PersistentFlags().DurationVar(&flagDiscoveryInterval, "bridge-discovery-interval", 5*time.Minute, "How often the bridge IP is re-discovered when --philips-hue-bridge-id is set")
 	rootCmd.PersistentFlags().StringVar(&flagPhilipsHueApiKey, "philips-hue-apikey", "", "Philips Hue API Key")
+	rootCmd.PersistentFlags().StringVar(&flagPhilipsHueApiKey2, "philips-hue-apikey-secondary", "", "Secondary Philips Hue API Key, used when the primary is rejected")
+	rootCmd.PersistentFlags().DurationVar(&flagCommandQueueAge, "command-queue-max-age", 30*time.Second, "How long commands are held while the Hue bridge is offline")
+	rootCmd.PersistentFlags().StringVar(&flagMode, "mode", modeBoth, "What this instance does: events (Hue → Loxone), commands (Loxone → Hue) or both")
+	rootCmd.PersistentFlags().DurationVar(&flagOccupancyDecay, "occupancy-decay", 0, "Emit /room/<name>/occupied with this decay (0 disables)")
+	rootCmd.PersistentFlags().StringSliceVar(&flagRoomAverages, "room-averages", nil, "Sensor channels averaged per room as /room/<name>/<channel>_avg, e.g. temperature,humidity; weights and zones from average_weights and average_zones in the config")
+	rootCmd.PersistentFlags().Float64Var(&flagAverageOutlier, "average-outlier", 3, "Readings further than this from the median of a room are left out of --room-averages")
+	rootCmd.PersistentFlags().DurationVar(&flagReportInterval, "report-interval", 0, "Also re-send the last value of --report-channels at this interval, for Loxone statistics (0 disables)")
+	rootCmd.PersistentFlags().StringSliceVar(&flagReportChannels, "report-channels", []string{"temperature", "light_level", "battery"}, "Analog channels re-sent by --report-interval")
+	rootCmd.PersistentFlags().StringVar(&flagBoolEncoding, "bool-encoding", udp.BoolDigits, "How booleans are sent to Loxone: 1/0, true/false or ON/OFF; per channel via bool_encodings in the config")
+	rootCmd.PersistentFlags().StringVar(&flagPathStyle, "path-style", client.PathStyleIDs, "Message paths: ids (/sensor/<id>/temperature) or hierarchical (/<level>/<room>/<device>/temperature, levels from path_levels in the config)")
+	rootCmd.PersistentFlags().BoolVar(&flagStrictPaths, "strict-paths", false, "Refuse to start when two resources would send on the same path (otherwise only logged and reported)")
+	rootCmd.PersistentFlags().StringSliceVar(&flagForward, "forward", nil, "Rooms, zones and devices (ids) whose events are forwarded, a room or zone with its devices and scenes; empty forwards everything")
+	rootCmd.PersistentFlags().StringSliceVar(&flagMotionExclude, "grouped-motion-exclude", []string{"bridge_home"}, "grouped_motion owners (rtype or id) not forwarded as /group/<id>/motion")
+	rootCmd.PersistentFlags().BoolVar(&flagHomeMotion, "home-motion", false, "Publish bridge_home grouped motion as /home/motion")
+	rootCmd.PersistentFlags().BoolVar(&flagHomeLight, "home-light", false, "Publish the bridge_home grouped light as /home/on and /home/brightness")
+	rootCmd.PersistentFlags().StringSliceVar(&flagCriticalTypes, "critical-types", []string{"contact", "tamper", "security_area_motion"}, "Hue resource types sent without queueing or dropping; failures raise /gateway/alert")
+	rootCmd.PersistentFlags().BoolVar(&flagDeferEntertainment, "entertainment-defer", true, "Defer commands for lights locked by an entertainment session until it ends (otherwise reject them)")
+	rootCmd.PersistentFlags().StringVar(&flagAPIListen, "api-listen", "", "Listen address of the admin HTTP API, e.g. :8080 (disabled when empty)")
+	rootCmd.PersistentFlags().BoolVar(&flagPprof, "pprof", false, "Serve net/http/pprof profiles on /debug/pprof/ of the admin API (requires --api-listen)")
+	rootCmd.PersistentFlags().DurationVar(&flagCommandTimeout, "command-timeout", 5*time.Second, "Timeout for each Loxone command")
+	rootCmd.PersistentFlags().StringToStringVar(&flagCommandTimeouts, "command-timeouts", nil, "Per domain or domain/action timeouts, e.g. scene=15s,grouped_light/on=1s")
+	rootCmd.PersistentFlags().StringVar(&flagCommandGrammar, "command-grammar", udp.GrammarV1, "Command grammar: v1 (/<domain>/<id>/<action> <value>) or v2 (set|get <domain>/<id> param=value)")
+	rootCmd.PersistentFlags().StringToStringVar(&flagGrammarSources, "command-grammar-sources", nil, "Per source IP grammar, e.g. 10.0.0.20=v2")
+	rootCmd.PersistentFlags().StringVar(&flagBrightnessCurve, "brightness-curve", "linear", "Default dimmer curve: linear, soft, perceptual or gamma:<n>; per group via brightness_curves in the config")
+	rootCmd.PersistentFlags().StringVar(&flagMinDim, "min-dim", "", "Default minimum brightness, e.g. 5 (clamp) or 5:off (switch off below); per group via min_dims in the config")
+	rootCmd.PersistentFlags().DurationVar(&flagTransition, "transition", 0, "Default fade for on/off/dim commands without one (0 = bridge default); per room, zone or grouped_light via transitions in the config")
+	rootCmd.PersistentFlags().DurationVar(&flagUsageInterval, "bridge-usage-interval", time.Hour, "How often bridge resource counts are checked against their limits (0 disables)")
+	rootCmd.PersistentFlags().IntVar(&flagBridgeMaxConcurrent, "bridge-max-concurrent", bridge.DefaultMaxConcurrent, "Maximum parallel requests to the bridge, shared by polling, commands and the API")
+	rootCmd.PersistentFlags().DurationVar(&flagStreamBackoffMax, "event-stream-backoff-max", 30*time.Second, "Longest delay between event stream reconnect attempts")
+	rootCmd.PersistentFlags().IntVar(&flagStreamAlertAfter, "event-stream-alert-after", 5, "Consecutive failed event stream connects before health turns unhealthy and /gateway/alert is sent")
+	rootCmd.PersistentFlags().StringVar(&flagHAID, "ha-id", "", "HA instance id (default: hostname); with several leaders at once the lowest id wins")
+	rootCmd.PersistentFlags().StringVar(&flagHAListen, "ha-listen", ":7070", "HA heartbeat listen address")
+	rootCmd.PersistentFlags().StringSliceVar(&flagHAPeers, "ha-peers", nil, "HA peer heartbeat addresses (host:port); enables leader election, only the leader talks to Loxone")
+	rootCmd.PersistentFlags().DurationVar(&flagHAInterval, "ha-interval", time.Second, "HA heartbeat interval; a peer silent for 3 intervals is considered down")
+	rootCmd.PersistentFlags().BoolVar(&flagReadOnly, "read-only", false, "Forward events to Loxone but reject (and log) every command, e.g. while staging a new Loxone program")
+	rootCmd.PersistentFlags().StringVar(&flagEcho, "echo", client.EchoOff, "Events caused by a recent Loxone command: off (forward), tag (append origin=loxone) or suppress")
+	rootCmd.PersistentFlags().DurationVar(&flagEchoWindow, "echo-window", gateway.DefaultEchoWindow, "How long after a Loxone command or Hue accessory input a light change is attributed to it")
+	rootCmd.PersistentFlags().BoolVar(&flagSourceAttribution, "source-attribution", false, "Tag light changes with their origin (origin=loxone, accessory or app) so Loxone can tell manual overrides apart")
+	rootCmd.PersistentFlags().DurationVar(&flagOverrideTimeout, "override-timeout", 0, "Emit /room/<name>/override 1 for this long after a manual Hue change, or until Loxone sends a command (0 disables; implies --source-attribution)")
+	rootCmd.PersistentFlags().StringVar(&flagDailyReport, "daily-report", "", "Local time (HH:MM) of the daily summary logged and sent as /gateway/daily_report and to the sinks (empty disables)")
+	rootCmd.PersistentFlags().Float64Var(&flagBatteryLow, "battery-low", 0, "Battery level (percent) at or below which /device/<id>/battery_low 1 is sent; it clears 5 points higher (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&flagBatteryLowRepeat, "battery-low-repeat", 24*time.Hour, "Re-send battery_low 1 of devices still low at this interval (0 sends it once)")
+	rootCmd.PersistentFlags().StringVar(&flagStaleEvents, "stale-events", client.StaleOff, "Events the bridge created more than --stale-after ago, e.g. replayed after a reconnect: off (forward), mark (append stale=1) or drop (critical types are marked)")
+	rootCmd.PersistentFlags().DurationVar(&flagStaleAfter, "stale-after", 30*time.Second, "Age of a bridge event (by its creationtime) after which --stale-events applies")
+	rootCmd.PersistentFlags().IntVar(&flagMaxEventSize, "event-stream-max-event", 2<<20, "Longest event stream line in bytes; larger events end the connection")
+	rootCmd.PersistentFlags().IntVar(&flagUDPQueueSize, "udp-queue-size", 1024, "Messages to Loxone buffered before new ones are dropped")
+	rootCmd.PersistentFlags().IntVar(&flagCommandWorkers, "command-workers", 16, "Loxone commands applied at once; further commands wait within their timeout")
+	rootCmd.PersistentFlags().DurationVar(&flagInventoryRefresh, "inventory-refresh", 0, "How often names, rooms and scenes are reloaded from the bridge (0 keeps poller.names.interval, default 1h)")
+	rootCmd.PersistentFlags().Float64Var(&flagUDPDropAlert, "udp-drop-alert", 0.01, "Share of UDP messages to Loxone dropped per minute that raises /gateway/alert udp_drops")
+	rootCmd.PersistentFlags().IntVar(&flagEventStormLimit, "event-storm-limit", 0, "Messages per second to Loxone above which low-priority channels (--event-storm-drop) are dropped for a while, e.g. 100 (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&flagEventStormHold, "event-storm-hold", 10*time.Second, "Minimum time --event-storm-limit keeps dropping once exceeded")
+	rootCmd.PersistentFlags().StringSliceVar(&flagEventStormDrop, "event-storm-drop", []string{"brightness", "light_level", "temperature", "humidity", "power", "energy"}, "Channels dropped during an event storm; critical types are always sent")
+	rootCmd.PersistentFlags().StringVar(&flagUDPBind, "udp-bind", "", "Local IP address or interface name (e.g. eth0.20) the UDP traffic to and from Loxone uses (default: all interfaces)")
+	rootCmd.PersistentFlags().DurationVar(&flagUDPMaxConnAge, "udp-max-conn-age", 0, "Re-resolve and re-dial the UDP connection to Loxone once it is this old, e.g. 15m for NATs that forget it silently (0 disables)")
+	rootCmd.PersistentFlags().IntVar(&flagLoxoneProbePort, "loxone-probe-port", 80, "TCP port of the Miniserver probed to detect an outage for the failsafe rules")
+	rootCmd.PersistentFlags().DurationVar(&flagLoxoneDownAfter, "loxone-down-after", 30*time.Second, "How long the Miniserver must be unreachable before the failsafe rules take over")
+	rootCmd.PersistentFlags().StringVar(&flagPersistFile, "persist-file", "", "File keeping the last value of every channel, re-sent to Loxone after a restart (disabled when empty)")
+	rootCmd.PersistentFlags().DurationVar(&flagPersistQuiet, "persist-quiet", 10*time.Second, "Wait this long after startup before re-sending persisted values; channels updated meanwhile are skipped")
+	rootCmd.PersistentFlags().BoolVar(&flagPersistMark, "persist-mark", true, "Tag re-sent persisted values with restored=1")
+	rootCmd.PersistentFlags().StringSliceVar(&flagPersistChannels, "persist-channels", []string{"on", "dimmable", "brightness", "temperature", "light_level", "humidity", "battery", "power", "energy"}, "Channels kept by --persist-file")
+	rootCmd.PersistentFlags().StringVar(&flagEventResumeFile, "event-resume-file", "", "File keeping the position in the bridge event stream, so events replayed after a quick restart are not sent to Loxone twice (disabled when empty)")
+	rootCmd.PersistentFlags().DurationVar(&flagEventResumeMaxAge, "event-resume-max-age", 10*time.Minute, "A position in --event-resume-file older than this is ignored")
+	rootCmd.PersistentFlags().BoolVar(&flagInventoryChanged, "inventory-changed", false, "Send /gateway/inventory_changed 1 to Loxone when devices, rooms, zones or scenes are added, removed or renamed (the sinks always get the details)")
+	rootCmd.PersistentFlags().StringVar(&flagCaptureRaw, "capture-raw", "", "Write raw event stream payloads and inbound Loxone datagrams to this file, API keys redacted (disabled when empty)")
+	rootCmd.PersistentFlags().Int64Var(&flagCaptureMaxSize, "capture-max-size", 10<<20, "Size in bytes at which the --capture-raw file is rotated")
+	rootCmd.PersistentFlags().IntVar(&flagCaptureFiles, "capture-files", 3, "Number of --capture-raw files kept, including the current one")
+	rootCmd.PersistentFlags().StringVar(&flagAuditFile, "audit-file", "", "Append every applied command with its source, target name and outcome to this file as JSON lines, for the history subcommand (kept in memory only when empty)")
+	rootCmd.PersistentFlags().BoolVar(&flagAuditState, "audit-state", false, "Record the target's state before and after each audited command (two extra bridge requests per command)")
+	rootCmd.PersistentFlags().StringVar(&flagTimezone, "timezone", "", "IANA time zone of command_windows, e.g. Europe/Brussels (default: local time)")
 
 	// Bind flags → Viper config keys
 	_ = viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 	_ = viper.BindPFlag("loxone_ip", rootCmd.PersistentFlags().Lookup("loxone-ip"))
 	_ = viper.BindPFlag("loxone_udp_port", rootCmd.PersistentFlags().Lookup("loxone-udp-port"))
+	_ = viper.BindPFlag("loxone_levels", rootCmd.PersistentFlags().Lookup("loxone-levels"))
+	_ = viper.BindPFlag("listen_udp_port", rootCmd.PersistentFlags().Lookup("listen-udp-port"))
 	_ = viper.BindPFlag("philips_hue_ip", rootCmd.PersistentFlags().Lookup("philips-hue-ip"))
+	_ = viper.BindPFlag("philips_hue_host", rootCmd.PersistentFlags().Lookup("philips-hue-host"))
+	_ = viper.BindPFlag("dns_server", rootCmd.PersistentFlags().Lookup("dns-server"))
+	_ = viper.BindPFlag("hue_proxy", rootCmd.PersistentFlags().Lookup("hue-proxy"))
+	_ = viper.BindPFlag("philips_hue_bridge_id", rootCmd.PersistentFlags().Lookup("philips-hue-bridge-id"))
+	_ = viper.BindPFlag("bridge_discovery_interval", rootCmd.PersistentFlags().Lookup("bridge-discovery-interval"))
 	_ = viper.BindPFlag("philips_hue_apikey", rootCmd.PersistentFlags().Lookup("philips-hue-apikey"))
+	_ = viper.BindPFlag("philips_hue_apikey_secondary", rootCmd.PersistentFlags().Lookup("philips-hue-apikey-secondary"))
+	_ = viper.BindPFlag("command_queue_max_age", rootCmd.PersistentFlags().Lookup("command-queue-max-age"))
+	_ = viper.BindPFlag("mode", rootCmd.PersistentFlags().Lookup("mode"))
+	_ = viper.BindPFlag("occupancy_decay", rootCmd.PersistentFlags().Lookup("occupancy-decay"))
+	_ = viper.BindPFlag("room_averages", rootCmd.PersistentFlags().Lookup("room-averages"))
+	_ = viper.BindPFlag("average_outlier", rootCmd.PersistentFlags().Lookup("average-outlier"))
+	_ = viper.BindPFlag("report_interval", rootCmd.PersistentFlags().Lookup("report-interval"))
+	_ = viper.BindPFlag("report_channels", rootCmd.PersistentFlags().Lookup("report-channels"))
+	_ = viper.BindPFlag("bool_encoding", rootCmd.PersistentFlags().Lookup("bool-encoding"))
+	_ = viper.BindPFlag("path_style", rootCmd.PersistentFlags().Lookup("path-style"))
+	_ = viper.BindPFlag("strict_paths", rootCmd.PersistentFlags().Lookup("strict-paths"))
+	_ = viper.BindPFlag("forward", rootCmd.PersistentFlags().Lookup("forward"))
+	_ = viper.BindPFlag("grouped_motion_exclude", rootCmd.PersistentFlags().Lookup("grouped-motion-exclude"))
+	_ = viper.BindPFlag("home_motion", rootCmd.PersistentFlags().Lookup("home-motion"))
+	_ = viper.BindPFlag("home_light", rootCmd.PersistentFlags().Lookup("home-light"))
+	_ = viper.BindPFlag("critical_types", rootCmd.PersistentFlags().Lookup("critical-types"))
+	_ = viper.BindPFlag("entertainment_defer", rootCmd.PersistentFlags().Lookup("entertainment-defer"))
+	_ = viper.BindPFlag("api_listen", rootCmd.PersistentFlags().Lookup("api-listen"))
+	_ = viper.BindPFlag("pprof", rootCmd.PersistentFlags().Lookup("pprof"))
+	_ = viper.BindPFlag("command_timeout", rootCmd.PersistentFlags().Lookup("command-timeout"))
+	_ = viper.BindPFlag("command_timeouts", rootCmd.PersistentFlags().Lookup("command-timeouts"))
+	_ = viper.BindPFlag("command_grammar", rootCmd.PersistentFlags().Lookup("command-grammar"))
+	_ = viper.BindPFlag("command_grammar_sources", rootCmd.PersistentFlags().Lookup("command-grammar-sources"))
+	_ = viper.BindPFlag("brightness_curve", rootCmd.PersistentFlags().Lookup("brightness-curve"))
+	_ = viper.BindPFlag("min_dim", rootCmd.PersistentFlags().Lookup("min-dim"))
+	_ = viper.BindPFlag("transition", rootCmd.PersistentFlags().Lookup("transition"))
+	_ = viper.BindPFlag("bridge_usage_interval", rootCmd.PersistentFlags().Lookup("bridge-usage-interval"))
+	_ = viper.BindPFlag("bridge_max_concurrent", rootCmd.PersistentFlags().Lookup("bridge-max-concurrent"))
+	_ = viper.BindPFlag("event_stream_backoff_max", rootCmd.PersistentFlags().Lookup("event-stream-backoff-max"))
+	_ = viper.BindPFlag("event_stream_alert_after", rootCmd.PersistentFlags().Lookup("event-stream-alert-after"))
+	_ = viper.BindPFlag("ha_id", rootCmd.PersistentFlags().Lookup("ha-id"))
+	_ = viper.BindPFlag("ha_listen", rootCmd.PersistentFlags().Lookup("ha-listen"))
+	_ = viper.BindPFlag("ha_peers", rootCmd.PersistentFlags().Lookup("ha-peers"))
+	_ = viper.BindPFlag("ha_interval", rootCmd.PersistentFlags().Lookup("ha-interval"))
+	_ = viper.BindPFlag("read_only", rootCmd.PersistentFlags().Lookup("read-only"))
+	_ = viper.BindPFlag("echo", rootCmd.PersistentFlags().Lookup("echo"))
+	_ = viper.BindPFlag("echo_window", rootCmd.PersistentFlags().Lookup("echo-window"))
+	_ = viper.BindPFlag("source_attribution", rootCmd.PersistentFlags().Lookup("source-attribution"))
+	_ = viper.BindPFlag("override_timeout", rootCmd.PersistentFlags().Lookup("override-timeout"))
+	_ = viper.BindPFlag("daily_report", rootCmd.PersistentFlags().Lookup("daily-report"))
+	_ = viper.BindPFlag("battery_low", rootCmd.PersistentFlags().Lookup("battery-low"))
+	_ = viper.BindPFlag("battery_low_repeat", rootCmd.PersistentFlags().Lookup("battery-low-repeat"))
+	_ = viper.BindPFlag("stale_events", rootCmd.PersistentFlags().Lookup("stale-events"))
+	_ = viper.BindPFlag("stale_after", rootCmd.PersistentFlags().Lookup("stale-after"))
+	_ = viper.BindPFlag("event_stream_max_event", rootCmd.PersistentFlags().Lookup("event-stream-max-event"))
+	_ = viper.BindPFlag("udp_queue_size", rootCmd.PersistentFlags().Lookup("udp-queue-size"))
+	_ = viper.BindPFlag("command_workers", rootCmd.PersistentFlags().Lookup("command-workers"))
+	_ = viper.BindPFlag("inventory_refresh", rootCmd.PersistentFlags().Lookup("inventory-refresh"))
+	_ = viper.BindPFlag("udp_drop_alert", rootCmd.PersistentFlags().Lookup("udp-drop-alert"))
+	_ = viper.BindPFlag("event_storm_limit", rootCmd.PersistentFlags().Lookup("event-storm-limit"))
+	_ = viper.BindPFlag("event_storm_hold", rootCmd.PersistentFlags().Lookup("event-storm-hold"))
+	_ = viper.BindPFlag("event_storm_drop", rootCmd.PersistentFlags().Lookup("event-storm-drop"))
+	_ = viper.BindPFlag("udp_bind", rootCmd.PersistentFlags().Lookup("udp-bind"))
+	_ = viper.BindPFlag("udp_max_conn_age", rootCmd.PersistentFlags().Lookup("udp-max-conn-age"))
+	_ = viper.BindPFlag("loxone_probe_port", rootCmd.PersistentFlags().Lookup("loxone-probe-port"))
+	_ = viper.BindPFlag("loxone_down_after", rootCmd.PersistentFlags().Lookup("loxone-down-after"))
+	_ = viper.BindPFlag("timezone", rootCmd.PersistentFlags().Lookup("timezone"))
+	_ = viper.BindPFlag("capture_raw", rootCmd.PersistentFlags().Lookup("capture-raw"))
+	_ = viper.BindPFlag("capture_max_size", rootCmd.PersistentFlags().Lookup("capture-max-size"))
+	_ = viper.BindPFlag("capture_files", rootCmd.PersistentFlags().Lookup("capture-files"))
+	_ = viper.BindPFlag("audit_file", rootCmd.PersistentFlags().Lookup("audit-file"))
+	_ = viper.BindPFlag("audit_state", rootCmd.PersistentFlags().Lookup("audit-state"))
+	_ = viper.BindPFlag("persist_file", rootCmd.PersistentFlags().Lookup("persist-file"))
+	_ = viper.BindPFlag("persist_quiet", rootCmd.PersistentFlags().Lookup("persist-quiet"))
+	_ = viper.BindPFlag("persist_mark", rootCmd.PersistentFlags().Lookup("persist-mark"))
+	_ = viper.BindPFlag("persist_channels", rootCmd.PersistentFlags().Lookup("persist-channels"))
+	_ = viper.BindPFlag("event_resume_file", rootCmd.PersistentFlags().Lookup("event-resume-file"))
+	_ = viper.BindPFlag("event_resume_max_age", rootCmd.PersistentFlags().Lookup("event-resume-max-age"))
+	_ = viper.BindPFlag("inventory_changed", rootCmd.PersistentFlags().Lookup("inventory-changed"))
 
 	// Env: MYAPP_LOXONE_IP, MYAPP_DEBUG, etc.
 	viper.SetEnvPrefix("")
@@ -107,8 +354,83 @@ func initConfig() {
 	debug = viper.GetBool("debug")
 	flagLoxoneIP = viper.GetString("loxone_ip")
 	flagLoxoneUdpPort = viper.GetInt("loxone_udp_port")
+	flagListenUDPPort = viper.GetInt("listen_udp_port")
 	flagPhilipsHueIP = viper.GetString("philips_hue_ip")
+	flagPhilipsHueHost = viper.GetString("philips_hue_host")
+	flagDNSServer = viper.GetString("dns_server")
+	flagHueProxy = viper.GetString("hue_proxy")
 	flagPhilipsHueApiKey = viper.GetString("philips_hue_apikey")
+	flagBridgeID = viper.GetString("philips_hue_bridge_id")
+	flagDiscoveryInterval = viper.GetDuration("bridge_discovery_interval")
+	flagPhilipsHueApiKey2 = viper.GetString("philips_hue_apikey_secondary")
+	flagCommandQueueAge = viper.GetDuration("command_queue_max_age")
+	flagOccupancyDecay = viper.GetDuration("occupancy_decay")
+	flagRoomAverages = viper.GetStringSlice("room_averages")
+	flagAverageOutlier = viper.GetFloat64("average_outlier")
+	flagReportInterval = viper.GetDuration("report_interval")
+	flagReportChannels = viper.GetStringSlice("report_channels")
+	flagPathStyle = viper.GetString("path_style")
+	flagStrictPaths = viper.GetBool("strict_paths")
+	flagBoolEncoding = viper.GetString("bool_encoding")
+	flagMotionExclude = viper.GetStringSlice("grouped_motion_exclude")
+	flagForward = viper.GetStringSlice("forward")
+	flagHomeMotion = viper.GetBool("home_motion")
+	flagHomeLight = viper.GetBool("home_light")
+	flagCriticalTypes = viper.GetStringSlice("critical_types")
+	flagDeferEntertainment = viper.GetBool("entertainment_defer")
+	flagAPIListen = viper.GetString("api_listen")
+	flagPprof = viper.GetBool("pprof")
+	flagCommandTimeout = viper.GetDuration("command_timeout")
+	flagCommandTimeouts = viper.GetStringMapString("command_timeouts")
+	flagCommandGrammar = viper.GetString("command_grammar")
+	flagGrammarSources = viper.GetStringMapString("command_grammar_sources")
+	flagBrightnessCurve = viper.GetString("brightness_curve")
+	flagMinDim = viper.GetString("min_dim")
+	flagTransition = viper.GetDuration("transition")
+	flagUsageInterval = viper.GetDuration("bridge_usage_interval")
+	flagBridgeMaxConcurrent = viper.GetInt("bridge_max_concurrent")
+	flagStreamBackoffMax = viper.GetDuration("event_stream_backoff_max")
+	flagStreamAlertAfter = viper.GetInt("event_stream_alert_after")
+	flagHAID = viper.GetString("ha_id")
+	flagHAListen = viper.GetString("ha_listen")
+	flagHAPeers = viper.GetStringSlice("ha_peers")
+	flagHAInterval = viper.GetDuration("ha_interval")
+	flagReadOnly = viper.GetBool("read_only")
+	flagEcho = viper.GetString("echo")
+	flagEchoWindow = viper.GetDuration("echo_window")
+	flagOverrideTimeout = viper.GetDuration("override_timeout")
+	flagDailyReport = viper.GetString("daily_report")
+	flagBatteryLow = viper.GetFloat64("battery_low")
+	flagBatteryLowRepeat = viper.GetDuration("battery_low_repeat")
+	flagStaleEvents = viper.GetString("stale_events")
+	flagStaleAfter = viper.GetDuration("stale_after")
+	flagMaxEventSize = viper.GetInt("event_stream_max_event")
+	flagUDPQueueSize = viper.GetInt("udp_queue_size")
+	flagCommandWorkers = viper.GetInt("command_workers")
+	flagInventoryRefresh = viper.GetDuration("inventory_refresh")
+	flagSourceAttribution = viper.GetBool("source_attribution") || flagOverrideTimeout > 0
+	flagUDPDropAlert = viper.GetFloat64("udp_drop_alert")
+	flagUDPMaxConnAge = viper.GetDuration("udp_max_conn_age")
+	flagUDPBind = viper.GetString("udp_bind")
+	flagEventStormLimit = viper.GetInt("event_storm_limit")
+	flagEventStormHold = viper.GetDuration("event_storm_hold")
+	flagEventStormDrop = viper.GetStringSlice("event_storm_drop")
+	flagLoxoneProbePort = viper.GetInt("loxone_probe_port")
+	flagLoxoneDownAfter = viper.GetDuration("loxone_down_after")
+	flagTimezone = viper.GetString("timezone")
+	flagCaptureRaw = viper.GetString("capture_raw")
+	flagCaptureMaxSize = viper.GetInt64("capture_max_size")
+	flagCaptureFiles = viper.GetInt("capture_files")
+	flagAuditFile = viper.GetString("audit_file")
+	flagAuditState = viper.GetBool("audit_state")
+	flagPersistFile = viper.GetString("persist_file")
+	flagPersistQuiet = viper.GetDuration("persist_quiet")
+	flagPersistMark = viper.GetBool("persist_mark")
+	flagPersistChannels = viper.GetStringSlice("persist_channels")
+	flagEventResumeFile = viper.GetString("event_resume_file")
+	flagEventResumeMaxAge = viper.GetDuration("event_resume_max_age")
+	flagInventoryChanged = viper.GetBool("inventory_changed")
+	flagMode = viper.GetString("mode")
 }
 
 func Run(cmd *cobra.Command) error {
@@ -117,68 +439,309 @@ func Run(cmd *cobra.Command) error {
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// UDP server (listen on all interfaces, same port as Loxone or pick your own)
-	// Commonly Loxone will send to us on some port; expose it with a flag if you like.
-	//serverAddr := &net.UDPAddr{IP: net.IPv4zero, Port: flagLoxoneUdpPort}
-	//udpServer, err := net.ListenUDP("udp", serverAddr)
-	//if err != nil {
-	//	return fmt.Errorf("listen UDP: %w", err)
-	//}
-	//defer udpServer.Close()
-
-	clientLogger := slog.With("module", "client", "loxone_ip", flagLoxoneIP, "loxone_udp_port", flagLoxoneUdpPort)
-	udpClient, err := udp.NewClient(ctx, udp.ClientConfig{
-		Remote:          net.JoinHostPort(flagLoxoneIP, strconv.Itoa(flagLoxoneUdpPort)),
-		WriteTimeout:    1 * time.Second,
-		QueueSize:       1024,
-		BaseBackoff:     250 * time.Millisecond,
-		MaxBackoff:      8 * time.Second,
-		ResolveInterval: 0, // re-resolve every reconnect; or set e.g. 1m
-		Logger:          clientLogger,
-	})
+	runEvents := flagMode == modeEvents || flagMode == modeBoth
+	runCommands := flagMode == modeCommands || flagMode == modeBoth
+
+	// With HA peers only the leader talks to Loxone; standbys keep their event
+	// stream and inventory warm.
+	var state *gateway.State
+	elector, err := newElector(func(leader bool) { state.SetLeader(leader) })
+	if err != nil {
+		return err
+	}
+	var active func() bool
+	if elector != nil {
+		active = elector.IsLeader
+	}
+
+	// With --udp-bind both directions use that interface, so Loxone sees the
+	// address it was configured with.
+	bindIP, err := udp.BindIP(flagUDPBind)
 	if err != nil {
 		return err
 	}
 
-	defer udpClient.Close()
+	// Gateway status is sent to Loxone whenever a target is configured; in
+	// commands-only mode it is optional.
+	var udpClient *udp.Client
+	var sender gateway.Sender
+	if flagLoxoneIP != "" {
+		clientLogger := slog.With("module", "client", "loxone_ip", flagLoxoneIP, "loxone_udp_port", flagLoxoneUdpPort)
+		c, err := udp.NewClient(ctx, udp.ClientConfig{
+			Remote:          net.JoinHostPort(flagLoxoneIP, strconv.Itoa(flagLoxoneUdpPort)),
+			LocalIP:         bindIP,
+			WriteTimeout:    1 * time.Second,
+			QueueSize:       flagUDPQueueSize,
+			BaseBackoff:     250 * time.Millisecond,
+			MaxBackoff:      8 * time.Second,
+			ResolveInterval: 0, // re-resolve every reconnect; or set e.g. 1m
+			MaxConnAge:      flagUDPMaxConnAge,
+			Logger:          clientLogger,
+			Active:          active,
+		})
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		udpClient, sender = c, c
+	}
+
+	// e.g. {"bool_encodings": {"motion": "ON/OFF", "bridge_online": "true/false"}}
+	bools, err := udp.NewBools(flagBoolEncoding, viper.GetStringMapString("bool_encodings"))
+	if err != nil {
+		return err
+	}
+
+	state = gateway.NewState(sender)
+	state.SetBools(bools)
+	state.SetStandby(elector != nil)
+	if udpClient != nil {
+		go gateway.WatchDrops(ctx, gateway.DropConfig{Stats: udpClient.Stats, State: state, Threshold: flagUDPDropAlert})
+	}
+	state.SetVersion(version.Get().Version)
+
+	keys := bridge.NewKeys(flagPhilipsHueApiKey, flagPhilipsHueApiKey2)
+	keys.OnFailover = state.SetAPIKeyIndex
+
+	addr, err := bridgeAddress(ctx)
+	if err != nil {
+		return err
+	}
+
+	home, err := bridge.NewHome(addr, keys)
+	if err != nil {
+		return err
+	}
+	home.SetMaxConcurrent(flagBridgeMaxConcurrent)
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	poller := client.NewPoller(ctx, flagPhilipsHueIP, flagPhilipsHueApiKey)
+	if elector != nil {
+		g.Go(func() error {
+			return elector.Run(ctx)
+		})
+	}
 
-	g.Go(func() error {
-		serverAddr := &net.UDPAddr{IP: net.IPv4zero, Port: flagLoxoneUdpPort}
+	if flagBridgeID != "" {
+		locator := &bridge.Locator{BridgeID: flagBridgeID, Address: addr, Interval: flagDiscoveryInterval}
+		g.Go(func() error {
+			return locator.Run(ctx)
+		})
+	}
 
-		// Build Hue adapter (openhue)
-		hueAdapter, err := hue.NewAdapter(flagPhilipsHueIP, flagPhilipsHueApiKey, slog.Default())
-		if err != nil {
-			return fmt.Errorf("hue adapter: %w", err)
-		}
+	// One inventory shared by the streamer, the command adapter and the gateway controller.
+	poller, err := client.NewPoller(ctx, home)
+	if err != nil {
+		return err
+	}
+	// e.g. {"names": {"<device, room or zone id>": "Living room"}}
+	poller.SetNameOverrides(viper.GetStringMapString("names"))
+	schedule, err := pollerSchedule(poller, home, state)
+	if err != nil {
+		return err
+	}
+	poller.SetSchedule(schedule)
+	g.Go(func() error {
+		return checkConfig(ctx, poller, state)
+	})
+	if flagUsageInterval > 0 {
+		g.Go(func() error {
+			watchUsage(ctx, home, state, flagUsageInterval)
+			return nil
+		})
+	}
 
-		udpSrv, err := udp.NewServer(udp.ServerConfig{
-			ListenAddr: serverAddr,
-			Handler:    hueAdapter,
-			Logger:     slog.Default(),
+	var raw *capture.Writer
+	if flagCaptureRaw != "" {
+		raw, err = capture.New(capture.Config{
+			Path:     flagCaptureRaw,
+			MaxSize:  flagCaptureMaxSize,
+			MaxFiles: flagCaptureFiles,
+			Redact:   []string{flagPhilipsHueApiKey, flagPhilipsHueApiKey2},
 		})
 		if err != nil {
 			return err
 		}
-		defer udpSrv.Close()
+		defer raw.Close()
+		slog.Warn("capturing raw payloads", "path", flagCaptureRaw)
+	}
+
+	hueAdapter, curves, err := newHueAdapter(home, poller, bools)
+	if err != nil {
+		return err
+	}
+	// entertainment sessions are learned from the event stream
+	entertainment := gateway.NewEntertainment()
+	// rooms and devices paused with /gateway/pause or the API
+	pauses := gateway.NewPauses()
+	// resources Loxone commanded recently, to recognize their event feedback
+	var echoes *gateway.Echoes
+	if flagEcho != client.EchoOff || flagSourceAttribution {
+		echoes = gateway.NewEchoes(flagEchoWindow, poller)
+	}
+	hueAdapter.UseEntertainment(entertainment, flagDeferEntertainment)
 
-		return udpSrv.Run(ctx)
+	// Every command reaching the bridge, whatever its source, is audited.
+	auditCfg := gateway.AuditConfig{File: flagAuditFile, Names: poller, Logger: slog.Default()}
+	if flagAuditState {
+		auditCfg.Reader = hueAdapter
+	}
+	audit, err := gateway.NewAudit(auditCfg)
+	if err != nil {
+		return err
+	}
+	defer audit.Close()
+
+	// Hold commands while the bridge restarts instead of failing them.
+	queue, err := gateway.NewCommandQueue(gateway.QueueConfig{
+		Handler: audit.Track(hueAdapter),
+		State:   state,
+		MaxAge:  flagCommandQueueAge,
+		Logger:  slog.Default(),
 	})
+	if err != nil {
+		return err
+	}
+	entertainment.Replay = queue
 
+	// Record failures of every command the gateway receives, whatever the source;
+	// commands for resources the bridge does not have fail before reaching it.
+	failures := hue.NewFailures(poller.CheckResources(queue), 0)
+	var commands udp.CommandHandler = failures
+	windows, err := commandWindows(poller)
+	if err != nil {
+		return err
+	}
+	if windows != nil {
+		commands = windows.Gate(commands)
+	}
+	if echoes != nil {
+		commands = echoes.Track(commands)
+	}
+	if flagReadOnly {
+		slog.Warn("read-only mode: events are forwarded, commands are rejected")
+		commands = gateway.ReadOnly{Logger: slog.Default()}
+	}
+	if elector != nil {
+		commands = ha.Gate(commands, elector)
+	}
 	g.Go(func() error {
+		return queue.Run(ctx)
+	})
 
-		streamer := client.NewStreamer(ctx, flagPhilipsHueIP, flagPhilipsHueApiKey, udpClient, poller)
-		err := streamer.Run(ctx)
+	// The failsafe rules only run while the Miniserver is unreachable, which
+	// only commands Loxone itself sends over UDP disprove.
+	var liveness *gateway.Liveness
+	udpCommands := commands
+	rules, err := failsafeRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		liveness = gateway.NewLiveness(gateway.LivenessConfig{
+			Addr:      net.JoinHostPort(flagLoxoneIP, strconv.Itoa(flagLoxoneProbePort)),
+			DownAfter: flagLoxoneDownAfter,
+			Logger:    slog.Default(),
+		})
+		udpCommands = liveness.Track(commands)
+		g.Go(func() error {
+			return liveness.Run(ctx)
+		})
+	}
+
+	if flagAPIListen != "" {
+		apiSrv, err := api.New(api.Config{Addr: flagAPIListen, Logger: slog.Default()})
 		if err != nil {
-			slog.Error("streamer failed", "error", err.Error())
+			return err
 		}
+		apiSrv.Handle("PUT /api/raw/{rtype}/{id}", api.RawHandler(commands))
+		apiSrv.Handle("GET /api/errors", api.ErrorsHandler(failures))
+		apiSrv.Handle("GET /api/history", api.HistoryHandler(audit))
+		apiSrv.Handle("GET /api/health", api.HealthHandler(state))
+		apiSrv.Handle("GET /api/schema", api.SchemaHandler(currentSchema()))
+		apiSrv.Handle("GET /api/version", api.VersionHandler())
+		apiSrv.Handle("GET /api/logs", api.LogsHandler(logBuffer))
+		apiSrv.Handle("GET /api/bridge/stats", api.BridgeStatsHandler(home))
+		apiSrv.Handle("GET /api/runtime", api.RuntimeHandler())
+		if flagPprof {
+			api.HandlePprof(apiSrv)
+		}
+		apiSrv.Handle("GET /api/pauses", api.PausesHandler(pauses))
+		apiSrv.Handle("PUT /api/pauses/{kind}/{name}", api.PauseHandler(pauses))
+		apiSrv.Handle("DELETE /api/pauses/{kind}/{name}", api.ResumeHandler(pauses))
+		apiSrv.Handle("GET /api/behaviors", api.BehaviorsHandler(home))
+		if !flagReadOnly { // behaviors change the bridge directly, bypassing commands
+			apiSrv.Handle("POST /api/behaviors", api.CreateBehaviorHandler(home))
+			apiSrv.Handle("PUT /api/behaviors/{id}", api.UpdateBehaviorHandler(home))
+			apiSrv.Handle("DELETE /api/behaviors/{id}", api.DeleteBehaviorHandler(home))
+		}
+		g.Go(func() error {
+			return apiSrv.Run(ctx)
+		})
+	}
 
-		return err
+	if runCommands {
+		timeouts, err := parseTimeouts(flagCommandTimeouts)
+		if err != nil {
+			return err
+		}
+		// e.g. {"command_acl": {"192.168.1.77": ["zone/garden"]}}; unlisted sources control everything
+		var authorizer udp.Authorizer
+		if rules := viper.GetStringMapStringSlice("command_acl"); len(rules) > 0 {
+			acl, err := gateway.NewACL(rules, poller)
+			if err != nil {
+				return err
+			}
+			authorizer = acl
+		}
+		aliases, err := commandAliases()
+		if err != nil {
+			return err
+		}
+		g.Go(func() error {
+			// Loxone virtual outputs send commands here; they are executed
+			// through the hue adapter behind the commands chain.
+			serverAddr := &net.UDPAddr{IP: net.IPv4zero, Port: listenUDPPort()}
+			if bindIP != nil {
+				serverAddr.IP = bindIP
+			}
 
-	})
+			udpSrv, err := udp.NewServer(udp.ServerConfig{
+				ListenAddr: serverAddr,
+				Handler:    udpCommands,
+				Timeout:    flagCommandTimeout,
+				Timeouts:   timeouts,
+				Grammar:    flagCommandGrammar,
+				Grammars:   flagGrammarSources,
+				Reader:     hueAdapter,
+				Active:     active,
+				Authorizer: authorizer,
+				Workers:    flagCommandWorkers,
+				Capture:    raw,
+				Aliases:    aliases,
+				Gateway: gateway.NewController(gateway.ControllerConfig{
+					State:   state,
+					Level:   logLevel,
+					Refresh: poller.Refresh,
+					Pauses:  pauses,
+					Windows: windows,
+				}),
+				Logger: slog.Default(),
+			})
+			if err != nil {
+				return err
+			}
+			defer udpSrv.Close()
+
+			return udpSrv.Run(ctx)
+		})
+	}
+
+	if runEvents {
+		if err := startEvents(ctx, g, udpClient, addr, keys, home, poller, state, commands, entertainment, curves, bools, pauses, echoes, liveness, rules, raw); err != nil {
+			return err
+		}
+	}
 
 	g.Go(func() error {
 
@@ -193,3 +756,322 @@ func Run(cmd *cobra.Command) error {
 
 	return g.Wait()
 }
+
+// bridgeAddress builds the bridge address from the host, ip or bridge id flags,
+// discovering the ip via mDNS when only the id is known.
+func bridgeAddress(ctx context.Context) (*bridge.Address, error) {
+	host := flagPhilipsHueHost
+	if host == "" {
+		host = flagPhilipsHueIP
+	}
+	addr := bridge.NewAddress(host)
+	addr.SetDNSServer(flagDNSServer)
+	if err := addr.SetProxy(flagHueProxy); err != nil {
+		return nil, err
+	}
+	if flagBridgeID != "" && addr.Host() == "" {
+		ip, err := bridge.Discover(ctx, flagBridgeID, 10*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("discover bridge %s: %w", flagBridgeID, err)
+		}
+		slog.Info("hue bridge discovered", "bridge_id", flagBridgeID, "ip", ip)
+		addr.Set(ip)
+	}
+	return addr, nil
+}
+
+// startEvents wires the event stream (streamer, filters, hooks and sinks) into g.
+func startEvents(ctx context.Context, g *errgroup.Group, udpClient *udp.Client, addr *bridge.Address, keys *bridge.Keys, home *bridge.Home, poller *client.Poller, state *gateway.State, commands udp.CommandHandler, entertainment *gateway.Entertainment, curves *curve.Curves, bools *udp.Bools, pauses *gateway.Pauses, echoes *gateway.Echoes, liveness *gateway.Liveness, rules []client.FailsafeRule, raw *capture.Writer) error {
+	// e.g. {"deadband": {"temperature": "0.2", "light_level": "5%"}}
+	deadband, err := client.NewDeadband(viper.GetStringMapString("deadband"))
+	if err != nil {
+		return err
+	}
+	// e.g. {"sampling": {"scene": "10s", "light": "changes", "temperature": "off"}}
+	sampler, err := client.NewSampler(viper.GetStringMapString("sampling"))
+	if err != nil {
+		return err
+	}
+	var breaker *client.Breaker
+	if flagEventStormLimit > 0 {
+		low := make([]resource.Metric, len(flagEventStormDrop))
+		for i, c := range flagEventStormDrop {
+			low[i] = resource.Metric(c)
+		}
+		breaker, err = client.NewBreaker(client.BreakerConfig{Limit: flagEventStormLimit, Hold: flagEventStormHold, Low: low, State: state})
+		if err != nil {
+			return err
+		}
+	}
+
+	var occupancy *client.Occupancy
+	if flagOccupancyDecay > 0 {
+		occupancy = client.NewOccupancy(client.OccupancyConfig{
+			Sender: udpClient,
+			Decay:  flagOccupancyDecay,
+			Bools:  bools,
+		})
+		g.Go(func() error {
+			return occupancy.Run(ctx)
+		})
+	}
+
+	var reporter *client.Reporter
+	if flagReportInterval > 0 {
+		channels := make([]resource.Metric, 0, len(flagReportChannels))
+		for _, c := range flagReportChannels {
+			m, err := resource.ParseMetric(strings.TrimSpace(c))
+			if err != nil {
+				return fmt.Errorf("report channels: %w", err)
+			}
+			channels = append(channels, m)
+		}
+		reporter = client.NewReporter(client.ReporterConfig{
+			Sender:   udpClient,
+			Interval: flagReportInterval,
+			Channels: channels,
+			Bools:    bools,
+		})
+		g.Go(func() error {
+			return reporter.Run(ctx)
+		})
+	}
+
+	var failsafe *client.Failsafe
+	if liveness != nil {
+		// through the full chain, so read-only mode, HA standby and the
+		// command windows hold for failsafe commands too
+		var err error
+		failsafe, err = client.NewFailsafe(client.FailsafeConfig{
+			Rules:   rules,
+			Names:   poller,
+			Handler: commands,
+			Down:    liveness.Down,
+		})
+		if err != nil {
+			return err
+		}
+		g.Go(func() error {
+			return failsafe.Run(ctx)
+		})
+	}
+
+	// hue() in scripts goes through the same chain as the failsafe
+	hooks, err := buildHooks(poller, commands)
+	if err != nil {
+		return err
+	}
+
+	sinks, err := buildSinks(ctx, g)
+	if err != nil {
+		return err
+	}
+	if flagPersistFile != "" {
+		channels := make([]resource.Metric, 0, len(flagPersistChannels))
+		for _, c := range flagPersistChannels {
+			m, err := resource.ParseMetric(strings.TrimSpace(c))
+			if err != nil {
+				return fmt.Errorf("persist channels: %w", err)
+			}
+			channels = append(channels, m)
+		}
+		persist, err := client.NewPersist(client.PersistConfig{
+			File:     flagPersistFile,
+			Sender:   udpClient,
+			Channels: channels,
+			Quiet:    flagPersistQuiet,
+			Mark:     flagPersistMark,
+			Bools:    bools,
+		})
+		if err != nil {
+			return err
+		}
+		g.Go(func() error {
+			return persist.Run(ctx)
+		})
+		sinks = append(sinks, persist) // sees every message after the hooks
+	}
+	poller.OnInventoryChanged(func(diff client.InventoryDiff) {
+		msg := diff.Message()
+		for _, s := range sinks {
+			s.Write(msg)
+		}
+		if flagInventoryChanged {
+			udpClient.Send([]byte("/gateway/inventory_changed " + bools.Format("inventory_changed", true)))
+		}
+	})
+	var resume *client.Resume
+	if flagEventResumeFile != "" {
+		resume, err = client.NewResume(client.ResumeConfig{File: flagEventResumeFile, MaxAge: flagEventResumeMaxAge})
+		if err != nil {
+			return err
+		}
+		g.Go(func() error {
+			return resume.Run(ctx)
+		})
+	}
+
+	var sources *client.Sources
+	if flagSourceAttribution {
+		sources = client.NewSources(flagEchoWindow)
+	}
+	var overrides *client.Overrides
+	if flagOverrideTimeout > 0 {
+		// e.g. {"override_timeouts": {"kitchen": "2h"}}
+		timeouts, err := client.ParseOverrideTimeouts(viper.GetStringMapString("override_timeouts"))
+		if err != nil {
+			return err
+		}
+		overrides = client.NewOverrides(client.OverrideConfig{
+			Sender:   udpClient,
+			Timeout:  flagOverrideTimeout,
+			Timeouts: timeouts,
+			Bools:    bools,
+		})
+		g.Go(func() error {
+			return overrides.Run(ctx)
+		})
+	}
+
+	var daily *client.Daily
+	if flagDailyReport != "" {
+		at, err := parseTimeOfDay(flagDailyReport)
+		if err != nil {
+			return err
+		}
+		daily = client.NewDaily(client.DailyConfig{
+			Sender: udpClient,
+			Sinks:  sinks,
+			Stats:  udpClient.Stats,
+			At:     at,
+		})
+		g.Go(func() error {
+			return daily.Run(ctx)
+		})
+	}
+
+	var batteries *client.Batteries
+	if flagBatteryLow > 0 {
+		batteries = client.NewBatteries(client.BatteryConfig{
+			Sender:    udpClient,
+			Threshold: flagBatteryLow,
+			Repeat:    flagBatteryLowRepeat,
+			Poll:      batteryLevels(home),
+			Bools:     bools,
+		})
+		g.Go(func() error {
+			return batteries.Run(ctx)
+		})
+	}
+
+	streamerOpts := []client.StreamerOption{client.WithBackoff(flagStreamBackoffMax)}
+	for _, s := range sinks {
+		streamerOpts = append(streamerOpts, client.WithSink(s))
+	}
+	streamer, err := client.NewStreamer(ctx, client.StreamerConfig{
+		Bridge:       addr,
+		Keys:         keys,
+		UDPClient:    udpClient,
+		Levels:       flagLoxoneLevels,
+		Poller:       poller,
+		State:        state,
+		Deadband:     deadband,
+		Sampler:      sampler,
+		Breaker:      breaker,
+		Occupancy:    occupancy,
+		Failsafe:     failsafe,
+		Reporter:     reporter,
+		Pauses:       pauses,
+		Echoes:       echoes,
+		EchoMode:     flagEcho,
+		Sources:      sources,
+		Overrides:    overrides,
+		Daily:        daily,
+		Batteries:    batteries,
+		StaleMode:    flagStaleEvents,
+		StaleAfter:   flagStaleAfter,
+		MaxEventSize: flagMaxEventSize,
+		Capture:      raw,
+		Resume:       resume,
+		Hooks:        hooks,
+		Forward:      flagForward,
+
+		MotionExclude: flagMotionExclude,
+		HomeMotion:    flagHomeMotion,
+		HomeLight:     flagHomeLight,
+		Critical:      flagCriticalTypes,
+		Entertainment: entertainment,
+		Curves:        curves,
+		Bools:         bools,
+		AlertAfter:    flagStreamAlertAfter,
+	}, streamerOpts...)
+	if err != nil {
+		return err
+	}
+	g.Go(func() error {
+		err := streamer.Run(ctx)
+		if err != nil {
+			slog.Error("streamer failed", "error", err.Error())
+		}
+
+		return err
+
+	})
+	return nil
+}
+
+// commandAliases reads command_aliases (see udp.AliasConfig).
+func commandAliases() (*udp.Aliases, error) {
+	var cfg udp.AliasConfig
+	if err := viper.UnmarshalKey("command_aliases", &cfg); err != nil {
+		return nil, fmt.Errorf("command_aliases: %w", err)
+	}
+	aliases, err := udp.NewAliases(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("command_aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// failsafeRules reads e.g.
+// {"failsafe": [{"motion": "hall sensor", "group": "hall", "for": "5m"}]}.
+func failsafeRules() ([]client.FailsafeRule, error) {
+	var rules []client.FailsafeRule
+	if err := viper.UnmarshalKey("failsafe", &rules); err != nil {
+		return nil, fmt.Errorf("failsafe: %w", err)
+	}
+	if err := client.ValidateFailsafeRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// commandWindows reads e.g.
+// {"command_windows": {"kids_evening": {"target": "room/kids", "actions": ["on"], "block": "21:00-07:00"}}};
+// nil without windows.
+func commandWindows(names gateway.ACLResolver) (*gateway.Windows, error) {
+	var rules map[string]gateway.WindowRule
+	if err := viper.UnmarshalKey("command_windows", &rules); err != nil {
+		return nil, fmt.Errorf("command_windows: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	loc := time.Local
+	if flagTimezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(flagTimezone); err != nil {
+			return nil, fmt.Errorf("invalid --timezone %q: %w", flagTimezone, err)
+		}
+	}
+	return gateway.NewWindows(gateway.WindowConfig{Rules: rules, Names: names, Location: loc, Logger: slog.Default()})
+}
+
+// listenUDPPort is the port of the command server: --listen-udp-port, or the
+// Loxone port when unset, as before the flag existed.
+func listenUDPPort() int {
+	if flagListenUDPPort > 0 {
+		return flagListenUDPPort
+	}
+	return flagLoxoneUdpPort
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/spf13/viper"
+)
+
+// parseSchedule reads the poller jobs from the config on top of the defaults, e.g.
+// {"poller": {"names": {"interval": "30m", "jitter": 0.2}, "resync": {"interval": "15m"}}}
+func parseSchedule() (client.Schedule, error) {
+	s := client.DefaultSchedule()
+	if err := viper.UnmarshalKey("poller", &s); err != nil {
+		return s, fmt.Errorf("invalid poller config: %w", err)
+	}
+	if flagInventoryRefresh > 0 {
+		s.Names.Interval = flagInventoryRefresh
+	}
+	for name, job := range map[string]client.Job{"names": s.Names, "resync": s.Resync, "health": s.Health} {
+		if job.Interval < 0 || job.Timeout < 0 || job.Jitter < 0 || job.Jitter > 1 {
+			return s, fmt.Errorf("invalid poller.%s: interval and timeout must not be negative, jitter must be 0..1", name)
+		}
+	}
+	for i, job := range s.Resources {
+		if job.Type == "" || job.ID == "" {
+			return s, fmt.Errorf("invalid poller.resources[%d]: type and id are required", i)
+		}
+		if job.Interval < 5*time.Second || job.Timeout < 0 || (job.MaxInterval != 0 && job.MaxInterval < job.Interval) {
+			return s, fmt.Errorf("invalid poller.resources[%d] %s/%s: interval must be at least 5s and max_interval at least interval", i, job.Type, job.ID)
+		}
+	}
+	return s, nil
+}
+
+// pollerSchedule wires the jobs that need more than the poller itself: resync does
+// what /gateway/resync does, health probes the bridge.
+func pollerSchedule(poller *client.Poller, home *bridge.Home, state *gateway.State) (client.Schedule, error) {
+	s, err := parseSchedule()
+	if err != nil {
+		return s, err
+	}
+	s.OnResync = func(ctx context.Context) error {
+		if err := poller.Refresh(ctx); err != nil {
+			return err
+		}
+		state.Announce()
+		return nil
+	}
+	s.OnHealth = func(ctx context.Context) error {
+		err := home.Ping(ctx)
+		if errors.Is(err, context.Canceled) {
+			return err // shutting down; a timeout does count as offline
+		}
+		state.SetBridgeOnline(err == nil)
+		return err
+	}
+	return s, nil
+}
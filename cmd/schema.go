@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print every message path the current configuration can emit, as JSON",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(currentSchema())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func currentSchema() []client.PathSpec {
+	scale, _ := client.NewScale(viper.GetStringMapString("scale")) // checked by validateConfig
+	averages, _ := averageConfig()
+	return client.Schema(client.SchemaOptions{
+		Events:     flagMode != modeCommands,
+		Levels:     flagLoxoneLevels,
+		HomeMotion: flagHomeMotion,
+		HomeLight:  flagHomeLight,
+		Occupancy:  flagOccupancyDecay > 0,
+		Overrides:  flagOverrideTimeout > 0,
+		Daily:      flagDailyReport != "",
+		BatteryLow: flagBatteryLow > 0,
+		Usage:      flagUsageInterval > 0,
+		Breaker:    flagEventStormLimit > 0,
+		Inventory:  flagInventoryChanged,
+		Scale:      scale,
+		Averages:   averages.Channels,
+		AvgZones:   len(averages.Zones) > 0,
+	})
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Print the exact datagrams a sample event of every resource type produces with the current config",
+	Long: `selftest feeds one representative event per resource type through the same
+pipeline the gateway uses (scripts, scale, path style, routes, bool encodings,
+brightness curves) and prints the datagrams that would be sent to Loxone.
+It needs no bridge or Loxone; resources use the sample ids
+
+  device ` + client.SampleDevice + `
+  room   ` + client.SampleRoom + `
+  scene  ` + client.SampleScene + `
+
+Commands sent by scripts are rejected.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		poller := client.NewOfflinePoller()
+		poller.SetNameOverrides(viper.GetStringMapString("names"))
+		hooks, err := buildHooks(poller, gateway.ReadOnly{Logger: slog.Default()})
+		if err != nil {
+			return err
+		}
+		bools, err := udp.NewBools(flagBoolEncoding, viper.GetStringMapString("bool_encodings"))
+		if err != nil {
+			return err
+		}
+		curves, err := curve.New(flagBrightnessCurve, viper.GetStringMapString("brightness_curves"))
+		if err != nil {
+			return err
+		}
+
+		results, err := client.SelfTest(cmd.Context(), client.SelfTestConfig{
+			Poller:     poller,
+			Hooks:      hooks,
+			Bools:      bools,
+			Curves:     curves,
+			Critical:   flagCriticalTypes,
+			HomeMotion: flagHomeMotion,
+			HomeLight:  flagHomeLight,
+			Levels:     flagLoxoneLevels,
+		})
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tDATAGRAM\t")
+		for _, r := range results {
+			if len(r.Datagrams) == 0 {
+				fmt.Fprintf(w, "%s\t(nothing sent)\t\n", r.Type)
+			}
+			for _, d := range r.Datagrams {
+				fmt.Fprintf(w, "%s\t%s\t\n", r.Type, d)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+}
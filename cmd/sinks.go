@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/sink"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// buildSinks creates the optional outputs configured next to Loxone UDP and
+// starts their workers in g.
+func buildSinks(ctx context.Context, g *errgroup.Group) ([]client.Sink, error) {
+	// e.g. {"webhooks": [{"url": "http://nodered:1880/hue", "secret": "...", "schema_version": 1}]}
+	var sinks []client.Sink
+	var webhooks []sink.WebhookConfig
+	if err := viper.UnmarshalKey("webhooks", &webhooks); err != nil {
+		return nil, fmt.Errorf("webhooks: %w", err)
+	}
+	for _, cfg := range webhooks {
+		wh, err := sink.NewWebhook(cfg, slog.Default())
+		if err != nil {
+			return nil, err
+		}
+		g.Go(func() error {
+			return wh.Run(ctx)
+		})
+		sinks = append(sinks, wh)
+	}
+
+	// e.g. {"syslog": {"address": "10.0.0.5:514", "network": "udp"}}
+	if viper.IsSet("syslog") {
+		var syslogCfg sink.SyslogConfig
+		if err := viper.UnmarshalKey("syslog", &syslogCfg); err != nil {
+			return nil, fmt.Errorf("syslog: %w", err)
+		}
+		syslog, err := sink.NewSyslog(syslogCfg, slog.Default())
+		if err != nil {
+			return nil, err
+		}
+		g.Go(func() error {
+			return syslog.Run(ctx)
+		})
+		sinks = append(sinks, syslog)
+	}
+
+	// e.g. {"influx": {"url": "http://influx:8086/api/v2/write?org=home&bucket=hue", "token": "..."}}
+	if viper.IsSet("influx") {
+		var influxCfg sink.InfluxConfig
+		if err := viper.UnmarshalKey("influx", &influxCfg); err != nil {
+			return nil, fmt.Errorf("influx: %w", err)
+		}
+		influx, err := sink.NewInflux(influxCfg, slog.Default())
+		if err != nil {
+			return nil, err
+		}
+		g.Go(func() error {
+			return influx.Run(ctx)
+		})
+		sinks = append(sinks, influx)
+	}
+
+	return sinks, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the bridge's resource counts against their limits and the gateway's message counters",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagPhilipsHueIP == "" && flagPhilipsHueHost == "" && flagBridgeID == "" {
+			return fmt.Errorf("status requires --philips-hue-ip (or --philips-hue-host / --philips-hue-bridge-id)")
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		addr, err := bridgeAddress(ctx)
+		if err != nil {
+			return err
+		}
+		home, err := bridge.NewHome(addr, bridge.NewKeys(flagPhilipsHueApiKey, flagPhilipsHueApiKey2))
+		if err != nil {
+			return err
+		}
+		usage, err := home.Usage(ctx)
+		if err != nil {
+			return fmt.Errorf("bridge usage: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RESOURCE\tCOUNT\tLIMIT\tUSED\t")
+		for _, u := range usage {
+			warn := ""
+			if u.Near() {
+				warn = "nearly full"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.0f%%\t%s\n", u.Resource, u.Count, u.Limit, 100*u.Ratio(), warn)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return printGatewayStatus(ctx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+// printGatewayStatus prints the message counters of a gateway running with
+// --api-listen on this host; it is skipped without one.
+func printGatewayStatus(ctx context.Context) error {
+	u, err := localAPI("/api/health")
+	if err != nil || u == "" {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("gateway: not reachable (%v)\n", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	var h gateway.Health
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return fmt.Errorf("gateway health: %w", err)
+	}
+	if h.UDP == nil {
+		fmt.Println("gateway: no UDP statistics yet")
+		return nil
+	}
+	fmt.Printf("udp to loxone: %d delivered, %d dropped (queue full %d, send failed %d)\n",
+		h.UDP.Delivered, h.UDP.Dropped(), h.UDP.DroppedQueue, h.UDP.DroppedSend)
+	return nil
+}
+
+// watchUsage reports the bridge's resource counts to state every interval until
+// ctx is done; failures are logged and retried on the next tick.
+func watchUsage(ctx context.Context, home *bridge.Home, state *gateway.State, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if usage, err := home.Usage(ctx); err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("bridge usage check failed", "error", err)
+			}
+		} else {
+			state.SetUsage(usage)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// localAPI returns the URL of path on the gateway API of this host, or "" without
+// --api-listen.
+func localAPI(path string) (string, error) {
+	if flagAPIListen == "" {
+		return "", nil
+	}
+	_, port, err := net.SplitHostPort(flagAPIListen)
+	if err != nil {
+		return "", fmt.Errorf("invalid --api-listen %q: %w", flagAPIListen, err)
+	}
+	return "http://" + net.JoinHostPort("127.0.0.1", port) + path, nil
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/hue"
+	"github.com/samvdb/loxone-philips-hue/udp"
+	"github.com/spf13/viper"
+)
+
+const (
+	modeEvents   = "events"
+	modeCommands = "commands"
+	modeBoth     = "both"
+)
+
+// validateConfig checks that every setting the selected mode depends on is present.
+func validateConfig() error {
+	var missing []string
+	require := func(flag, value string) {
+		if value == "" {
+			missing = append(missing, "--"+flag)
+		}
+	}
+
+	switch flagMode {
+	case modeEvents, modeBoth:
+		require("loxone-ip", flagLoxoneIP)
+	case modeCommands:
+	default:
+		return fmt.Errorf("invalid --mode %q: expected %s, %s or %s", flagMode, modeEvents, modeCommands, modeBoth)
+	}
+	if flagPhilipsHueIP == "" && flagPhilipsHueHost == "" && flagBridgeID == "" {
+		missing = append(missing, "--philips-hue-ip (or --philips-hue-host / --philips-hue-bridge-id)")
+	}
+	require("philips-hue-apikey", flagPhilipsHueApiKey)
+
+	if _, err := parseTimeouts(flagCommandTimeouts); err != nil {
+		return err
+	}
+	for source, grammar := range flagGrammarSources {
+		if grammar != udp.GrammarV1 && grammar != udp.GrammarV2 {
+			return fmt.Errorf("invalid --command-grammar-sources %s=%q: expected %s or %s", source, grammar, udp.GrammarV1, udp.GrammarV2)
+		}
+	}
+	if _, err := curve.New(flagBrightnessCurve, viper.GetStringMapString("brightness_curves")); err != nil {
+		return err
+	}
+	if _, err := curve.NewFloors(flagMinDim, viper.GetStringMapString("min_dims")); err != nil {
+		return err
+	}
+	if _, err := hue.NewTransitions(flagTransition, viper.GetStringMapString("transitions")); err != nil {
+		return err
+	}
+	if flagCommandGrammar != udp.GrammarV1 && flagCommandGrammar != udp.GrammarV2 {
+		return fmt.Errorf("invalid --command-grammar %q: expected %s or %s", flagCommandGrammar, udp.GrammarV1, udp.GrammarV2)
+	}
+	if _, err := gateway.NewACL(viper.GetStringMapStringSlice("command_acl"), nil); err != nil {
+		return err
+	}
+	if _, err := commandAliases(); err != nil {
+		return err
+	}
+	if _, err := client.NewSampler(viper.GetStringMapString("sampling")); err != nil {
+		return err
+	}
+	if _, err := client.NewScale(viper.GetStringMapString("scale")); err != nil {
+		return err
+	}
+	if _, err := client.ParseOverrideTimeouts(viper.GetStringMapString("override_timeouts")); err != nil {
+		return err
+	}
+	if _, err := averageConfig(); err != nil {
+		return err
+	}
+	if _, err := commandWindows(nil); err != nil {
+		return err
+	}
+	if flagPersistQuiet < 0 {
+		return fmt.Errorf("invalid --persist-quiet %s: expected a positive duration", flagPersistQuiet)
+	}
+	if flagCaptureMaxSize < 4<<10 || flagCaptureFiles < 1 {
+		return fmt.Errorf("invalid --capture-max-size %d / --capture-files %d: expected at least 4 KiB and 1 file", flagCaptureMaxSize, flagCaptureFiles)
+	}
+	if flagPprof && flagAPIListen == "" {
+		return fmt.Errorf("--pprof requires --api-listen")
+	}
+	if rules, err := failsafeRules(); err != nil {
+		return err
+	} else if len(rules) > 0 && flagLoxoneIP == "" {
+		return fmt.Errorf("failsafe rules require --loxone-ip")
+	}
+	if flagLoxoneProbePort < 1 || flagLoxoneProbePort > 65535 {
+		return fmt.Errorf("invalid --loxone-probe-port %d", flagLoxoneProbePort)
+	}
+	if flagLoxoneDownAfter < time.Second {
+		return fmt.Errorf("invalid --loxone-down-after %s: expected at least 1s", flagLoxoneDownAfter)
+	}
+	switch flagStaleEvents {
+	case client.StaleOff, client.StaleMark, client.StaleDrop:
+	default:
+		return fmt.Errorf("invalid --stale-events %q: expected %s, %s or %s", flagStaleEvents, client.StaleOff, client.StaleMark, client.StaleDrop)
+	}
+	if flagStaleEvents != client.StaleOff && flagStaleAfter <= 0 {
+		return fmt.Errorf("invalid --stale-after %s: expected a positive duration", flagStaleAfter)
+	}
+	if flagMaxEventSize < 64<<10 || flagMaxEventSize > 64<<20 {
+		return fmt.Errorf("invalid --event-stream-max-event %d: expected 64 KiB to 64 MiB", flagMaxEventSize)
+	}
+	if flagUDPQueueSize < 16 || flagUDPQueueSize > 1<<16 {
+		return fmt.Errorf("invalid --udp-queue-size %d: expected 16 to 65536", flagUDPQueueSize)
+	}
+	if flagEventStormLimit < 0 || (flagEventStormLimit > 0 && flagEventStormHold < time.Second) {
+		return fmt.Errorf("invalid --event-storm-limit %d / --event-storm-hold %s: expected a limit >= 0 and a hold of at least 1s", flagEventStormLimit, flagEventStormHold)
+	}
+	if flagEventResumeMaxAge <= 0 {
+		return fmt.Errorf("invalid --event-resume-max-age %s: expected a positive duration", flagEventResumeMaxAge)
+	}
+	if _, err := udp.BindIP(flagUDPBind); err != nil {
+		return fmt.Errorf("invalid --udp-bind: %w", err)
+	}
+	if flagUDPMaxConnAge < 0 || (flagUDPMaxConnAge > 0 && flagUDPMaxConnAge < time.Minute) {
+		return fmt.Errorf("invalid --udp-max-conn-age %s: expected 0 or at least 1m", flagUDPMaxConnAge)
+	}
+	if flagCommandWorkers < 1 || flagCommandWorkers > 256 {
+		return fmt.Errorf("invalid --command-workers %d: expected 1 to 256", flagCommandWorkers)
+	}
+	if flagInventoryRefresh < 0 || (flagInventoryRefresh > 0 && flagInventoryRefresh < time.Minute) {
+		return fmt.Errorf("invalid --inventory-refresh %s: expected 0 or at least 1m", flagInventoryRefresh)
+	}
+	if flagBatteryLow < 0 || flagBatteryLow >= 100 {
+		return fmt.Errorf("invalid --battery-low %g: expected 0 <= percent < 100", flagBatteryLow)
+	}
+	if flagDailyReport != "" {
+		if _, err := parseTimeOfDay(flagDailyReport); err != nil {
+			return err
+		}
+	}
+	if _, err := parseSchedule(); err != nil {
+		return err
+	}
+	if len(flagHAPeers) > 0 && flagHAInterval <= 0 {
+		return fmt.Errorf("invalid --ha-interval %s: expected a positive duration", flagHAInterval)
+	}
+	if flagUDPDropAlert <= 0 || flagUDPDropAlert > 1 {
+		return fmt.Errorf("invalid --udp-drop-alert %g: expected 0 < rate <= 1", flagUDPDropAlert)
+	}
+	if flagStreamBackoffMax < time.Second {
+		return fmt.Errorf("invalid --event-stream-backoff-max %s: expected at least 1s", flagStreamBackoffMax)
+	}
+	if flagStreamAlertAfter < 1 {
+		return fmt.Errorf("invalid --event-stream-alert-after %d: expected at least 1", flagStreamAlertAfter)
+	}
+	if _, err := udp.NewBools(flagBoolEncoding, viper.GetStringMapString("bool_encodings")); err != nil {
+		return err
+	}
+	if flagPathStyle != client.PathStyleIDs && flagPathStyle != client.PathStyleHierarchical {
+		return fmt.Errorf("invalid --path-style %q: expected %s or %s", flagPathStyle, client.PathStyleIDs, client.PathStyleHierarchical)
+	}
+	switch flagEcho {
+	case client.EchoOff, client.EchoTag, client.EchoSuppress:
+	default:
+		return fmt.Errorf("invalid --echo %q: expected %s, %s or %s", flagEcho, client.EchoOff, client.EchoTag, client.EchoSuppress)
+	}
+	if flagLoxoneUdpPort <= 0 || flagLoxoneUdpPort > 65535 {
+		return fmt.Errorf("invalid --loxone-udp-port %d", flagLoxoneUdpPort)
+	}
+	if flagListenUDPPort < 0 || flagListenUDPPort > 65535 {
+		return fmt.Errorf("invalid --listen-udp-port %d", flagListenUDPPort)
+	}
+	if len(missing) > 0 {
+		return errors.New("mode " + flagMode + " requires " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// parseTimeOfDay converts --daily-report "07:30" to an offset from midnight.
+func parseTimeOfDay(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --daily-report %q: expected HH:MM", v)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseTimeouts converts --command-timeouts values ("15s") to durations.
+func parseTimeouts(raw map[string]string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(raw))
+	for key, v := range raw {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid --command-timeouts %s=%q: expected a positive duration", key, v)
+		}
+		out[key] = d
+	}
+	return out, nil
+}
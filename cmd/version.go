@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/samvdb/loxone-philips-hue/version"
+	"github.com/spf13/cobra"
+)
+
+var flagVersionJSON bool
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version, commit, build date and Go runtime",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := version.Get()
+		if flagVersionJSON {
+			return json.NewEncoder(os.Stdout).Encode(info)
+		}
+		fmt.Println(info)
+		return nil
+	},
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&flagVersionJSON, "json", false, "Print as JSON")
+	rootCmd.AddCommand(versionCmd)
+}
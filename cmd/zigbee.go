@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/spf13/cobra"
+)
+
+var zigbeeStatusCmd = &cobra.Command{
+	Use:   "zigbee-status",
+	Short: "Show the bridge's Zigbee channel and the connectivity of every device",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagPhilipsHueIP == "" && flagPhilipsHueHost == "" && flagBridgeID == "" {
+			return fmt.Errorf("zigbee-status requires --philips-hue-ip (or --philips-hue-host / --philips-hue-bridge-id)")
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		addr, err := bridgeAddress(ctx)
+		if err != nil {
+			return err
+		}
+		home, err := bridge.NewHome(addr, bridge.NewKeys(flagPhilipsHueApiKey, flagPhilipsHueApiKey2))
+		if err != nil {
+			return err
+		}
+		links, err := home.GetZigbeeConnectivity(ctx)
+		if err != nil {
+			return fmt.Errorf("zigbee connectivity: %w", err)
+		}
+		devices, err := home.GetDevices(ctx)
+		if err != nil {
+			return fmt.Errorf("devices: %w", err)
+		}
+		s := bridge.SummarizeZigbee(links)
+
+		channel := "unknown"
+		if s.Channel > 0 {
+			channel = fmt.Sprint(s.Channel)
+		}
+		fmt.Printf("zigbee channel: %s\ndevices: %d\n", channel, s.Devices)
+		statuses := make([]string, 0, len(s.ByStatus))
+		for status := range s.ByStatus {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Printf("  %s: %d\n", status, s.ByStatus[status])
+		}
+
+		if len(s.Problems) > 0 {
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "DEVICE\tNAME\tSTATUS\t")
+			for _, p := range s.Problems {
+				name := ""
+				if d, ok := devices[p.Owner.Rid]; ok && d.Metadata != nil && d.Metadata.Name != nil {
+					name = *d.Metadata.Name
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t\n", p.Owner.Rid, name, p.Status)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		if advice := s.Suggestion(); advice != "" {
+			fmt.Printf("\nsuggestion: %s\n", advice)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(zigbeeStatusCmd)
+}
@@ -0,0 +1,101 @@
+// Package curve maps Loxone's linear 0..100 dimmer values to Hue brightness and
+// back, since the two differ perceptually.
+package curve
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Presets accepted by Parse next to "gamma:<n>" and plain numbers.
+var Presets = map[string]float64{
+	"linear":     1.0,
+	"soft":       1.5,
+	"perceptual": 2.2,
+}
+
+// Curve is a gamma curve on the 0..100 range; Gamma 1 is linear.
+type Curve struct {
+	Gamma float64
+}
+
+var Linear = Curve{Gamma: 1}
+
+// Parse reads a preset name, "gamma:<n>" or a bare gamma value.
+func Parse(spec string) (Curve, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return Linear, nil
+	}
+	if g, ok := Presets[spec]; ok {
+		return Curve{Gamma: g}, nil
+	}
+	g, err := strconv.ParseFloat(strings.TrimPrefix(spec, "gamma:"), 64)
+	if err != nil || g <= 0 || math.IsInf(g, 0) {
+		return Curve{}, fmt.Errorf("invalid brightness curve %q: expected linear|soft|perceptual|gamma:<n>", spec)
+	}
+	return Curve{Gamma: g}, nil
+}
+
+// ToHue converts a Loxone dimmer value (0..100) to Hue brightness (0..100).
+func (c Curve) ToHue(v float64) float64 {
+	return apply(v, c.gamma())
+}
+
+// ToLoxone converts Hue brightness (0..100) back to a Loxone dimmer value.
+func (c Curve) ToLoxone(v float64) float64 {
+	return apply(v, 1/c.gamma())
+}
+
+func (c Curve) gamma() float64 {
+	if c.Gamma <= 0 {
+		return 1
+	}
+	return c.Gamma
+}
+
+func apply(v, gamma float64) float64 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 100:
+		return 100
+	}
+	return 100 * math.Pow(v/100, gamma)
+}
+
+// Curves holds a default curve and per-resource overrides (grouped_light ids).
+type Curves struct {
+	def  Curve
+	byID map[string]Curve
+}
+
+// New parses the default spec and the per-id specs.
+func New(def string, perID map[string]string) (*Curves, error) {
+	d, err := Parse(def)
+	if err != nil {
+		return nil, err
+	}
+	c := &Curves{def: d, byID: make(map[string]Curve, len(perID))}
+	for id, spec := range perID {
+		cv, err := Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		c.byID[id] = cv
+	}
+	return c, nil
+}
+
+// For returns the curve of id. A nil *Curves is linear.
+func (c *Curves) For(id string) Curve {
+	if c == nil {
+		return Linear
+	}
+	if cv, ok := c.byID[id]; ok {
+		return cv
+	}
+	return c.def
+}
@@ -0,0 +1,72 @@
+package curve
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    float64
+		wantErr bool
+	}{
+		{spec: "", want: 1},
+		{spec: "linear", want: 1},
+		{spec: "Perceptual", want: 2.2},
+		{spec: "gamma:1.8", want: 1.8},
+		{spec: "2", want: 2},
+		{spec: "gamma:0", wantErr: true},
+		{spec: "steep", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) expected error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.spec, err)
+			}
+			if got.Gamma != tt.want {
+				t.Errorf("Parse(%q).Gamma = %v, want %v", tt.spec, got.Gamma, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurve_RoundTrip(t *testing.T) {
+	c := Curve{Gamma: 2.2}
+	for _, v := range []float64{0, 1, 25, 50, 99, 100} {
+		if got := c.ToLoxone(c.ToHue(v)); math.Abs(got-v) > 1e-9 {
+			t.Errorf("ToLoxone(ToHue(%v)) = %v", v, got)
+		}
+	}
+	if got := c.ToHue(50); math.Abs(got-21.764) > 0.01 {
+		t.Errorf("ToHue(50) = %v, want ~21.76", got)
+	}
+}
+
+func TestCurves_For(t *testing.T) {
+	c, err := New("linear", map[string]string{"g1": "perceptual"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if got := c.For("g1").Gamma; got != 2.2 {
+		t.Errorf("For(g1).Gamma = %v, want 2.2", got)
+	}
+	if got := c.For("g2").Gamma; got != 1 {
+		t.Errorf("For(g2).Gamma = %v, want 1", got)
+	}
+	var none *Curves
+	if got := none.For("g1"); got != Linear {
+		t.Errorf("nil Curves For() = %v, want Linear", got)
+	}
+}
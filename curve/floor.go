@@ -0,0 +1,84 @@
+package curve
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Floor is the minimum Hue brightness a light or group is driven at; some bulbs
+// flicker at very low levels. Values below Min are raised to Min, or switch the
+// light off when Off is set.
+type Floor struct {
+	Min float64
+	Off bool
+}
+
+// ParseFloor reads "<min>" (clamp) or "<min>:off", e.g. "5" or "5:off".
+func ParseFloor(spec string) (Floor, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return Floor{}, nil
+	}
+	num, mode, _ := strings.Cut(spec, ":")
+	min, err := strconv.ParseFloat(strings.TrimSuffix(num, "%"), 64)
+	if err != nil || min < 0 || min > 100 {
+		return Floor{}, fmt.Errorf("invalid minimum dim level %q: expected 0..100[:off|:clamp]", spec)
+	}
+	switch mode {
+	case "", "clamp":
+		return Floor{Min: min}, nil
+	case "off":
+		return Floor{Min: min, Off: true}, nil
+	default:
+		return Floor{}, fmt.Errorf("invalid minimum dim level %q: mode must be off or clamp", spec)
+	}
+}
+
+// Apply returns the brightness to send and whether the light should be on.
+func (f Floor) Apply(v float64) (float64, bool) {
+	switch {
+	case v <= 0:
+		return 0, false
+	case v >= f.Min:
+		return v, true
+	case f.Off:
+		return 0, false
+	default:
+		return f.Min, true
+	}
+}
+
+// Floors holds a default floor and per-resource overrides (grouped_light ids).
+type Floors struct {
+	def  Floor
+	byID map[string]Floor
+}
+
+// NewFloors parses the default spec and the per-id specs.
+func NewFloors(def string, perID map[string]string) (*Floors, error) {
+	d, err := ParseFloor(def)
+	if err != nil {
+		return nil, err
+	}
+	f := &Floors{def: d, byID: make(map[string]Floor, len(perID))}
+	for id, spec := range perID {
+		fl, err := ParseFloor(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		f.byID[id] = fl
+	}
+	return f, nil
+}
+
+// For returns the floor of id. A nil *Floors has no floor.
+func (f *Floors) For(id string) Floor {
+	if f == nil {
+		return Floor{}
+	}
+	if fl, ok := f.byID[id]; ok {
+		return fl
+	}
+	return f.def
+}
@@ -0,0 +1,44 @@
+package curve
+
+import "testing"
+
+func TestFloor_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		in     float64
+		want   float64
+		wantOn bool
+	}{
+		{name: "no floor", spec: "", in: 1, want: 1, wantOn: true},
+		{name: "zero is off", spec: "5", in: 0, want: 0, wantOn: false},
+		{name: "clamped", spec: "5", in: 2, want: 5, wantOn: true},
+		{name: "explicit clamp", spec: "5%:clamp", in: 2, want: 5, wantOn: true},
+		{name: "below floor switches off", spec: "5:off", in: 2, want: 0, wantOn: false},
+		{name: "above floor", spec: "5:off", in: 40, want: 40, wantOn: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := ParseFloor(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseFloor(%q) unexpected error: %v", tt.spec, err)
+			}
+			got, on := f.Apply(tt.in)
+			if got != tt.want || on != tt.wantOn {
+				t.Errorf("Apply(%v) = %v, %v; want %v, %v", tt.in, got, on, tt.want, tt.wantOn)
+			}
+		})
+	}
+}
+
+func TestParseFloor_Invalid(t *testing.T) {
+	for _, spec := range []string{"abc", "101", "5:dim"} {
+		if _, err := ParseFloor(spec); err == nil {
+			t.Errorf("ParseFloor(%q) expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// ACLResolver places resources in their room or zone (usually the client.Poller).
+type ACLResolver interface {
+	GetAlias(id string) string
+	GroupOwner(groupedLightID string) string
+	SceneGroup(sceneID string) string
+}
+
+// ACL restricts what a command source may control. Sources without rules may
+// control everything. It implements udp.Authorizer.
+type ACL struct {
+	rules []aclRule
+	names ACLResolver
+}
+
+type aclRule struct {
+	source  *net.IPNet
+	targets []aclTarget
+}
+
+// aclTarget is "*", a domain ("scene") or a domain and resource ("zone/garden").
+type aclTarget struct {
+	domain resource.Type
+	ref    string // resource id or name; "" matches the whole domain
+}
+
+// NewACL parses rules keyed by source IP or CIDR, e.g.
+// {"192.168.1.77": ["zone/garden"], "10.0.0.0/24": ["scene", "gateway"]}.
+// A room or zone target also covers its grouped_light and scenes; resources are
+// matched by id or by name (names need names).
+func NewACL(rules map[string][]string, names ACLResolver) (*ACL, error) {
+	a := &ACL{names: names}
+	for source, targets := range rules {
+		n, err := parseSource(source)
+		if err != nil {
+			return nil, err
+		}
+		r := aclRule{source: n}
+		for _, t := range targets {
+			target, err := parseTarget(t)
+			if err != nil {
+				return nil, fmt.Errorf("command acl for %s: %w", source, err)
+			}
+			r.targets = append(r.targets, target)
+		}
+		a.rules = append(a.rules, r)
+	}
+	return a, nil
+}
+
+func parseTarget(s string) (aclTarget, error) {
+	domain, ref, _ := strings.Cut(strings.TrimSpace(s), "/")
+	if domain == "" || strings.Contains(ref, "/") {
+		return aclTarget{}, fmt.Errorf("invalid target %q: expected *, <domain> or <domain>/<id or name>", s)
+	}
+	return aclTarget{domain: resource.Type(domain), ref: ref}, nil
+}
+
+func parseSource(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command acl source %q: %w", s, err)
+		}
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid command acl source %q: expected an IP or CIDR", s)
+	}
+	bits := 8 * len(ip.To16())
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Authorize allows cmd when no rule covers src, or when a rule for src has a
+// matching target.
+func (a *ACL) Authorize(src net.IP, cmd udp.Command) error {
+	restricted := false
+	for _, r := range a.rules {
+		if !r.source.Contains(src) {
+			continue
+		}
+		restricted = true
+		for _, t := range r.targets {
+			if a.allows(t, cmd) {
+				return nil
+			}
+		}
+	}
+	if !restricted {
+		return nil
+	}
+	return fmt.Errorf("source %s may not control %s/%s", src, cmd.Domain, cmd.ID)
+}
+
+func (a *ACL) allows(t aclTarget, cmd udp.Command) bool {
+	if t.domain == "*" {
+		return true
+	}
+	if t.domain == cmd.Domain && (t.ref == "" || a.is(string(cmd.ID), t.ref)) {
+		return true
+	}
+	if (t.domain != resource.TypeRoom && t.domain != resource.TypeZone) || t.ref == "" || a.names == nil {
+		return false
+	}
+	switch cmd.Domain {
+	case resource.TypeGroupedLight:
+		return a.is(a.names.GroupOwner(string(cmd.ID)), t.ref)
+	case resource.TypeScene:
+		return a.is(a.names.SceneGroup(string(cmd.ID)), t.ref)
+	}
+	return false
+}
+
+// is reports whether id is ref, by id or (case-insensitive) name.
+func (a *ACL) is(id, ref string) bool {
+	if id == "" {
+		return false
+	}
+	if id == ref {
+		return true
+	}
+	return a.names != nil && strings.EqualFold(a.names.GetAlias(id), ref)
+}
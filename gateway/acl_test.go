@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"net"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type fakeResolver struct{}
+
+func (fakeResolver) GetAlias(id string) string {
+	return map[string]string{"zone-g": "Garden", "room-k": "Kitchen"}[id]
+}
+
+func (fakeResolver) GroupOwner(id string) string {
+	return map[string]string{"gl-g": "zone-g", "gl-k": "room-k"}[id]
+}
+
+func (fakeResolver) SceneGroup(id string) string {
+	return map[string]string{"sc-g": "zone-g", "sc-k": "room-k"}[id]
+}
+
+func TestACLAuthorize(t *testing.T) {
+	acl, err := NewACL(map[string][]string{
+		"192.168.1.77": {"zone/garden"},
+		"10.0.0.0/24":  {"scene", "gateway"},
+	}, fakeResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		cmd  udp.Command
+		want bool
+	}{
+		{name: "unlisted source", src: "192.168.1.10", cmd: udp.Command{Domain: "grouped_light", ID: "gl-k"}, want: true},
+		{name: "zone by name", src: "192.168.1.77", cmd: udp.Command{Domain: "zone", ID: "zone-g"}, want: true},
+		{name: "grouped_light of the zone", src: "192.168.1.77", cmd: udp.Command{Domain: "grouped_light", ID: "gl-g"}, want: true},
+		{name: "scene of the zone", src: "192.168.1.77", cmd: udp.Command{Domain: "scene", ID: "sc-g"}, want: true},
+		{name: "other room", src: "192.168.1.77", cmd: udp.Command{Domain: "grouped_light", ID: "gl-k"}, want: false},
+		{name: "other scene", src: "192.168.1.77", cmd: udp.Command{Domain: "scene", ID: "sc-k"}, want: false},
+		{name: "raw", src: "192.168.1.77", cmd: udp.Command{Domain: udp.DomainRaw, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Rtype: "light"}, want: false},
+		{name: "gateway not granted", src: "192.168.1.77", cmd: udp.Command{Domain: "gateway", Action: "resync"}, want: false},
+		{name: "cidr domain", src: "10.0.0.5", cmd: udp.Command{Domain: "scene", ID: "sc-k"}, want: true},
+		{name: "cidr gateway", src: "10.0.0.5", cmd: udp.Command{Domain: "gateway", Action: "night"}, want: true},
+		{name: "cidr other domain", src: "10.0.0.5", cmd: udp.Command{Domain: "grouped_light", ID: "gl-g"}, want: false},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := acl.Authorize(net.ParseIP(tt.src), tt.cmd)
+			if got := err == nil; got != tt.want {
+				t.Errorf("Authorize() error = %v, want allowed=%v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewACL_Invalid(t *testing.T) {
+	for _, rules := range []map[string][]string{
+		{"not-an-ip": {"*"}},
+		{"10.0.0.0/33": {"*"}},
+		{"10.0.0.1": {"zone/a/b"}},
+		{"10.0.0.1": {""}},
+	} {
+		if _, err := NewACL(rules, nil); err == nil {
+			t.Errorf("NewACL(%v) error = nil, want error", rules)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Audit outcomes reported in AuditRecord.Outcome.
+const (
+	AuditOK     = "ok"
+	AuditFailed = "failed"
+)
+
+type AuditConfig struct {
+	// File (optional) gets one JSON line per command, appended across restarts,
+	// so the trail survives the in-memory history.
+	File string
+
+	// Size is how many records History keeps in memory. Default 1000.
+	Size int
+
+	// Names (optional) resolves resource ids to their names (usually the
+	// client.Poller).
+	Names AuditNames
+
+	// Reader (optional) reads the state of the target before and after each
+	// command; it costs two bridge requests per command.
+	Reader udp.StateReader
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+type AuditNames interface {
+	GetAlias(id string) string
+}
+
+// AuditRecord is one applied command, for "who turned the lights off at 3am".
+// The command is kept as plain strings: composite and raw ids are not UUIDs.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source,omitempty"` // see udp.WithSource
+	Domain  string    `json:"domain"`
+	ID      string    `json:"id"`
+	Action  string    `json:"action"`
+	Value   string    `json:"value,omitempty"`
+	Name    string    `json:"name,omitempty"`   // resolved resource name
+	Before  []string  `json:"before,omitempty"` // state lines, e.g. "/grouped_light/<id>/on 1"
+	After   []string  `json:"after,omitempty"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// AuditQuery selects records; zero fields match everything.
+type AuditQuery struct {
+	Since  time.Time
+	Until  time.Time
+	Target string // resource id or name, case-insensitive
+	Source string // prefix, e.g. "udp:" or "api:10.0.0.5"
+	Limit  int    // newest records kept
+}
+
+// Audit records every command passing the handler returned from Track with its
+// source, the resource name, the state around it and the outcome.
+type Audit struct {
+	cfg AuditConfig
+	log *slog.Logger
+	now func() time.Time
+
+	mu     sync.Mutex
+	recent []AuditRecord
+	file   *os.File
+}
+
+func NewAudit(cfg AuditConfig) (*Audit, error) {
+	if cfg.Size <= 0 {
+		cfg.Size = 1000
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	a := &Audit{cfg: cfg, log: cfg.Logger.With("module", "audit"), now: time.Now}
+	if cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("audit file: %w", err)
+		}
+		a.file = f
+	}
+	return a, nil
+}
+
+// Close closes the audit file.
+func (a *Audit) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// Track wraps next so every command it applies is recorded.
+func (a *Audit) Track(next udp.CommandHandler) udp.CommandHandler {
+	return auditTracker{audit: a, next: next}
+}
+
+type auditTracker struct {
+	audit *Audit
+	next  udp.CommandHandler
+}
+
+func (t auditTracker) Apply(ctx context.Context, cmd udp.Command) error {
+	a := t.audit
+	rec := AuditRecord{
+		Time:    a.now(),
+		Source:  udp.Source(ctx),
+		Domain:  string(cmd.Domain),
+		ID:      string(cmd.ID),
+		Action:  cmd.Action,
+		Value:   cmd.Value.String(),
+		Outcome: AuditOK,
+	}
+	if a.cfg.Names != nil {
+		rec.Name = a.cfg.Names.GetAlias(string(cmd.ID))
+	}
+	rec.Before = a.read(ctx, cmd)
+	err := t.next.Apply(ctx, cmd)
+	if err != nil {
+		rec.Outcome, rec.Error = AuditFailed, err.Error()
+	} else {
+		rec.After = a.read(ctx, cmd)
+	}
+	a.record(rec)
+	return err
+}
+
+// read returns the state lines of cmd's target; domains the reader does not
+// know leave them empty.
+func (a *Audit) read(ctx context.Context, cmd udp.Command) []string {
+	if a.cfg.Reader == nil {
+		return nil
+	}
+	lines, err := a.cfg.Reader.Read(ctx, cmd)
+	if err != nil {
+		return nil
+	}
+	return lines
+}
+
+func (a *Audit) record(rec AuditRecord) {
+	a.log.Info("command", "source", rec.Source, "domain", rec.Domain, "id", rec.ID, "name", rec.Name,
+		"action", rec.Action, "value", rec.Value, "before", rec.Before, "after", rec.After, "outcome", rec.Outcome, "error", rec.Error)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recent = append(a.recent, rec)
+	if len(a.recent) > a.cfg.Size {
+		a.recent = a.recent[len(a.recent)-a.cfg.Size:]
+	}
+	if a.file == nil {
+		return
+	}
+	b, err := json.Marshal(rec)
+	if err == nil {
+		_, err = a.file.Write(append(b, '\n'))
+	}
+	if err != nil {
+		a.log.Warn("audit file write failed", "file", a.cfg.File, "error", err)
+	}
+}
+
+// History returns the in-memory records matching q, oldest first.
+func (a *Audit) History(q AuditQuery) []AuditRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return q.filter(a.recent)
+}
+
+// ReadAudit returns the records of an audit file matching q, oldest first.
+func ReadAudit(path string, q AuditQuery) ([]AuditRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []AuditRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		var rec AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if q.match(rec) {
+			recs = append(recs, rec)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return q.limit(recs), nil
+}
+
+// ParseAuditQuery reads a query from "since", "until" (RFC 3339 or a duration
+// back from now, e.g. 12h), "target", "source" and "limit" values, as used by
+// the history subcommand and GET /api/history.
+func ParseAuditQuery(get func(key string) string, now time.Time) (AuditQuery, error) {
+	q := AuditQuery{Target: get("target"), Source: get("source")}
+	var err error
+	if q.Since, err = parseAuditTime(get("since"), now); err != nil {
+		return AuditQuery{}, fmt.Errorf("since: %w", err)
+	}
+	if q.Until, err = parseAuditTime(get("until"), now); err != nil {
+		return AuditQuery{}, fmt.Errorf("until: %w", err)
+	}
+	if s := get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return AuditQuery{}, fmt.Errorf("invalid limit %q", s)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+func parseAuditTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC 3339 time or a duration, e.g. 12h")
+	}
+	return t, nil
+}
+
+func (q AuditQuery) filter(recs []AuditRecord) []AuditRecord {
+	var out []AuditRecord
+	for _, rec := range recs {
+		if q.match(rec) {
+			out = append(out, rec)
+		}
+	}
+	return q.limit(out)
+}
+
+func (q AuditQuery) match(rec AuditRecord) bool {
+	switch {
+	case !q.Since.IsZero() && rec.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && rec.Time.After(q.Until):
+		return false
+	case q.Source != "" && !strings.HasPrefix(rec.Source, q.Source):
+		return false
+	case q.Target != "" && !strings.EqualFold(rec.ID, q.Target) && !strings.EqualFold(rec.Name, q.Target):
+		return false
+	}
+	return true
+}
+
+func (q AuditQuery) limit(recs []AuditRecord) []AuditRecord {
+	if q.Limit > 0 && len(recs) > q.Limit {
+		return recs[len(recs)-q.Limit:]
+	}
+	return recs
+}
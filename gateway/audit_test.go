@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type auditNames map[string]string
+
+func (n auditNames) GetAlias(id string) string { return n[id] }
+
+type failHandler struct{}
+
+func (failHandler) Apply(ctx context.Context, cmd udp.Command) error {
+	return errors.New("bridge said no")
+}
+
+// stateReader reports "on" as 1 after the first read.
+type stateReader struct{ reads int }
+
+func (r *stateReader) Read(ctx context.Context, cmd udp.Command) ([]string, error) {
+	r.reads++
+	if r.reads == 1 {
+		return []string{"/grouped_light/" + string(cmd.ID) + "/on 0"}, nil
+	}
+	return []string{"/grouped_light/" + string(cmd.ID) + "/on 1"}, nil
+}
+
+func TestAudit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	reader := &stateReader{}
+	a, err := NewAudit(AuditConfig{File: file, Names: auditNames{"gl-1": "Kitchen"}, Reader: reader})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	on := udp.Command{Domain: "grouped_light", ID: "gl-1", Action: "on", Value: udp.BoolValue(true)}
+	if err := a.Track(nopHandler{}).Apply(udp.WithSource(context.Background(), "udp:192.168.1.77"), on); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	off := udp.Command{Domain: "grouped_light", ID: "gl-2", Action: "on", Value: udp.BoolValue(false)}
+	if err := a.Track(failHandler{}).Apply(udp.WithSource(context.Background(), "failsafe"), off); err == nil {
+		t.Fatal("Apply() error = nil, want the handler's error")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AuditRecord{
+		{
+			Time: time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC), Source: "udp:192.168.1.77", Domain: "grouped_light", ID: "gl-1", Action: "on", Value: "true", Name: "Kitchen",
+			Before: []string{"/grouped_light/gl-1/on 0"}, After: []string{"/grouped_light/gl-1/on 1"}, Outcome: AuditOK,
+		},
+		{
+			Time: time.Date(2025, 3, 1, 4, 0, 0, 0, time.UTC), Source: "failsafe", Domain: "grouped_light", ID: "gl-2", Action: "on", Value: "false",
+			Before: []string{"/grouped_light/gl-2/on 1"}, Outcome: AuditFailed, Error: "bridge said no",
+		},
+	}
+	if got := a.History(AuditQuery{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("History() = %+v, want %+v", got, want)
+	}
+	got, err := ReadAudit(file, AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAudit() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name  string
+		query AuditQuery
+		want  int // index into want, -1 for no match
+	}{
+		{name: "by name", query: AuditQuery{Target: "kitchen"}, want: 0},
+		{name: "by id", query: AuditQuery{Target: "gl-2"}, want: 1},
+		{name: "by source prefix", query: AuditQuery{Source: "udp:"}, want: 0},
+		{name: "since", query: AuditQuery{Since: time.Date(2025, 3, 1, 3, 30, 0, 0, time.UTC)}, want: 1},
+		{name: "limit keeps newest", query: AuditQuery{Limit: 1}, want: 1},
+		{name: "no match", query: AuditQuery{Source: "api:"}, want: -1},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := a.History(tt.query)
+			switch {
+			case tt.want < 0 && len(got) != 0:
+				t.Errorf("History(%+v) = %+v, want none", tt.query, got)
+			case tt.want >= 0 && (len(got) != 1 || !reflect.DeepEqual(got[0], want[tt.want])):
+				t.Errorf("History(%+v) = %+v, want %+v", tt.query, got, want[tt.want])
+			}
+		})
+	}
+}
+
+func TestParseAuditQuery(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	params := map[string]string{"since": "12h", "until": "2025-03-01T11:00:00Z", "target": "Kitchen", "limit": "5"}
+	q, err := ParseAuditQuery(func(key string) string { return params[key] }, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AuditQuery{Since: now.Add(-12 * time.Hour), Until: now.Add(-time.Hour), Target: "Kitchen", Limit: 5}
+	if q != want {
+		t.Errorf("ParseAuditQuery() = %+v, want %+v", q, want)
+	}
+
+	for _, bad := range []map[string]string{{"since": "yesterday"}, {"limit": "-1"}} {
+		if _, err := ParseAuditQuery(func(key string) string { return bad[key] }, now); err == nil {
+			t.Errorf("ParseAuditQuery(%v) error = nil", bad)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type ControllerConfig struct {
+	State *State
+
+	// Level is the log level changed by /gateway/loglevel.
+	Level *slog.LevelVar
+
+	// Refresh reloads the bridge inventory (usually Poller.Refresh).
+	Refresh func(ctx context.Context) error
+
+	// Pauses (optional) is changed by /gateway/pause and /gateway/resume.
+	Pauses *Pauses
+
+	// Windows (optional) is lifted by /gateway/allow.
+	Windows *Windows
+}
+
+// Controller handles /gateway/... commands so Loxone can administer the gateway
+// without SSH. It implements udp.GatewayHandler.
+type Controller struct {
+	cfg ControllerConfig
+}
+
+func NewController(cfg ControllerConfig) *Controller {
+	return &Controller{cfg: cfg}
+}
+
+func (c *Controller) HandleGateway(ctx context.Context, cmd udp.GatewayCommand) error {
+	switch cmd.Action {
+	case "refresh_names":
+		return c.refresh(ctx)
+	case "resync":
+		if err := c.refresh(ctx); err != nil {
+			return err
+		}
+		c.cfg.State.Announce()
+		return nil
+	case "loglevel":
+		if c.cfg.Level == nil {
+			return fmt.Errorf("log level is not adjustable")
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(cmd.Value)); err != nil {
+			return err
+		}
+		c.cfg.Level.Set(level)
+		slog.Info("log level changed", "level", level.String())
+		return nil
+	case "vacation", "night":
+		v := strings.ToLower(cmd.Value)
+		c.cfg.State.SetMode(cmd.Action, v == "true" || v == "1")
+		return nil
+	case "pause":
+		if c.cfg.Pauses == nil {
+			return fmt.Errorf("pausing is not available")
+		}
+		scope, raw, _ := strings.Cut(cmd.Value, " ")
+		var d time.Duration
+		if raw != "" {
+			var err error
+			if d, err = time.ParseDuration(raw); err != nil {
+				return err
+			}
+		}
+		return c.cfg.Pauses.Pause(scope, d)
+	case "resume":
+		if c.cfg.Pauses == nil {
+			return fmt.Errorf("pausing is not available")
+		}
+		return c.cfg.Pauses.Resume(cmd.Value)
+	case "allow":
+		if c.cfg.Windows == nil {
+			return fmt.Errorf("no command windows are configured")
+		}
+		name, raw, _ := strings.Cut(cmd.Value, " ")
+		var d time.Duration
+		if raw != "" {
+			var err error
+			if d, err = time.ParseDuration(raw); err != nil {
+				return err
+			}
+		}
+		return c.cfg.Windows.Allow(name, d)
+	default:
+		return fmt.Errorf("unsupported gateway action: %s", cmd.Action)
+	}
+}
+
+func (c *Controller) refresh(ctx context.Context) error {
+	if c.cfg.Refresh == nil {
+		return fmt.Errorf("refresh not available")
+	}
+	return c.cfg.Refresh(ctx)
+}
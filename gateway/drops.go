@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type DropConfig struct {
+	// Stats returns the UDP client's counters (usually udp.Client.Stats).
+	Stats func() udp.ClientStats
+	State *State
+
+	// Threshold is the share of dropped messages per interval (0..1) that raises
+	// /gateway/alert udp_drops. Default 0.01.
+	Threshold float64
+
+	// Interval between checks. Default 1m.
+	Interval time.Duration
+}
+
+// WatchDrops publishes the UDP delivery counters every interval and alerts when
+// the drop rate of the last interval exceeds the threshold.
+func WatchDrops(ctx context.Context, cfg DropConfig) {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 0.01
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var prev udp.ClientStats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cur := cfg.Stats()
+		cfg.State.SetUDPStats(cur)
+		if rate, dropped := dropRate(prev, cur); rate > cfg.Threshold {
+			cfg.State.Alert("udp_drops", fmt.Errorf("%d of %d messages dropped in the last %s (%.1f%%)", dropped, dropped+cur.Delivered-prev.Delivered, cfg.Interval, 100*rate))
+		}
+		prev = cur
+	}
+}
+
+// dropRate returns the share of messages dropped between two snapshots.
+func dropRate(prev, cur udp.ClientStats) (float64, uint64) {
+	dropped := cur.Dropped() - prev.Dropped()
+	total := dropped + cur.Delivered - prev.Delivered
+	if total == 0 {
+		return 0, 0
+	}
+	return float64(dropped) / float64(total), dropped
+}
@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func TestDropRate(t *testing.T) {
+	tests := []struct {
+		name        string
+		prev, cur   udp.ClientStats
+		wantRate    float64
+		wantDropped uint64
+	}{
+		{name: "idle", wantRate: 0},
+		{name: "no drops", prev: udp.ClientStats{Delivered: 10}, cur: udp.ClientStats{Delivered: 110}, wantRate: 0},
+		{
+			name:        "queue and send drops",
+			prev:        udp.ClientStats{Delivered: 100, DroppedQueue: 5},
+			cur:         udp.ClientStats{Delivered: 190, DroppedQueue: 12, DroppedSend: 3},
+			wantRate:    0.1,
+			wantDropped: 10,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rate, dropped := dropRate(tt.prev, tt.cur)
+			if rate != tt.wantRate || dropped != tt.wantDropped {
+				t.Errorf("dropRate() = %v, %d, want %v, %d", rate, dropped, tt.wantRate, tt.wantDropped)
+			}
+		})
+	}
+}
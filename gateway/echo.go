@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// DefaultEchoWindow is how long after a command its resource's events count as
+// the command's echo; the bridge reports the change well within a second.
+const DefaultEchoWindow = 3 * time.Second
+
+// EchoResolver places grouped_lights and scenes in their room or zone (usually
+// the client.Poller).
+type EchoResolver interface {
+	GroupOwner(groupedLightID string) string
+	SceneGroup(sceneID string) string
+}
+
+// Echoes remembers the resources Loxone commanded recently, so the event stream
+// feedback of those commands can be recognized instead of re-triggering Loxone
+// logic. Commands are recorded by the handler returned from Track, together with
+// the room or zone they address, whose member lights report the change.
+type Echoes struct {
+	window time.Duration
+	now    func() time.Time
+	owners EchoResolver
+
+	mu     sync.Mutex
+	recent map[string]time.Time // key: resource id, value: last command
+}
+
+// NewEchoes returns an empty tracker; owners is optional, without it group and
+// scene commands only match events of the grouped_light or scene itself.
+func NewEchoes(window time.Duration, owners EchoResolver) *Echoes {
+	if window <= 0 {
+		window = DefaultEchoWindow
+	}
+	return &Echoes{window: window, now: time.Now, owners: owners, recent: make(map[string]time.Time)}
+}
+
+// Track wraps next so every command is recorded before it reaches the bridge;
+// the event may arrive before the bridge answered the command.
+func (e *Echoes) Track(next udp.CommandHandler) udp.CommandHandler {
+	return echoTracker{echoes: e, next: next}
+}
+
+type echoTracker struct {
+	echoes *Echoes
+	next   udp.CommandHandler
+}
+
+func (t echoTracker) Apply(ctx context.Context, cmd udp.Command) error {
+	t.echoes.record(string(cmd.ID), t.echoes.owner(cmd))
+	return t.next.Apply(ctx, cmd)
+}
+
+// owner returns the room or zone cmd addresses through its grouped_light or
+// scene, or "".
+func (e *Echoes) owner(cmd udp.Command) string {
+	if e.owners == nil {
+		return ""
+	}
+	switch cmd.Domain {
+	case resource.TypeGroupedLight:
+		return e.owners.GroupOwner(string(cmd.ID))
+	case resource.TypeScene:
+		return e.owners.SceneGroup(string(cmd.ID))
+	}
+	return ""
+}
+
+func (e *Echoes) record(ids ...string) {
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range ids {
+		if id != "" {
+			e.recent[id] = now
+		}
+	}
+	for k, t := range e.recent {
+		if now.Sub(t) > e.window {
+			delete(e.recent, k)
+		}
+	}
+}
+
+// Recent reports whether any of ids was commanded within the window.
+func (e *Echoes) Recent(ids ...string) bool {
+	if e == nil {
+		return false
+	}
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range ids {
+		if t, ok := e.recent[id]; ok && now.Sub(t) <= e.window {
+			return true
+		}
+	}
+	return false
+}
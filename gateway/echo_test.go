@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type nopHandler struct{}
+
+func (nopHandler) Apply(ctx context.Context, cmd udp.Command) error { return nil }
+
+func TestEchoes(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := NewEchoes(2*time.Second, nil)
+	e.now = func() time.Time { return now }
+	h := e.Track(nopHandler{})
+
+	_ = h.Apply(context.Background(), udp.Command{Domain: "grouped_light", ID: "gl-1", Action: "on", Value: udp.BoolValue(true)})
+	raw, err := udp.NewRawCommand("light", "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", []byte(`{"on":{"on":true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Apply(context.Background(), raw)
+
+	if !e.Recent("gl-1") || !e.Recent("other", "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab") {
+		t.Error("commanded resources not recent")
+	}
+	if e.Recent("gl-2") {
+		t.Error("uncommanded resource recent")
+	}
+	now = now.Add(3 * time.Second)
+	if e.Recent("gl-1") {
+		t.Error("echo window did not expire")
+	}
+}
+
+func TestEchoes_RecordsOwningGroup(t *testing.T) {
+	e := NewEchoes(2*time.Second, fakeResolver{})
+	h := e.Track(nopHandler{})
+
+	_ = h.Apply(context.Background(), udp.Command{Domain: "grouped_light", ID: "gl-k", Action: "dimmable", Value: udp.PercentValue(40)})
+	_ = h.Apply(context.Background(), udp.Command{Domain: "scene", ID: "sc-g", Action: "on", Value: udp.BoolValue(true)})
+
+	// the member lights of the kitchen and the garden report the change
+	if !e.Recent("room-k") {
+		t.Error("room of the commanded grouped_light not recent")
+	}
+	if !e.Recent("zone-g") {
+		t.Error("zone of the recalled scene not recent")
+	}
+	if e.Recent("room-x") {
+		t.Error("uncommanded room recent")
+	}
+}
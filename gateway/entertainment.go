@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Entertainment tracks active entertainment sessions (Hue Sync, gaming, ...). While
+// one streams, the bridge ignores regular commands for the lights in its area.
+type Entertainment struct {
+	// Replay (optional) receives deferred commands once the last session ended.
+	Replay udp.CommandHandler
+
+	mu       sync.Mutex
+	active   map[string]bool // entertainment_configuration ids
+	deferred map[string]udp.Command
+}
+
+func NewEntertainment() *Entertainment {
+	return &Entertainment{
+		active:   make(map[string]bool),
+		deferred: make(map[string]udp.Command),
+	}
+}
+
+// SetActive records the status of an entertainment configuration. When the last
+// session stops, deferred commands are handed to Replay.
+func (e *Entertainment) SetActive(configID string, active bool) {
+	e.mu.Lock()
+	if e.active[configID] == active {
+		e.mu.Unlock()
+		return
+	}
+	if active {
+		e.active[configID] = true
+	} else {
+		delete(e.active, configID)
+	}
+	var replay []udp.Command
+	if len(e.active) == 0 {
+		for _, cmd := range e.deferred {
+			replay = append(replay, cmd)
+		}
+		e.deferred = make(map[string]udp.Command)
+	}
+	e.mu.Unlock()
+
+	slog.Info("entertainment session changed", "config", configID, "active", active)
+	if len(replay) > 0 && e.Replay != nil {
+		go e.replay(replay)
+	}
+}
+
+// Active returns the ids of the running entertainment configurations.
+func (e *Entertainment) Active() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]string, 0, len(e.active))
+	for id := range e.active {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Defer holds cmd until no session is active. A later command for the same
+// target and action replaces an earlier one.
+func (e *Entertainment) Defer(cmd udp.Command) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.deferred[cmd.Key()] = cmd
+}
+
+func (e *Entertainment) replay(cmds []udp.Command) {
+	for _, cmd := range cmds {
+		ctx, cancel := context.WithTimeout(udp.WithSource(context.Background(), "entertainment"), 5*time.Second)
+		if err := e.Replay.Apply(ctx, cmd); err != nil {
+			slog.Warn("deferred command failed", "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action, "error", err)
+		} else {
+			slog.Info("deferred command applied", "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action)
+		}
+		cancel()
+	}
+}
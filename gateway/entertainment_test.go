@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type recordHandler chan udp.Command
+
+func (r recordHandler) Apply(_ context.Context, cmd udp.Command) error {
+	r <- cmd
+	return nil
+}
+
+func TestEntertainment_ReplaysLatestDeferredCommand(t *testing.T) {
+	t.Parallel()
+
+	replayed := make(recordHandler, 4)
+	ent := NewEntertainment()
+	ent.Replay = replayed
+
+	ent.SetActive("cfg-1", true)
+	ent.SetActive("cfg-2", true)
+	ent.Defer(udp.Command{Domain: "grouped_light", ID: "g1", Action: "dimmable", Value: udp.PercentValue(20)})
+	ent.Defer(udp.Command{Domain: "grouped_light", ID: "g1", Action: "dimmable", Value: udp.PercentValue(80)})
+
+	ent.SetActive("cfg-1", false)
+	select {
+	case cmd := <-replayed:
+		t.Fatalf("replayed %+v while cfg-2 is still active", cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ent.SetActive("cfg-2", false)
+	select {
+	case cmd := <-replayed:
+		if cmd.Value.Raw != "80" {
+			t.Errorf("replayed Value = %q, want %q", cmd.Value.Raw, "80")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deferred command was not replayed")
+	}
+	if got := ent.Active(); len(got) != 0 {
+		t.Errorf("Active() = %v, want none", got)
+	}
+}
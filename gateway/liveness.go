@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type LivenessConfig struct {
+	// Addr is a TCP address of the Miniserver, usually its web interface
+	// "<loxone-ip>:80". UDP gives no delivery feedback, so reachability is
+	// probed there.
+	Addr string
+
+	// DownAfter is how long without a successful probe or command from Loxone
+	// before it counts as down. Default 30s.
+	DownAfter time.Duration
+
+	// Interval between probes. Default DownAfter/3.
+	Interval time.Duration
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Liveness tracks whether the Miniserver is reachable, from periodic TCP probes
+// and the commands it sends; either proves it is up.
+type Liveness struct {
+	cfg LivenessConfig
+	log *slog.Logger
+	now func() time.Time
+
+	mu       sync.Mutex
+	last     time.Time // last sign of life
+	down     bool
+	onChange []func(down bool)
+}
+
+func NewLiveness(cfg LivenessConfig) *Liveness {
+	if cfg.DownAfter <= 0 {
+		cfg.DownAfter = 30 * time.Second
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = cfg.DownAfter / 3
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Liveness{
+		cfg:  cfg,
+		log:  cfg.Logger.With("module", "liveness", "addr", cfg.Addr),
+		now:  time.Now,
+		last: time.Now(), // assume up until proven otherwise
+	}
+}
+
+// Down reports whether Loxone is currently considered unreachable.
+func (l *Liveness) Down() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.down
+}
+
+// OnChange registers fn to be called when Loxone goes down or comes back.
+func (l *Liveness) OnChange(fn func(down bool)) {
+	l.mu.Lock()
+	l.onChange = append(l.onChange, fn)
+	l.mu.Unlock()
+}
+
+// Track wraps the handler of the commands Loxone sends, so each one counts as
+// a sign of life.
+func (l *Liveness) Track(next udp.CommandHandler) udp.CommandHandler {
+	return livenessTracker{liveness: l, next: next}
+}
+
+type livenessTracker struct {
+	liveness *Liveness
+	next     udp.CommandHandler
+}
+
+func (t livenessTracker) Apply(ctx context.Context, cmd udp.Command) error {
+	t.liveness.Seen()
+	return t.next.Apply(ctx, cmd)
+}
+
+// Seen records a sign of life.
+func (l *Liveness) Seen() {
+	l.mu.Lock()
+	l.last = l.now()
+	l.mu.Unlock()
+	l.evaluate()
+}
+
+// Run probes until ctx is done.
+func (l *Liveness) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if l.probe(ctx) {
+			l.Seen()
+		} else {
+			l.evaluate()
+		}
+	}
+}
+
+func (l *Liveness) probe(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, min(l.cfg.Interval, 5*time.Second))
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", l.cfg.Addr)
+	if err != nil {
+		l.log.Debug("loxone probe failed", "error", err)
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func (l *Liveness) evaluate() {
+	l.mu.Lock()
+	down := l.now().Sub(l.last) > l.cfg.DownAfter
+	if down == l.down {
+		l.mu.Unlock()
+		return
+	}
+	l.down = down
+	fns := append([]func(bool){}, l.onChange...)
+	l.mu.Unlock()
+
+	if down {
+		l.log.Warn("loxone unreachable", "for", l.cfg.DownAfter)
+	} else {
+		l.log.Info("loxone reachable again")
+	}
+	for _, fn := range fns {
+		fn(down)
+	}
+}
@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func TestLiveness(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLiveness(LivenessConfig{Addr: ln.Addr().String(), DownAfter: time.Minute})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	var changes []bool
+	l.OnChange(func(down bool) { changes = append(changes, down) })
+
+	if !l.probe(context.Background()) {
+		t.Fatal("probe of a listening Miniserver failed")
+	}
+	ln.Close()
+	if l.probe(context.Background()) {
+		t.Fatal("probe of a closed port succeeded")
+	}
+
+	now = now.Add(30 * time.Second)
+	l.evaluate()
+	if l.Down() {
+		t.Fatal("down before DownAfter")
+	}
+	now = now.Add(time.Minute)
+	l.evaluate()
+	if !l.Down() {
+		t.Fatal("not down after DownAfter without signs of life")
+	}
+
+	if err := l.Track(nopHandler{}).Apply(context.Background(), udp.Command{}); err != nil {
+		t.Fatal(err)
+	}
+	if l.Down() {
+		t.Error("still down after a command from Loxone")
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("changes = %v, want [true false]", changes)
+	}
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope kinds that can be paused.
+const (
+	ScopeRoom   = "room"
+	ScopeDevice = "device"
+)
+
+// Pause is one paused scope as reported by Pauses.List.
+type Pause struct {
+	Scope string     `json:"scope"`           // "/room/kitchen"
+	Until *time.Time `json:"until,omitempty"` // nil: until resumed
+}
+
+// Pauses holds the rooms and devices whose events are not forwarded to Loxone,
+// e.g. while lights are rearranged in the Hue app or the robot vacuum runs, so
+// Loxone logic is not triggered. Names match case-insensitively with "_" for
+// spaces; ids match as well.
+type Pauses struct {
+	mu     sync.Mutex
+	scopes map[string]time.Time // key: normalized scope; zero: until resumed
+	now    func() time.Time
+}
+
+func NewPauses() *Pauses {
+	return &Pauses{scopes: make(map[string]time.Time), now: time.Now}
+}
+
+// ParseScope normalizes "/room/<name or id>" or "/device/<name or id>".
+func ParseScope(s string) (string, error) {
+	kind, name, ok := strings.Cut(strings.Trim(strings.TrimSpace(s), "/"), "/")
+	if !ok || name == "" || (kind != ScopeRoom && kind != ScopeDevice) {
+		return "", fmt.Errorf("invalid scope %q: expected /room/<name or id> or /device/<name or id>", s)
+	}
+	return "/" + kind + "/" + scopeName(name), nil
+}
+
+// Scope builds the normalized scope of kind for a resource name or id.
+func Scope(kind, name string) string {
+	return "/" + kind + "/" + scopeName(name)
+}
+
+func scopeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+}
+
+// Pause suppresses scope for d, or until Resume if d is 0.
+func (p *Pauses) Pause(scope string, d time.Duration) error {
+	key, err := ParseScope(scope)
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	if d > 0 {
+		until = p.now().Add(d)
+	}
+	p.mu.Lock()
+	p.scopes[key] = until
+	p.mu.Unlock()
+	slog.Info("forwarding paused", "scope", key, "for", d)
+	return nil
+}
+
+// Resume forwards scope again; "all" resumes every scope.
+func (p *Pauses) Resume(scope string) error {
+	if strings.EqualFold(strings.Trim(scope, "/"), "all") {
+		p.mu.Lock()
+		clear(p.scopes)
+		p.mu.Unlock()
+		slog.Info("forwarding resumed", "scope", "all")
+		return nil
+	}
+	key, err := ParseScope(scope)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	_, ok := p.scopes[key]
+	delete(p.scopes, key)
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s is not paused", key)
+	}
+	slog.Info("forwarding resumed", "scope", key)
+	return nil
+}
+
+// Paused reports whether any of scopes (built with Scope) is paused. Expired
+// pauses are dropped.
+func (p *Pauses) Paused(scopes ...string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.scopes) == 0 {
+		return false
+	}
+	now := p.now()
+	for _, s := range scopes {
+		until, ok := p.scopes[s]
+		if !ok {
+			continue
+		}
+		if !until.IsZero() && now.After(until) {
+			delete(p.scopes, s)
+			slog.Info("forwarding resumed", "scope", s, "reason", "expired")
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// List returns the active pauses sorted by scope.
+func (p *Pauses) List() []Pause {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	out := make([]Pause, 0, len(p.scopes))
+	for s, until := range p.scopes {
+		if !until.IsZero() && now.After(until) {
+			continue
+		}
+		pause := Pause{Scope: s}
+		if !until.IsZero() {
+			u := until
+			pause.Until = &u
+		}
+		out = append(out, pause)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Scope < out[j].Scope })
+	return out
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauses(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := NewPauses()
+	p.now = func() time.Time { return now }
+
+	if err := p.Pause("/room/Living Room", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Pause("/device/hall_sensor", 30*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Pause("/zone/garden", 0); err == nil {
+		t.Error("zone scope accepted")
+	}
+
+	if !p.Paused(Scope(ScopeRoom, "living room")) {
+		t.Error("room not paused")
+	}
+	if !p.Paused(Scope(ScopeRoom, "kitchen"), Scope(ScopeDevice, "Hall sensor")) {
+		t.Error("device not paused")
+	}
+	if p.Paused(Scope(ScopeRoom, "kitchen")) {
+		t.Error("unrelated room paused")
+	}
+
+	now = now.Add(time.Hour)
+	if p.Paused(Scope(ScopeDevice, "hall_sensor")) {
+		t.Error("pause did not expire")
+	}
+	if got := p.List(); len(got) != 1 || got[0].Scope != "/room/living_room" || got[0].Until != nil {
+		t.Errorf("List() = %+v", got)
+	}
+
+	if err := p.Resume("/room/living_room"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Resume("/room/living_room"); err == nil {
+		t.Error("resuming twice succeeded")
+	}
+	if p.Paused(Scope(ScopeRoom, "living room")) {
+		t.Error("room still paused after resume")
+	}
+
+	var nilPauses *Pauses
+	if nilPauses.Paused("/room/x") {
+		t.Error("nil Pauses paused")
+	}
+}
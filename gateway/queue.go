@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type QueueConfig struct {
+	// Handler applies commands once the bridge is reachable (usually the hue adapter).
+	Handler udp.CommandHandler
+	State   *State
+
+	// MaxAge bounds how long a command may wait for the bridge. Default 30s.
+	MaxAge time.Duration
+
+	// Size is the maximum number of queued commands. Default 64.
+	Size int
+
+	// ApplyTimeout bounds each replayed command. Default 5s.
+	ApplyTimeout time.Duration
+
+	Logger *slog.Logger
+}
+
+// CommandQueue is a udp.CommandHandler that holds commands while the bridge is
+// offline (restart, firmware update) and replays them once it is back.
+type CommandQueue struct {
+	cfg QueueConfig
+	log *slog.Logger
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending []queuedCommand
+	live    map[string]time.Time // key: domain/id, value: last command applied directly
+}
+
+type queuedCommand struct {
+	cmd      udp.Command
+	source   string // see udp.WithSource
+	received time.Time
+}
+
+func NewCommandQueue(cfg QueueConfig) (*CommandQueue, error) {
+	if cfg.Handler == nil {
+		return nil, errors.New("Handler required")
+	}
+	if cfg.State == nil {
+		return nil, errors.New("State required")
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 30 * time.Second
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = 64
+	}
+	if cfg.ApplyTimeout <= 0 {
+		cfg.ApplyTimeout = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &CommandQueue{
+		cfg:  cfg,
+		log:  cfg.Logger.With("module", "queue"),
+		now:  time.Now,
+		live: make(map[string]time.Time),
+	}, nil
+}
+
+func (q *CommandQueue) Apply(ctx context.Context, cmd udp.Command) error {
+	if !q.cfg.State.BridgeOnline() {
+		q.enqueue(ctx, cmd)
+		return nil
+	}
+
+	q.applied(cmd)
+	err := q.cfg.Handler.Apply(ctx, cmd)
+	if err != nil && bridgeUnreachable(err) {
+		q.cfg.State.SetBridgeOnline(false)
+		q.enqueue(ctx, cmd)
+		return nil
+	}
+	return err
+}
+
+// Run replays queued commands whenever the bridge comes back online.
+func (q *CommandQueue) Run(ctx context.Context) error {
+	for {
+		if err := q.cfg.State.WaitBridgeOnline(ctx); err != nil {
+			return err
+		}
+		q.drain(ctx)
+
+		timer := time.NewTimer(time.Second)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (q *CommandQueue) enqueue(ctx context.Context, cmd udp.Command) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= q.cfg.Size {
+		// drop oldest to keep the most recent intent
+		q.log.Warn("command queue full; dropping oldest", "cmd", q.pending[0].cmd)
+		q.pending = q.pending[1:]
+	}
+	q.pending = append(q.pending, queuedCommand{cmd: cmd, source: udp.Source(ctx), received: q.now()})
+	q.log.Info("bridge offline; command queued", "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action, "queued", len(q.pending))
+}
+
+func (q *CommandQueue) drain(ctx context.Context) {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	for i, p := range pending {
+		if age := q.now().Sub(p.received); age > q.cfg.MaxAge {
+			q.log.Warn("dropping stale queued command", "cmd", p.cmd, "age", age.String())
+			continue
+		}
+		if q.superseded(p) {
+			q.log.Info("dropping queued command superseded by a newer one", "cmd", p.cmd)
+			continue
+		}
+		callCtx, cancel := context.WithTimeout(udp.WithSource(ctx, p.source), q.cfg.ApplyTimeout)
+		err := q.cfg.Handler.Apply(callCtx, p.cmd)
+		cancel()
+		if err != nil && bridgeUnreachable(err) {
+			// bridge went away again; put the rest back
+			q.cfg.State.SetBridgeOnline(false)
+			q.mu.Lock()
+			q.pending = append(append([]queuedCommand(nil), pending[i:]...), q.pending...)
+			q.mu.Unlock()
+			return
+		}
+		if err != nil {
+			q.log.Error("replay failed", "cmd", p.cmd, "error", err.Error())
+			continue
+		}
+		q.log.Info("replayed queued command", "cmd", p.cmd)
+	}
+}
+
+// applied records that cmd went to the bridge directly, so older queued commands
+// for its resource are not replayed over it.
+func (q *CommandQueue) applied(cmd udp.Command) {
+	now := q.now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for k, t := range q.live {
+		if now.Sub(t) > q.cfg.MaxAge {
+			delete(q.live, k)
+		}
+	}
+	q.live[resourceKey(cmd)] = now
+}
+
+// superseded reports whether a newer command for the resource of p was applied
+// since p was queued.
+func (q *CommandQueue) superseded(p queuedCommand) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.live[resourceKey(p.cmd)]
+	return ok && t.After(p.received)
+}
+
+func resourceKey(cmd udp.Command) string {
+	return string(cmd.Domain) + "/" + string(cmd.ID)
+}
+
+// bridgeUnreachable reports whether err means the bridge could not be reached at all
+// (as opposed to the bridge rejecting the request).
+func bridgeUnreachable(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
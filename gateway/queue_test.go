@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// recordingHandler remembers what it applied; err fails every command.
+type recordingHandler struct {
+	mu      sync.Mutex
+	applied []string
+	err     error
+}
+
+func (h *recordingHandler) Apply(ctx context.Context, cmd udp.Command) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err != nil {
+		return h.err
+	}
+	h.applied = append(h.applied, cmd.Key()+" "+cmd.Value.Raw)
+	return nil
+}
+
+func (h *recordingHandler) got() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.applied...)
+}
+
+func newTestQueue(t *testing.T, h udp.CommandHandler) (*CommandQueue, *State, *time.Time) {
+	t.Helper()
+	state := NewState(nil)
+	q, err := NewCommandQueue(QueueConfig{Handler: h, State: state, MaxAge: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	return q, state, &now
+}
+
+func TestCommandQueue(t *testing.T) {
+	cmd := func(id, action, v string) udp.Command {
+		return udp.Command{Domain: "grouped_light", ID: resource.ID("gl-" + id), Action: action, Value: udp.RawValue(v)}
+	}
+
+	tests := []struct {
+		name   string
+		queued []udp.Command
+		wait   time.Duration // between queueing and the bridge coming back
+		live   []udp.Command // applied once the bridge is back, before the replay
+		want   []string
+	}{
+		{
+			name:   "replayed in order",
+			queued: []udp.Command{cmd("1", "on", "1"), cmd("2", "on", "0"), cmd("1", "dimmable", "40")},
+			want:   []string{"grouped_light/gl-1/on 1", "grouped_light/gl-2/on 0", "grouped_light/gl-1/dimmable 40"},
+		},
+		{
+			name:   "expired",
+			queued: []udp.Command{cmd("1", "on", "1")},
+			wait:   time.Minute,
+		},
+		{
+			name:   "superseded by a live command",
+			queued: []udp.Command{cmd("1", "on", "0"), cmd("2", "on", "1")},
+			wait:   time.Second,
+			live:   []udp.Command{cmd("1", "dimmable", "80")},
+			want:   []string{"grouped_light/gl-1/dimmable 80", "grouped_light/gl-2/on 1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := &recordingHandler{}
+			q, state, now := newTestQueue(t, h)
+			ctx := context.Background()
+
+			state.SetBridgeOnline(false)
+			for _, c := range tt.queued {
+				if err := q.Apply(ctx, c); err != nil {
+					t.Fatalf("Apply() while offline: %v", err)
+				}
+			}
+			if got := h.got(); len(got) != 0 {
+				t.Fatalf("applied while offline: %v", got)
+			}
+
+			*now = now.Add(tt.wait)
+			state.SetBridgeOnline(true)
+			for _, c := range tt.live {
+				if err := q.Apply(ctx, c); err != nil {
+					t.Fatalf("Apply() live: %v", err)
+				}
+			}
+			q.drain(ctx)
+			if got := h.got(); len(got)+len(tt.want) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applied %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandQueue_QueuesWhenBridgeUnreachable(t *testing.T) {
+	h := &recordingHandler{err: &net.OpError{Op: "dial", Err: net.UnknownNetworkError("down")}}
+	q, state, _ := newTestQueue(t, h)
+	ctx := context.Background()
+
+	if err := q.Apply(ctx, udp.Command{Domain: "grouped_light", ID: "gl-1", Action: "on", Value: udp.BoolValue(true)}); err != nil {
+		t.Fatalf("Apply() = %v, want the command queued", err)
+	}
+	if state.BridgeOnline() {
+		t.Error("bridge still online after an unreachable error")
+	}
+
+	h.mu.Lock()
+	h.err = nil
+	h.mu.Unlock()
+	state.SetBridgeOnline(true)
+	q.drain(ctx)
+	if got, want := h.got(), []string{"grouped_light/gl-1/on true"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("applied %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,27 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// ErrReadOnly is returned for every command while the gateway runs with --read-only.
+var ErrReadOnly = errors.New("read-only mode: command rejected")
+
+// ReadOnly is a udp.CommandHandler that logs and rejects every command, so events
+// can be forwarded to a staging Loxone program without it switching real lights.
+type ReadOnly struct {
+	Logger *slog.Logger
+}
+
+func (r ReadOnly) Apply(ctx context.Context, cmd udp.Command) error {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Warn("read-only: command rejected", "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action, "value", cmd.Value)
+	return ErrReadOnly
+}
@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Sender delivers a raw datagram to Loxone. *udp.Client satisfies it.
+type Sender interface {
+	Send(b []byte)
+}
+
+// State tracks gateway-wide conditions (bridge reachability, ...) and reports
+// transitions to Loxone under the /gateway/... namespace.
+type State struct {
+	sender Sender
+
+	mu           sync.RWMutex
+	bridgeKnown  bool
+	bridgeOnline bool
+	onlineCh     chan struct{} // closed while the bridge is online
+	apiKeyIndex  int
+	modes        map[string]bool // vacation, night
+	alerts       int
+	configIssues []string
+	usage        []bridge.Usage
+	version      string
+	streamDown   bool
+	standby      bool // HA: another instance leads
+	udpStats     *udp.ClientStats
+	bools        *udp.Bools
+	breaker      *BreakerStatus
+}
+
+// BreakerStatus is the state of the event storm breaker.
+type BreakerStatus struct {
+	Open    bool   `json:"open"`    // low-priority channels are being dropped
+	Trips   int    `json:"trips"`   // times it opened since start
+	Dropped uint64 `json:"dropped"` // messages dropped while open, since start
+}
+
+// Health is a point-in-time snapshot of the gateway conditions.
+type Health struct {
+	BridgeOnline bool             `json:"bridge_online"`
+	EventStream  bool             `json:"event_stream_ok"` // false after too many failed reconnects
+	APIKeyIndex  int              `json:"apikey_index"`    // 0 = primary key
+	Modes        map[string]bool  `json:"modes"`
+	Alerts       int              `json:"alerts"` // raised since start
+	ConfigIssues []string         `json:"config_issues,omitempty"`
+	Standby      bool             `json:"standby,omitempty"` // HA: another instance leads
+	UDP          *udp.ClientStats `json:"udp,omitempty"`     // messages to Loxone
+	Usage        []bridge.Usage   `json:"usage,omitempty"`   // bridge tables vs. their limits
+	Breaker      *BreakerStatus   `json:"breaker,omitempty"` // event storm breaker (--event-storm-limit)
+}
+
+func NewState(sender Sender) *State {
+	s := &State{
+		sender:       sender,
+		bridgeOnline: true, // assume reachable until proven otherwise
+		onlineCh:     make(chan struct{}),
+		modes:        make(map[string]bool),
+	}
+	close(s.onlineCh)
+	return s
+}
+
+// SetBridgeOnline records bridge reachability and emits /gateway/bridge_online 0|1
+// on every change (and on the first report).
+func (s *State) SetBridgeOnline(online bool) {
+	s.mu.Lock()
+	changed := !s.bridgeKnown || s.bridgeOnline != online
+	if s.bridgeOnline != online {
+		if online {
+			close(s.onlineCh)
+		} else {
+			s.onlineCh = make(chan struct{})
+		}
+	}
+	s.bridgeKnown = true
+	s.bridgeOnline = online
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if online {
+		slog.Info("hue bridge online")
+	} else {
+		slog.Warn("hue bridge offline; gateway degraded")
+	}
+	s.emitBool("bridge_online", online)
+}
+
+// SetEventStreamOK records whether the event stream is healthy and emits
+// /gateway/event_stream_ok 0|1 on every change.
+func (s *State) SetEventStreamOK(ok bool) {
+	s.mu.Lock()
+	changed := s.streamDown == ok
+	s.streamDown = !ok
+	s.mu.Unlock()
+
+	if changed {
+		s.emitBool("event_stream_ok", ok)
+	}
+}
+
+// SetUDPStats records the delivery counters of the Loxone UDP client and emits
+// /gateway/udp_dropped <total>.
+func (s *State) SetUDPStats(stats udp.ClientStats) {
+	s.mu.Lock()
+	s.udpStats = &stats
+	s.mu.Unlock()
+
+	s.emit("udp_dropped", fmt.Sprintf("%d", stats.Dropped()))
+}
+
+// SetBools sets how /gateway/... booleans are rendered (default 1/0).
+func (s *State) SetBools(b *udp.Bools) {
+	s.mu.Lock()
+	s.bools = b
+	s.mu.Unlock()
+}
+
+// SetStandby records the initial HA role without emitting anything.
+func (s *State) SetStandby(standby bool) {
+	s.mu.Lock()
+	s.standby = standby
+	s.mu.Unlock()
+}
+
+// SetLeader records an HA role change. A new leader emits /gateway/leader 1 and
+// re-announces every status channel, since Loxone heard nothing from it so far.
+func (s *State) SetLeader(leader bool) {
+	s.SetStandby(!leader)
+	if leader {
+		s.emitBool("leader", true)
+		s.Announce()
+	}
+}
+
+// SetAPIKeyIndex records that the gateway failed over to another hue-application-key
+// and emits /gateway/apikey_failover 1.
+func (s *State) SetAPIKeyIndex(idx int) {
+	s.mu.Lock()
+	s.apiKeyIndex = idx
+	s.mu.Unlock()
+
+	s.emitBool("apikey_failover", idx > 0)
+}
+
+func (s *State) Health() Health {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	modes := make(map[string]bool, len(s.modes))
+	for k, v := range s.modes {
+		modes[k] = v
+	}
+	return Health{
+		BridgeOnline: s.bridgeOnline,
+		EventStream:  !s.streamDown,
+		APIKeyIndex:  s.apiKeyIndex,
+		Modes:        modes,
+		Alerts:       s.alerts,
+		ConfigIssues: append([]string(nil), s.configIssues...),
+		Standby:      s.standby,
+		UDP:          s.udpStats,
+		Usage:        append([]bridge.Usage(nil), s.usage...),
+		Breaker:      s.breaker,
+	}
+}
+
+// SetUsage records the bridge's resource counts, emits /gateway/usage/<resource>
+// <count> for each table and /gateway/usage_near 0|1, and warns about tables that
+// are nearly full.
+func (s *State) SetUsage(usage []bridge.Usage) {
+	s.mu.Lock()
+	s.usage = append([]bridge.Usage(nil), usage...)
+	s.mu.Unlock()
+
+	near := false
+	for _, u := range usage {
+		if u.Near() {
+			near = true
+			slog.Warn("hue bridge table nearly full; new entries may fail silently", "resource", u.Resource, "count", u.Count, "limit", u.Limit)
+		}
+		s.emit("usage/"+u.Resource, fmt.Sprintf("%d", u.Count))
+	}
+	s.emitBool("usage_near", near)
+}
+
+// SetVersion records the gateway version and emits /gateway/version <version>, so
+// Loxone can tell which build it talks to.
+func (s *State) SetVersion(v string) {
+	s.mu.Lock()
+	s.version = v
+	s.mu.Unlock()
+
+	s.emit("version", v)
+}
+
+// SetConfigIssues records problems found when checking the configuration against
+// the bridge and emits /gateway/config_ok 0|1.
+func (s *State) SetConfigIssues(issues []string) {
+	s.mu.Lock()
+	s.configIssues = append([]string(nil), issues...)
+	s.mu.Unlock()
+
+	s.emitBool("config_ok", len(issues) == 0)
+}
+
+// SetBreaker records the event storm breaker and emits /gateway/event_storm 0|1
+// when it opens or closes.
+func (s *State) SetBreaker(status BreakerStatus) {
+	s.mu.Lock()
+	changed := s.breaker == nil || s.breaker.Open != status.Open
+	s.breaker = &status
+	s.mu.Unlock()
+
+	if changed {
+		s.emitBool("event_storm", status.Open)
+	}
+}
+
+// Alert reports a failure that must not go unnoticed (e.g. an alarm-grade message
+// that could not be delivered) and emits /gateway/alert <kind>.
+func (s *State) Alert(kind string, err error) {
+	s.mu.Lock()
+	s.alerts++
+	s.mu.Unlock()
+
+	slog.Error("gateway alert", "kind", kind, "error", err)
+	s.emit("alert", kind)
+}
+
+// SetMode switches a gateway mode (vacation, night) and echoes /gateway/<mode> 0|1.
+func (s *State) SetMode(mode string, on bool) {
+	s.mu.Lock()
+	s.modes[mode] = on
+	s.mu.Unlock()
+
+	slog.Info("gateway mode changed", "mode", mode, "on", on)
+	s.emitBool(mode, on)
+}
+
+func (s *State) Mode(mode string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.modes[mode]
+}
+
+// Announce re-emits every gateway status channel, e.g. after Loxone restarted.
+func (s *State) Announce() {
+	h := s.Health()
+	s.mu.RLock()
+	v := s.version
+	s.mu.RUnlock()
+	if v != "" {
+		s.emit("version", v)
+	}
+	s.emitBool("bridge_online", h.BridgeOnline)
+	s.emitBool("event_stream_ok", h.EventStream)
+	s.emitBool("apikey_failover", h.APIKeyIndex > 0)
+	for mode, on := range h.Modes {
+		s.emitBool(mode, on)
+	}
+}
+
+func (s *State) BridgeOnline() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bridgeOnline
+}
+
+// WaitBridgeOnline blocks until the bridge is online or ctx is done.
+func (s *State) WaitBridgeOnline(ctx context.Context) error {
+	s.mu.RLock()
+	ch := s.onlineCh
+	s.mu.RUnlock()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *State) emit(channel string, value string) {
+	if s.sender == nil {
+		return
+	}
+	s.sender.Send([]byte(fmt.Sprintf("/gateway/%s %s", channel, value)))
+}
+
+func (s *State) emitBool(channel string, v bool) {
+	s.mu.RLock()
+	bools := s.bools
+	s.mu.RUnlock()
+	s.emit(channel, bools.Format(channel, v))
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *recordingSender) Send(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, string(b))
+}
+
+func TestState_BridgeOnline(t *testing.T) {
+	sender := &recordingSender{}
+	s := NewState(sender)
+
+	s.SetBridgeOnline(true) // first report is always sent
+	s.SetBridgeOnline(true)
+	s.SetBridgeOnline(false)
+	s.SetBridgeOnline(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.WaitBridgeOnline(ctx); err == nil {
+		t.Error("WaitBridgeOnline() returned while the bridge is offline")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.WaitBridgeOnline(context.Background()) }()
+	s.SetBridgeOnline(true)
+	if err := <-done; err != nil {
+		t.Errorf("WaitBridgeOnline() = %v", err)
+	}
+
+	want := []string{"/gateway/bridge_online 1", "/gateway/bridge_online 0", "/gateway/bridge_online 1"}
+	if !reflect.DeepEqual(sender.sent, want) {
+		t.Errorf("sent %v, want %v", sender.sent, want)
+	}
+}
+
+func TestState_Announce(t *testing.T) {
+	sender := &recordingSender{}
+	s := NewState(sender)
+	s.SetVersion("1.2.3")
+	s.SetMode("night", true)
+	sender.sent = nil
+
+	s.Announce()
+	want := []string{
+		"/gateway/version 1.2.3",
+		"/gateway/bridge_online 1",
+		"/gateway/event_stream_ok 1",
+		"/gateway/apikey_failover 0",
+		"/gateway/night 1",
+	}
+	if !reflect.DeepEqual(sender.sent, want) {
+		t.Errorf("Announce() sent %v, want %v", sender.sent, want)
+	}
+}
@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// WindowRule blocks matching commands during a daily time window, e.g.
+// {"command_windows": {"kids_evening": {"target": "room/kids", "actions": ["on"], "block": "21:00-07:00"}}}.
+type WindowRule struct {
+	Target  string   `mapstructure:"target"`  // *, <domain> or <domain>/<id or name>, as in command_acl
+	Actions []string `mapstructure:"actions"` // default all actions
+	Block   string   `mapstructure:"block"`   // "HH:MM-HH:MM", may span midnight
+}
+
+type WindowConfig struct {
+	// Rules by name; /gateway/allow <name> lifts one for a while.
+	Rules map[string]WindowRule
+
+	// Names places resources in their room or zone (usually the client.Poller).
+	Names ACLResolver
+
+	// Location the windows are in. Defaults to time.Local.
+	Location *time.Location
+
+	// Logger (optional). Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Windows gates commands by time of day, for Hue-specific rules that are
+// awkward to build in Loxone, e.g. no lights on in the kids' room after 21:00.
+// A rule can be lifted for a while with /gateway/allow.
+type Windows struct {
+	rules []window
+	match *ACL
+	loc   *time.Location
+	log   *slog.Logger
+	now   func() time.Time
+
+	mu      sync.Mutex
+	allowed map[string]time.Time // key: rule name, value: until
+}
+
+type window struct {
+	name     string
+	target   aclTarget
+	actions  []string
+	from, to time.Duration // offsets from midnight
+}
+
+func NewWindows(cfg WindowConfig) (*Windows, error) {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	w := &Windows{
+		match:   &ACL{names: cfg.Names},
+		loc:     cfg.Location,
+		log:     cfg.Logger.With("module", "windows"),
+		now:     time.Now,
+		allowed: make(map[string]time.Time),
+	}
+	for name, r := range cfg.Rules {
+		target, err := parseTarget(r.Target)
+		if err != nil {
+			return nil, fmt.Errorf("command window %s: %w", name, err)
+		}
+		from, to, err := parseWindow(r.Block)
+		if err != nil {
+			return nil, fmt.Errorf("command window %s: %w", name, err)
+		}
+		w.rules = append(w.rules, window{name: strings.ToLower(name), target: target, actions: r.Actions, from: from, to: to})
+	}
+	// deterministic order for the error messages
+	slices.SortFunc(w.rules, func(a, b window) int { return strings.Compare(a.name, b.name) })
+	return w, nil
+}
+
+// parseWindow parses "21:00-07:00".
+func parseWindow(s string) (from, to time.Duration, err error) {
+	a, b, ok := strings.Cut(s, "-")
+	if ok {
+		if from, err = parseClock(a); err == nil {
+			to, err = parseClock(b)
+		}
+	}
+	if !ok || err != nil || from == to {
+		return 0, 0, fmt.Errorf("invalid block %q: expected HH:MM-HH:MM", s)
+	}
+	return from, to, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Gate wraps next so commands inside a window are rejected.
+func (w *Windows) Gate(next udp.CommandHandler) udp.CommandHandler {
+	return windowGate{windows: w, next: next}
+}
+
+type windowGate struct {
+	windows *Windows
+	next    udp.CommandHandler
+}
+
+func (g windowGate) Apply(ctx context.Context, cmd udp.Command) error {
+	if err := g.windows.Check(cmd); err != nil {
+		return err
+	}
+	return g.next.Apply(ctx, cmd)
+}
+
+// Check returns an error if a window currently blocks cmd.
+func (w *Windows) Check(cmd udp.Command) error {
+	now := w.now().In(w.loc)
+	for _, r := range w.rules {
+		if !r.blocks(now) || !r.covers(w.match, cmd) || w.isAllowed(r.name, now) {
+			continue
+		}
+		return fmt.Errorf("%s/%s %s blocked by command window %s until %s", cmd.Domain, cmd.ID, cmd.Action, r.name, r.end(now).Format("15:04"))
+	}
+	return nil
+}
+
+// Allow lifts the rule name for d, or until its current window ends if d is 0.
+func (w *Windows) Allow(name string, d time.Duration) error {
+	name = strings.ToLower(name)
+	i := slices.IndexFunc(w.rules, func(r window) bool { return r.name == name })
+	if i < 0 {
+		return fmt.Errorf("no command window %q", name)
+	}
+	now := w.now().In(w.loc)
+	until := now.Add(d)
+	if d == 0 {
+		until = w.rules[i].end(now)
+	}
+	w.mu.Lock()
+	w.allowed[name] = until
+	w.mu.Unlock()
+	w.log.Info("command window lifted", "window", name, "until", until.Format(time.DateTime))
+	return nil
+}
+
+func (w *Windows) isAllowed(name string, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	until, ok := w.allowed[name]
+	if ok && !now.Before(until) {
+		delete(w.allowed, name)
+		return false
+	}
+	return ok
+}
+
+func (r window) covers(match *ACL, cmd udp.Command) bool {
+	if len(r.actions) > 0 && !slices.Contains(r.actions, cmd.Action) {
+		return false
+	}
+	return match.allows(r.target, cmd)
+}
+
+// blocks reports whether now is inside the window. The offsets are wall-clock
+// time, so on the days clocks change a window still starts and ends at its
+// hours rather than an hour early or late.
+func (r window) blocks(now time.Time) bool {
+	h, m, _ := now.Clock()
+	off := time.Duration(h*60+m) * time.Minute
+	if r.from < r.to {
+		return off >= r.from && off < r.to
+	}
+	return off >= r.from || off < r.to
+}
+
+// end returns the next time the window closes after now.
+func (r window) end(now time.Time) time.Time {
+	t := onDay(now, r.to)
+	if !t.After(now) {
+		t = onDay(now.AddDate(0, 0, 1), r.to)
+	}
+	return t
+}
+
+// onDay returns the wall-clock time off on the day of t.
+func onDay(t time.Time, off time.Duration) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, int(off/time.Hour), int(off%time.Hour/time.Minute), 0, 0, t.Location())
+}
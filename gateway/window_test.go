@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // Europe/Brussels for the DST test
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func testWindows(t *testing.T, at string) *Windows {
+	t.Helper()
+	loc := time.FixedZone("CET", 3600)
+	w, err := NewWindows(WindowConfig{
+		Rules: map[string]WindowRule{
+			"Kids_Evening": {Target: "room/kitchen", Actions: []string{"on"}, Block: "21:00-07:00"},
+			"garden_noon":  {Target: "zone/garden", Block: "12:00-13:00"},
+		},
+		Names:    fakeResolver{},
+		Location: loc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, err := time.ParseInLocation("2006-01-02 15:04", "2026-03-10 "+at, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = func() time.Time { return now }
+	return w
+}
+
+func TestWindowsCheck(t *testing.T) {
+	kitchenOn := udp.Command{Domain: "grouped_light", ID: "gl-k", Action: "on", Value: udp.BoolValue(true)}
+	tests := []struct {
+		name    string
+		at      string
+		cmd     udp.Command
+		blocked bool
+	}{
+		{name: "before the window", at: "20:59", cmd: kitchenOn},
+		{name: "in the window", at: "21:00", cmd: kitchenOn, blocked: true},
+		{name: "after midnight", at: "06:59", cmd: kitchenOn, blocked: true},
+		{name: "window over", at: "07:00", cmd: kitchenOn},
+		{name: "other action", at: "22:00", cmd: udp.Command{Domain: "grouped_light", ID: "gl-k", Action: "dimmable", Value: udp.PercentValue(10)}},
+		{name: "scene of the room", at: "22:00", cmd: udp.Command{Domain: "scene", ID: "sc-k", Action: "on"}, blocked: true},
+		{name: "other room", at: "22:00", cmd: udp.Command{Domain: "grouped_light", ID: "gl-g", Action: "on"}},
+		{name: "all actions", at: "12:30", cmd: udp.Command{Domain: "grouped_light", ID: "gl-g", Action: "dimmable"}, blocked: true},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := testWindows(t, tt.at).Check(tt.cmd)
+			if got := err != nil; got != tt.blocked {
+				t.Errorf("Check() error = %v, want blocked=%v", err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestWindowsAllow(t *testing.T) {
+	w := testWindows(t, "22:00")
+	h := w.Gate(nopHandler{})
+	cmd := udp.Command{Domain: "grouped_light", ID: "gl-k", Action: "on", Value: udp.BoolValue(true)}
+	ctx := context.Background()
+
+	err := h.Apply(ctx, cmd)
+	if err == nil || !strings.Contains(err.Error(), "kids_evening until 07:00") {
+		t.Fatalf("Apply() error = %v, want blocked until 07:00", err)
+	}
+	if err := w.Allow("nope", 0); err == nil {
+		t.Fatal("Allow() of an unknown window succeeded")
+	}
+
+	if err := w.Allow("kids_evening", 30*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Apply(ctx, cmd); err != nil {
+		t.Fatalf("Apply() while allowed: %v", err)
+	}
+	now := w.now()
+	w.now = func() time.Time { return now.Add(31 * time.Minute) }
+	if err := h.Apply(ctx, cmd); err == nil {
+		t.Fatal("Apply() after the allowance succeeded")
+	}
+
+	// without a duration, until the window ends
+	if err := w.Allow("kids_evening", 0); err != nil {
+		t.Fatal(err)
+	}
+	w.now = func() time.Time { return now.Add(8*time.Hour + 59*time.Minute) }
+	if err := h.Apply(ctx, cmd); err != nil {
+		t.Fatalf("Apply() before the window ends: %v", err)
+	}
+}
+
+func TestWindowDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Brussels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := window{from: 21 * time.Hour, to: 7 * time.Hour}
+	tests := []struct {
+		name    string
+		at      string
+		blocked bool
+		end     string
+	}{
+		// clocks go forward at 02:00 on 2026-03-29 and back at 03:00 on 2026-10-25
+		{name: "spring before end", at: "2026-03-29 06:30", blocked: true, end: "2026-03-29 07:00"},
+		{name: "spring after end", at: "2026-03-29 07:30", blocked: false, end: "2026-03-30 07:00"},
+		{name: "autumn before end", at: "2026-10-25 06:30", blocked: true, end: "2026-10-25 07:00"},
+		{name: "autumn at start", at: "2026-10-25 21:00", blocked: true, end: "2026-10-26 07:00"},
+		{name: "autumn before start", at: "2026-10-25 20:30", blocked: false, end: "2026-10-26 07:00"},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			now, err := time.ParseInLocation("2006-01-02 15:04", tt.at, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.blocks(now); got != tt.blocked {
+				t.Errorf("blocks(%s) = %v, want %v", tt.at, got, tt.blocked)
+			}
+			if got := r.end(now).Format("2006-01-02 15:04"); got != tt.end {
+				t.Errorf("end(%s) = %s, want %s", tt.at, got, tt.end)
+			}
+		})
+	}
+}
+
+func TestNewWindowsInvalid(t *testing.T) {
+	for _, r := range []WindowRule{
+		{Target: "room/kids", Block: "21:00"},
+		{Target: "room/kids", Block: "21:00-21:00"},
+		{Target: "room/kids", Block: "9pm-7am"},
+		{Target: "room/kids/x", Block: "21:00-07:00"},
+	} {
+		if _, err := NewWindows(WindowConfig{Rules: map[string]WindowRule{"r": r}}); err == nil {
+			t.Errorf("NewWindows(%+v) succeeded", r)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package ha runs two (or more) gateway instances as leader and warm standbys.
+// Instances exchange UDP heartbeats; only the leader forwards events and accepts
+// commands, the standbys keep their inventory and event stream warm so they can
+// take over within one lease.
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const heartbeatPrefix = "lph-ha"
+
+type Config struct {
+	// ID identifies this instance; it must be unique among the peers. When two
+	// instances claim leadership at once the lower ID wins.
+	ID string
+
+	// Listen is the local heartbeat address, e.g. ":7070".
+	Listen string
+
+	// Peers are the heartbeat addresses of the other instances.
+	Peers []string
+
+	// Interval between heartbeats. Default 1s.
+	Interval time.Duration
+
+	// Lease is how long a silent peer is still considered alive. Default 3*Interval.
+	Lease time.Duration
+
+	// OnChange (optional) is called when this instance gains or loses leadership.
+	OnChange func(leader bool)
+
+	Logger *slog.Logger
+}
+
+// Elector decides whether this instance is the leader.
+type Elector struct {
+	cfg    Config
+	log    *slog.Logger
+	leader atomic.Bool
+
+	mu    sync.Mutex
+	peers map[string]peer // key: peer ID
+}
+
+type peer struct {
+	seen   time.Time
+	leader bool
+}
+
+func New(cfg Config) (*Elector, error) {
+	if cfg.ID == "" || strings.ContainsAny(cfg.ID, " \t\n") {
+		return nil, fmt.Errorf("ha: invalid instance id %q", cfg.ID)
+	}
+	if cfg.Listen == "" {
+		return nil, errors.New("ha: listen address required")
+	}
+	if len(cfg.Peers) == 0 {
+		return nil, errors.New("ha: at least one peer required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 3 * cfg.Interval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Elector{
+		cfg:   cfg,
+		log:   cfg.Logger.With("module", "ha", "id", cfg.ID),
+		peers: make(map[string]peer),
+	}, nil
+}
+
+// IsLeader reports whether this instance currently leads. It starts as standby.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run sends heartbeats and re-evaluates leadership every interval until ctx is done.
+func (e *Elector) Run(ctx context.Context) error {
+	laddr, err := net.ResolveUDPAddr("udp", e.cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("ha: listen address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return fmt.Errorf("ha: listen: %w", err)
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+	go e.receive(conn)
+
+	e.log.Info("ha standby; waiting for peers", "peers", e.cfg.Peers, "lease", e.cfg.Lease.String())
+	// listen for a full lease before claiming, so a running leader is noticed first
+	start := time.Now()
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if time.Since(start) >= e.cfg.Lease {
+			e.set(e.decide(time.Now()))
+		}
+		e.heartbeat(conn)
+		select {
+		case <-ctx.Done():
+			e.set(false)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// decide applies the election rules: an alive leader is followed (the incumbent
+// keeps its role when a peer rejoins); two leaders resolve to the lower ID; with
+// no leader around the lowest alive ID takes over.
+func (e *Elector) decide(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	me := e.leader.Load()
+	lowest := true
+	for id, p := range e.peers {
+		if now.Sub(p.seen) > e.cfg.Lease {
+			continue
+		}
+		if p.leader && (!me || id < e.cfg.ID) {
+			return false
+		}
+		if id < e.cfg.ID {
+			lowest = false
+		}
+	}
+	return me || lowest
+}
+
+func (e *Elector) set(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+	if leader {
+		e.log.Warn("ha: this instance is now the leader")
+	} else {
+		e.log.Warn("ha: this instance is now standby")
+	}
+	if e.cfg.OnChange != nil {
+		e.cfg.OnChange(leader)
+	}
+}
+
+func (e *Elector) heartbeat(conn *net.UDPConn) {
+	msg := []byte(fmt.Sprintf("%s %s %d", heartbeatPrefix, e.cfg.ID, boolDigit(e.leader.Load())))
+	for _, p := range e.cfg.Peers {
+		addr, err := net.ResolveUDPAddr("udp", p)
+		if err != nil {
+			e.log.Debug("ha: resolve peer", "peer", p, "error", err)
+			continue
+		}
+		if _, err := conn.WriteToUDP(msg, addr); err != nil {
+			e.log.Debug("ha: heartbeat", "peer", p, "error", err)
+		}
+	}
+}
+
+func (e *Elector) receive(conn *net.UDPConn) {
+	buf := make([]byte, 256)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		id, leader, ok := parseHeartbeat(string(buf[:n]))
+		if !ok || id == e.cfg.ID {
+			continue
+		}
+		e.mu.Lock()
+		e.peers[id] = peer{seen: time.Now(), leader: leader}
+		e.mu.Unlock()
+	}
+}
+
+// parseHeartbeat reads "lph-ha <id> <0|1>".
+func parseHeartbeat(s string) (id string, leader bool, ok bool) {
+	f := strings.Fields(s)
+	if len(f) != 3 || f[0] != heartbeatPrefix || (f[2] != "0" && f[2] != "1") {
+		return "", false, false
+	}
+	return f[1], f[2] == "1", true
+}
+
+func boolDigit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package ha
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	now := time.Now()
+	alive, dead := now.Add(-time.Second), now.Add(-time.Minute)
+	tests := []struct {
+		name  string
+		me    bool
+		peers map[string]peer
+		want  bool
+	}{
+		{name: "alone", want: true},
+		{name: "lower id standby alive", peers: map[string]peer{"a": {seen: alive}}, want: false},
+		{name: "higher id standby alive", peers: map[string]peer{"c": {seen: alive}}, want: true},
+		{name: "lower id dead", peers: map[string]peer{"a": {seen: dead, leader: true}}, want: true},
+		{name: "follow higher id leader", peers: map[string]peer{"c": {seen: alive, leader: true}}, want: false},
+		{name: "incumbent keeps role", me: true, peers: map[string]peer{"a": {seen: alive}}, want: true},
+		{name: "two leaders, lower id wins", me: true, peers: map[string]peer{"a": {seen: alive, leader: true}}, want: false},
+		{name: "two leaders, we are lower", me: true, peers: map[string]peer{"c": {seen: alive, leader: true}}, want: true},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			e, err := New(Config{ID: "b", Listen: ":0", Peers: []string{"x:1"}, Lease: 3 * time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.leader.Store(tt.me)
+			if tt.peers != nil {
+				e.peers = tt.peers
+			}
+			if got := e.decide(now); got != tt.want {
+				t.Errorf("decide() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	return c.LocalAddr().String()
+}
+
+func TestElectorFailover(t *testing.T) {
+	addrA, addrB := freeAddr(t), freeAddr(t)
+	cfg := func(id, listen, peer string) Config {
+		return Config{ID: id, Listen: listen, Peers: []string{peer}, Interval: 20 * time.Millisecond}
+	}
+	a, err := New(cfg("a", addrA, addrB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(cfg("b", addrB, addrA))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	doneA := make(chan struct{})
+	go func() { _ = a.Run(ctxA); close(doneA) }()
+	go func() { _ = b.Run(ctxB) }()
+
+	waitFor(t, "a leads", func() bool { return a.IsLeader() && !b.IsLeader() })
+
+	stopA()
+	<-doneA
+	waitFor(t, "b takes over", b.IsLeader)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseHeartbeat(t *testing.T) {
+	if id, leader, ok := parseHeartbeat("lph-ha pi-2 1"); !ok || id != "pi-2" || !leader {
+		t.Errorf("parseHeartbeat() = %q, %v, %v", id, leader, ok)
+	}
+	for _, bad := range []string{"", "lph-ha pi-2", "hello pi-2 1", "lph-ha pi-2 yes"} {
+		if _, _, ok := parseHeartbeat(bad); ok {
+			t.Errorf("parseHeartbeat(%q) ok = true, want false", bad)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package ha
+
+import (
+	"context"
+	"errors"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// ErrStandby is returned for commands sent to an instance that is not the leader.
+var ErrStandby = errors.New("ha standby: send commands to the leader")
+
+// Gate passes commands to next only while e leads.
+func Gate(next udp.CommandHandler, e *Elector) udp.CommandHandler {
+	return gate{next: next, e: e}
+}
+
+type gate struct {
+	next udp.CommandHandler
+	e    *Elector
+}
+
+func (g gate) Apply(ctx context.Context, cmd udp.Command) error {
+	if !g.e.IsLeader() {
+		return ErrStandby
+	}
+	return g.next.Apply(ctx, cmd)
+}
@@ -2,54 +2,138 @@ package hue
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"log/slog"
 
 	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/curve"
+	"github.com/samvdb/loxone-philips-hue/gateway"
 	"github.com/samvdb/loxone-philips-hue/udp"
 )
 
+// Inventory resolves resource ids to their user-given names and group members
+// (usually the shared client.Poller).
+type Inventory interface {
+	GetAlias(id string) string
+	Lights(groupID string) []string
+	GroupOwner(groupedLightID string) string
+	SceneGroup(sceneID string) string
+}
+
 type Adapter struct {
-	home   *openhue.Home
+	home   *bridge.Home
+	names  Inventory
 	logger *slog.Logger
+
+	ent         *gateway.Entertainment
+	deferLocked bool
+	entLights   entertainmentLights
+	curves      *curve.Curves
+	floors      *curve.Floors
+	transitions *Transitions
+	bools       *udp.Bools
+	composites  *Composites
 }
 
-func NewAdapter(ip, appKey string, logger *slog.Logger) (*Adapter, error) {
+// UseCurves maps dimmer values through per-group brightness curves and minimum
+// dim levels (either may be nil).
+func (a *Adapter) UseCurves(c *curve.Curves, f *curve.Floors) {
+	a.curves = c
+	a.floors = f
+}
 
-	h, err := openhue.NewHome(ip, appKey)
-	if err != nil {
-		return nil, err
+// UseBools sets how booleans in get replies are rendered (default 1/0).
+func (a *Adapter) UseBools(b *udp.Bools) {
+	a.bools = b
+}
+
+// NewAdapter creates an adapter; names is optional, it enriches logs and is needed
+// for room/zone expansion commands.
+func NewAdapter(home *bridge.Home, names Inventory, logger *slog.Logger) (*Adapter, error) {
+	if home == nil {
+		return nil, errors.New("home required")
 	}
+	return &Adapter{home: home, names: names, logger: logger.With("module", "hue")}, nil
+}
 
-	slog.Debug("connect to home bridge", "ip", ip, "apikey", appKey)
-	return &Adapter{home: h, logger: logger.With("module", "hue")}, nil
+func (a *Adapter) name(id string) string {
+	if a.names == nil {
+		return ""
+	}
+	return a.names.GetAlias(id)
 }
 
 func (a *Adapter) Apply(ctx context.Context, cmd udp.Command) error {
+	var err error
 	switch cmd.Domain {
 
 	case "grouped_light":
-		return a.applyGroupedLight(ctx, cmd)
+		if err = a.guardGroupedLight(ctx, cmd); err == nil {
+			err = a.applyGroupedLight(ctx, cmd)
+		}
+	case "light":
+		if err = a.guardLight(ctx, cmd); err == nil {
+			err = a.applyLight(ctx, cmd)
+		}
 	case "scene":
-		return a.applyScene(ctx, cmd)
+		if err = a.guardScene(ctx, cmd); err == nil {
+			err = a.applyScene(ctx, cmd)
+		}
+	case "room", "zone":
+		err = a.applyGroup(ctx, cmd)
+	case udp.DomainAlarm:
+		err = a.applyAlarm(ctx, cmd)
+	case udp.DomainComposite:
+		err = a.applyComposite(ctx, cmd)
+	case udp.DomainRaw:
+		err = a.applyRaw(ctx, cmd)
 	default:
 		return fmt.Errorf("unsupported domain: %s", cmd.Domain)
 	}
+	if errors.Is(err, errDeferred) {
+		return nil
+	}
+	return err
+}
+
+// applyRaw PUTs the command's JSON to the resource unchanged; entertainment
+// locks are not checked since the caller takes full responsibility.
+func (a *Adapter) applyRaw(ctx context.Context, cmd udp.Command) error {
+	id := string(cmd.ID)
+	a.logger.Info("raw put", "type", cmd.Rtype, "id", id, "name", a.name(id), "body", cmd.Value.Raw)
+	return a.home.PutResource(ctx, string(cmd.Rtype), id, []byte(cmd.Value.Raw))
+}
+
+// value returns the value of cmd, checking it is of the kind cmd's action
+// takes; commands not built by the udp package may carry another.
+func value(cmd udp.Command) (udp.Value, error) {
+	if k := cmd.Kind(); cmd.Value.Kind != k {
+		return udp.Value{}, fmt.Errorf("%s expects a %s value, got %s", cmd.Action, k, cmd.Value.Kind)
+	}
+	return cmd.Value, nil
 }
 
 func (a *Adapter) applyScene(ctx context.Context, cmd udp.Command) error {
-	id := cmd.ID
+	id := string(cmd.ID)
 	switch cmd.Action {
 	case "on":
 		// can only be turned on
 		on := openhue.SceneRecallActionActive
-		a.logger.Info("set scene on/off", "id", id, "on", on)
+		a.logger.Info("set scene on/off", "id", id, "name", a.name(id), "on", on)
 
-		return a.home.UpdateScene(cmd.ID, openhue.ScenePut{
-			Recall: &openhue.SceneRecall{Action: &on},
+		return a.home.UpdateScene(ctx, string(cmd.ID), openhue.ScenePut{
+			Recall: &openhue.SceneRecall{Action: &on, Duration: a.transition(cmd)},
+		})
+	case "recall":
+		// dynamic_palette starts the scene's palette as a dynamic scene
+		action := openhue.SceneRecallAction(strings.ToLower(cmd.Value.Raw))
+		a.logger.Info("recall scene", "id", id, "name", a.name(id), "action", action)
+		return a.home.UpdateScene(ctx, id, openhue.ScenePut{
+			Recall: &openhue.SceneRecall{Action: &action, Duration: a.transition(cmd)},
 		})
 	default:
 		return fmt.Errorf("unsupported scene action: %s", cmd.Action)
@@ -57,35 +141,35 @@ func (a *Adapter) applyScene(ctx context.Context, cmd udp.Command) error {
 }
 
 func (a *Adapter) applyGroupedLight(ctx context.Context, cmd udp.Command) error {
-	id := cmd.ID
+	id := string(cmd.ID)
+	v, err := value(cmd)
+	if err != nil {
+		return err
+	}
 	switch cmd.Action {
 	case "on":
-		val := strings.ToLower(cmd.Value)
-		on := val == "true" || val == "1"
+		on := v.Bool
 
-		a.logger.Info("set light on/off", "id", id, "on", on)
+		a.logger.Info("set light on/off", "id", id, "name", a.name(id), "on", on)
 		// Replace with your openhue call:
-		_, err := a.home.GetGroupedLightById(cmd.ID)
+		_, err := a.home.GetGroupedLight(ctx, string(cmd.ID))
 		if err != nil {
 			return err
 		}
-		return a.home.UpdateGroupedLight(cmd.ID, openhue.GroupedLightPut{
-			On: &openhue.On{On: &on},
+		return a.home.UpdateGroupedLight(ctx, string(cmd.ID), openhue.GroupedLightPut{
+			On:       &openhue.On{On: &on},
+			Dynamics: a.groupedDynamics(cmd),
 		})
 	case "dimmable":
-		val, _ := strconv.ParseFloat(cmd.Value, 64)
-		// n is 0..100
-		b := openhue.Brightness(val)
-		on := true
-		if val <= 0.0 {
-			on = false
-		}
-		a.logger.Info("set light brightness", "id", id, "brightness", b)
-		return a.home.UpdateGroupedLight(id, openhue.GroupedLightPut{
+		level, on := a.floors.For(id).Apply(a.curves.For(id).ToHue(v.Percent))
+		b := openhue.Brightness(level)
+		a.logger.Info("set light brightness", "id", id, "name", a.name(id), "brightness", b, "on", on)
+		return a.home.UpdateGroupedLight(ctx, id, openhue.GroupedLightPut{
 			Dimming: &openhue.Dimming{
 				Brightness: &b,
 			},
-			On: &openhue.On{On: &on},
+			On:       &openhue.On{On: &on},
+			Dynamics: a.groupedDynamics(cmd),
 		})
 	default:
 		return fmt.Errorf("unsupported light action: %s", cmd.Action)
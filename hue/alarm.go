@@ -0,0 +1,60 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// sirenDuration bounds a siren the alarm system never switches off again; the
+// bridge stops the signal on its own afterwards.
+const sirenDuration = 15 * time.Minute
+
+// sirenColors alternate red and white.
+var sirenColors = []openhue.Color{xy(0.675, 0.322), xy(0.3227, 0.329)}
+
+func xy(x, y float32) openhue.Color {
+	return openhue.Color{Xy: &openhue.GamutPosition{X: &x, Y: &y}}
+}
+
+// applyAlarm turns every light of a room or zone into a visual siren, or stops it.
+func (a *Adapter) applyAlarm(ctx context.Context, cmd udp.Command) error {
+	if a.names == nil {
+		return errors.New("alarm commands need the inventory")
+	}
+	if cmd.Action != "siren" {
+		return fmt.Errorf("unsupported %s action: %s", cmd.Domain, cmd.Action)
+	}
+	lights := a.names.Lights(string(cmd.ID))
+	if len(lights) == 0 {
+		return fmt.Errorf("%s %s has no known lights", cmd.Domain, cmd.ID)
+	}
+
+	v, err := value(cmd)
+	if err != nil {
+		return err
+	}
+	on := v.Bool
+	signal := openhue.SignalingSignalNoSignal
+	body := openhue.LightPut{Signaling: &openhue.Signaling{Signal: &signal}}
+	if on {
+		signal = openhue.SignalingSignalAlternating
+		ms := int(sirenDuration / time.Millisecond)
+		colors := sirenColors
+		body.Signaling.Duration = &ms
+		body.Signaling.Color = &colors
+	}
+
+	a.logger.Warn("alarm siren", "id", cmd.ID, "name", a.name(string(cmd.ID)), "on", on, "lights", len(lights))
+	var errs []error
+	for _, id := range lights {
+		if err := a.home.UpdateLight(ctx, id, body); err != nil {
+			errs = append(errs, fmt.Errorf("light %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
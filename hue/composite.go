@@ -0,0 +1,110 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// CompositeMember is one resource driven by a composite channel, e.g.
+// {"composites": {"living_all": [{"target": "grouped_light/<id>"}, {"target": "light/<plug id>", "on_off": true}]}}.
+type CompositeMember struct {
+	Target string  `mapstructure:"target"` // grouped_light/<id> or light/<id>
+	Scale  float64 `mapstructure:"scale"`  // applied to dimmer values; default 1
+	OnOff  bool    `mapstructure:"on_off"` // switch only (plugs): on while the scaled value is above 0
+}
+
+// Composites are named virtual channels that fan one Loxone command out to
+// several Hue resources, for rooms that mix a grouped light with plugs or
+// lights outside the room.
+type Composites struct {
+	channels map[string][]compositeMember
+}
+
+type compositeMember struct {
+	domain resource.Type
+	id     resource.ID
+	scale  float64
+	onOff  bool
+}
+
+func NewComposites(cfg map[string][]CompositeMember) (*Composites, error) {
+	c := &Composites{channels: make(map[string][]compositeMember, len(cfg))}
+	for name, members := range cfg {
+		if len(members) == 0 {
+			return nil, fmt.Errorf("composite %s has no members", name)
+		}
+		for _, m := range members {
+			domain, id, ok := strings.Cut(m.Target, "/")
+			if !ok || id == "" || (domain != string(resource.TypeGroupedLight) && domain != string(resource.TypeLight)) {
+				return nil, fmt.Errorf("composite %s: invalid target %q: expected grouped_light/<id> or light/<id>", name, m.Target)
+			}
+			rid, err := resource.ParseID(id)
+			if err != nil {
+				return nil, fmt.Errorf("composite %s: %w", name, err)
+			}
+			if m.Scale < 0 {
+				return nil, fmt.Errorf("composite %s: negative scale for %s", name, m.Target)
+			}
+			if m.Scale == 0 {
+				m.Scale = 1
+			}
+			c.channels[strings.ToLower(name)] = append(c.channels[strings.ToLower(name)], compositeMember{
+				domain: resource.Type(domain), id: rid, scale: m.Scale, onOff: m.OnOff,
+			})
+		}
+	}
+	return c, nil
+}
+
+// UseComposites enables /composite/<name>/... commands.
+func (a *Adapter) UseComposites(c *Composites) {
+	a.composites = c
+}
+
+// applyComposite sends cmd to every member; one failing member does not keep
+// the others from switching.
+func (a *Adapter) applyComposite(ctx context.Context, cmd udp.Command) error {
+	if a.composites == nil {
+		return errors.New("no composites are configured")
+	}
+	members, ok := a.composites.channels[strings.ToLower(string(cmd.ID))]
+	if !ok {
+		return fmt.Errorf("unknown composite %s", cmd.ID)
+	}
+	a.logger.Info("set composite", "name", cmd.ID, "action", cmd.Action, "value", cmd.Value, "members", len(members))
+	var errs []error
+	for _, m := range members {
+		if err := a.applyMember(ctx, m, cmd); err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", m.domain, m.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// command is cmd addressed to m, with a dimmer value scaled (and capped at
+// 100) or, for on_off members, turned into on/off.
+func (m compositeMember) command(cmd udp.Command) udp.Command {
+	sub := udp.Command{Domain: m.domain, ID: m.id, Action: cmd.Action, Value: cmd.Value, Transition: cmd.Transition}
+	if cmd.Value.Kind == udp.KindPercent {
+		level := math.Min(math.Round(cmd.Value.Percent*m.scale), 100)
+		sub.Value = udp.PercentValue(level)
+		if m.onOff {
+			sub.Action, sub.Value = "on", udp.BoolValue(level > 0)
+		}
+	}
+	return sub
+}
+
+func (a *Adapter) applyMember(ctx context.Context, m compositeMember, cmd udp.Command) error {
+	sub := m.command(cmd)
+	if m.domain == resource.TypeGroupedLight {
+		return a.Apply(ctx, sub)
+	}
+	return a.applyLight(ctx, sub)
+}
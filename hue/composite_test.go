@@ -0,0 +1,72 @@
+package hue
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func TestNewCompositesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []CompositeMember
+	}{
+		{name: "empty"},
+		{name: "no id", members: []CompositeMember{{Target: "grouped_light/"}}},
+		{name: "unsupported domain", members: []CompositeMember{{Target: "scene/abc"}}},
+		{name: "id is not a uuid", members: []CompositeMember{{Target: "light/abc"}}},
+		{name: "negative scale", members: []CompositeMember{{Target: "light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Scale: -1}}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewComposites(map[string][]CompositeMember{"all": tt.members}); err == nil {
+				t.Errorf("NewComposites(%v) error = nil, want error", tt.members)
+			}
+		})
+	}
+}
+
+func TestCompositeMemberCommand(t *testing.T) {
+	c, err := NewComposites(map[string][]CompositeMember{
+		"Living_All": {
+			{Target: "grouped_light/0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"},
+			{Target: "light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Scale: 1.5},
+			{Target: "light/5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0", Scale: 0.5, OnOff: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewComposites() error = %v", err)
+	}
+	members := c.channels["living_all"]
+	if len(members) != 3 {
+		t.Fatalf("members = %d, want 3", len(members))
+	}
+
+	tests := []struct {
+		name string
+		cmd  udp.Command
+		want []string // action=value per member
+	}{
+		{name: "dimmable", cmd: udp.Command{Action: "dimmable", Value: udp.PercentValue(60)}, want: []string{"dimmable=60", "dimmable=90", "on=true"}},
+		{name: "scaled value capped", cmd: udp.Command{Action: "dimmable", Value: udp.PercentValue(80)}, want: []string{"dimmable=80", "dimmable=100", "on=true"}},
+		{name: "plug off below 1", cmd: udp.Command{Action: "dimmable", Value: udp.PercentValue(0.8)}, want: []string{"dimmable=1", "dimmable=1", "on=false"}},
+		{name: "on passes through", cmd: udp.Command{Action: "on", Value: udp.BoolValue(true)}, want: []string{"on=true", "on=true", "on=true"}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			for i, m := range members {
+				sub := m.command(tt.cmd)
+				if got := sub.Action + "=" + sub.Value.Raw; got != tt.want[i] {
+					t.Errorf("member %s: command = %s, want %s", m.id, got, tt.want[i])
+				}
+				if sub.Domain != m.domain || sub.ID != m.id {
+					t.Errorf("member %s: addressed %s/%s", m.id, sub.Domain, sub.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// ErrEntertainmentActive matches every *EntertainmentError.
+var ErrEntertainmentActive = errors.New("lights locked by an active entertainment session")
+
+// EntertainmentError is returned when a command targets lights that an
+// entertainment session is currently streaming to.
+type EntertainmentError struct {
+	ConfigID string
+}
+
+func (e *EntertainmentError) Error() string {
+	return fmt.Sprintf("%s (entertainment_configuration %s)", ErrEntertainmentActive, e.ConfigID)
+}
+
+func (e *EntertainmentError) Is(target error) bool {
+	return target == ErrEntertainmentActive
+}
+
+// UseEntertainment makes the adapter check commands against running entertainment
+// sessions. Locked commands are deferred until the session ends when deferLocked is
+// set, otherwise they fail with an *EntertainmentError.
+func (a *Adapter) UseEntertainment(ent *gateway.Entertainment, deferLocked bool) {
+	a.ent = ent
+	a.deferLocked = deferLocked
+}
+
+// checkEntertainment returns nil when the lights of group (a room or zone id) are free.
+func (a *Adapter) checkEntertainment(ctx context.Context, cmd udp.Command, group string) error {
+	members := a.names.Lights(group)
+	active := a.ent.Active()
+	for _, configID := range active {
+		lights, err := a.entLights.get(ctx, a.home, configID, active)
+		if err != nil {
+			return err
+		}
+		for _, id := range members {
+			if lights[id] {
+				return a.locked(cmd, configID)
+			}
+		}
+	}
+	return nil
+}
+
+// locked defers cmd or fails it because configID streams to its lights.
+func (a *Adapter) locked(cmd udp.Command, configID string) error {
+	if a.deferLocked {
+		a.logger.Info("command deferred until entertainment session ends", "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action, "config", configID)
+		a.ent.Defer(cmd)
+		return errDeferred
+	}
+	return &EntertainmentError{ConfigID: configID}
+}
+
+// errDeferred signals Apply that the command was parked and must not be sent.
+var errDeferred = errors.New("deferred")
+
+// entertainmentLights caches the light services of running entertainment
+// configurations, so a session costs one bridge request instead of one per command.
+type entertainmentLights struct {
+	mu     sync.Mutex
+	lights map[string]map[string]bool // key: entertainment_configuration id
+}
+
+// get returns the light services of configID, fetching them on first use. Entries
+// of sessions that are no longer active are dropped, since the configuration may
+// be edited before it streams again.
+func (c *entertainmentLights) get(ctx context.Context, home *bridge.Home, configID string, active []string) (map[string]bool, error) {
+	c.mu.Lock()
+	lights, ok := c.lights[configID]
+	c.mu.Unlock()
+	if ok {
+		return lights, nil
+	}
+
+	cfg, err := home.GetResource(ctx, "entertainment_configuration", configID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || len(cfg.Raw) == 0 {
+		return nil, fmt.Errorf("entertainment_configuration %s not found", configID)
+	}
+	var body struct {
+		LightServices []bridge.ResourceRef `json:"light_services"`
+	}
+	if err := json.Unmarshal(cfg.Raw, &body); err != nil {
+		return nil, fmt.Errorf("entertainment_configuration %s: %w", configID, err)
+	}
+	lights = make(map[string]bool, len(body.LightServices))
+	for _, ref := range body.LightServices {
+		lights[ref.Rid] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keep := make(map[string]map[string]bool, len(active))
+	for _, id := range active {
+		if l, ok := c.lights[id]; ok {
+			keep[id] = l
+		}
+	}
+	keep[configID] = lights
+	c.lights = keep
+	return lights, nil
+}
+
+// guardGroupedLight checks the room or zone owning the grouped_light.
+func (a *Adapter) guardGroupedLight(ctx context.Context, cmd udp.Command) error {
+	if a.ent == nil || len(a.ent.Active()) == 0 {
+		return nil
+	}
+	if a.names == nil {
+		return errors.New("entertainment checks need the inventory")
+	}
+	owner := a.names.GroupOwner(string(cmd.ID))
+	if owner == "" {
+		// created after the last inventory refresh
+		gl, err := a.home.GetGroupedLight(ctx, string(cmd.ID))
+		if err != nil {
+			return err
+		}
+		if gl == nil {
+			return fmt.Errorf("grouped_light %s not found", cmd.ID)
+		}
+		ref, ok := refOf(gl.Owner)
+		if !ok {
+			return fmt.Errorf("grouped_light %s has no owner", cmd.ID)
+		}
+		owner = ref.Rid
+	}
+	return a.checkEntertainment(ctx, cmd, owner)
+}
+
+// guardLight checks whether a running session streams to the light itself.
+func (a *Adapter) guardLight(ctx context.Context, cmd udp.Command) error {
+	if a.ent == nil {
+		return nil
+	}
+	active := a.ent.Active()
+	for _, configID := range active {
+		lights, err := a.entLights.get(ctx, a.home, configID, active)
+		if err != nil {
+			return err
+		}
+		if lights[string(cmd.ID)] {
+			return a.locked(cmd, configID)
+		}
+	}
+	return nil
+}
+
+// guardScene checks the room or zone a scene belongs to.
+func (a *Adapter) guardScene(ctx context.Context, cmd udp.Command) error {
+	if a.ent == nil || len(a.ent.Active()) == 0 {
+		return nil
+	}
+	if a.names == nil {
+		return errors.New("entertainment checks need the inventory")
+	}
+	group := a.names.SceneGroup(string(cmd.ID))
+	if group == "" {
+		// created after the last inventory refresh
+		scene, err := a.home.GetScene(ctx, string(cmd.ID))
+		if err != nil {
+			return err
+		}
+		if scene == nil {
+			return fmt.Errorf("scene %s not found", cmd.ID)
+		}
+		ref, ok := refOf(scene.Group)
+		if !ok {
+			return fmt.Errorf("scene %s has no group", cmd.ID)
+		}
+		group = ref.Rid
+	}
+	return a.checkEntertainment(ctx, cmd, group)
+}
+
+func refOf(id *openhue.ResourceIdentifier) (bridge.ResourceRef, bool) {
+	if id == nil || id.Rid == nil || id.Rtype == nil {
+		return bridge.ResourceRef{}, false
+	}
+	return bridge.ResourceRef{Rid: *id.Rid, Rtype: string(*id.Rtype)}, true
+}
@@ -0,0 +1,144 @@
+package hue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Failure classes reported in Failure.Class.
+const (
+	FailureTimeout       = "timeout"
+	FailureUnreachable   = "unreachable"
+	FailureAuth          = "auth"
+	FailureNotFound      = "not_found"
+	FailureRejected      = "rejected" // the bridge refused the request (4xx, 207 errors)
+	FailureBridge        = "bridge"   // 5xx
+	FailureEntertainment = "entertainment"
+	FailureInvalid       = "invalid"   // unsupported domain/action
+	FailureCancelled     = "cancelled" // superseded by a newer command
+)
+
+// Failure is a structured record of a command that could not be applied.
+type Failure struct {
+	CorrelationID string      `json:"correlation_id"`
+	Time          time.Time   `json:"time"`
+	Command       udp.Command `json:"command"`
+	Class         string      `json:"class"`
+	Error         string      `json:"error"`
+	HueStatus     int         `json:"hue_status,omitempty"`
+}
+
+// Failures wraps a udp.CommandHandler and remembers failed commands: the most recent
+// ones and the last failure per resource.
+type Failures struct {
+	next udp.CommandHandler
+	size int
+
+	mu     sync.RWMutex
+	recent []Failure
+	last   map[string]Failure // key: "<domain>/<id>"
+}
+
+// NewFailures records failures of next, keeping up to size recent records (default 100).
+func NewFailures(next udp.CommandHandler, size int) *Failures {
+	if size <= 0 {
+		size = 100
+	}
+	return &Failures{next: next, size: size, last: make(map[string]Failure)}
+}
+
+func (f *Failures) Apply(ctx context.Context, cmd udp.Command) error {
+	err := f.next.Apply(ctx, cmd)
+	if err == nil {
+		return nil
+	}
+
+	rec := Failure{
+		CorrelationID: correlationID(),
+		Time:          time.Now(),
+		Command:       cmd,
+		Class:         classify(err),
+		Error:         err.Error(),
+	}
+	var apiErr *bridge.ApiError
+	if errors.As(err, &apiErr) {
+		rec.HueStatus = apiErr.StatusCode
+	}
+	slog.Warn("command failed", "correlation_id", rec.CorrelationID, "class", rec.Class, "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action, "error", rec.Error)
+
+	f.mu.Lock()
+	f.recent = append(f.recent, rec)
+	if len(f.recent) > f.size {
+		f.recent = f.recent[len(f.recent)-f.size:]
+	}
+	f.last[string(cmd.Domain)+"/"+string(cmd.ID)] = rec
+	f.mu.Unlock()
+
+	return err
+}
+
+// Recent returns the recorded failures, newest last.
+func (f *Failures) Recent() []Failure {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]Failure(nil), f.recent...)
+}
+
+// Last returns the last failure per "<domain>/<id>".
+func (f *Failures) Last() map[string]Failure {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make(map[string]Failure, len(f.last))
+	for k, v := range f.last {
+		out[k] = v
+	}
+	return out
+}
+
+func classify(err error) string {
+	var apiErr *bridge.ApiError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrEntertainmentActive):
+		return FailureEntertainment
+	case errors.Is(err, context.DeadlineExceeded):
+		return FailureTimeout
+	case errors.Is(err, context.Canceled):
+		return FailureCancelled
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return FailureAuth
+		case apiErr.StatusCode == http.StatusNotFound:
+			return FailureNotFound
+		case apiErr.StatusCode >= 500:
+			return FailureBridge
+		}
+		return FailureRejected
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return FailureTimeout
+		}
+		return FailureUnreachable
+	case strings.HasPrefix(err.Error(), "unsupported"):
+		return FailureInvalid
+	}
+	return FailureRejected
+}
+
+func correlationID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
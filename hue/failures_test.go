@@ -0,0 +1,70 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+type errHandler struct{ err error }
+
+func (h errHandler) Apply(context.Context, udp.Command) error { return h.err }
+
+func TestFailures_Classify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantClass string
+	}{
+		{name: "timeout", err: fmt.Errorf("update: %w", context.DeadlineExceeded), wantClass: FailureTimeout},
+		{name: "forbidden", err: &bridge.ApiError{StatusCode: http.StatusForbidden}, wantClass: FailureAuth},
+		{name: "not found", err: &bridge.ApiError{StatusCode: http.StatusNotFound}, wantClass: FailureNotFound},
+		{name: "bridge error", err: &bridge.ApiError{StatusCode: http.StatusServiceUnavailable}, wantClass: FailureBridge},
+		{name: "entertainment", err: &EntertainmentError{ConfigID: "cfg"}, wantClass: FailureEntertainment},
+		{name: "unsupported", err: errors.New("unsupported domain: foo"), wantClass: FailureInvalid},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := NewFailures(errHandler{err: tt.err}, 0)
+			cmd := udp.Command{Domain: "grouped_light", ID: "g1", Action: "on", Value: udp.BoolValue(true)}
+			if err := f.Apply(context.Background(), cmd); !errors.Is(err, tt.err) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.err)
+			}
+
+			last, ok := f.Last()["grouped_light/g1"]
+			if !ok {
+				t.Fatal("no failure retained for grouped_light/g1")
+			}
+			if last.Class != tt.wantClass {
+				t.Errorf("Class = %q, want %q", last.Class, tt.wantClass)
+			}
+			if last.CorrelationID == "" {
+				t.Error("CorrelationID is empty")
+			}
+		})
+	}
+}
+
+func TestFailures_KeepsRecentBounded(t *testing.T) {
+	f := NewFailures(errHandler{err: errors.New("boom")}, 2)
+	for _, id := range []resource.ID{"a", "b", "c"} {
+		_ = f.Apply(context.Background(), udp.Command{Domain: "scene", ID: id, Action: "on"})
+	}
+	recent := f.Recent()
+	if len(recent) != 2 || recent[0].Command.ID != "b" || recent[1].Command.ID != "c" {
+		t.Errorf("Recent() = %+v, want failures for b and c", recent)
+	}
+	if len(f.Last()) != 3 {
+		t.Errorf("Last() has %d entries, want 3", len(f.Last()))
+	}
+}
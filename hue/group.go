@@ -0,0 +1,51 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// applyGroup expands a room/zone intent that the bridge cannot express into
+// per-light commands, e.g. "all lights on except the reading lamp". Excluded
+// lights are left untouched.
+func (a *Adapter) applyGroup(ctx context.Context, cmd udp.Command) error {
+	if a.names == nil {
+		return errors.New("group commands need the inventory")
+	}
+	var on bool
+	switch cmd.Action {
+	case "lights_on_except":
+		on = true
+	case "lights_off_except":
+		on = false
+	default:
+		return fmt.Errorf("unsupported %s action: %s", cmd.Domain, cmd.Action)
+	}
+
+	lights := a.names.Lights(string(cmd.ID))
+	if len(lights) == 0 {
+		return fmt.Errorf("%s %s has no known lights", cmd.Domain, cmd.ID)
+	}
+	except := make(map[string]bool)
+	for _, id := range strings.Split(cmd.Value.Raw, ",") {
+		except[strings.TrimSpace(id)] = true
+	}
+
+	a.logger.Info("set group lights", "domain", cmd.Domain, "id", cmd.ID, "name", a.name(string(cmd.ID)), "on", on, "except", cmd.Value.Raw)
+	dynamics := a.lightDynamics(cmd)
+	var errs []error
+	for _, id := range lights {
+		if except[id] {
+			continue
+		}
+		if err := a.home.UpdateLight(ctx, id, openhue.LightPut{On: &openhue.On{On: &on}, Dynamics: dynamics}); err != nil {
+			errs = append(errs, fmt.Errorf("light %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
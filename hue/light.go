@@ -0,0 +1,42 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+
+	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// applyLight drives a single light service, e.g. a bulb outside any room or a
+// plug: on, brightness (or dimmable), color_temp in mirek and color as x,y or RGB.
+func (a *Adapter) applyLight(ctx context.Context, cmd udp.Command) error {
+	id := string(cmd.ID)
+	v, err := value(cmd)
+	if err != nil {
+		return err
+	}
+	body := openhue.LightPut{Dynamics: a.lightDynamics(cmd)}
+	switch cmd.Action {
+	case "on":
+		body.On = &openhue.On{On: &v.Bool}
+		a.logger.Info("set light on/off", "id", id, "name", a.name(id), "on", v.Bool)
+	case "dimmable", "brightness":
+		level, on := a.floors.For(id).Apply(a.curves.For(id).ToHue(v.Percent))
+		b := openhue.Brightness(level)
+		body.On = &openhue.On{On: &on}
+		body.Dimming = &openhue.Dimming{Brightness: &b}
+		a.logger.Info("set light brightness", "id", id, "name", a.name(id), "brightness", b, "on", on)
+	case "color_temp":
+		mirek := openhue.Mirek(v.Mirek)
+		body.ColorTemperature = &openhue.ColorTemperature{Mirek: &mirek}
+		a.logger.Info("set light color temperature", "id", id, "name", a.name(id), "mirek", mirek)
+	case "color":
+		c := xy(float32(v.XY.X), float32(v.XY.Y))
+		body.Color = &c
+		a.logger.Info("set light color", "id", id, "name", a.name(id), "x", v.XY.X, "y", v.XY.Y)
+	default:
+		return fmt.Errorf("unsupported light action: %s", cmd.Action)
+	}
+	return a.home.UpdateLight(ctx, id, body)
+}
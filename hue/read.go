@@ -0,0 +1,48 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+
+	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// Read answers v2 "get" commands with the current state in v1 path form, so the
+// reply can be fed back into the same Loxone inputs.
+func (a *Adapter) Read(ctx context.Context, cmd udp.Command) ([]string, error) {
+	switch cmd.Domain {
+	case "grouped_light":
+		gl, err := a.home.GetGroupedLight(ctx, string(cmd.ID))
+		if err != nil {
+			return nil, err
+		}
+		if gl == nil {
+			return nil, fmt.Errorf("grouped_light %s not found", cmd.ID)
+		}
+		if gl.On == nil || gl.On.On == nil {
+			return nil, fmt.Errorf("grouped_light %s has no on state", cmd.ID)
+		}
+		lines := []string{fmt.Sprintf("/grouped_light/%s/on %s", cmd.ID, a.bools.Format("on", *gl.On.On))}
+		// on/off-only groups have no dimming
+		if gl.Dimming != nil && gl.Dimming.Brightness != nil {
+			lines = append(lines, fmt.Sprintf("/grouped_light/%s/dimmable %.0f", cmd.ID, float64(*gl.Dimming.Brightness)))
+		}
+		return lines, nil
+	case "scene":
+		scene, err := a.home.GetScene(ctx, string(cmd.ID))
+		if err != nil {
+			return nil, err
+		}
+		if scene == nil {
+			return nil, fmt.Errorf("scene %s not found", cmd.ID)
+		}
+		if scene.Status == nil || scene.Status.Active == nil {
+			return nil, fmt.Errorf("scene %s has no status", cmd.ID)
+		}
+		active := *scene.Status.Active != openhue.SceneGetStatusActiveInactive
+		return []string{fmt.Sprintf("/scene/%s/on %s", cmd.ID, a.bools.Format("on", active))}, nil
+	default:
+		return nil, fmt.Errorf("unsupported domain: %s", cmd.Domain)
+	}
+}
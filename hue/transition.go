@@ -0,0 +1,93 @@
+package hue
+
+import (
+	"fmt"
+	"time"
+
+	openhue "github.com/openhue/openhue-go"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// maxTransition is the longest fade the bridge accepts (dynamics.duration, ms).
+const maxTransition = 6000 * time.Second
+
+// Transitions holds the default fade applied to commands that do not carry one,
+// optionally overridden per room, zone or grouped_light id.
+type Transitions struct {
+	def  time.Duration
+	byID map[string]time.Duration
+}
+
+// NewTransitions parses per-id durations such as "800ms" or "2s".
+func NewTransitions(def time.Duration, perID map[string]string) (*Transitions, error) {
+	if def < 0 || def > maxTransition {
+		return nil, fmt.Errorf("invalid transition %s: expected 0..%s", def, maxTransition)
+	}
+	t := &Transitions{def: def, byID: make(map[string]time.Duration, len(perID))}
+	for id, spec := range perID {
+		d, err := time.ParseDuration(spec)
+		if err != nil || d < 0 || d > maxTransition {
+			return nil, fmt.Errorf("invalid transition %s=%q: expected a duration up to %s", id, spec, maxTransition)
+		}
+		t.byID[id] = d
+	}
+	return t, nil
+}
+
+// For returns the transition of the first id that has one configured, or the
+// default. A nil *Transitions means no transition.
+func (t *Transitions) For(ids ...string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	for _, id := range ids {
+		if d, ok := t.byID[id]; ok {
+			return d
+		}
+	}
+	return t.def
+}
+
+// UseTransitions sets the fades applied when Loxone does not send one.
+func (a *Adapter) UseTransitions(t *Transitions) {
+	a.transitions = t
+}
+
+// transition resolves the fade for cmd: the command's own, then the group's
+// (grouped_light or scene id, then its room or zone), then the default.
+func (a *Adapter) transition(cmd udp.Command) *int {
+	d := cmd.Transition
+	if d == 0 {
+		d = a.transitions.For(string(cmd.ID), a.owningGroup(cmd))
+	}
+	if d <= 0 {
+		return nil
+	}
+	ms := int(min(d, maxTransition) / time.Millisecond)
+	return &ms
+}
+
+// owningGroup returns the room or zone id a grouped_light or scene belongs to, or "".
+func (a *Adapter) owningGroup(cmd udp.Command) string {
+	if a.names == nil {
+		return ""
+	}
+	if cmd.Domain == "scene" {
+		return a.names.SceneGroup(string(cmd.ID))
+	}
+	return a.names.GroupOwner(string(cmd.ID))
+}
+
+func (a *Adapter) groupedDynamics(cmd udp.Command) *openhue.Dynamics {
+	if ms := a.transition(cmd); ms != nil {
+		return &openhue.Dynamics{Duration: ms}
+	}
+	return nil
+}
+
+func (a *Adapter) lightDynamics(cmd udp.Command) *openhue.LightDynamics {
+	if ms := a.transition(cmd); ms != nil {
+		return &openhue.LightDynamics{Duration: ms}
+	}
+	return nil
+}
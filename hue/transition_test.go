@@ -0,0 +1,103 @@
+package hue
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/bridge"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+// fakeInventory maps grouped_lights and scenes to their room.
+type fakeInventory struct {
+	groups map[string]string
+	scenes map[string]string
+}
+
+func (f fakeInventory) GetAlias(string) string           { return "" }
+func (f fakeInventory) Lights(string) []string           { return nil }
+func (f fakeInventory) GroupOwner(id string) string      { return f.groups[id] }
+func (f fakeInventory) SceneGroup(sceneID string) string { return f.scenes[sceneID] }
+
+func TestTransitionsFor(t *testing.T) {
+	tr, err := NewTransitions(400*time.Millisecond, map[string]string{
+		"room-1": "2s",
+		"gl-1":   "0s",
+	})
+	if err != nil {
+		t.Fatalf("NewTransitions() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ids  []string
+		want time.Duration
+	}{
+		{name: "default", ids: []string{"gl-2", "room-2"}, want: 400 * time.Millisecond},
+		{name: "room", ids: []string{"gl-2", "room-1"}, want: 2 * time.Second},
+		{name: "grouped_light wins over room", ids: []string{"gl-1", "room-1"}, want: 0},
+		{name: "no ids", want: 400 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tr.For(tt.ids...); got != tt.want {
+				t.Errorf("For(%v) = %s, want %s", tt.ids, got, tt.want)
+			}
+		})
+	}
+
+	var none *Transitions
+	if got := none.For("room-1"); got != 0 {
+		t.Errorf("nil For() = %s, want 0", got)
+	}
+}
+
+func TestNewTransitions_Invalid(t *testing.T) {
+	if _, err := NewTransitions(-time.Second, nil); err == nil {
+		t.Error("NewTransitions(-1s) error = nil, want error")
+	}
+	if _, err := NewTransitions(0, map[string]string{"room-1": "slow"}); err == nil {
+		t.Error("NewTransitions(slow) error = nil, want error")
+	}
+}
+
+func TestAdapterTransition_Owner(t *testing.T) {
+	tr, err := NewTransitions(0, map[string]string{"room-1": "2s"})
+	if err != nil {
+		t.Fatalf("NewTransitions() error = %v", err)
+	}
+	a, err := NewAdapter(&bridge.Home{}, fakeInventory{
+		groups: map[string]string{"gl-1": "room-1"},
+		scenes: map[string]string{"scene-1": "room-1"},
+	}, slog.Default())
+	if err != nil {
+		t.Fatalf("NewAdapter() error = %v", err)
+	}
+	a.UseTransitions(tr)
+
+	tests := []struct {
+		name string
+		cmd  udp.Command
+		want int
+	}{
+		{name: "grouped_light in room", cmd: udp.Command{Domain: "grouped_light", ID: "gl-1"}, want: 2000},
+		{name: "scene in room", cmd: udp.Command{Domain: "scene", ID: "scene-1"}, want: 2000},
+		{name: "scene id is not a grouped_light", cmd: udp.Command{Domain: "scene", ID: "gl-1"}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := 0
+			if ms := a.transition(tt.cmd); ms != nil {
+				got = *ms
+			}
+			if got != tt.want {
+				t.Errorf("transition(%s/%s) = %dms, want %dms", tt.cmd.Domain, tt.cmd.ID, got, tt.want)
+			}
+		})
+	}
+}
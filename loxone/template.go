@@ -0,0 +1,138 @@
+// Package loxone writes Loxone Config templates for the gateway's UDP paths, so
+// virtual inputs and outputs don't have to be typed in by hand.
+package loxone
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Input is one virtual UDP input command, e.g. a motion sensor.
+type Input struct {
+	Title  string
+	Path   string // as sent by the gateway, e.g. "/sensor/<id>/motion"
+	Analog bool   // digital inputs take 1/0
+}
+
+// Output is one virtual output command, e.g. switching a room's lights.
+type Output struct {
+	Title  string
+	On     string // e.g. "/grouped_light/<id>/on 1"; analog outputs use <v> for the value
+	Off    string // digital outputs only
+	Analog bool
+}
+
+type virtualInUDP struct {
+	XMLName xml.Name          `xml:"VirtualInUdp"`
+	Title   string            `xml:"Title,attr"`
+	Comment string            `xml:"Comment,attr"`
+	Address string            `xml:"Address,attr"`
+	Port    int               `xml:"Port,attr"`
+	Cmds    []virtualInUDPCmd `xml:"VirtualInUdpCmd"`
+}
+
+type virtualInUDPCmd struct {
+	Title         string `xml:"Title,attr"`
+	Comment       string `xml:"Comment,attr"`
+	Address       string `xml:"Address,attr"`
+	Check         string `xml:"Check,attr"`
+	Signed        bool   `xml:"Signed,attr"`
+	Analog        bool   `xml:"Analog,attr"`
+	SourceValLow  int    `xml:"SourceValLow,attr"`
+	DestValLow    int    `xml:"DestValLow,attr"`
+	SourceValHigh int    `xml:"SourceValHigh,attr"`
+	DestValHigh   int    `xml:"DestValHigh,attr"`
+	DefVal        int    `xml:"DefVal,attr"`
+	MinVal        int    `xml:"MinVal,attr"`
+	MaxVal        int    `xml:"MaxVal,attr"`
+}
+
+type virtualOut struct {
+	XMLName        xml.Name        `xml:"VirtualOut"`
+	Title          string          `xml:"Title,attr"`
+	Comment        string          `xml:"Comment,attr"`
+	Address        string          `xml:"Address,attr"`
+	CmdInit        string          `xml:"CmdInit,attr"`
+	CloseAfterSend bool            `xml:"CloseAfterSend,attr"`
+	CmdSep         string          `xml:"CmdSep,attr"`
+	Cmds           []virtualOutCmd `xml:"VirtualOutCmd"`
+}
+
+type virtualOutCmd struct {
+	Title        string `xml:"Title,attr"`
+	Comment      string `xml:"Comment,attr"`
+	CmdOnMethod  string `xml:"CmdOnMethod,attr"`
+	CmdOn        string `xml:"CmdOn,attr"`
+	CmdOnHTTP    string `xml:"CmdOnHTTP,attr"`
+	CmdOnPost    string `xml:"CmdOnPost,attr"`
+	CmdOffMethod string `xml:"CmdOffMethod,attr"`
+	CmdOff       string `xml:"CmdOff,attr"`
+	CmdOffHTTP   string `xml:"CmdOffHTTP,attr"`
+	CmdOffPost   string `xml:"CmdOffPost,attr"`
+	Analog       bool   `xml:"Analog,attr"`
+	Repeat       int    `xml:"Repeat,attr"`
+	RepeatRate   int    `xml:"RepeatRate,attr"`
+}
+
+// WriteInputs writes a virtual UDP input template listening on port, to import
+// in Loxone Config (Virtual UDP Input, "Import template").
+func WriteInputs(w io.Writer, title string, port int, inputs []Input) error {
+	t := virtualInUDP{Title: title, Port: port}
+	for _, in := range inputs {
+		cmd := virtualInUDPCmd{
+			Title:         in.Title,
+			Comment:       in.Path,
+			Check:         in.Path + ` \v`,
+			Signed:        true,
+			Analog:        in.Analog,
+			SourceValHigh: 100,
+			DestValHigh:   100,
+			MinVal:        -2147483648,
+			MaxVal:        2147483647,
+		}
+		if !in.Analog {
+			cmd.SourceValHigh, cmd.DestValHigh, cmd.MinVal, cmd.MaxVal = 1, 1, 0, 1
+		}
+		t.Cmds = append(t.Cmds, cmd)
+	}
+	return write(w, t)
+}
+
+// WriteOutputs writes a virtual output template sending to the gateway's
+// command server at host:port.
+func WriteOutputs(w io.Writer, title, host string, port int, outputs []Output) error {
+	t := virtualOut{
+		Title:          title,
+		Address:        "/dev/udp/" + host + "/" + strconv.Itoa(port),
+		CloseAfterSend: true,
+	}
+	for _, out := range outputs {
+		if out.On == "" {
+			return fmt.Errorf("output %q has no command", out.Title)
+		}
+		t.Cmds = append(t.Cmds, virtualOutCmd{
+			Title:        out.Title,
+			CmdOnMethod:  "GET",
+			CmdOn:        out.On,
+			CmdOffMethod: "GET",
+			CmdOff:       out.Off,
+			Analog:       out.Analog,
+		})
+	}
+	return write(w, t)
+}
+
+func write(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,54 @@
+package loxone
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteInputs(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteInputs(&buf, "Hue", 1234, []Input{
+		{Title: "Hallway motion", Path: "/sensor/abc/motion"},
+		{Title: "Hallway temperature", Path: "/sensor/abc/temperature", Analog: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="utf-8"?>`,
+		`<VirtualInUdp Title="Hue" Comment="" Address="" Port="1234">`,
+		`Title="Hallway motion" Comment="/sensor/abc/motion" Address="" Check="/sensor/abc/motion \v" Signed="true" Analog="false" SourceValLow="0" DestValLow="0" SourceValHigh="1" DestValHigh="1" DefVal="0" MinVal="0" MaxVal="1"`,
+		`Check="/sensor/abc/temperature \v" Signed="true" Analog="true" SourceValLow="0" DestValLow="0" SourceValHigh="100" DestValHigh="100" DefVal="0" MinVal="-2147483648" MaxVal="2147483647"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteInputs() missing %s in\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteOutputs(&buf, "Hue", "192.168.1.20", 1234, []Output{
+		{Title: "Kitchen on", On: "/grouped_light/gl/on 1", Off: "/grouped_light/gl/on 0"},
+		{Title: "Kitchen brightness", On: "/grouped_light/gl/dimmable <v>", Analog: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<VirtualOut Title="Hue" Comment="" Address="/dev/udp/192.168.1.20/1234" CmdInit="" CloseAfterSend="true" CmdSep="">`,
+		`Title="Kitchen on" Comment="" CmdOnMethod="GET" CmdOn="/grouped_light/gl/on 1" CmdOnHTTP="" CmdOnPost="" CmdOffMethod="GET" CmdOff="/grouped_light/gl/on 0"`,
+		`CmdOn="/grouped_light/gl/dimmable &lt;v&gt;"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteOutputs() missing %s in\n%s", want, got)
+		}
+	}
+
+	if err := WriteOutputs(&buf, "Hue", "192.168.1.20", 1234, []Output{{Title: "empty"}}); err == nil {
+		t.Error("WriteOutputs() with an empty command: error = nil")
+	}
+}
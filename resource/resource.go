@@ -0,0 +1,191 @@
+// Package resource holds the identifiers shared by the event, command and bridge
+// layers, so a device id cannot silently stand in for a service rid and a
+// resource type cannot be confused with a message channel.
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ID is a CLIP v2 resource id, a lowercase UUID such as
+// "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab". Devices and their services (light,
+// motion, ...) each have their own ID; an Owner names the device.
+type ID string
+
+// ParseID validates s and returns it in canonical (lowercase) form.
+func ParseID(s string) (ID, error) {
+	id := ID(strings.ToLower(s))
+	if !id.Valid() {
+		return "", fmt.Errorf("invalid resource id %q: want a UUID", s)
+	}
+	return id, nil
+}
+
+// Valid reports whether id is a lowercase UUID.
+func (id ID) Valid() bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (id ID) String() string { return string(id) }
+
+func (id ID) MarshalText() ([]byte, error) { return []byte(id), nil }
+
+// UnmarshalText accepts an empty id or a UUID.
+func (id *ID) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*id = ""
+		return nil
+	}
+	v, err := ParseID(string(b))
+	if err != nil {
+		return err
+	}
+	*id = v
+	return nil
+}
+
+// Type is a CLIP v2 resource type ("light", "grouped_light", ...). Commands also
+// use it as their domain, which adds a few gateway-only values.
+type Type string
+
+const (
+	TypeBridge                     Type = "bridge"
+	TypeBridgeHome                 Type = "bridge_home"
+	TypeButton                     Type = "button"
+	TypeContact                    Type = "contact"
+	TypeDevice                     Type = "device"
+	TypeDevicePower                Type = "device_power"
+	TypeEntertainmentConfiguration Type = "entertainment_configuration"
+	TypeGeofenceClient             Type = "geofence_client"
+	TypeGroupedLight               Type = "grouped_light"
+	TypeGroupedLightLevel          Type = "grouped_light_level"
+	TypeGroupedMotion              Type = "grouped_motion"
+	TypeLight                      Type = "light"
+	TypeLightLevel                 Type = "light_level"
+	TypeMotion                     Type = "motion"
+	TypeRelativeHumidity           Type = "relative_humidity"
+	TypeRelativeRotary             Type = "relative_rotary"
+	TypeRoom                       Type = "room"
+	TypeScene                      Type = "scene"
+	TypeSecurityAreaMotion         Type = "security_area_motion"
+	TypeTamper                     Type = "tamper"
+	TypeTemperature                Type = "temperature"
+	TypeZigbeeConnectivity         Type = "zigbee_connectivity"
+	TypeZone                       Type = "zone"
+)
+
+var knownTypes = map[Type]bool{
+	TypeBridge: true, TypeBridgeHome: true, TypeButton: true, TypeContact: true, TypeDevice: true, TypeDevicePower: true,
+	TypeEntertainmentConfiguration: true, TypeGeofenceClient: true, TypeGroupedLight: true,
+	TypeGroupedLightLevel: true, TypeGroupedMotion: true, TypeLight: true, TypeLightLevel: true,
+	TypeMotion: true, TypeRelativeHumidity: true, TypeRelativeRotary: true, TypeRoom: true, TypeScene: true, TypeSecurityAreaMotion: true, TypeTamper: true, TypeTemperature: true,
+	TypeZigbeeConnectivity: true, TypeZone: true,
+}
+
+// ParseType validates the spelling of s; the bridge adds types over time, so
+// unknown but well-formed types are accepted (see Known).
+func ParseType(s string) (Type, error) {
+	if !token(s) {
+		return "", fmt.Errorf("invalid resource type %q", s)
+	}
+	return Type(s), nil
+}
+
+// Known reports whether the gateway handles resources of type t.
+func (t Type) Known() bool { return knownTypes[t] }
+
+func (t Type) String() string { return string(t) }
+
+func (t Type) MarshalText() ([]byte, error) { return []byte(t), nil }
+
+// UnmarshalText accepts an empty or well-formed type.
+func (t *Type) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*t = ""
+		return nil
+	}
+	v, err := ParseType(string(b))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// Metric is the last path segment of a message to Loxone ("on", "motion",
+// "temperature", ...), i.e. which value of a resource it carries.
+type Metric string
+
+const (
+	MetricActive      Metric = "active"
+	MetricBattery     Metric = "battery"
+	MetricBrightness  Metric = "brightness"
+	MetricDimmable    Metric = "dimmable"
+	MetricEnergy      Metric = "energy"
+	MetricHumidity    Metric = "humidity"
+	MetricLightLevel  Metric = "light_level"
+	MetricMotion      Metric = "motion"
+	MetricOccupied    Metric = "occupied"
+	MetricOn          Metric = "on"
+	MetricPower       Metric = "power"
+	MetricState       Metric = "state"
+	MetricTamper      Metric = "tamper"
+	MetricTemperature Metric = "temperature"
+)
+
+// ParseMetric validates s as a path segment.
+func ParseMetric(s string) (Metric, error) {
+	if !token(s) {
+		return "", fmt.Errorf("invalid metric %q", s)
+	}
+	return Metric(s), nil
+}
+
+func (m Metric) String() string { return string(m) }
+
+func (m Metric) MarshalText() ([]byte, error) { return []byte(m), nil }
+
+// UnmarshalText accepts an empty or well-formed metric.
+func (m *Metric) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*m = ""
+		return nil
+	}
+	v, err := ParseMetric(string(b))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// token reports whether s is a non-empty lowercase identifier.
+func token(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package resource
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    ID
+		wantErr bool
+	}{
+		{in: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", want: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab"},
+		{in: "3F1F2B0E-8D3A-4C4E-9C55-0123456789AB", want: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab"},
+		{in: "", wantErr: true},
+		{in: "kitchen", wantErr: true},
+		{in: "3f1f2b0e8d3a-4c4e-9c55-0123456789ab-", wantErr: true},
+		{in: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ag", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseID(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseID(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseID(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	type owner struct {
+		ID     ID     `json:"rid"`
+		Type   Type   `json:"rtype"`
+		Metric Metric `json:"metric,omitempty"`
+	}
+	var o owner
+	if err := json.Unmarshal([]byte(`{"rid":"3F1F2B0E-8D3A-4C4E-9C55-0123456789AB","rtype":"light"}`), &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.ID != "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab" || o.Type != TypeLight || !o.Type.Known() {
+		t.Errorf("decoded %+v", o)
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"rid":"3f1f2b0e-8d3a-4c4e-9c55-0123456789ab","rtype":"light"}`; string(b) != want {
+		t.Errorf("Marshal = %s, want %s", b, want)
+	}
+
+	for _, bad := range []string{
+		`{"rid":"light-1","rtype":"light"}`,
+		`{"rid":"","rtype":"Light"}`,
+		`{"rid":"","rtype":"light","metric":"on off"}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &o); err == nil {
+			t.Errorf("Unmarshal(%s) accepted malformed input", bad)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Package script lets users attach Starlark scripts to outgoing messages to
+// transform, suppress or add messages and to issue Hue commands.
+//
+// A script defines on_message(msg); msg has the fields path, value, type, id and
+// channel. The return value decides what is forwarded:
+//
+//	None                  forward msg unchanged
+//	False                 suppress msg
+//	dict / struct         forward a replacement ({"path": ..., "value": ...})
+//	list of the above     forward several messages
+//
+// Builtins: emit(path, value) sends an extra message, hue(domain, id, action, value)
+// applies a command like a UDP datagram would, log(...) writes to the gateway log.
+//
+// on_message runs concurrently for messages from the event stream and the
+// pollers, so globals are frozen once the script is loaded; keep per-call state
+// in locals.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/resource"
+	"github.com/samvdb/loxone-philips-hue/udp"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Rule attaches a script file to messages of a hue resource type and/or path glob.
+type Rule struct {
+	Type string `mapstructure:"type"` // e.g. "temperature"; empty matches all
+	Path string `mapstructure:"path"` // path.Match glob, e.g. "/sensor/*/motion"; empty matches all
+	File string `mapstructure:"file"`
+}
+
+type Config struct {
+	Rules []Rule
+
+	// Handler (optional) applies commands issued with hue(...).
+	Handler udp.CommandHandler
+
+	Logger *slog.Logger
+}
+
+// Engine runs the configured scripts. It implements client.MessageHook.
+type Engine struct {
+	scripts []*compiled
+	handler udp.CommandHandler
+	log     *slog.Logger
+}
+
+type compiled struct {
+	rule Rule
+	fn   starlark.Callable
+}
+
+// thread-local keys
+const (
+	localCtx  = "ctx"
+	localEmit = "emit"
+)
+
+func New(cfg Config) (*Engine, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	e := &Engine{
+		handler: cfg.Handler,
+		log:     cfg.Logger.With("module", "script"),
+	}
+
+	for _, rule := range cfg.Rules {
+		if rule.File == "" {
+			return nil, errors.New("script rule without file")
+		}
+		if rule.Path != "" {
+			if _, err := path.Match(rule.Path, ""); err != nil {
+				return nil, fmt.Errorf("script %s: bad path pattern: %w", rule.File, err)
+			}
+		}
+
+		thread := &starlark.Thread{Name: rule.File, Print: e.print}
+		globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, rule.File, nil, e.builtins())
+		if err != nil {
+			return nil, fmt.Errorf("script %s: %w", rule.File, err)
+		}
+		globals.Freeze()
+		fn, ok := globals["on_message"].(starlark.Callable)
+		if !ok {
+			return nil, fmt.Errorf("script %s: on_message(msg) not defined", rule.File)
+		}
+		e.scripts = append(e.scripts, &compiled{rule: rule, fn: fn})
+		e.log.Info("script loaded", "file", rule.File, "type", rule.Type, "path", rule.Path)
+	}
+	return e, nil
+}
+
+func (e *Engine) Process(ctx context.Context, msg client.Message) ([]client.Message, error) {
+	msgs := []client.Message{msg}
+	for _, s := range e.scripts {
+		var next []client.Message
+		for _, m := range msgs {
+			if !s.matches(m) {
+				next = append(next, m)
+				continue
+			}
+			out, err := e.call(ctx, s, m)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		msgs = next
+	}
+	return msgs, nil
+}
+
+func (s *compiled) matches(m client.Message) bool {
+	if s.rule.Type != "" && s.rule.Type != string(m.Type) {
+		return false
+	}
+	if s.rule.Path != "" {
+		ok, _ := path.Match(s.rule.Path, m.Path)
+		return ok
+	}
+	return true
+}
+
+func (e *Engine) call(ctx context.Context, s *compiled, m client.Message) ([]client.Message, error) {
+	var emitted []client.Message
+	thread := &starlark.Thread{Name: s.rule.File, Print: e.print}
+	thread.SetLocal(localCtx, ctx)
+	thread.SetLocal(localEmit, &emitted)
+
+	res, err := starlark.Call(thread, s.fn, starlark.Tuple{toStarlark(m)}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", s.rule.File, err)
+	}
+
+	out, err := fromStarlark(res, m)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", s.rule.File, err)
+	}
+	return append(out, emitted...), nil
+}
+
+func (e *Engine) builtins() starlark.StringDict {
+	return starlark.StringDict{
+		"emit": starlark.NewBuiltin("emit", e.emit),
+		"hue":  starlark.NewBuiltin("hue", e.hue),
+		"log":  starlark.NewBuiltin("log", e.logBuiltin),
+	}
+}
+
+func (e *Engine) emit(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var p, v string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &p, "value", &v); err != nil {
+		return nil, err
+	}
+	emitted, ok := thread.Local(localEmit).(*[]client.Message)
+	if !ok {
+		return nil, fmt.Errorf("emit: only allowed inside on_message")
+	}
+	*emitted = append(*emitted, client.Message{Path: p, Value: v, Channel: lastSegment(p)})
+	return starlark.None, nil
+}
+
+func (e *Engine) hue(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var domain, id, action, value string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "domain", &domain, "id", &id, "action", &action, "value", &value); err != nil {
+		return nil, err
+	}
+	if e.handler == nil {
+		return nil, fmt.Errorf("hue: commands are not available")
+	}
+	ctx, ok := thread.Local(localCtx).(context.Context)
+	if !ok {
+		return nil, fmt.Errorf("hue: only allowed inside on_message")
+	}
+	// validated and parsed like a command received from Loxone
+	cmd, err := udp.NewCommand(domain, id, action, value, "")
+	if err != nil {
+		return nil, fmt.Errorf("hue: %w", err)
+	}
+	e.log.Info("script command", "file", thread.Name, "domain", cmd.Domain, "id", cmd.ID, "action", cmd.Action, "value", cmd.Value.Raw)
+	if err := e.handler.Apply(udp.WithSource(ctx, "script:"+thread.Name), cmd); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
+func (e *Engine) logBuiltin(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	parts := make([]string, 0, len(args))
+	for _, a := range args {
+		if s, ok := starlark.AsString(a); ok {
+			parts = append(parts, s)
+		} else {
+			parts = append(parts, a.String())
+		}
+	}
+	e.print(thread, strings.Join(parts, " "))
+	return starlark.None, nil
+}
+
+func (e *Engine) print(thread *starlark.Thread, msg string) {
+	e.log.Info(msg, "file", thread.Name)
+}
+
+func toStarlark(m client.Message) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"path":    starlark.String(m.Path),
+		"value":   starlark.String(m.Value),
+		"type":    starlark.String(m.Type),
+		"id":      starlark.String(m.ID),
+		"channel": starlark.String(m.Channel),
+	})
+}
+
+func fromStarlark(v starlark.Value, orig client.Message) ([]client.Message, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return []client.Message{orig}, nil
+	case starlark.Bool:
+		if v {
+			return []client.Message{orig}, nil
+		}
+		return nil, nil
+	case *starlark.List:
+		var out []client.Message
+		for i := 0; i < v.Len(); i++ {
+			m, err := messageFrom(v.Index(i), orig)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, m)
+		}
+		return out, nil
+	default:
+		m, err := messageFrom(v, orig)
+		if err != nil {
+			return nil, err
+		}
+		return []client.Message{m}, nil
+	}
+}
+
+// messageFrom reads path/value from a dict or struct; missing fields keep orig's.
+func messageFrom(v starlark.Value, orig client.Message) (client.Message, error) {
+	get := func(name string) (starlark.Value, error) {
+		switch v := v.(type) {
+		case *starlark.Dict:
+			val, found, err := v.Get(starlark.String(name))
+			if err != nil || !found {
+				return nil, err
+			}
+			return val, nil
+		case starlark.HasAttrs:
+			val, err := v.Attr(name)
+			if err != nil {
+				return nil, nil
+			}
+			return val, nil
+		default:
+			return nil, fmt.Errorf("on_message returned %s, want None, bool, dict, struct or list", v.Type())
+		}
+	}
+
+	m := orig
+	for name, dst := range map[string]*string{"path": &m.Path, "value": &m.Value} {
+		val, err := get(name)
+		if err != nil {
+			return client.Message{}, err
+		}
+		if val == nil {
+			continue
+		}
+		if s, ok := starlark.AsString(val); ok {
+			*dst = s
+		} else {
+			*dst = val.String()
+		}
+	}
+	m.Channel = lastSegment(m.Path)
+	return m, nil
+}
+
+func lastSegment(p string) resource.Metric {
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return resource.Metric(p[i+1:])
+	}
+	return resource.Metric(p)
+}
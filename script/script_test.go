@@ -0,0 +1,123 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+	"github.com/samvdb/loxone-philips-hue/gateway"
+	"github.com/samvdb/loxone-philips-hue/udp"
+)
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	f := filepath.Join(t.TempDir(), "hook.star")
+	if err := os.WriteFile(f, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestEngine_Process(t *testing.T) {
+	f := writeScript(t, `
+def on_message(msg):
+    if msg.value == "0":
+        return False
+    emit("/extra" + msg.path, "x")
+    return {"value": msg.value + "0"}
+`)
+	e, err := New(Config{Rules: []Rule{{Type: "motion", File: f}}})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	got, err := e.Process(context.Background(), client.Message{Type: "motion", Path: "/sensor/a/motion", Value: "1"})
+	if err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Value != "10" || got[1].Path != "/extra/sensor/a/motion" {
+		t.Errorf("Process() = %+v, want transformed + emitted message", got)
+	}
+
+	got, _ = e.Process(context.Background(), client.Message{Type: "motion", Path: "/sensor/a/motion", Value: "0"})
+	if len(got) != 0 {
+		t.Errorf("Process() = %+v, want suppressed", got)
+	}
+
+	got, _ = e.Process(context.Background(), client.Message{Type: "temperature", Path: "/sensor/a/temperature", Value: "0"})
+	if len(got) != 1 {
+		t.Errorf("Process() = %+v, want non-matching message unchanged", got)
+	}
+}
+
+func TestEngine_GlobalsFrozen(t *testing.T) {
+	f := writeScript(t, `
+seen = {}
+
+def on_message(msg):
+    seen[msg.path] = msg.value
+`)
+	e, err := New(Config{Rules: []Rule{{File: f}}})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := e.Process(context.Background(), client.Message{Path: "/sensor/a/motion", Value: "1"}); err == nil {
+		t.Error("Process() modified a global, want a frozen error")
+	}
+}
+
+type recordHandler struct{ got []udp.Command }
+
+func (h *recordHandler) Apply(_ context.Context, cmd udp.Command) error {
+	h.got = append(h.got, cmd)
+	return nil
+}
+
+const hueScript = `
+def on_message(msg):
+    hue("grouped_light", "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", "on", msg.value)
+`
+
+func TestEngine_Hue(t *testing.T) {
+	h := &recordHandler{}
+	e, err := New(Config{Rules: []Rule{{File: writeScript(t, hueScript)}}, Handler: h})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := e.Process(context.Background(), client.Message{Path: "/sensor/a/motion", Value: "1"}); err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	if len(h.got) != 1 || h.got[0].ID != "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab" || h.got[0].Value.Kind != udp.KindBool || !h.got[0].Value.Bool {
+		t.Errorf("applied %+v, want grouped_light on", h.got)
+	}
+
+	if _, err := e.Process(context.Background(), client.Message{Path: "/sensor/a/motion", Value: "maybe"}); err == nil {
+		t.Error("Process() with an invalid value, want an error")
+	}
+	if len(h.got) != 1 {
+		t.Errorf("applied %+v, want the invalid command dropped", h.got)
+	}
+}
+
+// The gateway hands scripts its full command chain, so read-only mode rejects
+// their commands like any other.
+func TestEngine_HueReadOnly(t *testing.T) {
+	e, err := New(Config{Rules: []Rule{{File: writeScript(t, hueScript)}}, Handler: gateway.ReadOnly{}})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	_, err = e.Process(context.Background(), client.Message{Path: "/sensor/a/motion", Value: "1"})
+	if !errors.Is(err, gateway.ErrReadOnly) {
+		t.Errorf("Process() error = %v, want %v", err, gateway.ErrReadOnly)
+	}
+}
+
+func TestNew_MissingEntryPoint(t *testing.T) {
+	f := writeScript(t, "x = 1\n")
+	if _, err := New(Config{Rules: []Rule{{File: f}}}); err == nil {
+		t.Fatalf("New() expected error, got nil")
+	}
+}
@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+// SchemaVersion is the newest version of the event JSON sent to external
+// consumers (webhooks). Consumers should check "schema_version" and ignore
+// fields they do not know.
+//
+// Compatibility policy: within a version fields are only added, never renamed,
+// removed or given a new meaning; anything else introduces a new version. The
+// previous version stays selectable (e.g. webhook schema_version) for at least
+// one release after a new one ships, so consumers can migrate on their own time.
+const SchemaVersion = 1
+
+// SchemaVersions lists the versions Encode can produce.
+var SchemaVersions = []int{1}
+
+// EventV1 is version 1 of the event JSON. It is decoupled from client.Message
+// so internal changes do not leak to consumers.
+type EventV1 struct {
+	SchemaVersion int       `json:"schema_version"`
+	Path          string    `json:"path"`
+	Value         string    `json:"value"`
+	Type          string    `json:"type,omitempty"`
+	ID            string    `json:"id,omitempty"`
+	Channel       string    `json:"channel,omitempty"`
+	Time          time.Time `json:"time"`
+	Origin        string    `json:"origin,omitempty"`
+	Stale         bool      `json:"stale,omitempty"`
+	Restored      bool      `json:"restored,omitempty"`
+}
+
+func toV1(msg client.Message) EventV1 {
+	return EventV1{
+		SchemaVersion: 1,
+		Path:          msg.Path,
+		Value:         msg.Value,
+		Type:          string(msg.Type),
+		ID:            string(msg.ID),
+		Channel:       string(msg.Channel),
+		Time:          msg.Time,
+		Origin:        msg.Origin,
+		Stale:         msg.Stale,
+		Restored:      msg.Restored,
+	}
+}
+
+// Encode renders msg as event JSON of the given schema version; 0 means
+// SchemaVersion.
+func Encode(msg client.Message, version int) ([]byte, error) {
+	switch version {
+	case 0, 1:
+		return json.Marshal(toV1(msg))
+	default:
+		return nil, fmt.Errorf("unsupported event schema version %d (supported: %v)", version, SchemaVersions)
+	}
+}
+
+func validSchemaVersion(version int) error {
+	if version == 0 {
+		return nil
+	}
+	for _, v := range SchemaVersions {
+		if v == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported event schema version %d (supported: %v)", version, SchemaVersions)
+}
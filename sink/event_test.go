@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+// TestEncodeV1 freezes the version 1 wire format; changing the expected JSON
+// means a new schema version (see SchemaVersion).
+func TestEncodeV1(t *testing.T) {
+	t.Parallel()
+
+	msg := client.Message{
+		Path:    "/sensor/abc/temperature",
+		Value:   "21.50",
+		Type:    "temperature",
+		ID:      "abc",
+		Channel: "temperature",
+		Time:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Stale:   true,
+	}
+	want := `{"schema_version":1,"path":"/sensor/abc/temperature","value":"21.50","type":"temperature","id":"abc","channel":"temperature","time":"2024-03-01T12:00:00Z","stale":true}`
+	for _, version := range []int{0, 1} {
+		got, err := Encode(msg, version)
+		if err != nil {
+			t.Fatalf("Encode(v%d) error = %v", version, err)
+		}
+		if string(got) != want {
+			t.Errorf("Encode(v%d) = %s, want %s", version, got, want)
+		}
+	}
+
+	if _, err := Encode(msg, 99); err == nil {
+		t.Error("Encode(v99) error = nil, want error")
+	}
+	if _, err := NewWebhook(WebhookConfig{URL: "http://127.0.0.1", SchemaVersion: 99}, nil); err == nil {
+		t.Error("NewWebhook(schema_version 99) error = nil, want error")
+	}
+}
@@ -0,0 +1,185 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+type InfluxConfig struct {
+	// URL is the full write endpoint, e.g. "http://influx:8086/api/v2/write?org=home&bucket=hue"
+	// or "http://victoria:8428/write".
+	URL string `mapstructure:"url"`
+
+	// Token (optional) is sent as "Authorization: Token <token>".
+	Token string `mapstructure:"token"`
+
+	// Measurement name. Default "hue".
+	Measurement string `mapstructure:"measurement"`
+
+	// FlushInterval bounds how long points are buffered. Default 10s.
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+
+	// BatchSize flushes early once this many points are buffered. Default 500.
+	BatchSize int `mapstructure:"batch_size"`
+}
+
+// Influx writes numeric messages (temperature, light level, motion, ...) as line
+// protocol to InfluxDB or VictoriaMetrics. It implements client.Sink.
+type Influx struct {
+	cfg    InfluxConfig
+	client *http.Client
+	log    *slog.Logger
+
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	count int
+	full  chan struct{}
+}
+
+func NewInflux(cfg InfluxConfig, logger *slog.Logger) (*Influx, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("influx url required")
+	}
+	if cfg.Measurement == "" {
+		cfg.Measurement = "hue"
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Influx{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		log:    logger.With("module", "influx"),
+		full:   make(chan struct{}, 1),
+	}, nil
+}
+
+// Write buffers msg as a point; non-numeric values are skipped.
+func (x *Influx) Write(msg client.Message) {
+	line, ok := LineProtocol(x.cfg.Measurement, msg)
+	if !ok {
+		return
+	}
+
+	x.mu.Lock()
+	x.buf.WriteString(line)
+	x.buf.WriteByte('\n')
+	x.count++
+	full := x.count >= x.cfg.BatchSize
+	x.mu.Unlock()
+
+	if full {
+		select {
+		case x.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run flushes buffered points periodically until ctx is cancelled, then flushes
+// what is left.
+func (x *Influx) Run(ctx context.Context) error {
+	ticker := time.NewTicker(x.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx is done, so the last write gets a deadline of its own
+			last, cancel := context.WithTimeout(context.WithoutCancel(ctx), x.client.Timeout)
+			defer cancel()
+			if err := x.flush(last); err != nil {
+				x.log.Warn("influx write failed; points dropped", "error", err.Error())
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		case <-x.full:
+		}
+		if err := x.flush(ctx); err != nil && ctx.Err() == nil {
+			x.log.Warn("influx write failed; points dropped", "error", err.Error())
+		}
+	}
+}
+
+func (x *Influx) flush(ctx context.Context) error {
+	x.mu.Lock()
+	if x.count == 0 {
+		x.mu.Unlock()
+		return nil
+	}
+	body := append([]byte(nil), x.buf.Bytes()...)
+	points := x.count
+	x.buf.Reset()
+	x.count = 0
+	x.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, x.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if x.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+x.cfg.Token)
+	}
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	x.log.Debug("influx points written", "points", points)
+	return nil
+}
+
+// LineProtocol renders msg as "<measurement>,channel=..,type=..,id=.. value=<v> <ns>".
+// It reports false for values that are not numeric.
+func LineProtocol(measurement string, msg client.Message) (string, bool) {
+	v, err := strconv.ParseFloat(msg.Value, 64)
+	if err != nil {
+		return "", false
+	}
+	ts := msg.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	var b strings.Builder
+	b.WriteString(escapeTag(measurement))
+	for _, tag := range [][2]string{{"channel", string(msg.Channel)}, {"type", string(msg.Type)}, {"id", string(msg.ID)}, {"path", msg.Path}} {
+		if tag[1] == "" {
+			continue
+		}
+		b.WriteString(",")
+		b.WriteString(tag[0])
+		b.WriteString("=")
+		b.WriteString(escapeTag(tag[1]))
+	}
+	b.WriteString(" value=")
+	b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(ts.UnixNano(), 10))
+	return b.String(), true
+}
+
+var tagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+func escapeTag(s string) string {
+	return tagEscaper.Replace(s)
+}
@@ -0,0 +1,147 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+func TestLineProtocol(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+
+	got, ok := LineProtocol("hue", client.Message{
+		Path: "/sensor/abc/temperature", Value: "21.50", Type: "temperature", ID: "abc", Channel: "temperature", Time: ts,
+	})
+	want := "hue,channel=temperature,type=temperature,id=abc,path=/sensor/abc/temperature value=21.5 1700000000000000000"
+	if !ok || got != want {
+		t.Errorf("LineProtocol() = %q, %v; want %q", got, ok, want)
+	}
+
+	// booleans reach the sinks as 1/0 whatever Loxone gets, e.g. ON/OFF
+	got, ok = LineProtocol("hue", client.Message{
+		Path: "/sensor/abc/motion", Value: "1", Type: "motion", ID: "abc", Channel: "motion", Time: ts, Bool: true,
+	})
+	want = "hue,channel=motion,type=motion,id=abc,path=/sensor/abc/motion value=1 1700000000000000000"
+	if !ok || got != want {
+		t.Errorf("LineProtocol() = %q, %v; want %q", got, ok, want)
+	}
+
+	if _, ok := LineProtocol("hue", client.Message{Value: "abc-uuid"}); ok {
+		t.Errorf("LineProtocol() accepted a non-numeric value")
+	}
+}
+
+// influxServer records the bodies POSTed to it and answers with status.
+func influxServer(t *testing.T, status int) (*httptest.Server, <-chan string) {
+	t.Helper()
+	bodies := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Token s3cret" {
+			t.Errorf("Authorization = %q, want %q", got, "Token s3cret")
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies
+}
+
+func receive(t *testing.T, bodies <-chan string) string {
+	t.Helper()
+	select {
+	case body := <-bodies:
+		return body
+	case <-time.After(5 * time.Second):
+		t.Fatal("no points written")
+		return ""
+	}
+}
+
+func TestInflux_BatchSize(t *testing.T) {
+	srv, bodies := influxServer(t, http.StatusNoContent)
+	x, err := NewInflux(InfluxConfig{URL: srv.URL, Token: "s3cret", BatchSize: 2, FlushInterval: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("NewInflux() unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go x.Run(ctx)
+
+	ts := time.Unix(1700000000, 0)
+	x.Write(client.Message{Path: "/sensor/a/motion", Value: "1", Channel: "motion", Time: ts})
+	x.Write(client.Message{Path: "/scene/a/on", Value: "abc-uuid", Time: ts}) // not a point
+	x.Write(client.Message{Path: "/sensor/b/battery", Value: "80", Channel: "battery", Time: ts})
+
+	want := "hue,channel=motion,path=/sensor/a/motion value=1 1700000000000000000\n" +
+		"hue,channel=battery,path=/sensor/b/battery value=80 1700000000000000000\n"
+	if got := receive(t, bodies); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestInflux_FlushInterval(t *testing.T) {
+	srv, bodies := influxServer(t, http.StatusNoContent)
+	x, err := NewInflux(InfluxConfig{URL: srv.URL, Token: "s3cret", FlushInterval: 10 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatalf("NewInflux() unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go x.Run(ctx)
+
+	x.Write(client.Message{Path: "/sensor/a/temperature", Value: "21.50", Time: time.Unix(1700000000, 0)})
+
+	if got, want := receive(t, bodies), "hue,path=/sensor/a/temperature value=21.5 1700000000000000000\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestInflux_FlushOnCancel(t *testing.T) {
+	srv, bodies := influxServer(t, http.StatusNoContent)
+	x, err := NewInflux(InfluxConfig{URL: srv.URL, Token: "s3cret", FlushInterval: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("NewInflux() unexpected error: %v", err)
+	}
+	x.Write(client.Message{Path: "/sensor/a/light_level", Value: "12000", Time: time.Unix(1700000000, 0)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := x.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want context.Canceled", err)
+	}
+	if got, want := receive(t, bodies), "hue,path=/sensor/a/light_level value=12000 1700000000000000000\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestInflux_Non2xx(t *testing.T) {
+	srv, bodies := influxServer(t, http.StatusBadRequest)
+	x, err := NewInflux(InfluxConfig{URL: srv.URL, Token: "s3cret"}, nil)
+	if err != nil {
+		t.Fatalf("NewInflux() unexpected error: %v", err)
+	}
+	x.Write(client.Message{Path: "/sensor/a/motion", Value: "1"})
+
+	if err := x.flush(context.Background()); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("flush() = %v, want the 400 status", err)
+	}
+	receive(t, bodies)
+
+	// the rejected points are dropped, not written again
+	if err := x.flush(context.Background()); err != nil {
+		t.Errorf("second flush() = %v, want nothing to write", err)
+	}
+	select {
+	case body := <-bodies:
+		t.Errorf("rejected points written again: %q", body)
+	default:
+	}
+}
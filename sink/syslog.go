@@ -0,0 +1,167 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+type SyslogConfig struct {
+	// Address of the collector, e.g. "10.0.0.5:514".
+	Address string `mapstructure:"address"`
+
+	// Network is "udp" (default) or "tcp".
+	Network string `mapstructure:"network"`
+
+	// Facility is the syslog facility number, 0 (kern) to 23 (local7). Default 16 (local0).
+	Facility *int `mapstructure:"facility"`
+
+	// AppName in the syslog header. Default "loxone-philips-hue".
+	AppName string `mapstructure:"app_name"`
+
+	// QueueSize is the outgoing message buffer. Default 256.
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+// Syslog emits every message as an RFC 5424 syslog line. It implements client.Sink.
+type Syslog struct {
+	cfg      SyslogConfig
+	facility int
+	hostname string
+	ch       chan client.Message
+	log      *slog.Logger
+	conn     net.Conn
+}
+
+// sdID is the structured-data id carrying the message metadata (32473 is the
+// example private enterprise number reserved for documentation).
+const sdID = "hue@32473"
+
+// severityInfo is the syslog severity used for all messages.
+const severityInfo = 6
+
+func NewSyslog(cfg SyslogConfig, logger *slog.Logger) (*Syslog, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("syslog address required")
+	}
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Network != "udp" && cfg.Network != "tcp" {
+		return nil, fmt.Errorf("syslog network must be udp or tcp, got %q", cfg.Network)
+	}
+	facility := 16
+	if cfg.Facility != nil {
+		if *cfg.Facility < 0 || *cfg.Facility > 23 {
+			return nil, fmt.Errorf("syslog facility must be 0-23, got %d", *cfg.Facility)
+		}
+		facility = *cfg.Facility
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "loxone-philips-hue"
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &Syslog{
+		cfg:      cfg,
+		facility: facility,
+		hostname: hostname,
+		ch:       make(chan client.Message, cfg.QueueSize),
+		log:      logger.With("module", "syslog", "address", cfg.Address),
+	}, nil
+}
+
+// Write enqueues msg; when the queue is full the message is dropped.
+func (s *Syslog) Write(msg client.Message) {
+	select {
+	case s.ch <- msg:
+	default:
+		s.log.Warn("syslog queue saturated; dropping message", "path", msg.Path)
+	}
+}
+
+// Run sends queued messages until ctx is cancelled, redialing after write errors.
+func (s *Syslog) Run(ctx context.Context) error {
+	defer func() {
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-s.ch:
+			if err := s.send(ctx, msg); err != nil {
+				s.log.Warn("syslog send failed; message dropped", "path", msg.Path, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (s *Syslog) send(ctx context.Context, msg client.Message) error {
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, s.cfg.Network, s.cfg.Address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	line := FormatRFC5424(s.facility, s.hostname, s.cfg.AppName, msg)
+	if s.cfg.Network == "tcp" {
+		// RFC 6587 octet counting
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := s.conn.Write([]byte(line)); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// FormatRFC5424 renders msg as
+// "<PRI>1 TIMESTAMP HOST APP - CHANNEL [hue@32473 type=".." id=".." path=".."] <path> <value>".
+func FormatRFC5424(facility int, hostname, appName string, msg client.Message) string {
+	ts := msg.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	msgID := msg.Channel
+	if msgID == "" {
+		msgID = "-"
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[" + sdID)
+	for _, p := range [][2]string{{"type", string(msg.Type)}, {"id", string(msg.ID)}, {"path", msg.Path}} {
+		if p[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&sd, ` %s="%s"`, p[0], sdEscaper.Replace(p[1]))
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s - %s %s %s %s",
+		facility*8+severityInfo, ts.UTC().Format(time.RFC3339Nano), hostname, appName, msgID, sd.String(), msg.Path, msg.Value)
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+func TestSyslog_UDP(t *testing.T) {
+	kern, local7 := 0, 23
+	tests := []struct {
+		name     string
+		facility *int
+		wantPRI  string
+	}{
+		{name: "default local0", wantPRI: "<134>1 "},
+		{name: "kern", facility: &kern, wantPRI: "<6>1 "},
+		{name: "local7", facility: &local7, wantPRI: "<190>1 "},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("ListenPacket() unexpected error: %v", err)
+			}
+			defer pc.Close()
+
+			s, err := NewSyslog(SyslogConfig{Address: pc.LocalAddr().String(), Facility: tt.facility, AppName: "test"}, nil)
+			if err != nil {
+				t.Fatalf("NewSyslog() unexpected error: %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go s.Run(ctx)
+
+			s.Write(client.Message{Path: "/light/kitchen/on", Value: "1", Type: "light", ID: "abc"})
+
+			buf := make([]byte, 2048)
+			_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("ReadFrom() unexpected error: %v", err)
+			}
+			line := string(buf[:n])
+			if !strings.HasPrefix(line, tt.wantPRI) {
+				t.Errorf("line = %q, want prefix %q", line, tt.wantPRI)
+			}
+			if !strings.Contains(line, ` test - `) || !strings.HasSuffix(line, `id="abc" path="/light/kitchen/on"] /light/kitchen/on 1`) {
+				t.Errorf("line = %q", line)
+			}
+		})
+	}
+}
+
+func TestNewSyslog_InvalidFacility(t *testing.T) {
+	facility := 24
+	if _, err := NewSyslog(SyslogConfig{Address: "127.0.0.1:514", Facility: &facility}, nil); err == nil {
+		t.Fatal("NewSyslog() expected error for facility 24")
+	}
+}
@@ -0,0 +1,157 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body when a secret is set.
+const SignatureHeader = "X-Hue-Gateway-Signature"
+
+type WebhookConfig struct {
+	URL string `mapstructure:"url"`
+
+	// Secret (optional) signs each body with HMAC-SHA256.
+	Secret string `mapstructure:"secret"`
+
+	// Timeout bounds each POST. Default 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Retries after the first failed attempt. Default 3; 0 disables retries.
+	Retries *int `mapstructure:"retries"`
+
+	// QueueSize is the outgoing message buffer. Default 256.
+	QueueSize int `mapstructure:"queue_size"`
+
+	// SchemaVersion pins the event JSON version (see SchemaVersion), so a
+	// gateway upgrade cannot change the payload under a consumer. Default: newest.
+	SchemaVersion int `mapstructure:"schema_version"`
+}
+
+// Webhook POSTs every message as JSON to a URL, e.g. for Node-RED or n8n.
+// It implements client.Sink.
+type Webhook struct {
+	cfg     WebhookConfig
+	retries int
+	client  *http.Client
+	ch      chan client.Message
+	log     *slog.Logger
+}
+
+func NewWebhook(cfg WebhookConfig, logger *slog.Logger) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook url required")
+	}
+	if err := validSchemaVersion(cfg.SchemaVersion); err != nil {
+		return nil, fmt.Errorf("webhook %s: %w", cfg.URL, err)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	retries := 3
+	if cfg.Retries != nil {
+		if *cfg.Retries < 0 {
+			return nil, fmt.Errorf("webhook %s: retries must not be negative", cfg.URL)
+		}
+		retries = *cfg.Retries
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Webhook{
+		cfg:     cfg,
+		retries: retries,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		ch:      make(chan client.Message, cfg.QueueSize),
+		log:     logger.With("module", "webhook", "url", cfg.URL),
+	}, nil
+}
+
+// Write enqueues msg; when the queue is full the message is dropped.
+func (w *Webhook) Write(msg client.Message) {
+	select {
+	case w.ch <- msg:
+	default:
+		w.log.Warn("webhook queue saturated; dropping message", "path", msg.Path)
+	}
+}
+
+// Run delivers queued messages until ctx is cancelled.
+func (w *Webhook) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-w.ch:
+			if err := w.deliver(ctx, msg); err != nil && ctx.Err() == nil {
+				w.log.Warn("dropping message after retries", "path", msg.Path, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (w *Webhook) deliver(ctx context.Context, msg client.Message) error {
+	body, err := Encode(msg, w.cfg.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	backoff := 250 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, body)
+		if err == nil || attempt >= w.retries {
+			return err
+		}
+		w.log.Debug("webhook post failed; retrying", "attempt", attempt+1, "error", err.Error(), "backoff", backoff.String())
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.cfg.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as sent in SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
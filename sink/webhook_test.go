@@ -0,0 +1,77 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/client"
+)
+
+func TestWebhook_RetriesAndSigns(t *testing.T) {
+	var calls atomic.Int32
+	got := make(chan client.Message, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if want := "sha256=" + Sign("s3cret", body); r.Header.Get(SignatureHeader) != want {
+			t.Errorf("signature = %q, want %q", r.Header.Get(SignatureHeader), want)
+		}
+		var msg client.Message
+		_ = json.Unmarshal(body, &msg)
+		got <- msg
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(WebhookConfig{URL: srv.URL, Secret: "s3cret"}, nil)
+	if err != nil {
+		t.Fatalf("NewWebhook() unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	w.Write(client.Message{Path: "/sensor/a/motion", Value: "1"})
+
+	select {
+	case msg := <-got:
+		if msg.Path != "/sensor/a/motion" || msg.Value != "1" {
+			t.Errorf("received %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestWebhook_ZeroRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	retries := 0
+	w, err := NewWebhook(WebhookConfig{URL: srv.URL, Retries: &retries}, nil)
+	if err != nil {
+		t.Fatalf("NewWebhook() unexpected error: %v", err)
+	}
+	if err := w.deliver(context.Background(), client.Message{Path: "/sensor/a/motion", Value: "1"}); err == nil {
+		t.Fatal("deliver() expected error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
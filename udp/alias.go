@@ -0,0 +1,86 @@
+package udp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// AliasConfig renames the verbs and domains of commands, so Loxone templates
+// made for other integrations work unchanged, e.g.
+// {"command_aliases": {"actions": {"bri": "dimmable", "switch": "on"},
+// "domains": {"light": "grouped_light"}, "default_domain": "grouped_light"}}.
+type AliasConfig struct {
+	Actions map[string]string `mapstructure:"actions"` // alias → action
+	Domains map[string]string `mapstructure:"domains"` // alias → domain
+
+	// DefaultDomain is used for commands without one: "/<id>/<action> <value>"
+	// (v1) or "set <id> <action>=<value>" (v2).
+	DefaultDomain string `mapstructure:"default_domain"`
+}
+
+// Aliases resolves AliasConfig; a nil Aliases changes nothing.
+type Aliases struct {
+	actions       map[string]string
+	domains       map[string]resource.Type
+	defaultDomain resource.Type
+}
+
+func NewAliases(cfg AliasConfig) (*Aliases, error) {
+	a := &Aliases{
+		actions:       make(map[string]string, len(cfg.Actions)),
+		domains:       make(map[string]resource.Type, len(cfg.Domains)),
+		defaultDomain: resource.Type(strings.ToLower(cfg.DefaultDomain)),
+	}
+	for alias, action := range cfg.Actions {
+		alias, action = strings.ToLower(alias), strings.ToLower(action)
+		if err := checkAlias(alias, action); err != nil {
+			return nil, fmt.Errorf("action alias %w", err)
+		}
+		a.actions[alias] = action
+	}
+	for alias, domain := range cfg.Domains {
+		alias, domain = strings.ToLower(alias), strings.ToLower(domain)
+		if err := checkAlias(alias, domain); err != nil {
+			return nil, fmt.Errorf("domain alias %w", err)
+		}
+		a.domains[alias] = resource.Type(domain)
+	}
+	if strings.ContainsAny(string(a.defaultDomain), "/ \t") {
+		return nil, fmt.Errorf("invalid default domain %q", cfg.DefaultDomain)
+	}
+	return a, nil
+}
+
+func checkAlias(alias, target string) error {
+	switch {
+	case alias == "" || target == "" || strings.ContainsAny(alias+target, "/ \t"):
+		return fmt.Errorf("%q → %q: expected single path segments", alias, target)
+	case alias == target:
+		return fmt.Errorf("%q points to itself", alias)
+	}
+	return nil
+}
+
+// fallback is the domain of commands without one, or "" if they are invalid.
+func (a *Aliases) fallback() resource.Type {
+	if a == nil {
+		return ""
+	}
+	return a.defaultDomain
+}
+
+// resolve replaces an aliased domain and action of cmd.
+func (a *Aliases) resolve(cmd Command) Command {
+	if a == nil {
+		return cmd
+	}
+	if d, ok := a.domains[strings.ToLower(string(cmd.Domain))]; ok {
+		cmd.Domain = d
+	}
+	if action, ok := a.actions[strings.ToLower(cmd.Action)]; ok {
+		cmd.Action = action
+	}
+	return cmd
+}
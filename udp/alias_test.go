@@ -0,0 +1,69 @@
+package udp
+
+import (
+	"testing"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+func TestAliases(t *testing.T) {
+	aliases, err := NewAliases(AliasConfig{
+		Actions:       map[string]string{"BRI": "dimmable", "switch": "on"},
+		Domains:       map[string]string{"light": "grouped_light"},
+		DefaultDomain: "grouped_light",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string
+		want Command
+	}{
+		{line: "/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/bri 40", want: Command{Domain: resource.TypeGroupedLight, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 40, Raw: "40"}}},
+		{line: "/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/switch 1", want: Command{Domain: resource.TypeGroupedLight, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}}},
+		{line: "/scene/5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0/on 1", want: Command{Domain: resource.TypeScene, ID: "5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseCommand(tt.line, aliases)
+		if err != nil || got != tt.want {
+			t.Errorf("parseCommand(%q) = %+v, %v; want %+v", tt.line, got, err, tt.want)
+		}
+	}
+	if _, err := parseCommand("/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/switch 1", nil); err == nil {
+		t.Error("parseCommand() without a default domain accepted a path without one")
+	}
+
+	_, cmds, err := parseV2("set 3f1f2b0e-8d3a-4c4e-9c55-0123456789ab bri=40 switch=1", aliases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Command{
+		{Domain: resource.TypeGroupedLight, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 40, Raw: "40"}},
+		{Domain: resource.TypeGroupedLight, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}},
+	}
+	if len(cmds) != 2 || cmds[0] != want[0] || cmds[1] != want[1] {
+		t.Errorf("parseV2() = %+v, want %+v", cmds, want)
+	}
+}
+
+func TestNewAliasesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AliasConfig
+	}{
+		{name: "slash", cfg: AliasConfig{Actions: map[string]string{"a/b": "on"}}},
+		{name: "empty target", cfg: AliasConfig{Domains: map[string]string{"light": ""}}},
+		{name: "self", cfg: AliasConfig{Actions: map[string]string{"On": "on"}}},
+		{name: "default domain", cfg: AliasConfig{DefaultDomain: "grouped light"}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewAliases(tt.cfg); err == nil {
+				t.Errorf("NewAliases(%+v) error = nil, want error", tt.cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+)
+
+// BindIP resolves s, an IP address or an interface name (e.g. "eth0.20"), to
+// the local IP UDP traffic should use, so multi-homed hosts send to Loxone from
+// the VLAN it expects. An interface resolves to its first IPv4 address, or its
+// first address when it has no IPv4 one. Empty s returns nil (any interface).
+func BindIP(s string) (net.IP, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return ip, nil
+	}
+	ifi, err := net.InterfaceByName(s)
+	if err != nil {
+		return nil, fmt.Errorf("bind %q: not an IP address or interface: %w", s, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("bind %q: %w", s, err)
+	}
+	var first net.IP
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if n.IP.To4() != nil {
+			return n.IP, nil
+		}
+		if first == nil {
+			first = n.IP
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("bind %q: interface has no IP address", s)
+	}
+	return first, nil
+}
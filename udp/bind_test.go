@@ -0,0 +1,39 @@
+package udp
+
+import (
+	"net"
+	"testing"
+)
+
+func TestBindIP(t *testing.T) {
+	t.Parallel()
+
+	if ip, err := BindIP(""); ip != nil || err != nil {
+		t.Errorf("BindIP(\"\") = %v, %v; want nil, nil", ip, err)
+	}
+	if ip, err := BindIP("192.168.20.5"); err != nil || !ip.Equal(net.ParseIP("192.168.20.5")) {
+		t.Errorf("BindIP(ip) = %v, %v", ip, err)
+	}
+	if _, err := BindIP("no-such-interface0"); err == nil {
+		t.Error("BindIP(unknown interface) error = nil, want error")
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+		ip, err := BindIP(ifi.Name)
+		if err != nil {
+			t.Fatalf("BindIP(%q) error = %v", ifi.Name, err)
+		}
+		if !ip.IsLoopback() {
+			t.Errorf("BindIP(%q) = %v, want a loopback address", ifi.Name, ip)
+		}
+		return
+	}
+	t.Skip("no loopback interface")
+}
@@ -3,9 +3,11 @@ package udp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"sync"
+	"sync/atomic"
 
 	"math/rand"
 	"syscall"
@@ -16,6 +18,10 @@ type ClientConfig struct {
 	// Remote is "<host>:<port>", e.g. "192.168.1.234:1234" (Loxone target).
 	Remote string
 
+	// LocalIP (optional) is the source address of the datagrams, for hosts with
+	// several interfaces (see BindIP). Default: chosen by the routing table.
+	LocalIP net.IP
+
 	// WriteTimeout bounds each UDP write. Default 1s.
 	WriteTimeout time.Duration
 
@@ -29,12 +35,22 @@ type ClientConfig struct {
 	// ResolveInterval re-resolves the remote each reconnect. Default: every reconnect.
 	ResolveInterval time.Duration
 
-	// Logger (optional). If nil, logs are disabled.
+	// MaxConnAge re-resolves and re-dials a connection older than this before
+	// the next write, since a connected socket can go stale without errors after
+	// a NAT or router reboot. 0 keeps the connection until a write fails.
+	MaxConnAge time.Duration
+
+	// Logger (optional). Defaults to slog.Default().
 	Logger *slog.Logger
+
+	// Active (optional) reports whether this instance may talk to Loxone; while it
+	// returns false (HA standby) messages are discarded.
+	Active func() bool
 }
 
 type Client struct {
 	cfg ClientConfig
+	log *slog.Logger
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -42,38 +58,95 @@ type Client struct {
 	mu        sync.RWMutex
 	conn      *net.UDPConn
 	remoteUDP *net.UDPAddr
+	dialed    time.Time // when conn was dialed
 
-	ch   chan []byte
-	wg   sync.WaitGroup
-	rand *rand.Rand
+	ch    chan []byte
+	ready chan struct{} // closed after the first dial attempt
+	wg    sync.WaitGroup
+	rand  *rand.Rand
 
 	// throttle hostname re-resolution
 	lastResolve time.Time
+	dialMu      sync.Mutex // serializes reconnects from the sender loop and SendCritical
+
+	delivered    atomic.Uint64
+	droppedQueue atomic.Uint64 // queue saturated
+	droppedSend  atomic.Uint64 // send retries exhausted or non-retryable error
+}
+
+// ClientStats counts messages since the client started.
+type ClientStats struct {
+	Delivered    uint64 `json:"delivered"`
+	DroppedQueue uint64 `json:"dropped_queue"` // the queue was full
+	DroppedSend  uint64 `json:"dropped_send"`  // the write kept failing
+}
+
+// Dropped returns all dropped messages.
+func (s ClientStats) Dropped() uint64 {
+	return s.DroppedQueue + s.DroppedSend
+}
+
+// Stats returns the delivery counters.
+func (c *Client) Stats() ClientStats {
+	return ClientStats{
+		Delivered:    c.delivered.Load(),
+		DroppedQueue: c.droppedQueue.Load(),
+		DroppedSend:  c.droppedSend.Load(),
+	}
 }
 
-func NewClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
+// NewClient starts a client sending to cfg.Remote; opts override cfg.
+func NewClient(ctx context.Context, cfg ClientConfig, opts ...ClientOption) (*Client, error) {
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.Remote == "" {
+		return nil, errors.New("udp client: Remote required")
+	}
 	cfg = withDefaults(cfg)
 	ctx, cancel := context.WithCancel(ctx)
 
 	c := &Client{
 		cfg:    cfg,
+		log:    cfg.Logger,
 		ctx:    ctx,
 		cancel: cancel,
 		ch:     make(chan []byte, cfg.QueueSize),
+		ready:  make(chan struct{}),
 		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 
-	// initial resolve + dial (non-fatal if it fails; the loop will retry)
-	if err := c.resolveAndDial(); err != nil {
-		slog.Warn("initial dial failed; will retry in background", "err", err)
-	}
-
 	c.wg.Add(1)
 	go c.runSender()
 
 	return c, nil
 }
 
+// Ready is closed once the client attempted its first dial, successful or not.
+func (c *Client) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// ClientOption adjusts a ClientConfig, for programs embedding the client.
+type ClientOption func(*ClientConfig)
+
+// WithLogger sets ClientConfig.Logger.
+func WithLogger(l *slog.Logger) ClientOption {
+	return func(cfg *ClientConfig) { cfg.Logger = l }
+}
+
+// WithBackoff sets ClientConfig.BaseBackoff and MaxBackoff.
+func WithBackoff(base, max time.Duration) ClientOption {
+	return func(cfg *ClientConfig) {
+		cfg.BaseBackoff = base
+		cfg.MaxBackoff = max
+	}
+}
+
+func (c *Client) isActive() bool {
+	return c.cfg.Active == nil || c.cfg.Active()
+}
+
 func (c *Client) Close() error {
 	c.cancel()
 	close(c.ch)
@@ -90,7 +163,7 @@ func (c *Client) Close() error {
 // Send enqueues a datagram to be sent. It never blocks longer than 1ms.
 // If the queue is full, it drops the oldest item (log + keep moving).
 func (c *Client) Send(b []byte) {
-	if b == nil {
+	if b == nil || !c.isActive() {
 		return
 	}
 	select {
@@ -99,20 +172,64 @@ func (c *Client) Send(b []byte) {
 		// drop oldest to keep recent signals flowing
 		select {
 		case <-c.ch:
+			c.droppedQueue.Add(1)
 		default:
 		}
 		select {
 		case c.ch <- append([]byte(nil), b...):
 		default:
 			// extremely congested; drop new one as well
-			slog.Warn("udp queue saturated; dropping message")
+			c.droppedQueue.Add(1)
+			c.log.Warn("udp queue saturated; dropping message")
 		}
 	}
 }
 
+// SendCritical writes b directly, bypassing the queue and its drop policy. It
+// retries until the datagram is written or ctx is done and reports the failure,
+// so alarm-grade signals are never dropped silently.
+func (c *Client) SendCritical(ctx context.Context, b []byte) error {
+	if b == nil || !c.isActive() {
+		return nil
+	}
+	c.redialIfOld()
+	backoff := c.cfg.BaseBackoff
+	for {
+		err := c.write(b)
+		if err == nil {
+			c.delivered.Add(1)
+			return nil
+		}
+		if !retryable(err) && c.isConnReady() {
+			c.droppedSend.Add(1)
+			return err
+		}
+		c.log.Debug("critical udp send failed; retrying", "err", err, "backoff", backoff.String())
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.droppedSend.Add(1)
+			return fmt.Errorf("critical send: %w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		_ = c.reconnect(backoff)
+		// no jitter here: c.rand belongs to the sender goroutine
+		backoff = min(backoff*2, c.cfg.MaxBackoff)
+	}
+}
+
 func (c *Client) runSender() {
 	defer c.wg.Done()
 
+	// initial resolve + dial off the caller's path, so a slow resolver does not
+	// block startup (non-fatal if it fails; the loop will retry)
+	if err := c.resolveAndDial(); err != nil {
+		c.log.Warn("initial dial failed; will retry in background", "err", err)
+	}
+	close(c.ready)
+
 	backoff := c.cfg.BaseBackoff
 
 	for {
@@ -124,11 +241,12 @@ func (c *Client) runSender() {
 				return
 			}
 
-			// ensure we have a connection
+			// ensure we have a fresh connection
+			c.redialIfOld()
 			if !c.isConnReady() {
 				if err := c.reconnect(backoff); err != nil {
 					backoff = c.nextBackoff(backoff)
-					slog.Warn("reconnect failed", "err", err, "backoff", backoff.String())
+					c.log.Warn("reconnect failed", "err", err, "backoff", backoff.String())
 					c.sleep(backoff)
 					// requeue attempt: we try send now; if it fails, message may drop after retries below
 				} else {
@@ -147,18 +265,21 @@ func (c *Client) runSender() {
 					break
 				}
 				if !retryable(err) {
-					slog.Warn("udp send non-retryable", "err", err)
+					c.log.Warn("udp send non-retryable", "err", err)
 					break
 				}
 				// retry: reconnect + backoff
-				slog.Debug("udp send failed; will reconnect and retry",
+				c.log.Debug("udp send failed; will reconnect and retry",
 					"attempt", attempt, "err", err, "backoff", backoff.String())
 				_ = c.reconnect(backoff) // error logged inside
 				c.sleep(backoff)
 				backoff = c.nextBackoff(backoff)
 			}
-			if !sent {
-				slog.Warn("dropping message after retries")
+			if sent {
+				c.delivered.Add(1)
+			} else {
+				c.droppedSend.Add(1)
+				c.log.Warn("dropping message after retries")
 			}
 		}
 	}
@@ -177,10 +298,13 @@ func (c *Client) write(b []byte) error {
 }
 
 func (c *Client) reconnect(wait time.Duration) error {
+	c.dialMu.Lock()
+	defer c.dialMu.Unlock()
+
 	// Always re-resolve (or at a minimum cadence)
 	if c.cfg.ResolveInterval == 0 || time.Since(c.lastResolve) >= c.cfg.ResolveInterval {
 		if err := c.resolve(); err != nil {
-			slog.Warn("resolve failed", "err", err)
+			c.log.Warn("resolve failed", "err", err)
 			return err
 		}
 		c.lastResolve = time.Now()
@@ -196,19 +320,50 @@ func (c *Client) reconnect(wait time.Duration) error {
 	c.mu.Unlock()
 
 	// dial
-	conn, err := net.DialUDP("udp", nil, remote)
+	var local *net.UDPAddr
+	if c.cfg.LocalIP != nil {
+		local = &net.UDPAddr{IP: c.cfg.LocalIP}
+	}
+	conn, err := net.DialUDP("udp", local, remote)
 	if err != nil {
 		return err
 	}
 
 	c.mu.Lock()
 	c.conn = conn
+	c.dialed = time.Now()
 	c.mu.Unlock()
 
-	slog.Info("udp connected", "remote", remote.String())
+	c.log.Info("udp connected", "remote", remote.String())
 	return nil
 }
 
+// redialIfOld replaces a connection older than MaxConnAge, re-resolving the
+// remote regardless of ResolveInterval. If resolving fails the old connection
+// is kept for another MaxConnAge.
+func (c *Client) redialIfOld() {
+	if c.cfg.MaxConnAge <= 0 {
+		return
+	}
+	c.mu.RLock()
+	age := time.Since(c.dialed)
+	old := c.conn != nil && age >= c.cfg.MaxConnAge
+	c.mu.RUnlock()
+	if !old {
+		return
+	}
+	c.log.Debug("udp connection reached max age; re-dialing", "age", age.Round(time.Second).String())
+	c.dialMu.Lock()
+	c.lastResolve = time.Time{}
+	c.dialMu.Unlock()
+	if err := c.reconnect(0); err != nil {
+		c.log.Warn("re-dial of aged udp connection failed", "err", err)
+		c.mu.Lock()
+		c.dialed = time.Now()
+		c.mu.Unlock()
+	}
+}
+
 func (c *Client) resolveAndDial() error {
 	if err := c.resolve(); err != nil {
 		return err
@@ -284,5 +439,8 @@ func withDefaults(cfg ClientConfig) ClientConfig {
 	if cfg.MaxBackoff <= 0 {
 		cfg.MaxBackoff = 10 * time.Second
 	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
 	return cfg
 }
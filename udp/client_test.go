@@ -0,0 +1,103 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClientSendCritical(t *testing.T) {
+	t.Parallel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	c, err := NewClient(context.Background(), ClientConfig{Remote: pc.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.SendCritical(ctx, []byte("/contact/abc/state 1")); err != nil {
+		t.Fatalf("SendCritical() unexpected error: %v", err)
+	}
+	if s := c.Stats(); s.Delivered != 1 || s.Dropped() != 0 {
+		t.Errorf("Stats() = %+v, want 1 delivered", s)
+	}
+
+	buf := make([]byte, 64)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() unexpected error: %v", err)
+	}
+	if got := string(buf[:n]); got != "/contact/abc/state 1" {
+		t.Errorf("received %q, want %q", got, "/contact/abc/state 1")
+	}
+}
+
+func TestClientMaxConnAge(t *testing.T) {
+	t.Parallel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	c, err := NewClient(context.Background(), ClientConfig{Remote: pc.LocalAddr().String(), MaxConnAge: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	defer c.Close()
+	<-c.Ready()
+
+	c.mu.RLock()
+	first, dialed := c.conn, c.dialed
+	c.mu.RUnlock()
+
+	time.Sleep(30 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.SendCritical(ctx, []byte("/light/abc/on 1")); err != nil {
+		t.Fatalf("SendCritical() unexpected error: %v", err)
+	}
+	c.mu.RLock()
+	second, redialed := c.conn, c.dialed
+	c.mu.RUnlock()
+	if second == first || !redialed.After(dialed) {
+		t.Error("connection older than MaxConnAge was not re-dialed")
+	}
+
+	buf := make([]byte, 64)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() unexpected error: %v", err)
+	}
+	if got := string(buf[:n]); got != "/light/abc/on 1" {
+		t.Errorf("received %q, want %q", got, "/light/abc/on 1")
+	}
+}
+
+func TestNewClient_Options(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewClient(context.Background(), ClientConfig{}); err == nil {
+		t.Fatal("NewClient() without Remote succeeded")
+	}
+	c, err := NewClient(context.Background(), ClientConfig{Remote: "127.0.0.1:9"}, WithBackoff(time.Millisecond, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.cfg.BaseBackoff != time.Millisecond || c.cfg.MaxBackoff != time.Second || c.log == nil {
+		t.Errorf("options not applied: %+v", c.cfg)
+	}
+}
@@ -0,0 +1,66 @@
+package udp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Boolean encodings for values sent to Loxone; which one fits depends on the
+// command recognition of the virtual input.
+const (
+	BoolDigits = "1/0"
+	BoolWords  = "true/false"
+	BoolOnOff  = "ON/OFF"
+)
+
+// Bools renders boolean values, globally and per channel (motion, state,
+// bridge_online, ...). A nil *Bools renders 1/0.
+type Bools struct {
+	def     [2]string // true, false
+	channel map[string][2]string
+}
+
+// NewBools parses the default encoding and per-channel overrides, each one of
+// BoolDigits, BoolWords or BoolOnOff (case-insensitive).
+func NewBools(def string, perChannel map[string]string) (*Bools, error) {
+	d, err := parseBool(def)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bools{def: d, channel: make(map[string][2]string, len(perChannel))}
+	for channel, enc := range perChannel {
+		e, err := parseBool(enc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", channel, err)
+		}
+		b.channel[channel] = e
+	}
+	return b, nil
+}
+
+func parseBool(enc string) ([2]string, error) {
+	switch strings.ToLower(strings.TrimSpace(enc)) {
+	case "", BoolDigits:
+		return [2]string{"1", "0"}, nil
+	case BoolWords:
+		return [2]string{"true", "false"}, nil
+	case strings.ToLower(BoolOnOff):
+		return [2]string{"ON", "OFF"}, nil
+	}
+	return [2]string{}, fmt.Errorf("invalid boolean encoding %q: expected %s, %s or %s", enc, BoolDigits, BoolWords, BoolOnOff)
+}
+
+// Format renders v for channel.
+func (b *Bools) Format(channel string, v bool) string {
+	enc := [2]string{"1", "0"}
+	if b != nil {
+		enc = b.def
+		if e, ok := b.channel[channel]; ok {
+			enc = e
+		}
+	}
+	if v {
+		return enc[0]
+	}
+	return enc[1]
+}
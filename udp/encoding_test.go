@@ -0,0 +1,36 @@
+package udp
+
+import "testing"
+
+func TestBools(t *testing.T) {
+	t.Parallel()
+
+	b, err := NewBools("true/false", map[string]string{"motion": "ON/OFF", "state": "1/0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		channel string
+		v       bool
+		want    string
+	}{
+		{"bridge_online", true, "true"},
+		{"bridge_online", false, "false"},
+		{"motion", true, "ON"},
+		{"motion", false, "OFF"},
+		{"state", true, "1"},
+	}
+	for _, tt := range tests {
+		if got := b.Format(tt.channel, tt.v); got != tt.want {
+			t.Errorf("Format(%s, %v) = %q, want %q", tt.channel, tt.v, got, tt.want)
+		}
+	}
+
+	var nilBools *Bools
+	if got := nilBools.Format("motion", true); got != "1" {
+		t.Errorf("nil Format() = %q, want 1", got)
+	}
+	if _, err := NewBools("yes/no", nil); err == nil {
+		t.Error("NewBools() accepted an unknown encoding")
+	}
+}
@@ -0,0 +1,109 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/samvdb/loxone-philips-hue/resource"
+)
+
+// Command grammars; the parser is selected per source (see ServerConfig.Grammars).
+const (
+	// GrammarV1 is the original path grammar: "/<domain>/<id>/<action> <value>".
+	GrammarV1 = "v1"
+	// GrammarV2 uses explicit verbs and named parameters:
+	//   set <domain>/<id> <param>=<value> [<param>=<value> ...]
+	//   get <domain>/<id>
+	GrammarV2 = "v2"
+)
+
+// v2 verbs
+const (
+	VerbSet = "set"
+	VerbGet = "get"
+)
+
+// parseV2 parses one v2 line. A set yields one Command per parameter; a get yields
+// a single Command whose Action is VerbGet. aliases (optional) rename the domain
+// and parameters before validation.
+func parseV2(line string, aliases *Aliases) (string, []Command, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("expected '<set|get> <domain>/<id> [param=value ...]'")
+	}
+	verb := strings.ToLower(parts[0])
+	if verb != VerbSet && verb != VerbGet {
+		return "", nil, fmt.Errorf("unsupported verb: %s", parts[0])
+	}
+	d, i, ok := strings.Cut(strings.Trim(parts[1], "/"), "/")
+	if !ok && aliases.fallback() != "" {
+		d, i = string(aliases.fallback()), d // "<id>"
+	}
+	if d == "" || i == "" || strings.Contains(i, "/") {
+		return "", nil, fmt.Errorf("bad target: %s", parts[1])
+	}
+	domain := aliases.resolve(Command{Domain: resource.Type(d)}).Domain
+	id, err := parseTarget(domain, i)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch verb {
+	case VerbGet:
+		if len(parts) != 2 {
+			return "", nil, fmt.Errorf("get takes no parameters")
+		}
+		probe := Command{Domain: domain, ID: id, Action: "on"}
+		if err := validateCommand(&probe, "1"); err != nil {
+			return "", nil, err
+		}
+		return verb, []Command{{Domain: domain, ID: id, Action: VerbGet}}, nil
+	case VerbSet:
+		if len(parts) == 2 {
+			return "", nil, fmt.Errorf("set needs at least one param=value")
+		}
+		cmds := make([]Command, 0, len(parts)-2)
+		var transition time.Duration
+		for _, p := range parts[2:] {
+			name, value, ok := strings.Cut(p, "=")
+			if !ok || name == "" {
+				return "", nil, fmt.Errorf("bad parameter %q: expected name=value", p)
+			}
+			// transition applies to every other parameter of the line
+			if strings.EqualFold(name, "transition") {
+				d, err := parseTransition(value)
+				if err != nil {
+					return "", nil, err
+				}
+				transition = d
+				continue
+			}
+			cmd := aliases.resolve(Command{Domain: domain, ID: id, Action: strings.ToLower(name)})
+			if err := validateCommand(&cmd, value); err != nil {
+				return "", nil, err
+			}
+			cmds = append(cmds, cmd)
+		}
+		if len(cmds) == 0 {
+			return "", nil, fmt.Errorf("set needs at least one param=value besides transition")
+		}
+		for i := range cmds {
+			cmds[i].Transition = transition
+		}
+		return verb, cmds, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported verb: %s", parts[0])
+	}
+}
+
+// grammarFor returns the grammar configured for the sender.
+func (s *Server) grammarFor(addr *net.UDPAddr) string {
+	if addr != nil {
+		if g, ok := s.grammars[addr.IP.String()]; ok {
+			return g
+		}
+	}
+	return s.grammar
+}
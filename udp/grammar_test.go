@@ -0,0 +1,108 @@
+package udp
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseV2(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		wantVerb      string
+		want          []Command
+		wantErrSubstr string
+	}{
+		{
+			name:     "set several params",
+			line:     "set grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab on=1 dimmable=75",
+			wantVerb: VerbSet,
+			want: []Command{
+				{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}},
+				{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 75, Raw: "75"}},
+			},
+		},
+		{
+			name:     "transition applies to all params",
+			line:     "set grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab on=1 transition=800ms dimmable=75",
+			wantVerb: VerbSet,
+			want: []Command{
+				{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}, Transition: 800 * time.Millisecond},
+				{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 75, Raw: "75"}, Transition: 800 * time.Millisecond},
+			},
+		},
+		{
+			name:     "leading slash and upper-case verb",
+			line:     "SET /scene/5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0 on=true",
+			wantVerb: VerbSet,
+			want:     []Command{{Domain: "scene", ID: "5c1e6a9d-2b7f-4a8e-b3c4-d5e6f7a8b9c0", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "true"}}},
+		},
+		{
+			name:     "get",
+			line:     "get grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
+			wantVerb: VerbGet,
+			want:     []Command{{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: VerbGet}},
+		},
+		{name: "set without params", line: "set grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", wantErrSubstr: "at least one"},
+		{name: "only transition", line: "set grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab transition=1s", wantErrSubstr: "at least one"},
+		{name: "get with params", line: "get grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab on=1", wantErrSubstr: "no parameters"},
+		{name: "bad param", line: "set grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab on", wantErrSubstr: "expected name=value"},
+		{name: "bad value", line: "set grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab dimmable=101", wantErrSubstr: "dimmable expects"},
+		{name: "bad target", line: "set grouped_light on=1", wantErrSubstr: "bad target"},
+		{name: "unknown domain", line: "get speaker/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", wantErrSubstr: "unsupported domain"},
+		{name: "unknown verb", line: "toggle grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", wantErrSubstr: "unsupported verb"},
+		{name: "v1 line", line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on 1", wantErrSubstr: "unsupported verb"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			verb, got, err := parseV2(tt.line, nil)
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("parseV2() error = %v, want to contain %q", err, tt.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseV2() unexpected error: %v", err)
+			}
+			if verb != tt.wantVerb {
+				t.Errorf("parseV2() verb = %q, want %q", verb, tt.wantVerb)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseV2() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerGrammarFor(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		ListenAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Handler:    &blockingHandler{},
+		Grammars:   map[string]string{"10.0.0.20": GrammarV2},
+	})
+	if err != nil {
+		t.Fatalf("NewServer() unexpected error: %v", err)
+	}
+	if got := s.grammarFor(&net.UDPAddr{IP: net.ParseIP("10.0.0.20")}); got != GrammarV2 {
+		t.Errorf("grammarFor(10.0.0.20) = %q, want %q", got, GrammarV2)
+	}
+	if got := s.grammarFor(&net.UDPAddr{IP: net.ParseIP("10.0.0.21")}); got != GrammarV1 {
+		t.Errorf("grammarFor(10.0.0.21) = %q, want %q", got, GrammarV1)
+	}
+
+	if _, err := NewServer(ServerConfig{
+		ListenAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Handler:    &blockingHandler{},
+		Grammar:    "v3",
+	}); err == nil {
+		t.Error("NewServer() expected error for unknown grammar")
+	}
+}
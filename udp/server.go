@@ -3,40 +3,150 @@ package udp
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/samvdb/loxone-philips-hue/capture"
+	"github.com/samvdb/loxone-philips-hue/resource"
 )
 
 type Server struct {
 	conn       *net.UDPConn
 	log        *slog.Logger
 	handle     CommandHandler
+	gateway    GatewayHandler
 	listenAddr *net.UDPAddr
 	readBuf    int
+	timeout    time.Duration
+	timeouts   map[string]time.Duration
+	grammar    string
+	grammars   map[string]string
+	reader     StateReader
+	active     func() bool
+	auth       Authorizer
+	workers    chan struct{} // semaphore bounding concurrent commands
+	capture    *capture.Writer
+	aliases    *Aliases
+
+	mu       sync.Mutex
+	inflight map[string]*inflightCommand // key: domain/id/action
+	lanes    map[string]*lane            // key: domain/id
+	queued   int                         // commands waiting in all lanes
+	wg       sync.WaitGroup
+}
+
+type inflightCommand struct {
+	cmd        Command
+	addr       *net.UDPAddr
+	ctx        context.Context
+	cancel     context.CancelFunc
+	queued     bool
+	superseded bool
+}
+
+// lane applies the commands for one resource in arrival order, so "on" followed
+// by "dimmable" cannot reach the bridge the other way round.
+type lane struct {
+	pending []*inflightCommand
 }
 
+// maxQueued bounds the commands waiting across all lanes; further commands are
+// dropped until the bridge catches up.
+const maxQueued = 256
+
 // CommandHandler receives parsed commands and should call Hue.
 type CommandHandler interface {
 	Apply(ctx context.Context, cmd Command) error
 }
 
+// GatewayHandler receives /gateway/... commands that administer the gateway itself
+// instead of the Hue bridge.
+type GatewayHandler interface {
+	HandleGateway(ctx context.Context, cmd GatewayCommand) error
+}
+
+// Authorizer decides whether a source may send a command; gateway commands are
+// checked as Command{Domain: "gateway", Action: <action>}.
+type Authorizer interface {
+	Authorize(src net.IP, cmd Command) error
+}
+
+// StateReader answers v2 "get" commands with "<path> <value>" lines.
+type StateReader interface {
+	Read(ctx context.Context, cmd Command) ([]string, error)
+}
+
+type GatewayCommand struct {
+	Action string // "resync" | "refresh_names" | "loglevel" | "vacation" | "night" | "pause" | "resume" | "allow"
+	Value  string // raw value, empty for resync/refresh_names; "<scope> [duration]" for pause
+}
+
 type Command struct {
-	Domain string // "light"
-	ID     string // hue resource id (UUID-ish for v2)
-	Action string // "on" | "dimmable"
-	Value  string // raw value e.g. "true", "75"
+	Domain resource.Type `json:"domain"` // "light"
+	ID     resource.ID   `json:"id"`     // hue resource id (UUID for v2)
+	Action string        `json:"action"` // "on" | "dimmable"
+	Value  Value         `json:"value"`  // parsed by action when received, see Kind
+
+	// Rtype is the resource type a raw command PUTs to; empty for other domains.
+	Rtype resource.Type `json:"rtype,omitempty"`
+
+	// Transition is the requested fade; 0 means use the configured default.
+	Transition time.Duration `json:"transition,omitempty"`
+}
+
+// Key identifies the target of cmd as "<domain>/<id>/<action>".
+func (c Command) Key() string {
+	return string(c.Domain) + "/" + string(c.ID) + "/" + c.Action
 }
 
 type ServerConfig struct {
 	ListenAddr *net.UDPAddr
 	Handler    CommandHandler
+	Gateway    GatewayHandler // optional; /gateway/... commands are rejected without it
 	Logger     *slog.Logger
 	ReadBuf    int // bytes, default 2k
+
+	// Timeout bounds each command. Default 5s.
+	Timeout time.Duration
+
+	// Timeouts overrides Timeout per "domain" or "domain/action"
+	// (e.g. "scene": 15s, "grouped_light/on": 1s); the most specific key wins.
+	Timeouts map[string]time.Duration
+
+	// Grammar is the command grammar (GrammarV1 or GrammarV2). Default GrammarV1.
+	Grammar string
+
+	// Grammars selects the grammar per source IP, overriding Grammar.
+	Grammars map[string]string
+
+	// Reader (optional) answers v2 "get" commands; they are rejected without it.
+	Reader StateReader
+
+	// Active (optional) reports whether this instance should act on commands; while
+	// it returns false (HA standby) every datagram is ignored.
+	Active func() bool
+
+	// Authorizer (optional) restricts what each source may control; it runs before
+	// the command is applied.
+	Authorizer Authorizer
+
+	// Workers bounds the commands applied at once; further commands wait (within
+	// their timeout). Default 16.
+	Workers int
+
+	// Capture (optional) records every inbound datagram.
+	Capture *capture.Writer
+
+	// Aliases (optional) rename command domains and actions, in every grammar.
+	Aliases *Aliases
 }
 
 func NewServer(cfg ServerConfig) (*Server, error) {
@@ -52,12 +162,39 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 	if cfg.Logger == nil {
 		cfg.Logger = slog.Default()
 	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Grammar == "" {
+		cfg.Grammar = GrammarV1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 16
+	}
+	for _, g := range append([]string{cfg.Grammar}, mapValues(cfg.Grammars)...) {
+		if g != GrammarV1 && g != GrammarV2 {
+			return nil, fmt.Errorf("unknown grammar %q", g)
+		}
+	}
 
 	return &Server{
 		listenAddr: cfg.ListenAddr,
 		log:        cfg.Logger.With("module", "udpserver", "addr", cfg.ListenAddr.String()),
 		handle:     cfg.Handler,
+		gateway:    cfg.Gateway,
 		readBuf:    cfg.ReadBuf,
+		timeout:    cfg.Timeout,
+		timeouts:   cfg.Timeouts,
+		inflight:   make(map[string]*inflightCommand),
+		lanes:      make(map[string]*lane),
+		grammar:    cfg.Grammar,
+		grammars:   cfg.Grammars,
+		reader:     cfg.Reader,
+		active:     cfg.Active,
+		auth:       cfg.Authorizer,
+		workers:    make(chan struct{}, cfg.Workers),
+		capture:    cfg.Capture,
+		aliases:    cfg.Aliases,
 	}, nil
 }
 
@@ -72,6 +209,7 @@ func (s *Server) Run(ctx context.Context) error {
 		return fmt.Errorf("listen UDP: %w", err)
 	}
 	s.conn = conn
+	defer s.wg.Wait()
 	s.log.Info("udp server started")
 	buf := make([]byte, s.readBuf)
 	for {
@@ -101,74 +239,487 @@ func (s *Server) Run(ctx context.Context) error {
 			return fmt.Errorf("read udp: %w", err)
 		}
 
+		s.capture.Write("udp", addr.String(), buf[:n])
 		line := string(bytes.TrimSpace(buf[:n]))
 		if line == "" {
 			continue
 		}
+		if s.active != nil && !s.active() {
+			s.log.Debug("standby; ignoring command", "from", addr.String(), "line", line)
+			continue
+		}
+
+		if strings.HasPrefix(line, gatewayPrefix) {
+			s.applyGateway(ctx, addr, line)
+			continue
+		}
 
-		cmd, perr := parseCommand(line)
+		if s.grammarFor(addr) == GrammarV2 {
+			s.applyV2(ctx, addr, line)
+			continue
+		}
+
+		var cmd Command
+		var perr error
+		if strings.HasPrefix(line, rawPrefix) {
+			cmd, perr = parseRawCommand(line)
+		} else {
+			cmd, perr = parseCommand(line, s.aliases)
+		}
 		if perr != nil {
 			s.log.Warn("invalid command", "from", addr.String(), "line", line, "error", perr.Error())
 			continue
 		}
 
-		// Handle in-line; UDP is cheap—if needed later, you can add a worker pool.
-		callCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		slog.Info("applying command", "domain", cmd.Domain, "action", cmd.Action, "id", cmd.ID, "value", cmd.Value)
-		err = s.handle.Apply(callCtx, cmd)
+		s.dispatch(ctx, addr, cmd)
+	}
+}
+
+// dispatch queues cmd on the lane of its resource. A command still queued or in
+// flight for the same resource and action is dropped or cancelled: only the newest
+// value matters.
+func (s *Server) dispatch(ctx context.Context, addr *net.UDPAddr, cmd Command) {
+	if !s.authorized(addr, cmd) {
+		return
+	}
+	key := cmd.Key()
+	laneKey := string(cmd.Domain) + "/" + string(cmd.ID)
+	callCtx, cancel := context.WithTimeout(WithSource(ctx, "udp:"+addr.IP.String()), s.timeoutFor(cmd))
+	self := &inflightCommand{cmd: cmd, addr: addr, ctx: callCtx, cancel: cancel, queued: true}
+
+	s.mu.Lock()
+	l := s.lanes[laneKey]
+	if prev := s.inflight[key]; prev != nil {
+		prev.superseded = true
+		prev.cancel()
+		if prev.queued && l != nil {
+			l.remove(prev)
+			s.queued--
+			s.log.Info("command superseded by a newer one", "cmd", fmt.Sprintf("%+v", prev.cmd))
+		}
+	}
+	if s.queued >= maxQueued {
+		s.mu.Unlock()
 		cancel()
+		s.log.Warn("command queue full; dropping command", "from", addr.String(), "cmd", fmt.Sprintf("%+v", cmd))
+		return
+	}
+	s.inflight[key] = self
+	s.queued++
+	if l != nil {
+		l.pending = append(l.pending, self)
+		s.mu.Unlock()
+		return
+	}
+	l = &lane{pending: []*inflightCommand{self}}
+	s.lanes[laneKey] = l
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.drain(laneKey, l)
+}
+
+// drain applies the commands of l one by one and retires the lane once it is empty.
+func (s *Server) drain(laneKey string, l *lane) {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		if len(l.pending) == 0 {
+			delete(s.lanes, laneKey)
+			s.mu.Unlock()
+			return
+		}
+		c := l.pending[0]
+		l.pending = l.pending[1:]
+		c.queued = false
+		s.queued--
+		s.mu.Unlock()
+
+		s.apply(c)
+	}
+}
+
+func (s *Server) apply(c *inflightCommand) {
+	defer c.cancel()
+	cmd := c.cmd
+
+	var err error
+	select {
+	case s.workers <- struct{}{}:
+		slog.Info("applying command", "domain", cmd.Domain, "action", cmd.Action, "id", cmd.ID, "value", cmd.Value)
+		err = s.handle.Apply(c.ctx, cmd)
+		<-s.workers
+	case <-c.ctx.Done():
+		err = fmt.Errorf("waiting for a worker: %w", c.ctx.Err())
+	}
+
+	key := cmd.Key()
+	s.mu.Lock()
+	superseded := c.superseded
+	if s.inflight[key] == c {
+		delete(s.inflight, key)
+	}
+	s.mu.Unlock()
+
+	switch {
+	case err != nil && superseded:
+		s.log.Info("command superseded by a newer one", "cmd", fmt.Sprintf("%+v", cmd))
+	case err != nil:
+		s.log.Error("apply failed", "cmd", fmt.Sprintf("%+v", cmd), "error", err.Error())
+	default:
+		s.log.Debug("command applied", "from", c.addr.String(), "cmd", fmt.Sprintf("%+v", cmd))
+	}
+}
+
+// remove drops c from the pending commands.
+func (l *lane) remove(c *inflightCommand) {
+	for i, p := range l.pending {
+		if p == c {
+			l.pending = append(l.pending[:i], l.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) authorized(addr *net.UDPAddr, cmd Command) bool {
+	if s.auth == nil {
+		return true
+	}
+	if err := s.auth.Authorize(addr.IP, cmd); err != nil {
+		s.log.Warn("command not authorized", "from", addr.String(), "cmd", fmt.Sprintf("%+v", cmd), "error", err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *Server) applyV2(ctx context.Context, addr *net.UDPAddr, line string) {
+	verb, cmds, err := parseV2(line, s.aliases)
+	if err != nil {
+		s.log.Warn("invalid command", "from", addr.String(), "line", line, "grammar", GrammarV2, "error", err.Error())
+		return
+	}
+	if verb == VerbGet {
+		s.answer(ctx, addr, cmds[0])
+		return
+	}
+	for _, cmd := range cmds {
+		s.dispatch(ctx, addr, cmd)
+	}
+}
+
+// answer replies to a v2 get with the current state, one datagram per line.
+func (s *Server) answer(ctx context.Context, addr *net.UDPAddr, cmd Command) {
+	if s.reader == nil {
+		s.log.Warn("get commands disabled", "from", addr.String(), "cmd", fmt.Sprintf("%+v", cmd))
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		callCtx, cancel := context.WithTimeout(ctx, s.timeoutFor(cmd))
+		defer cancel()
+
+		lines, err := s.reader.Read(callCtx, cmd)
 		if err != nil {
-			s.log.Error("apply failed", "cmd", fmt.Sprintf("%+v", cmd), "error", err.Error())
-			continue
+			s.log.Error("get failed", "cmd", fmt.Sprintf("%+v", cmd), "error", err.Error())
+			return
 		}
-		s.log.Debug("command applied", "from", addr.String(), "cmd", fmt.Sprintf("%+v", cmd))
+		for _, l := range lines {
+			if _, err := s.conn.WriteToUDP([]byte(l), addr); err != nil {
+				s.log.Warn("get reply failed", "to", addr.String(), "error", err.Error())
+				return
+			}
+		}
+	}()
+}
+
+func mapValues(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
+
+func (s *Server) timeoutFor(cmd Command) time.Duration {
+	if d, ok := s.timeouts[string(cmd.Domain)+"/"+cmd.Action]; ok && d > 0 {
+		return d
+	}
+	if d, ok := s.timeouts[string(cmd.Domain)]; ok && d > 0 {
+		return d
 	}
+	return s.timeout
+}
+
+func (s *Server) applyGateway(ctx context.Context, addr *net.UDPAddr, line string) {
+	cmd, err := parseGatewayCommand(line)
+	if err != nil {
+		s.log.Warn("invalid gateway command", "from", addr.String(), "line", line, "error", err.Error())
+		return
+	}
+	if s.gateway == nil {
+		s.log.Warn("gateway commands disabled", "from", addr.String(), "line", line)
+		return
+	}
+	if !s.authorized(addr, Command{Domain: "gateway", Action: cmd.Action, Value: RawValue(cmd.Value)}) {
+		return
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	s.log.Info("applying gateway command", "action", cmd.Action, "value", cmd.Value)
+	if err := s.gateway.HandleGateway(callCtx, cmd); err != nil {
+		s.log.Error("gateway command failed", "cmd", fmt.Sprintf("%+v", cmd), "error", err.Error())
+	}
+}
+
+const gatewayPrefix = "/gateway/"
+
+// /gateway/resync
+// /gateway/refresh_names
+// /gateway/loglevel debug
+// /gateway/vacation 1
+// /gateway/night 0
+// /gateway/pause /room/kitchen [30m]
+// /gateway/resume /room/kitchen
+// /gateway/allow kids_evening [30m]
+func parseGatewayCommand(line string) (GatewayCommand, error) {
+	parts := strings.Fields(line)
+	if len(parts) == 3 && (parts[0] == gatewayPrefix+"pause" || parts[0] == gatewayPrefix+"allow") {
+		action := strings.TrimPrefix(parts[0], gatewayPrefix)
+		if d, err := time.ParseDuration(parts[2]); err != nil || d <= 0 {
+			return GatewayCommand{}, fmt.Errorf("%s duration expects a positive duration, e.g. 30m", action)
+		}
+		return GatewayCommand{Action: action, Value: parts[1] + " " + parts[2]}, nil
+	}
+	if len(parts) == 0 || len(parts) > 2 {
+		return GatewayCommand{}, fmt.Errorf("expected '/gateway/<action> [value]'")
+	}
+	action := strings.TrimPrefix(parts[0], gatewayPrefix)
+	if action == "" || strings.Contains(action, "/") {
+		return GatewayCommand{}, fmt.Errorf("bad path: %s", parts[0])
+	}
+	cmd := GatewayCommand{Action: action}
+	if len(parts) == 2 {
+		cmd.Value = parts[1]
+	}
+
+	switch cmd.Action {
+	case "resync", "refresh_names":
+		if cmd.Value != "" {
+			return GatewayCommand{}, fmt.Errorf("%s takes no value", cmd.Action)
+		}
+	case "loglevel":
+		switch strings.ToLower(cmd.Value) {
+		case "debug", "info", "warn", "error":
+		default:
+			return GatewayCommand{}, fmt.Errorf("loglevel expects debug|info|warn|error")
+		}
+	case "vacation", "night":
+		v := strings.ToLower(cmd.Value)
+		if v != "true" && v != "false" && v != "1" && v != "0" {
+			return GatewayCommand{}, fmt.Errorf("%s expects true|false|1|0", cmd.Action)
+		}
+	case "pause", "resume":
+		if cmd.Value == "" {
+			return GatewayCommand{}, fmt.Errorf("%s expects a scope, e.g. /room/kitchen", cmd.Action)
+		}
+	case "allow":
+		if cmd.Value == "" {
+			return GatewayCommand{}, fmt.Errorf("allow expects a command window name")
+		}
+	default:
+		return GatewayCommand{}, fmt.Errorf("unsupported gateway action: %s", cmd.Action)
+	}
+	return cmd, nil
+}
+
+const rawPrefix = "/raw/"
+
+// DomainRaw commands PUT Value (JSON) to the resource Rtype/ID.
+const DomainRaw resource.Type = "raw"
+
+// NewRawCommand builds a raw CLIP v2 passthrough command.
+func NewRawCommand(rtype, id string, body []byte) (Command, error) {
+	t, err := resource.ParseType(rtype)
+	if err != nil {
+		return Command{}, fmt.Errorf("expected '/raw/<rtype>/<id>': %w", err)
+	}
+	rid, err := resource.ParseID(id)
+	if err != nil {
+		return Command{}, fmt.Errorf("expected '/raw/<rtype>/<id>': %w", err)
+	}
+	if !json.Valid(body) {
+		return Command{}, fmt.Errorf("raw body is not valid JSON")
+	}
+	return Command{Domain: DomainRaw, ID: rid, Rtype: t, Action: "put", Value: RawValue(string(body))}, nil
+}
+
+// parseTarget validates the id segment of a command: a resource id, or the name
+// of a composite channel.
+func parseTarget(domain resource.Type, s string) (resource.ID, error) {
+	if domain == DomainComposite {
+		if s == "" || strings.Contains(s, "/") {
+			return "", fmt.Errorf("invalid composite name %q", s)
+		}
+		return resource.ID(s), nil
+	}
+	return resource.ParseID(s)
+}
+
+// DomainAlarm commands drive a room or zone's lights as a visual siren, e.g. when
+// the Loxone alarm goes off:
+//
+//	/alarm/<room or zone id>/siren 1
+//
+// Arming Hue Secure is not part of the local bridge API.
+const DomainAlarm resource.Type = "alarm"
+
+// DomainComposite commands fan out to the members of a configured composite
+// channel, e.g. the grouped light and the plugs of a room:
+//
+//	/composite/<name>/dimmable 60
+const DomainComposite resource.Type = "composite"
+
+// domainActions lists the actions each domain takes:
+//
+//	/grouped_light/<id>/on true
+//	/composite/<name>/dimmable 60
+//	/scene/<id>/recall active   (or dynamic_palette, static)
+//	/light/<id>/color 0.675,0.322   or #ff8000
+//	/room/<id>/lights_on_except <light_id>[,<light_id>...]
+//	/alarm/<id>/siren 1
+var domainActions = map[resource.Type][]string{
+	resource.TypeGroupedLight: {"on", "dimmable"},
+	DomainComposite:           {"on", "dimmable"},
+	resource.TypeScene:        {"on", "recall"},
+	resource.TypeLight:        {"on", "dimmable", "brightness", "color_temp", "color"},
+	resource.TypeRoom:         {"lights_on_except", "lights_off_except"},
+	resource.TypeZone:         {"lights_on_except", "lights_off_except"},
+	DomainAlarm:               {"siren"},
+}
+
+// checkRawValue checks the values of actions that take raw values.
+func checkRawValue(cmd Command) error {
+	switch cmd.Action {
+	case "recall":
+		switch strings.ToLower(cmd.Value.Raw) {
+		case "active", "dynamic_palette", "static":
+			return nil
+		}
+		return fmt.Errorf("recall expects active|dynamic_palette|static")
+	case "lights_on_except", "lights_off_except":
+		if cmd.Value.Raw == "" {
+			return fmt.Errorf("%s expects a comma separated list of light ids", cmd.Action)
+		}
+	}
+	return nil
+}
+
+// /raw/<rtype>/<id> {"on":{"on":true}}
+func parseRawCommand(line string) (Command, error) {
+	path, body, ok := strings.Cut(line, " ")
+	if !ok {
+		return Command{}, fmt.Errorf("expected '/raw/<rtype>/<id> <json>'")
+	}
+	rtype, id, _ := strings.Cut(strings.TrimPrefix(path, rawPrefix), "/")
+	return NewRawCommand(rtype, id, []byte(strings.TrimSpace(body)))
 }
 
 // /grouped_light/<id>/on true
 // /grouped_light/<id>/dimmable 75
+// /grouped_light/<id>/dimmable 75 2s   (optional transition)
 // /scene/<id>/on true
-func parseCommand(line string) (Command, error) {
+// /scene/<id>/recall dynamic_palette
+// /light/<id>/color #ff8000
+//
+// aliases (optional) rename the domain and action before validation.
+func parseCommand(line string, aliases *Aliases) (Command, error) {
 	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return Command{}, fmt.Errorf("expected '<path> <value>'")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Command{}, fmt.Errorf("expected '<path> <value>' and an optional transition")
 	}
 	path, value := parts[0], parts[1]
 
 	segs := strings.Split(strings.Trim(path, " \t\r\n"), "/")
 	// ["", "light", "<id>", "on"]  or  ["", "light", "<id>", "dimmable"]
+	if d := aliases.fallback(); d != "" && len(segs) == 3 && segs[0] == "" {
+		segs = []string{"", string(d), segs[1], segs[2]} // "/<id>/<action>"
+	}
 	if len(segs) < 4 || segs[0] != "" {
 		return Command{}, fmt.Errorf("bad path: %s", path)
 	}
 
-	cmd := Command{
-		Domain: segs[1],
-		ID:     segs[2],
+	cmd := aliases.resolve(Command{
+		Domain: resource.Type(segs[1]),
 		Action: segs[3],
-		Value:  value,
+	})
+	if len(parts) == 3 {
+		d, err := parseTransition(parts[2])
+		if err != nil {
+			return Command{}, err
+		}
+		cmd.Transition = d
+	}
+	if err := validateCommand(&cmd, value); err != nil {
+		return Command{}, err
 	}
+	id, err := parseTarget(cmd.Domain, segs[2])
+	if err != nil {
+		return Command{}, err
+	}
+	cmd.ID = id
+	return cmd, nil
+}
 
-	// basic validation
-	switch cmd.Domain {
-	case "grouped_light":
-	case "scene":
-	default:
-		return Command{}, fmt.Errorf("unsupported domain: %s", cmd.Domain)
+// parseTransition accepts a Go duration ("800ms", "2s") or plain milliseconds.
+func parseTransition(s string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(s); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, nil
 	}
-	switch cmd.Action {
-	case "on":
-		v := strings.ToLower(cmd.Value)
-		if v != "true" && v != "false" && v != "1" && v != "0" {
-			return Command{}, fmt.Errorf("on expects true|false|1|0")
-		}
-	case "dimmable":
-		n, err := strconv.Atoi(cmd.Value)
-		if err != nil || n < 0 || n > 100 {
-			return Command{}, fmt.Errorf("dimmable expects 0..100")
-		}
-	default:
-		return Command{}, fmt.Errorf("unsupported action: %s", cmd.Action)
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid transition %q: expected e.g. 800ms, 2s or milliseconds", s)
 	}
+	return d, nil
+}
 
+// NewCommand builds a command for domain/id, validated like one received from
+// Loxone; transition may be empty.
+func NewCommand(domain, id, action, value, transition string) (Command, error) {
+	cmd := Command{Domain: resource.Type(domain), Action: action}
+	if transition != "" {
+		d, err := parseTransition(transition)
+		if err != nil {
+			return Command{}, err
+		}
+		cmd.Transition = d
+	}
+	if err := validateCommand(&cmd, value); err != nil {
+		return Command{}, err
+	}
+	rid, err := parseTarget(cmd.Domain, id)
+	if err != nil {
+		return Command{}, err
+	}
+	cmd.ID = rid
 	return cmd, nil
 }
+
+// validateCommand checks the domain and action of cmd, then parses value into
+// cmd.Value; shared by every grammar, so handlers get the value typed.
+func validateCommand(cmd *Command, value string) error {
+	actions, ok := domainActions[cmd.Domain]
+	switch {
+	case !ok:
+		return fmt.Errorf("unsupported domain: %s", cmd.Domain)
+	case !slices.Contains(actions, cmd.Action) && cmd.Domain == DomainAlarm:
+		return fmt.Errorf("unsupported alarm action: %s", cmd.Action)
+	case !slices.Contains(actions, cmd.Action):
+		return fmt.Errorf("unsupported action: %s", cmd.Action)
+	}
+	if err := cmd.parseValue(value); err != nil {
+		return err
+	}
+	return checkRawValue(*cmd)
+}
@@ -1,8 +1,14 @@
 package udp
 
 import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestParseCommand_Valid(t *testing.T) {
@@ -13,72 +19,72 @@ func TestParseCommand_Valid(t *testing.T) {
 	}{
 		{
 			name: "light on true",
-			line: "/grouped_light/abc-123/on true",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on true",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "on",
-				Value:  "true",
+				Value:  Value{Kind: KindBool, Bool: true, Raw: "true"},
 			},
 		},
 		{
 			name: "light on 1",
-			line: "/grouped_light/abc-123/on 1",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on 1",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "on",
-				Value:  "1",
+				Value:  Value{Kind: KindBool, Bool: true, Raw: "1"},
 			},
 		},
 		{
 			name: "light on 0",
-			line: "/grouped_light/abc-123/on 0",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on 0",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "on",
-				Value:  "0",
+				Value:  Value{Kind: KindBool, Raw: "0"},
 			},
 		},
 		{
 			name: "light dimmable mid value",
-			line: "/grouped_light/abc-123/dimmable 50",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable 50",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "dimmable",
-				Value:  "50",
+				Value:  Value{Kind: KindPercent, Percent: 50, Raw: "50"},
 			},
 		},
 		{
 			name: "light dimmable 0",
-			line: "/grouped_light/abc-123/dimmable 0",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable 0",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "dimmable",
-				Value:  "0",
+				Value:  Value{Kind: KindPercent, Raw: "0"},
 			},
 		},
 		{
 			name: "light dimmable 100",
-			line: "/grouped_light/abc-123/dimmable 100",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable 100",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "dimmable",
-				Value:  "100",
+				Value:  Value{Kind: KindPercent, Percent: 100, Raw: "100"},
 			},
 		},
 		{
 			name: "extra whitespace",
-			line: "   /grouped_light/abc-123/on   true   ",
+			line: "   /grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on   true   ",
 			want: Command{
-				Domain: "light",
-				ID:     "abc-123",
+				Domain: "grouped_light",
+				ID:     "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab",
 				Action: "on",
-				Value:  "true",
+				Value:  Value{Kind: KindBool, Bool: true, Raw: "true"},
 			},
 		},
 	}
@@ -88,7 +94,7 @@ func TestParseCommand_Valid(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := parseCommand(tt.line)
+			got, err := parseCommand(tt.line, nil)
 			if err != nil {
 				t.Fatalf("parseCommand() unexpected error: %v", err)
 			}
@@ -122,12 +128,12 @@ func TestParseCommand_Invalid(t *testing.T) {
 		},
 		{
 			name:          "missing value",
-			line:          "/grouped_light/abc-123/on",
+			line:          "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on",
 			wantErrSubstr: "expected '<path> <value>'",
 		},
 		{
 			name:          "bad path no leading slash",
-			line:          "light/abc-123/on true",
+			line:          "light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on true",
 			wantErrSubstr: "bad path",
 		},
 		{
@@ -137,32 +143,32 @@ func TestParseCommand_Invalid(t *testing.T) {
 		},
 		{
 			name:          "unsupported domain",
-			line:          "/sensor/abc-123/on true",
+			line:          "/sensor/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on true",
 			wantErrSubstr: "unsupported domain",
 		},
 		{
 			name:          "unsupported action",
-			line:          "/grouped_light/abc-123/blink true",
+			line:          "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/blink true",
 			wantErrSubstr: "unsupported action",
 		},
 		{
 			name:          "on invalid value string",
-			line:          "/grouped_light/abc-123/on maybe",
+			line:          "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on maybe",
 			wantErrSubstr: "on expects true|false|1|0",
 		},
 		{
 			name:          "dimmable non-numeric",
-			line:          "/grouped_light/abc-123/dimmable high",
+			line:          "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable high",
 			wantErrSubstr: "dimmable expects 0..100",
 		},
 		{
 			name:          "dimmable negative",
-			line:          "/grouped_light/abc-123/dimmable -1",
+			line:          "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable -1",
 			wantErrSubstr: "dimmable expects 0..100",
 		},
 		{
 			name:          "dimmable above 100",
-			line:          "/grouped_light/abc-123/dimmable 101",
+			line:          "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable 101",
 			wantErrSubstr: "dimmable expects 0..100",
 		},
 	}
@@ -172,7 +178,7 @@ func TestParseCommand_Invalid(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := parseCommand(tt.line)
+			_, err := parseCommand(tt.line, nil)
 			if err == nil {
 				t.Fatalf("parseCommand() expected error, got nil")
 			}
@@ -182,3 +188,363 @@ func TestParseCommand_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCommand_Extensions(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		want          Command
+		wantErrSubstr string
+	}{
+		{
+			name: "alarm siren",
+			line: "/alarm/0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d/siren 1",
+			want: Command{Domain: "alarm", ID: "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", Action: "siren", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}},
+		},
+		{
+			name: "composite dimmable",
+			line: "/composite/living_all/dimmable 60",
+			want: Command{Domain: "composite", ID: "living_all", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 60, Raw: "60"}},
+		},
+		{
+			name: "dimmable with transition",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable 50 2s",
+			want: Command{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 50, Raw: "50"}, Transition: 2 * time.Second},
+		},
+		{
+			name: "on with transition in ms",
+			line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/on 1 800",
+			want: Command{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}, Transition: 800 * time.Millisecond},
+		},
+		{name: "alarm siren bad value", line: "/alarm/0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d/siren loud", wantErrSubstr: "siren expects"},
+		{name: "composite bad dimmable", line: "/composite/living_all/dimmable 120", wantErrSubstr: "dimmable expects"},
+		{name: "alarm arm unsupported", line: "/alarm/0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d/arm 1", wantErrSubstr: "unsupported alarm action"},
+		{name: "invalid transition", line: "/grouped_light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab/dimmable 50 soon", wantErrSubstr: "invalid transition"},
+		{name: "id is not a uuid", line: "/grouped_light/kitchen/on 1", wantErrSubstr: "invalid resource id"},
+		{
+			name: "uppercase id",
+			line: "/grouped_light/3F1F2B0E-8D3A-4C4E-9C55-0123456789AB/on 1",
+			want: Command{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCommand(tt.line, nil)
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("parseCommand() error = %v, want to contain %q", err, tt.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCommand() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseCommand() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGatewayCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		want          GatewayCommand
+		wantErrSubstr string
+	}{
+		{name: "resync", line: "/gateway/resync", want: GatewayCommand{Action: "resync"}},
+		{name: "refresh names", line: "/gateway/refresh_names", want: GatewayCommand{Action: "refresh_names"}},
+		{name: "loglevel", line: "/gateway/loglevel debug", want: GatewayCommand{Action: "loglevel", Value: "debug"}},
+		{name: "vacation", line: "/gateway/vacation 1", want: GatewayCommand{Action: "vacation", Value: "1"}},
+		{name: "night", line: " /gateway/night false ", want: GatewayCommand{Action: "night", Value: "false"}},
+		{name: "pause", line: "/gateway/pause /room/kitchen", want: GatewayCommand{Action: "pause", Value: "/room/kitchen"}},
+		{name: "pause for", line: "/gateway/pause /device/hall_sensor 30m", want: GatewayCommand{Action: "pause", Value: "/device/hall_sensor 30m"}},
+		{name: "pause bad duration", line: "/gateway/pause /room/kitchen soon", wantErrSubstr: "pause duration"},
+		{name: "resume without scope", line: "/gateway/resume", wantErrSubstr: "resume expects a scope"},
+		{name: "allow", line: "/gateway/allow kids_evening", want: GatewayCommand{Action: "allow", Value: "kids_evening"}},
+		{name: "allow for", line: "/gateway/allow kids_evening 30m", want: GatewayCommand{Action: "allow", Value: "kids_evening 30m"}},
+		{name: "allow without window", line: "/gateway/allow", wantErrSubstr: "allow expects"},
+		{name: "resync with value", line: "/gateway/resync 1", wantErrSubstr: "takes no value"},
+		{name: "bad loglevel", line: "/gateway/loglevel loud", wantErrSubstr: "loglevel expects"},
+		{name: "bad mode value", line: "/gateway/night maybe", wantErrSubstr: "night expects"},
+		{name: "unknown action", line: "/gateway/reboot", wantErrSubstr: "unsupported gateway action"},
+		{name: "nested path", line: "/gateway/a/b 1", wantErrSubstr: "bad path"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseGatewayCommand(tt.line)
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("parseGatewayCommand() error = %v, want to contain %q", err, tt.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGatewayCommand() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseGatewayCommand() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRawCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		want          Command
+		wantErrSubstr string
+	}{
+		{
+			name: "light on",
+			line: `/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab {"on": {"on": true}}`,
+			want: Command{Domain: DomainRaw, ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Rtype: "light", Action: "put", Value: RawValue(`{"on": {"on": true}}`)},
+		},
+		{name: "missing body", line: "/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", wantErrSubstr: "expected"},
+		{name: "missing id", line: `/raw/light {"on":{"on":true}}`, wantErrSubstr: "expected"},
+		{name: "nested id", line: `/raw/light/a/b {}`, wantErrSubstr: "expected"},
+		{name: "invalid json", line: `/raw/light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab {"on":`, wantErrSubstr: "not valid JSON"},
+		{name: "id is not a uuid", line: `/raw/light/abc {}`, wantErrSubstr: "invalid resource id"},
+		{name: "bad rtype", line: `/raw/Light/3f1f2b0e-8d3a-4c4e-9c55-0123456789ab {}`, wantErrSubstr: "invalid resource type"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseRawCommand(tt.line)
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("parseRawCommand() error = %v, want to contain %q", err, tt.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRawCommand() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseRawCommand() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCommand(t *testing.T) {
+	tests := []struct {
+		name                             string
+		domain, id, action, value, trans string
+		want                             Command
+		wantErrSubstr                    string
+	}{
+		{
+			name: "dim with transition", domain: "grouped_light", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "dimmable", value: "40", trans: "2s",
+			want: Command{Domain: "grouped_light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 40, Raw: "40"}, Transition: 2 * time.Second},
+		},
+		{name: "scene", domain: "scene", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "on", value: "true", want: Command{Domain: "scene", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "true"}}},
+		{name: "scene recall", domain: "scene", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "recall", value: "dynamic_palette", want: Command{Domain: "scene", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "recall", Value: RawValue("dynamic_palette")}},
+		{name: "scene bad recall", domain: "scene", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "recall", value: "inactive", wantErrSubstr: "active|dynamic_palette|static"},
+		{name: "scene not dimmable", domain: "scene", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "dimmable", value: "50", wantErrSubstr: "unsupported action"},
+		{name: "bad value", domain: "grouped_light", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "dimmable", value: "400", wantErrSubstr: "0..100"},
+		{name: "bad transition", domain: "grouped_light", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "on", value: "1", trans: "soon", wantErrSubstr: "transition"},
+		{name: "light color", domain: "light", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "color", value: "#ff8000", want: Command{Domain: "light", ID: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", Action: "color", Value: Value{Kind: KindColor, RGB: RGB{R: 255, G: 128}, XY: RGB{R: 255, G: 128}.XY(), Raw: "#ff8000"}}},
+		{name: "light bad color_temp", domain: "light", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "color_temp", value: "2700", wantErrSubstr: "153..500"},
+		{name: "light unknown action", domain: "light", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "alert", value: "1", wantErrSubstr: "unsupported action"},
+		{name: "bad domain", domain: "speaker", id: "3f1f2b0e-8d3a-4c4e-9c55-0123456789ab", action: "on", value: "1", wantErrSubstr: "unsupported domain"},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewCommand(tt.domain, tt.id, tt.action, tt.value, tt.trans)
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("NewCommand() error = %v, want to contain %q", err, tt.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCommand() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NewCommand() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type blockingHandler struct {
+	started chan Command
+	done    chan error
+}
+
+func (h *blockingHandler) Apply(ctx context.Context, cmd Command) error {
+	h.started <- cmd
+	<-ctx.Done()
+	h.done <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestServerDispatch_CancelsSupersededCommand(t *testing.T) {
+	t.Parallel()
+
+	h := &blockingHandler{started: make(chan Command, 2), done: make(chan error, 2)}
+	s, err := NewServer(ServerConfig{
+		ListenAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Handler:    h,
+		Timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewServer() unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	s.dispatch(ctx, addr, Command{Domain: "grouped_light", ID: "g1", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 10, Raw: "10"}})
+	<-h.started
+	s.dispatch(ctx, addr, Command{Domain: "grouped_light", ID: "g1", Action: "dimmable", Value: Value{Kind: KindPercent, Percent: 90, Raw: "90"}})
+	<-h.started
+
+	if err := <-h.done; !errors.Is(err, context.Canceled) {
+		t.Errorf("first command ended with %v, want %v", err, context.Canceled)
+	}
+	cancel()
+	s.wg.Wait()
+}
+
+func TestServerDispatch_BoundsWorkers(t *testing.T) {
+	h := &blockingHandler{started: make(chan Command, 2), done: make(chan error, 2)}
+	s, err := NewServer(ServerConfig{
+		ListenAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Handler:    h,
+		Timeout:    time.Second,
+		Workers:    1,
+	})
+	if err != nil {
+		t.Fatalf("NewServer() unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	s.dispatch(ctx, addr, Command{Domain: "grouped_light", ID: "g1", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}})
+	<-h.started
+	s.dispatch(ctx, addr, Command{Domain: "grouped_light", ID: "g2", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}})
+	select {
+	case cmd := <-h.started:
+		t.Errorf("%s started while the only worker was busy", cmd.Key())
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	s.wg.Wait()
+}
+
+// orderHandler records applied commands; the first one signals started and
+// blocks until release is closed.
+type orderHandler struct {
+	mu      sync.Mutex
+	applied []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *orderHandler) Apply(ctx context.Context, cmd Command) error {
+	h.mu.Lock()
+	first := len(h.applied) == 0
+	h.applied = append(h.applied, string(cmd.Action)+"="+cmd.Value.Raw)
+	h.mu.Unlock()
+	if first {
+		close(h.started)
+		<-h.release
+	}
+	return nil
+}
+
+func TestServerDispatch_KeepsOrderPerResource(t *testing.T) {
+	t.Parallel()
+
+	h := &orderHandler{started: make(chan struct{}), release: make(chan struct{})}
+	s, err := NewServer(ServerConfig{
+		ListenAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Handler:    h,
+		Timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewServer() unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	s.dispatch(ctx, addr, Command{Domain: "grouped_light", ID: "g1", Action: "on", Value: Value{Kind: KindBool, Bool: true, Raw: "1"}})
+	<-h.started
+	for _, c := range [][2]string{{"dimmable", "10"}, {"ct", "300"}, {"dimmable", "90"}, {"on", "0"}} {
+		s.dispatch(ctx, addr, Command{Domain: "grouped_light", ID: "g1", Action: c[0], Value: RawValue(c[1])})
+	}
+	close(h.release)
+	s.wg.Wait()
+
+	// the queued dimmable=10 was replaced by dimmable=90, which keeps its own place
+	want := []string{"on=1", "ct=300", "dimmable=90", "on=0"}
+	if !reflect.DeepEqual(h.applied, want) {
+		t.Errorf("applied %v, want %v", h.applied, want)
+	}
+	if len(s.lanes) != 0 || s.queued != 0 {
+		t.Errorf("%d lanes and %d commands left after draining", len(s.lanes), s.queued)
+	}
+}
+
+func TestServerTimeoutFor(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		ListenAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		Handler:    &blockingHandler{},
+		Timeouts: map[string]time.Duration{
+			"scene":            15 * time.Second,
+			"grouped_light/on": time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewServer() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		cmd  Command
+		want time.Duration
+	}{
+		{cmd: Command{Domain: "scene", Action: "on"}, want: 15 * time.Second},
+		{cmd: Command{Domain: "grouped_light", Action: "on"}, want: time.Second},
+		{cmd: Command{Domain: "grouped_light", Action: "dimmable"}, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := s.timeoutFor(tt.cmd); got != tt.want {
+			t.Errorf("timeoutFor(%s/%s) = %v, want %v", tt.cmd.Domain, tt.cmd.Action, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommand_GroupExpansion(t *testing.T) {
+	got, err := parseCommand("/room/0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d/lights_on_except l1,l2", nil)
+	if err != nil {
+		t.Fatalf("parseCommand() unexpected error: %v", err)
+	}
+	want := Command{Domain: "room", ID: "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", Action: "lights_on_except", Value: RawValue("l1,l2")}
+	if got != want {
+		t.Errorf("parseCommand() = %+v, want %+v", got, want)
+	}
+	if _, err := parseCommand("/zone/z1/dimmable 50", nil); err == nil {
+		t.Error("parseCommand() expected error for zone dimmable")
+	}
+}
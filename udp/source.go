@@ -0,0 +1,17 @@
+package udp
+
+import "context"
+
+type sourceKey struct{}
+
+// WithSource tags ctx with where the command applied under it came from, e.g.
+// "udp:192.168.1.77", "api:10.0.0.5" or "failsafe".
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// Source returns the source set by WithSource, or "".
+func Source(ctx context.Context) string {
+	s, _ := ctx.Value(sourceKey{}).(string)
+	return s
+}
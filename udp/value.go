@@ -0,0 +1,239 @@
+package udp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of a command value; the action decides it (see Command.Kind).
+type Kind int
+
+const (
+	KindRaw      Kind = iota // passed on as sent, e.g. JSON or a list of ids
+	KindBool                 // true|false|1|0
+	KindPercent              // 0..100
+	KindMirek                // color temperature, 153..500
+	KindRGB                  // #rrggbb or r,g,b
+	KindDuration             // 800ms, 2s or milliseconds
+	KindColor                // CIE x,y (0..1) or an RGB color, see KindRGB
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBool:
+		return "bool"
+	case KindPercent:
+		return "percent"
+	case KindMirek:
+		return "mirek"
+	case KindRGB:
+		return "rgb"
+	case KindDuration:
+		return "duration"
+	case KindColor:
+		return "color"
+	}
+	return "raw"
+}
+
+// Mirek range of Hue color temperature lights.
+const (
+	MinMirek = 153
+	MaxMirek = 500
+)
+
+// Value is a command value, parsed once when the command is received: Kind
+// tells which of the typed fields is set. Raw is the value as sent whatever the
+// kind, for logs, the audit and raw commands.
+type Value struct {
+	Kind     Kind
+	Bool     bool
+	Percent  float64
+	Mirek    int
+	RGB      RGB // also set by KindColor when sent as RGB
+	XY       XY
+	Duration time.Duration
+	Raw      string
+}
+
+// RawValue returns s as a KindRaw value.
+func RawValue(s string) Value {
+	return Value{Kind: KindRaw, Raw: s}
+}
+
+// BoolValue returns b as a KindBool value.
+func BoolValue(b bool) Value {
+	return Value{Kind: KindBool, Bool: b, Raw: strconv.FormatBool(b)}
+}
+
+// PercentValue returns p (0..100) as a KindPercent value.
+func PercentValue(p float64) Value {
+	return Value{Kind: KindPercent, Percent: p, Raw: strconv.FormatFloat(p, 'f', -1, 64)}
+}
+
+// String returns the value as sent.
+func (v Value) String() string {
+	return v.Raw
+}
+
+// MarshalJSON encodes the value as sent, e.g. "75".
+func (v Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Raw)
+}
+
+type RGB struct {
+	R, G, B uint8
+}
+
+// XY is a point in the CIE 1931 color space, as the bridge takes colors.
+type XY struct {
+	X, Y float64
+}
+
+// XY converts c (sRGB) to CIE x,y using the wide gamut conversion Philips
+// recommends; black becomes the D65 white point.
+func (c RGB) XY() XY {
+	linear := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f > 0.04045 {
+			return math.Pow((f+0.055)/1.055, 2.4)
+		}
+		return f / 12.92
+	}
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	x := r*0.664511 + g*0.154324 + b*0.162028
+	y := r*0.283881 + g*0.668433 + b*0.047685
+	z := r*0.000088 + g*0.072310 + b*0.986039
+	sum := x + y + z
+	if sum == 0 {
+		return XY{X: 0.3127, Y: 0.3290}
+	}
+	return XY{X: x / sum, Y: y / sum}
+}
+
+// valueKinds is the action schema: the kind of value each action takes. A
+// "<domain>/<action>" key overrides the plain action; actions not listed take
+// raw values.
+var valueKinds = map[string]Kind{
+	"on":       KindBool,
+	"dimmable": KindPercent,
+	"siren":    KindBool,
+
+	// single lights
+	"brightness": KindPercent,
+	"color_temp": KindMirek,
+	"color":      KindColor,
+}
+
+// Kind returns the kind of value c's action takes.
+func (c Command) Kind() Kind {
+	if k, ok := valueKinds[string(c.Domain)+"/"+c.Action]; ok {
+		return k
+	}
+	return valueKinds[c.Action]
+}
+
+// parseValue sets c.Value to s parsed according to c's action, e.g. Bool for
+// "on"; the error names the action, e.g. "dimmable expects 0..100".
+func (c *Command) parseValue(s string) error {
+	v, err := ParseValue(c.Kind(), s)
+	if err != nil {
+		return fmt.Errorf("%s %w", c.Action, err)
+	}
+	c.Value = v
+	return nil
+}
+
+// ParseValue parses s as a value of kind k.
+func ParseValue(k Kind, s string) (Value, error) {
+	v := Value{Kind: k, Raw: s}
+	switch k {
+	case KindBool:
+		switch strings.ToLower(s) {
+		case "true", "1":
+			v.Bool = true
+		case "false", "0":
+		default:
+			return Value{}, errors.New("expects true|false|1|0")
+		}
+	case KindPercent:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(n >= 0 && n <= 100) { // also rejects NaN
+			return Value{}, errors.New("expects 0..100")
+		}
+		v.Percent = n
+	case KindMirek:
+		n, err := strconv.Atoi(s)
+		if err != nil || n < MinMirek || n > MaxMirek {
+			return Value{}, fmt.Errorf("expects %d..%d", MinMirek, MaxMirek)
+		}
+		v.Mirek = n
+	case KindRGB:
+		rgb, err := parseRGB(s)
+		if err != nil {
+			return Value{}, err
+		}
+		v.RGB = rgb
+	case KindColor:
+		if xy, ok := parseXY(s); ok {
+			v.XY = xy
+			break
+		}
+		rgb, err := parseRGB(s)
+		if err != nil {
+			return Value{}, errors.New("expects x,y, #rrggbb or r,g,b")
+		}
+		v.RGB, v.XY = rgb, rgb.XY()
+	case KindDuration:
+		d, err := parseTransition(s)
+		if err != nil {
+			return Value{}, errors.New("expects a duration, e.g. 800ms, 2s or milliseconds")
+		}
+		v.Duration = d
+	}
+	return v, nil
+}
+
+// parseRGB accepts "#rrggbb" or "r,g,b" with components 0..255.
+func parseRGB(s string) (RGB, error) {
+	errRGB := errors.New("expects #rrggbb or r,g,b")
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return RGB{}, errRGB
+		}
+		return RGB{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n)}, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return RGB{}, errRGB
+	}
+	var c [3]uint8
+	for i, p := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 8)
+		if err != nil {
+			return RGB{}, errRGB
+		}
+		c[i] = uint8(n)
+	}
+	return RGB{R: c[0], G: c[1], B: c[2]}, nil
+}
+
+// parseXY accepts "x,y" with both coordinates in 0..1.
+func parseXY(s string) (XY, bool) {
+	xs, ys, ok := strings.Cut(s, ",")
+	if !ok {
+		return XY{}, false
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if errX != nil || errY != nil || !(x >= 0 && x <= 1) || !(y >= 0 && y <= 1) {
+		return XY{}, false
+	}
+	return XY{X: x, Y: y}, true
+}
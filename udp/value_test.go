@@ -0,0 +1,92 @@
+package udp
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    Kind
+		in      string
+		want    Value
+		wantErr string
+	}{
+		{name: "bool true", kind: KindBool, in: "TRUE", want: Value{Kind: KindBool, Bool: true, Raw: "TRUE"}},
+		{name: "bool 0", kind: KindBool, in: "0", want: Value{Kind: KindBool, Raw: "0"}},
+		{name: "bool bad", kind: KindBool, in: "yes", wantErr: "expects true|false|1|0"},
+		{name: "percent", kind: KindPercent, in: "42.5", want: Value{Kind: KindPercent, Percent: 42.5, Raw: "42.5"}},
+		{name: "percent above 100", kind: KindPercent, in: "100.1", wantErr: "expects 0..100"},
+		{name: "percent NaN", kind: KindPercent, in: "NaN", wantErr: "expects 0..100"},
+		{name: "mirek", kind: KindMirek, in: "366", want: Value{Kind: KindMirek, Mirek: 366, Raw: "366"}},
+		{name: "mirek too cold", kind: KindMirek, in: "100", wantErr: "expects 153..500"},
+		{name: "rgb hex", kind: KindRGB, in: "#ff8000", want: Value{Kind: KindRGB, RGB: RGB{R: 255, G: 128}, Raw: "#ff8000"}},
+		{name: "rgb triple", kind: KindRGB, in: "10, 20,30", want: Value{Kind: KindRGB, RGB: RGB{R: 10, G: 20, B: 30}, Raw: "10, 20,30"}},
+		{name: "rgb out of range", kind: KindRGB, in: "256,0,0", wantErr: "expects #rrggbb or r,g,b"},
+		{name: "rgb short hex", kind: KindRGB, in: "#fff", wantErr: "expects #rrggbb or r,g,b"},
+		{name: "color xy", kind: KindColor, in: "0.675, 0.322", want: Value{Kind: KindColor, XY: XY{X: 0.675, Y: 0.322}, Raw: "0.675, 0.322"}},
+		{name: "color black", kind: KindColor, in: "#000000", want: Value{Kind: KindColor, XY: XY{X: 0.3127, Y: 0.3290}, Raw: "#000000"}},
+		{name: "color out of range", kind: KindColor, in: "1.2,0.3", wantErr: "expects x,y, #rrggbb or r,g,b"},
+		{name: "duration", kind: KindDuration, in: "1.5s", want: Value{Kind: KindDuration, Duration: 1500 * time.Millisecond, Raw: "1.5s"}},
+		{name: "duration ms", kind: KindDuration, in: "250", want: Value{Kind: KindDuration, Duration: 250 * time.Millisecond, Raw: "250"}},
+		{name: "duration bad", kind: KindDuration, in: "soon", wantErr: "expects a duration"},
+		{name: "raw", kind: KindRaw, in: "l1,l2", want: Value{Raw: "l1,l2"}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseValue(tt.kind, tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseValue(%s, %q) error = %v, want %q", tt.kind, tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseValue(%s, %q) = %+v, %v; want %+v", tt.kind, tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandParseValue(t *testing.T) {
+	t.Parallel()
+
+	siren := Command{Domain: DomainAlarm, Action: "siren"}
+	if err := siren.parseValue("1"); err != nil || siren.Value != (Value{Kind: KindBool, Bool: true, Raw: "1"}) {
+		t.Errorf("siren parseValue() = %+v, %v", siren.Value, err)
+	}
+	dim := Command{Action: "dimmable"}
+	if err := dim.parseValue("150"); err == nil || err.Error() != "dimmable expects 0..100" {
+		t.Errorf("dimmable parseValue() error = %v", err)
+	}
+	if k := (Command{Domain: "room", Action: "lights_on_except"}).Kind(); k != KindRaw {
+		t.Errorf("lights_on_except Kind() = %s, want raw", k)
+	}
+}
+
+func TestRGBXY(t *testing.T) {
+	tests := []struct {
+		name string
+		in   RGB
+		want XY
+	}{
+		{name: "red", in: RGB{R: 255}, want: XY{X: 0.7006, Y: 0.2993}},
+		{name: "white", in: RGB{R: 255, G: 255, B: 255}, want: XY{X: 0.3227, Y: 0.3290}},
+		{name: "orange", in: RGB{R: 255, G: 128}, want: XY{X: 0.6112, Y: 0.3750}},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := tt.in.XY()
+			if math.Abs(got.X-tt.want.X) > 1e-3 || math.Abs(got.Y-tt.want.Y) > 1e-3 {
+				t.Errorf("%+v.XY() = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Package version holds the build information, set at link time:
+//
+//	go build -ldflags "-X github.com/samvdb/loxone-philips-hue/version.Version=1.2.3 \
+//	  -X github.com/samvdb/loxone-philips-hue/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/samvdb/loxone-philips-hue/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build information. Commit and date fall back to the VCS stamp
+// Go embeds in binaries built from a checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Date == "":
+				info.Date = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// String is a one-line summary, e.g. "1.2.3 (abc1234, 2025-01-02T03:04:05Z, go1.25.0 linux/arm64)".
+func (i Info) String() string {
+	s := i.Version + " ("
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		s += commit + ", "
+	}
+	if i.Date != "" {
+		s += i.Date + ", "
+	}
+	return s + i.GoVersion + " " + i.Platform + ")"
+}
@@ -0,0 +1,31 @@
+package version
+
+import "testing"
+
+func TestInfoString(t *testing.T) {
+	tests := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{
+			name: "full",
+			info: Info{Version: "1.2.3", Commit: "abcdef123456", Date: "2025-01-02T03:04:05Z", GoVersion: "go1.25.0", Platform: "linux/arm64"},
+			want: "1.2.3 (abcdef1, 2025-01-02T03:04:05Z, go1.25.0 linux/arm64)",
+		},
+		{
+			name: "dev",
+			info: Info{Version: "dev", GoVersion: "go1.25.0", Platform: "linux/amd64"},
+			want: "dev (go1.25.0 linux/amd64)",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range var
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.info.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}